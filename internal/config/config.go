@@ -24,10 +24,12 @@ type Config struct {
 	RTSPURL           string
 	OutputDir         string
 	Timeout           time.Duration
-	Verbose           bool      // Deprecated: use LogLevel instead, kept for backward compatibility
-	LogLevel          string    // Log level: error, warn, info, debug (default: info)
+	Verbose           bool   // Deprecated: use LogLevel instead, kept for backward compatibility
+	LogLevel          string // Log level: error, warn, info, debug (default: info)
 	SaveJPEG          bool
-	ContinuousDecoder bool // Enable continuous decoder session (can decode P-frames)
+	ContinuousDecoder bool   // Enable continuous decoder session (can decode P-frames)
+	RTPDumpFile       string // Record received RTP/RTCP to this rtpdump file while streaming
+	TranscodeAudio    bool   // Store G.711 audio as 16-bit PCM WAV instead of μ-law/A-law
 }
 
 // yamlConfig represents the YAML configuration structure
@@ -35,10 +37,12 @@ type yamlConfig struct {
 	RTSPURL           string `yaml:"rtsp_url"`
 	OutputDir         string `yaml:"output_dir"`
 	Timeout           string `yaml:"timeout"`
-	Verbose           bool   `yaml:"verbose"`             // Deprecated: use log_level instead
-	LogLevel          string `yaml:"log_level"`           // Log level: error, warn, info, debug
+	Verbose           bool   `yaml:"verbose"`   // Deprecated: use log_level instead
+	LogLevel          string `yaml:"log_level"` // Log level: error, warn, info, debug
 	SaveJPEG          bool   `yaml:"save_jpeg"`
 	ContinuousDecoder bool   `yaml:"continuous_decoder"`
+	RTPDumpFile       string `yaml:"rtpdump_file"`
+	TranscodeAudio    bool   `yaml:"transcode_audio"`
 }
 
 // LoadFromYAML loads configuration from a YAML file
@@ -59,7 +63,8 @@ func LoadFromYAML(filePath string) (*Config, map[string]bool, error) {
 	for key := range rawMap {
 		// Convert YAML key to our internal key names
 		switch key {
-		case "rtsp_url", "output_dir", "timeout", "verbose", "log_level", "save_jpeg", "continuous_decoder":
+		case "rtsp_url", "output_dir", "timeout", "verbose", "log_level", "save_jpeg", "continuous_decoder", "rtpdump_file",
+			"transcode_audio":
 			present[key] = true
 		}
 	}
@@ -78,6 +83,8 @@ func LoadFromYAML(filePath string) (*Config, map[string]bool, error) {
 		LogLevel:          yamlCfg.LogLevel,
 		SaveJPEG:          yamlCfg.SaveJPEG,
 		ContinuousDecoder: yamlCfg.ContinuousDecoder,
+		RTPDumpFile:       yamlCfg.RTPDumpFile,
+		TranscodeAudio:    yamlCfg.TranscodeAudio,
 	}
 
 	// Parse timeout duration from string
@@ -140,6 +147,12 @@ func (c *Config) merge(other *Config, present map[string]bool) {
 	if present["continuous_decoder"] {
 		c.ContinuousDecoder = other.ContinuousDecoder
 	}
+	if present["rtpdump_file"] && other.RTPDumpFile != "" {
+		c.RTPDumpFile = other.RTPDumpFile
+	}
+	if present["transcode_audio"] {
+		c.TranscodeAudio = other.TranscodeAudio
+	}
 }
 
 // isYAMLFile checks if the given path is a YAML file
@@ -198,6 +211,8 @@ func ParseFlags() (*Config, error) {
 	var flagLogLevel string
 	var flagSaveJPEG bool
 	var flagContinuousDecoder bool
+	var flagRTPDumpFile string
+	var flagTranscodeAudio bool
 
 	flag.StringVar(&flagURL, "url", "", "RTSP stream URL (required)")
 	flag.StringVar(&flagOutputDir, "output", "", "Output directory for frames")
@@ -206,6 +221,8 @@ func ParseFlags() (*Config, error) {
 	flag.StringVar(&flagLogLevel, "log-level", "", "Log level: error, warn, info, debug (default: info)")
 	flag.BoolVar(&flagSaveJPEG, "jpeg", false, "Save frames as JPEG images (requires ffmpeg)")
 	flag.BoolVar(&flagContinuousDecoder, "continuous-decoder", false, "Use continuous decoder session (can decode P-frames, default: true). Set to false for frame-by-frame mode (keyframes only)")
+	flag.StringVar(&flagRTPDumpFile, "rtpdump", "", "Record received RTP/RTCP to this rtpdump file")
+	flag.BoolVar(&flagTranscodeAudio, "transcode-audio", false, "Store G.711 (PCMU/PCMA) audio as 16-bit PCM WAV instead of μ-law/A-law")
 
 	// Temporarily replace os.Args to exclude the YAML file path
 	oldArgs := os.Args
@@ -253,6 +270,12 @@ func ParseFlags() (*Config, error) {
 	if flagSet["continuous-decoder"] {
 		config.ContinuousDecoder = flagContinuousDecoder
 	}
+	if flagRTPDumpFile != "" {
+		config.RTPDumpFile = flagRTPDumpFile
+	}
+	if flagSet["transcode-audio"] {
+		config.TranscodeAudio = flagTranscodeAudio
+	}
 
 	// Re-apply defaults for any values that are still empty/zero
 	config.setDefaults()
@@ -296,11 +319,11 @@ func (c *Config) GetLogLevel() logger.Level {
 	if c.Verbose && c.LogLevel == "" {
 		return logger.LevelDebug
 	}
-	
+
 	if c.LogLevel == "" {
 		return logger.LevelInfo
 	}
-	
+
 	level, err := logger.ParseLevel(c.LogLevel)
 	if err != nil {
 		// Default to info if parsing fails
@@ -319,7 +342,7 @@ func (c *Config) String() string {
 			logLevel = "info"
 		}
 	}
-	return fmt.Sprintf(
+	result := fmt.Sprintf(
 		"Configuration:\n  RTSP URL: %s\n  Output Dir: %s\n  Timeout: %v\n  Log Level: %s\n  Save JPEG: %t\n  Continuous Decoder: %t",
 		c.RTSPURL,
 		c.OutputDir,
@@ -328,4 +351,11 @@ func (c *Config) String() string {
 		c.SaveJPEG,
 		c.ContinuousDecoder,
 	)
+	if c.RTPDumpFile != "" {
+		result += fmt.Sprintf("\n  RTPDump File: %s", c.RTPDumpFile)
+	}
+	if c.TranscodeAudio {
+		result += "\n  Transcode Audio: true"
+	}
+	return result
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -100,3 +102,27 @@ func TestConfig_String(t *testing.T) {
 	assert.Contains(t, result, "true")
 	assert.Contains(t, result, "Save JPEG")
 }
+
+func TestConfig_RTPDump(t *testing.T) {
+	config := &Config{RTSPURL: "rtsp://example.com/stream", RTPDumpFile: "camera.rtp"}
+	require.NoError(t, config.Validate())
+	assert.Contains(t, config.String(), "RTPDump File: camera.rtp")
+
+	assert.NotContains(t, (&Config{RTSPURL: "rtsp://example.com/stream"}).String(), "RTPDump")
+}
+
+func TestConfig_TranscodeAudio(t *testing.T) {
+	config := &Config{RTSPURL: "rtsp://example.com/stream", TranscodeAudio: true}
+	assert.Contains(t, config.String(), "Transcode Audio: true")
+	assert.NotContains(t, (&Config{RTSPURL: "rtsp://example.com/stream"}).String(), "Transcode Audio")
+
+	path := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(path, []byte("rtsp_url: rtsp://example.com/stream\ntranscode_audio: true\n"), 0644))
+	loaded, present, err := LoadFromYAML(path)
+	require.NoError(t, err)
+
+	merged := &Config{}
+	merged.setDefaults()
+	merged.merge(loaded, present)
+	assert.True(t, merged.TranscodeAudio)
+}
@@ -0,0 +1,502 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// aacDefaultFrameLength is the number of samples in an AAC access unit
+	aacDefaultFrameLength = 1024
+
+	// adtsHeaderSize is the size of an ADTS header without CRC
+	adtsHeaderSize = 7
+	// adtsMaxFrameSize is the largest frame the 13-bit ADTS length field can describe
+	adtsMaxFrameSize = 1<<13 - 1
+)
+
+var (
+	// ErrInvalidAudioConfig indicates an AudioSpecificConfig or mpeg4-generic fmtp that cannot be used
+	ErrInvalidAudioConfig = errors.New("invalid audio config")
+	// ErrADTSUnsupported indicates an AAC configuration that ADTS headers cannot express
+	ErrADTSUnsupported = errors.New("configuration not representable in ADTS")
+)
+
+// aacSampleRates maps the samplingFrequencyIndex of ISO/IEC 14496-3 to Hz
+var aacSampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// AudioSpecificConfig is the MPEG-4 audio decoder configuration (ISO/IEC 14496-3 1.6.2.1)
+type AudioSpecificConfig struct {
+	ObjectType     int // Audio object type, e.g. 2 for AAC-LC
+	FrequencyIndex int // samplingFrequencyIndex; 15 when SampleRate is explicit
+	SampleRate     int
+	ChannelConfig  int
+	FrameLength    int // Samples per access unit: 1024, or 960 when frameLengthFlag is set
+}
+
+// ParseAudioSpecificConfig parses an AudioSpecificConfig, e.g. the config= fmtp parameter.
+// For SBR/PS configurations the core AAC object type and sample rate are returned.
+func ParseAudioSpecificConfig(data []byte) (*AudioSpecificConfig, error) {
+	r := newBitReader(data)
+	config := &AudioSpecificConfig{FrameLength: aacDefaultFrameLength}
+
+	objectType, err := readAudioObjectType(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudioConfig, err)
+	}
+	if config.FrequencyIndex, config.SampleRate, err = readSamplingFrequency(r); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudioConfig, err)
+	}
+	channels, err := r.readBits(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAudioConfig, err)
+	}
+	config.ChannelConfig = int(channels)
+
+	// Explicit SBR (5) and PS (29) signal the extension rate, then the core object type
+	if objectType == 5 || objectType == 29 {
+		if _, _, err := readSamplingFrequency(r); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAudioConfig, err)
+		}
+		if objectType, err = readAudioObjectType(r); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidAudioConfig, err)
+		}
+	}
+	config.ObjectType = objectType
+
+	// GASpecificConfig starts with frameLengthFlag
+	if isGAObjectType(objectType) {
+		if short, err := r.readFlag(); err == nil && short {
+			config.FrameLength = 960
+		}
+	}
+
+	if config.SampleRate == 0 {
+		return nil, fmt.Errorf("%w: reserved sampling frequency index %d", ErrInvalidAudioConfig, config.FrequencyIndex)
+	}
+	return config, nil
+}
+
+// readAudioObjectType reads a 5-bit object type with its 6-bit escape
+func readAudioObjectType(r *bitReader) (int, error) {
+	objectType, err := r.readBits(5)
+	if err != nil {
+		return 0, err
+	}
+	if objectType == 31 {
+		ext, err := r.readBits(6)
+		if err != nil {
+			return 0, err
+		}
+		objectType = 32 + ext
+	}
+	return int(objectType), nil
+}
+
+// readSamplingFrequency reads a samplingFrequencyIndex and the explicit 24-bit rate it may escape to
+func readSamplingFrequency(r *bitReader) (index, rate int, err error) {
+	value, err := r.readBits(4)
+	if err != nil {
+		return 0, 0, err
+	}
+	index = int(value)
+	if index == 15 {
+		value, err = r.readBits(24)
+		return index, int(value), err
+	}
+	if index < len(aacSampleRates) {
+		rate = aacSampleRates[index]
+	}
+	return index, rate, nil
+}
+
+// Bytes encodes the configuration as an AudioSpecificConfig, e.g. for an MP4 esds box
+func (c *AudioSpecificConfig) Bytes() []byte {
+	var acc uint64
+	var size int
+	put := func(value uint32, bits int) {
+		acc = acc<<bits | uint64(value)&(1<<bits-1)
+		size += bits
+	}
+
+	if c.ObjectType >= 31 {
+		put(31, 5)
+		put(uint32(c.ObjectType-32), 6)
+	} else {
+		put(uint32(c.ObjectType), 5)
+	}
+	put(uint32(c.FrequencyIndex), 4)
+	if c.FrequencyIndex == 15 {
+		put(uint32(c.SampleRate), 24)
+	}
+	put(uint32(c.ChannelConfig), 4)
+	if isGAObjectType(c.ObjectType) {
+		frameLengthFlag := uint32(0)
+		if c.FrameLength == 960 {
+			frameLengthFlag = 1
+		}
+		put(frameLengthFlag, 1)
+		put(0, 2) // dependsOnCoreCoder, extensionFlag
+	}
+
+	padding := (8 - size%8) % 8
+	acc <<= padding
+	size += padding
+	data := make([]byte, size/8)
+	for i := range data {
+		data[i] = byte(acc >> (8 * (len(data) - 1 - i)))
+	}
+	return data
+}
+
+// isGAObjectType reports whether an object type is followed by a GASpecificConfig
+func isGAObjectType(objectType int) bool {
+	switch objectType {
+	case 1, 2, 3, 4, 6, 7, 17, 19, 20, 21, 22, 23:
+		return true
+	}
+	return false
+}
+
+// ADTSHeader returns the 7-byte ADTS header (no CRC) for a raw AAC frame of payloadSize bytes
+func (c *AudioSpecificConfig) ADTSHeader(payloadSize int) ([]byte, error) {
+	if c.ObjectType < 1 || c.ObjectType > 4 {
+		return nil, fmt.Errorf("%w: object type %d", ErrADTSUnsupported, c.ObjectType)
+	}
+	if c.FrequencyIndex >= len(aacSampleRates) {
+		return nil, fmt.Errorf("%w: sample rate %d Hz has no frequency index", ErrADTSUnsupported, c.SampleRate)
+	}
+	if c.ChannelConfig > 7 {
+		return nil, fmt.Errorf("%w: channel configuration %d", ErrADTSUnsupported, c.ChannelConfig)
+	}
+	frameSize := adtsHeaderSize + payloadSize
+	if frameSize > adtsMaxFrameSize {
+		return nil, fmt.Errorf("%w: frame of %d bytes", ErrADTSUnsupported, frameSize)
+	}
+
+	profile := byte(c.ObjectType - 1)
+	return []byte{
+		0xFF,
+		0xF1, // MPEG-4, layer 0, no CRC
+		profile<<6 | byte(c.FrequencyIndex)<<2 | byte(c.ChannelConfig>>2)&0x01,
+		byte(c.ChannelConfig&0x03)<<6 | byte(frameSize>>11)&0x03,
+		byte(frameSize >> 3),
+		byte(frameSize&0x07)<<5 | 0x1F, // Buffer fullness 0x7FF: variable bitrate
+		0xFC,
+	}, nil
+}
+
+// aacAUHeaderFormat describes the AU-header fields of an mpeg4-generic stream (RFC 3640 section 3.2.1.1)
+type aacAUHeaderFormat struct {
+	sizeLength             int
+	indexLength            int
+	indexDeltaLength       int
+	ctsDeltaLength         int
+	dtsDeltaLength         int
+	randomAccessIndication bool
+	streamStateIndication  int
+	auxiliaryDataSizeLen   int
+	constantSize           int
+	constantDuration       int
+}
+
+// hasAUHeaders reports whether payloads start with an AU-headers-length field
+func (f *aacAUHeaderFormat) hasAUHeaders() bool {
+	return f.sizeLength > 0 || f.indexLength > 0 || f.indexDeltaLength > 0 || f.ctsDeltaLength > 0 ||
+		f.dtsDeltaLength > 0 || f.randomAccessIndication || f.streamStateIndication > 0
+}
+
+// aacAUHeader is one parsed AU-header
+type aacAUHeader struct {
+	size  int
+	index int // AU-Index for the first header, AU-Index-delta for the others
+}
+
+// AACDecoder depacketizes MPEG-4 AAC carried as mpeg4-generic (RFC 3640) into
+// raw access units: several AUs per packet and AUs fragmented over packets.
+type AACDecoder struct {
+	assembler frameAssembler
+	format    aacAUHeaderFormat
+	config    *AudioSpecificConfig
+}
+
+// NewAACDecoder creates a new AAC decoder; SetFMTP must be called before packets arrive
+func NewAACDecoder() *AACDecoder {
+	return &AACDecoder{
+		assembler: newFrameAssembler("[AACDecoder]"),
+	}
+}
+
+// SetFMTP applies the SDP format parameters of an mpeg4-generic track: the
+// AU-header field lengths, constant size/duration and the config= AudioSpecificConfig.
+// Parameter names are case-insensitive; AAC-hbr and AAC-lbr modes supply the usual defaults.
+func (d *AACDecoder) SetFMTP(fmtp map[string]string) error {
+	params := make(map[string]string, len(fmtp))
+	for key, value := range fmtp {
+		params[strings.ToLower(key)] = value
+	}
+
+	var format aacAUHeaderFormat
+	switch strings.ToLower(params["mode"]) {
+	case "aac-hbr":
+		format.sizeLength, format.indexLength, format.indexDeltaLength = 13, 3, 3
+	case "aac-lbr":
+		format.sizeLength, format.indexLength, format.indexDeltaLength = 6, 2, 2
+	}
+
+	var randomAccess int
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{
+		{"sizelength", &format.sizeLength},
+		{"indexlength", &format.indexLength},
+		{"indexdeltalength", &format.indexDeltaLength},
+		{"ctsdeltalength", &format.ctsDeltaLength},
+		{"dtsdeltalength", &format.dtsDeltaLength},
+		{"randomaccessindication", &randomAccess},
+		{"streamstateindication", &format.streamStateIndication},
+		{"auxiliarydatasizelength", &format.auxiliaryDataSizeLen},
+		{"constantsize", &format.constantSize},
+		{"constantduration", &format.constantDuration},
+	} {
+		value := params[param.name]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 32 && param.name != "constantsize" && param.name != "constantduration" {
+			return fmt.Errorf("%w: %s=%q", ErrInvalidAudioConfig, param.name, value)
+		}
+		*param.target = n
+	}
+	format.randomAccessIndication = randomAccess == 1
+
+	if !format.hasAUHeaders() && format.constantSize == 0 {
+		logger.Debug("[AACDecoder:SetFMTP] No AU headers configured: every packet carries one access unit")
+	}
+
+	var config *AudioSpecificConfig
+	if value := params["config"]; value != "" {
+		raw, err := hex.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("%w: config=%q: %v", ErrInvalidAudioConfig, value, err)
+		}
+		if config, err = ParseAudioSpecificConfig(raw); err != nil {
+			return err
+		}
+	}
+
+	d.format = format
+	d.config = config
+	return nil
+}
+
+// GetConfig returns the AudioSpecificConfig from config=, or nil if none was given
+func (d *AACDecoder) GetConfig() *AudioSpecificConfig {
+	return d.config
+}
+
+// ProcessPacket processes an RTP packet and returns the access units it completes
+func (d *AACDecoder) ProcessPacket(packet *rtp.Packet) []*AudioUnit {
+	if packet == nil || len(packet.Payload) == 0 {
+		return nil
+	}
+
+	added, _ := d.assembler.push(packet)
+	if !added {
+		return nil
+	}
+
+	// Packets of one timestamp are either one packet of complete AUs or the
+	// fragments of one AU; the marker closes both
+	var units []*AudioUnit
+	for fa := d.assembler.next(packet.Timestamp); fa != nil; fa = d.assembler.next(packet.Timestamp) {
+		units = append(units, d.finalizeUnits(fa)...)
+	}
+	return units
+}
+
+// finalizeUnits extracts the access units of a packet group
+func (d *AACDecoder) finalizeUnits(fa *FrameAssembly) []*AudioUnit {
+	seqs := sortedSequences(fa)
+	if len(seqs) == 1 && !fa.hasPacketLoss {
+		return d.unpackAUs(fa.packets[seqs[0]])
+	}
+
+	// A fragmented AU: every fragment repeats the AU-header of the whole AU
+	var data []byte
+	size := -1
+	for _, seq := range seqs {
+		headers, payload, err := d.parseAUHeaders(fa.packets[seq].Payload)
+		if err != nil || len(headers) != 1 {
+			size = -1
+			break
+		}
+		size = headers[0].size
+		data = append(data, payload...)
+	}
+	if fa.hasPacketLoss || size < 0 || (d.format.sizeLength > 0 && len(data) != size) {
+		logger.Debug("[AACDecoder:finalizeUnits] Dropping incomplete fragmented AU: timestamp=%d, have=%d bytes, size=%d",
+			fa.timestamp, len(data), size)
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	d.assembler.stats.TotalFrames++
+	return []*AudioUnit{{Data: data, Timestamp: fa.timestamp, Codec: CodecAAC}}
+}
+
+// unpackAUs splits a packet of complete AUs, timestamping each from its AU index
+func (d *AACDecoder) unpackAUs(packet *rtp.Packet) []*AudioUnit {
+	headers, payload, err := d.parseAUHeaders(packet.Payload)
+	if err != nil {
+		logger.Debug("[AACDecoder:unpackAUs] Invalid AU headers in seq=%d: %v", packet.SequenceNumber, err)
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	var units []*AudioUnit
+	index := 0
+	for i, header := range headers {
+		if i == 0 {
+			index = header.index
+		} else {
+			index += header.index + 1
+		}
+		if header.size > len(payload) {
+			// A single AU larger than the packet is the first fragment of a lost group
+			logger.Debug("[AACDecoder:unpackAUs] AU of %d bytes truncated to %d in seq=%d", header.size, len(payload), packet.SequenceNumber)
+			d.assembler.stats.CorruptedFrames++
+			break
+		}
+		units = append(units, &AudioUnit{
+			Data:      payload[:header.size],
+			Timestamp: packet.Timestamp + uint32(index*d.frameDuration()),
+			Codec:     CodecAAC,
+		})
+		payload = payload[header.size:]
+		d.assembler.stats.TotalFrames++
+	}
+	return units
+}
+
+// parseAUHeaders parses the AU-header and auxiliary sections and returns the AU headers
+// and the access unit data that follows. Without AU headers the payload is one AU.
+func (d *AACDecoder) parseAUHeaders(payload []byte) ([]aacAUHeader, []byte, error) {
+	f := &d.format
+	if !f.hasAUHeaders() {
+		size := len(payload)
+		if f.constantSize > 0 {
+			size = f.constantSize
+		}
+		var headers []aacAUHeader
+		for offset := 0; offset+size <= len(payload) && size > 0; offset += size {
+			headers = append(headers, aacAUHeader{size: size})
+		}
+		return headers, payload, nil
+	}
+
+	if len(payload) < 2 {
+		return nil, nil, ErrTruncatedBitstream
+	}
+	headersBits := int(payload[0])<<8 | int(payload[1])
+	headersBytes := (headersBits + 7) / 8
+	if 2+headersBytes > len(payload) {
+		return nil, nil, ErrTruncatedBitstream
+	}
+	r := newBitReader(payload[2 : 2+headersBytes])
+	payload = payload[2+headersBytes:]
+
+	var headers []aacAUHeader
+	for consumed := 0; consumed < headersBits; consumed = r.pos {
+		var header aacAUHeader
+		fields := []struct {
+			target *int
+			size   int
+		}{
+			{&header.size, f.sizeLength},
+			{&header.index, f.indexLength},
+		}
+		if len(headers) > 0 {
+			fields[1].size = f.indexDeltaLength
+		}
+		for _, field := range fields {
+			value, err := r.readBits(field.size)
+			if err != nil {
+				return nil, nil, err
+			}
+			*field.target = int(value)
+		}
+		// CTS and DTS deltas are each preceded by a presence flag
+		for _, length := range []int{f.ctsDeltaLength, f.dtsDeltaLength} {
+			if length == 0 {
+				continue
+			}
+			present, err := r.readFlag()
+			if err != nil {
+				return nil, nil, err
+			}
+			if present {
+				if err := r.skipBits(length); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		skip := f.streamStateIndication
+		if f.randomAccessIndication {
+			skip++
+		}
+		if err := r.skipBits(skip); err != nil {
+			return nil, nil, err
+		}
+		if f.sizeLength == 0 {
+			header.size = f.constantSize
+		}
+		if r.pos > headersBits {
+			return nil, nil, ErrTruncatedBitstream
+		}
+		headers = append(headers, header)
+	}
+
+	if f.auxiliaryDataSizeLen > 0 {
+		r = newBitReader(payload)
+		auxBits, err := r.readBits(f.auxiliaryDataSizeLen)
+		if err != nil {
+			return nil, nil, err
+		}
+		auxBytes := (f.auxiliaryDataSizeLen + int(auxBits) + 7) / 8
+		if auxBytes > len(payload) {
+			return nil, nil, ErrTruncatedBitstream
+		}
+		payload = payload[auxBytes:]
+	}
+	return headers, payload, nil
+}
+
+// frameDuration returns the RTP duration of one access unit
+func (d *AACDecoder) frameDuration() int {
+	if d.format.constantDuration > 0 {
+		return d.format.constantDuration
+	}
+	if d.config != nil {
+		return d.config.FrameLength
+	}
+	return aacDefaultFrameLength
+}
+
+// Reset clears partially received access units
+func (d *AACDecoder) Reset() {
+	d.assembler.reset()
+}
+
+// GetStats returns decoder statistics; TotalFrames counts access units
+func (d *AACDecoder) GetStats() DecoderStats {
+	return d.assembler.stats
+}
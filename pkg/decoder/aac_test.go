@@ -0,0 +1,166 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// aacHbrFMTP is a typical camera fmtp: AAC-LC, 16 kHz, mono
+var aacHbrFMTP = map[string]string{
+	"streamtype":       "5",
+	"profile-level-id": "15",
+	"mode":             "AAC-hbr",
+	"sizeLength":       "13",
+	"indexLength":      "3",
+	"indexDeltaLength": "3",
+	"config":           "1408",
+}
+
+// aacHbrPayload builds an AAC-hbr payload with 16-bit AU headers; size overrides the
+// AU-size of every header when non-zero (for fragments)
+func aacHbrPayload(size int, aus ...[]byte) []byte {
+	payload := []byte{byte(len(aus) * 16 >> 8), byte(len(aus) * 16)}
+	for _, au := range aus {
+		auSize := len(au)
+		if size != 0 {
+			auSize = size
+		}
+		payload = append(payload, byte(auSize>>5), byte(auSize<<3))
+	}
+	for _, au := range aus {
+		payload = append(payload, au...)
+	}
+	return payload
+}
+
+func aacPacket(seq uint16, timestamp uint32, payload []byte) *rtp.Packet {
+	return &rtp.Packet{SequenceNumber: seq, Timestamp: timestamp, Marker: true, SSRC: 0xAAC, PayloadType: 97, Payload: payload}
+}
+
+func TestParseAudioSpecificConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected AudioSpecificConfig
+		wantErr  bool
+	}{
+		{
+			name:     "AAC-LC 16 kHz mono",
+			data:     []byte{0x14, 0x08},
+			expected: AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 8, SampleRate: 16000, ChannelConfig: 1, FrameLength: 1024},
+		},
+		{
+			name:     "AAC-LC 48 kHz stereo, 960 samples",
+			data:     []byte{0x11, 0x94},
+			expected: AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 3, SampleRate: 48000, ChannelConfig: 2, FrameLength: 960},
+		},
+		{
+			name:     "explicit SBR reports the core",
+			data:     []byte{0x2B, 0x92, 0x08, 0x00},
+			expected: AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 7, SampleRate: 22050, ChannelConfig: 2, FrameLength: 1024},
+		},
+		{
+			name:     "explicit sample rate",
+			data:     []byte{0x17, 0x80, 0x1F, 0x40, 0x08},
+			expected: AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 15, SampleRate: 16000, ChannelConfig: 1, FrameLength: 1024},
+		},
+		{name: "truncated", data: []byte{0x14}, wantErr: true},
+		{name: "reserved frequency index", data: []byte{0x16, 0x88}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseAudioSpecificConfig(tt.data)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAudioConfig)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, *config)
+		})
+	}
+
+	// Bytes round-trips plain configurations
+	for _, data := range [][]byte{{0x14, 0x08}, {0x11, 0x94}, {0x17, 0x80, 0x1F, 0x40, 0x08}} {
+		config, err := ParseAudioSpecificConfig(data)
+		require.NoError(t, err)
+		assert.Equal(t, data, config.Bytes())
+	}
+}
+
+func TestAudioSpecificConfig_ADTSHeader(t *testing.T) {
+	config := &AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 4, SampleRate: 44100, ChannelConfig: 2, FrameLength: 1024}
+	header, err := config.ADTSHeader(371)
+	require.NoError(t, err)
+	// 378-byte frame, AAC-LC (profile 1), 44.1 kHz (index 4), 2 channels
+	assert.Equal(t, []byte{0xFF, 0xF1, 0x50, 0x80, 0x2F, 0x5F, 0xFC}, header)
+
+	_, err = config.ADTSHeader(adtsMaxFrameSize)
+	assert.ErrorIs(t, err, ErrADTSUnsupported)
+	_, err = (&AudioSpecificConfig{ObjectType: 42, FrequencyIndex: 4}).ADTSHeader(10)
+	assert.ErrorIs(t, err, ErrADTSUnsupported)
+	_, err = (&AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 15, SampleRate: 16001}).ADTSHeader(10)
+	assert.ErrorIs(t, err, ErrADTSUnsupported)
+}
+
+func TestAACDecoder_ProcessPacket(t *testing.T) {
+	au1, au2, au3 := []byte{0x21, 0x10, 0x04}, []byte{0x21, 0x11}, []byte{0x21, 0x12, 0x05, 0x60}
+
+	aac := NewAACDecoder()
+	require.NoError(t, aac.SetFMTP(aacHbrFMTP))
+	require.NotNil(t, aac.GetConfig())
+	assert.Equal(t, 16000, aac.GetConfig().SampleRate)
+
+	// Three AUs in one packet get consecutive timestamps
+	units := aac.ProcessPacket(aacPacket(1, 10000, aacHbrPayload(0, au1, au2, au3)))
+	require.Len(t, units, 3)
+	for i, expected := range [][]byte{au1, au2, au3} {
+		assert.Equal(t, expected, units[i].Data)
+		assert.Equal(t, uint32(10000+1024*i), units[i].Timestamp)
+		assert.Equal(t, CodecAAC, units[i].Codec)
+	}
+
+	// A fragmented AU is emitted once its last fragment arrives
+	large := append(append([]byte{}, au1...), au3...)
+	first := aacPacket(2, 13072, aacHbrPayload(len(large), au1))
+	first.Marker = false
+	assert.Empty(t, aac.ProcessPacket(first))
+	units = aac.ProcessPacket(aacPacket(3, 13072, aacHbrPayload(len(large), au3)))
+	require.Len(t, units, 1)
+	assert.Equal(t, large, units[0].Data)
+	assert.Equal(t, uint32(13072), units[0].Timestamp)
+
+	// The last fragment alone cannot be used
+	assert.Empty(t, aac.ProcessPacket(aacPacket(5, 15120, aacHbrPayload(len(large), au3))))
+	assert.Equal(t, 1, aac.GetStats().CorruptedFrames)
+	assert.Equal(t, 4, aac.GetStats().TotalFrames)
+
+	// Truncated AU-header section
+	assert.Empty(t, aac.ProcessPacket(aacPacket(6, 16144, []byte{0x00, 0x20, 0x00})))
+	assert.Equal(t, 2, aac.GetStats().CorruptedFrames)
+}
+
+func TestAACDecoder_SetFMTP(t *testing.T) {
+	aac := NewAACDecoder()
+	assert.ErrorIs(t, aac.SetFMTP(map[string]string{"config": "zz"}), ErrInvalidAudioConfig)
+	assert.ErrorIs(t, aac.SetFMTP(map[string]string{"sizelength": "x"}), ErrInvalidAudioConfig)
+
+	// AAC-lbr defaults: 8-bit AU headers with 6-bit sizes
+	require.NoError(t, aac.SetFMTP(map[string]string{"mode": "AAC-lbr", "config": "1194"}))
+	units := aac.ProcessPacket(aacPacket(1, 0, []byte{0x00, 0x10, 0x02 << 2, 0x01 << 2, 0xAA, 0xBB, 0xCC}))
+	require.Len(t, units, 2)
+	assert.Equal(t, []byte{0xAA, 0xBB}, units[0].Data)
+	assert.Equal(t, []byte{0xCC}, units[1].Data)
+	assert.Equal(t, uint32(960), units[1].Timestamp, "frameLengthFlag selects 960-sample frames")
+
+	// constantDuration overrides the frame length; index deltas skip AUs
+	require.NoError(t, aac.SetFMTP(map[string]string{"sizelength": "13", "indexlength": "3", "indexdeltalength": "3", "constantduration": "512"}))
+	payload := []byte{0x00, 0x20, 0x00, 0x08, 0x00, 0x09, 0xAA, 0xBB}
+	units = aac.ProcessPacket(aacPacket(2, 1000, payload))
+	require.Len(t, units, 2)
+	assert.Equal(t, uint32(1000), units[0].Timestamp)
+	assert.Equal(t, uint32(1000+2*512), units[1].Timestamp)
+}
@@ -0,0 +1,117 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// AVCCLengthSize is the NAL unit length prefix size written by
+	// AnnexBToAVCC and signalled in the avcC and hvcC records
+	AVCCLengthSize = 4
+)
+
+var (
+	// ErrInvalidAVCC indicates length-prefixed NAL units whose lengths overrun the data
+	ErrInvalidAVCC = errors.New("invalid AVCC data")
+)
+
+// SplitAnnexB splits Annex-B data into NAL units (without start codes).
+// Both 3- and 4-byte start codes are accepted; bytes before the first start
+// code and trailing zero bytes of each NAL unit are dropped. Emulation
+// prevention guarantees that 00 00 01 never occurs inside a NAL unit, and
+// RBSP trailing bits end in a 1 bit, so the zero bytes stripped are always
+// trailing_zero_8bits or the leading zero of a 4-byte start code.
+func SplitAnnexB(data []byte) [][]byte {
+	var nalUnits [][]byte
+	start := -1
+
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0x00 || data[i+1] != 0x00 || data[i+2] != 0x01 {
+			continue
+		}
+		if start >= 0 {
+			nalUnits = appendNAL(nalUnits, data[start:i])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 {
+		nalUnits = appendNAL(nalUnits, data[start:])
+	}
+
+	return nalUnits
+}
+
+// appendNAL appends nal with trailing zero bytes removed, skipping empty units
+func appendNAL(nalUnits [][]byte, nal []byte) [][]byte {
+	end := len(nal)
+	for end > 0 && nal[end-1] == 0x00 {
+		end--
+	}
+	if end == 0 {
+		return nalUnits
+	}
+	return append(nalUnits, nal[:end])
+}
+
+// AnnexBToAVCC converts Annex-B data, such as Frame.Data, to NAL units
+// prefixed with their 4-byte big-endian length as stored in MP4, Matroska
+// and FLV samples
+func AnnexBToAVCC(data []byte) []byte {
+	nalUnits := SplitAnnexB(data)
+	size := 0
+	for _, nal := range nalUnits {
+		size += AVCCLengthSize + len(nal)
+	}
+	avcc := make([]byte, 0, size)
+	for _, nal := range nalUnits {
+		avcc = binary.BigEndian.AppendUint32(avcc, uint32(len(nal)))
+		avcc = append(avcc, nal...)
+	}
+	return avcc
+}
+
+// SplitAVCC splits NAL units prefixed with lengthSize-byte (1, 2 or 4)
+// big-endian lengths
+func SplitAVCC(data []byte, lengthSize int) ([][]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, fmt.Errorf("%w: length size %d", ErrInvalidAVCC, lengthSize)
+	}
+	var nalUnits [][]byte
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < lengthSize {
+			return nil, fmt.Errorf("%w: truncated length at offset %d", ErrInvalidAVCC, offset)
+		}
+		var length int
+		for _, b := range data[offset : offset+lengthSize] {
+			length = length<<8 | int(b)
+		}
+		offset += lengthSize
+		if length > len(data)-offset {
+			return nil, fmt.Errorf("%w: NAL unit of %d bytes at offset %d exceeds the remaining %d bytes",
+				ErrInvalidAVCC, length, offset, len(data)-offset)
+		}
+		if length > 0 {
+			nalUnits = append(nalUnits, data[offset:offset+length])
+		}
+		offset += length
+	}
+	return nalUnits, nil
+}
+
+// AVCCToAnnexB converts NAL units prefixed with lengthSize-byte (1, 2 or 4)
+// lengths to Annex-B with 4-byte start codes
+func AVCCToAnnexB(data []byte, lengthSize int) ([]byte, error) {
+	nalUnits, err := SplitAVCC(data, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+	annexB := make([]byte, 0, len(data)+len(nalUnits)*startCodeSize)
+	for _, nal := range nalUnits {
+		annexB = append(annexB, startCode...)
+		annexB = append(annexB, nal...)
+	}
+	return annexB, nil
+}
@@ -0,0 +1,62 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnexBToAVCC(t *testing.T) {
+	// The IDR slice carries emulation prevention bytes: 00 00 03 01 is not a start code
+	idr := []byte{0x65, 0x88, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x42}
+	data := []byte{0x00, 0x00, 0x00, 0x01}
+	data = append(data, testH264SPS...)
+	data = append(data, 0x00, 0x00, 0x01) // 3-byte start code
+	data = append(data, testH264PPS...)
+	data = append(data, 0x00, 0x00, 0x00, 0x00, 0x01) // trailing_zero_8bits before a start code
+	data = append(data, idr...)
+
+	avcc := AnnexBToAVCC(data)
+	expected := append([]byte{0, 0, 0, 4}, testH264SPS...)
+	expected = append(append(expected, 0, 0, 0, 4), testH264PPS...)
+	expected = append(append(expected, 0, 0, 0, byte(len(idr))), idr...)
+	assert.Equal(t, expected, avcc)
+
+	converted, err := AVCCToAnnexB(avcc, AVCCLengthSize)
+	require.NoError(t, err)
+	assert.Equal(t, annexB(testH264SPS, testH264PPS, idr), converted)
+	assert.Equal(t, avcc, AnnexBToAVCC(converted), "round trip")
+
+	assert.Empty(t, AnnexBToAVCC(nil))
+	assert.Empty(t, AnnexBToAVCC([]byte{0x65, 0x88})) // No start code
+}
+
+func TestAVCCToAnnexB(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		lengthSize int
+		expected   []byte
+		err        error
+	}{
+		{"1-byte lengths", []byte{2, 0x67, 0x42, 1, 0x68}, 1, annexB([]byte{0x67, 0x42}, []byte{0x68}), nil},
+		{"2-byte lengths", []byte{0, 2, 0x67, 0x42, 0, 0, 0, 1, 0x68}, 2, annexB([]byte{0x67, 0x42}, []byte{0x68}), nil},
+		{"empty", nil, 4, []byte{}, nil},
+		{"3-byte lengths", []byte{0, 0, 1, 0x68}, 3, nil, ErrInvalidAVCC},
+		{"truncated length", []byte{0, 0, 0, 1, 0x68, 0, 0}, 4, nil, ErrInvalidAVCC},
+		{"length overruns", []byte{0, 0, 0, 3, 0x68, 0xCE}, 4, nil, ErrInvalidAVCC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := AVCCToAnnexB(tt.data, tt.lengthSize)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, converted)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package decoder
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// frameAssembler groups the RTP packets of one stream by timestamp for the
+// H.265 and MJPEG depacketizers. It tracks sequence numbers and SSRC changes
+// and counts them in stats; the depacketizer counts frames.
+type frameAssembler struct {
+	tag      string                    // Log prefix, e.g. "[H265Decoder]"
+	frameMap map[uint32]*FrameAssembly // Maps timestamp to frame assembly
+
+	source          rtp.SourceState
+	currentSSRC     uint32
+	ssrcInitialized bool
+
+	stats DecoderStats
+}
+
+func newFrameAssembler(tag string) frameAssembler {
+	return frameAssembler{tag: tag, frameMap: make(map[uint32]*FrameAssembly)}
+}
+
+// push adds a packet to the frame of its timestamp. added is false for
+// duplicates; ssrcChanged means a new stream started and cached state is stale.
+func (a *frameAssembler) push(packet *rtp.Packet) (added bool, ssrcChanged bool) {
+	if !a.ssrcInitialized {
+		a.currentSSRC = packet.SSRC
+		a.ssrcInitialized = true
+	}
+
+	if packet.SSRC != a.currentSSRC {
+		logger.Warn("%s SSRC changed: 0x%x → 0x%x (stream changed or camera rebooted)",
+			a.tag, a.currentSSRC, packet.SSRC)
+		a.reset()
+		a.currentSSRC = packet.SSRC
+		a.source.Reset()
+		a.stats.SSRCChanges++
+		ssrcChanged = true
+	}
+
+	update := a.source.Update(packet.SequenceNumber)
+	switch update.Status {
+	case rtp.SequenceDuplicate:
+		a.stats.DuplicatePackets++
+		logger.Debug("%s Dropping duplicate packet seq=%d", a.tag, packet.SequenceNumber)
+		return false, ssrcChanged
+	case rtp.SequenceRestarted:
+		logger.Warn("%s Sequence restarted at seq=%d without SSRC change", a.tag, packet.SequenceNumber)
+		a.reset()
+		a.stats.SequenceRestarts++
+	case rtp.SequenceInOrder:
+		if update.Gap > 0 {
+			a.stats.PacketLossEvents++
+			a.stats.PacketsLost += int(update.Gap)
+		}
+	}
+
+	fa, ok := a.frameMap[packet.Timestamp]
+	if !ok {
+		fa = &FrameAssembly{
+			timestamp:    packet.Timestamp,
+			packets:      make(map[uint16]*rtp.Packet),
+			firstArrival: time.Now(),
+			seqMin:       packet.SequenceNumber,
+			seqMax:       packet.SequenceNumber,
+		}
+		a.frameMap[packet.Timestamp] = fa
+	}
+	fa.packets[packet.SequenceNumber] = packet
+	if rtp.CompareSequence(packet.SequenceNumber, fa.seqMin) < 0 {
+		fa.seqMin = packet.SequenceNumber
+	}
+	if rtp.CompareSequence(packet.SequenceNumber, fa.seqMax) > 0 {
+		fa.seqMax = packet.SequenceNumber
+	}
+	if packet.Marker {
+		fa.markerReceived = true
+	}
+	fa.hasPacketLoss = fa.hasPacketLoss || hasSequenceGap(fa)
+	return true, ssrcChanged
+}
+
+// next removes and returns a frame ready to be finalized: first older frames
+// whose marker was lost and whose reordering window expired, then the frame
+// of the given timestamp once its marker arrived. It returns nil when none is ready.
+func (a *frameAssembler) next(timestamp uint32) *FrameAssembly {
+	for ts, fa := range a.frameMap {
+		if ts != timestamp && fa.isComplete() {
+			delete(a.frameMap, ts)
+			return fa
+		}
+	}
+	if fa, ok := a.frameMap[timestamp]; ok && fa.isComplete() {
+		delete(a.frameMap, timestamp)
+		return fa
+	}
+	return nil
+}
+
+// reset drops all partially assembled frames
+func (a *frameAssembler) reset() {
+	for k := range a.frameMap {
+		delete(a.frameMap, k)
+	}
+}
+
+// isComplete reports whether a frame has its marker or its reordering window expired
+func (fa *FrameAssembly) isComplete() bool {
+	return fa.markerReceived || time.Since(fa.firstArrival) > reorderWindowMs*time.Millisecond
+}
+
+// sortedSequences returns the frame's sequence numbers in RTP order
+func sortedSequences(fa *FrameAssembly) []uint16 {
+	seqs := make([]uint16, 0, len(fa.packets))
+	for seq := range fa.packets {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		return rtp.CompareSequence(seqs[i], seqs[j]) < 0
+	})
+	return seqs
+}
+
+// hasSequenceGap reports whether packets are missing between the frame's first and last packet
+func hasSequenceGap(fa *FrameAssembly) bool {
+	seqs := sortedSequences(fa)
+	for i := 1; i < len(seqs); i++ {
+		if gap := rtp.SequenceDistance(seqs[i-1]+1, seqs[i]); gap > 0 && gap < 100 {
+			return true
+		}
+	}
+	return false
+}
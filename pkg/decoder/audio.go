@@ -0,0 +1,63 @@
+package decoder
+
+import (
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// AudioDecoder turns the RTP packets of one audio stream into access units
+type AudioDecoder interface {
+	ProcessPacket(packet *rtp.Packet) []*AudioUnit
+	Reset()
+	GetStats() DecoderStats
+}
+
+// AudioUnit is one depacketized audio access unit
+type AudioUnit struct {
+	Data      []byte
+	Timestamp uint32 // RTP timestamp of the unit's first sample
+	Codec     Codec
+}
+
+// audioSource tracks the SSRC and sequence numbers of an audio stream whose
+// packets are passed through in arrival order, counting both in stats
+type audioSource struct {
+	tag             string // Log prefix, e.g. "[PCMDecoder]"
+	source          rtp.SourceState
+	currentSSRC     uint32
+	ssrcInitialized bool
+	stats           DecoderStats
+}
+
+// push accounts a packet. ok is false for duplicates; resync means the stream
+// changed or restarted and the expected timestamp no longer applies.
+func (a *audioSource) push(packet *rtp.Packet) (ok, resync bool) {
+	if !a.ssrcInitialized {
+		a.currentSSRC = packet.SSRC
+		a.ssrcInitialized = true
+	}
+	if packet.SSRC != a.currentSSRC {
+		logger.Warn("%s SSRC changed: 0x%x → 0x%x (stream changed or camera rebooted)", a.tag, a.currentSSRC, packet.SSRC)
+		a.currentSSRC = packet.SSRC
+		a.source.Reset()
+		a.stats.SSRCChanges++
+		resync = true
+	}
+
+	update := a.source.Update(packet.SequenceNumber)
+	switch update.Status {
+	case rtp.SequenceDuplicate:
+		a.stats.DuplicatePackets++
+		return false, resync
+	case rtp.SequenceRestarted:
+		logger.Warn("%s Sequence restarted at seq=%d without SSRC change", a.tag, packet.SequenceNumber)
+		a.stats.SequenceRestarts++
+		resync = true
+	case rtp.SequenceInOrder:
+		if update.Gap > 0 {
+			a.stats.PacketLossEvents++
+			a.stats.PacketsLost += int(update.Gap)
+		}
+	}
+	return true, resync
+}
@@ -0,0 +1,125 @@
+package decoder
+
+import "errors"
+
+var (
+	// ErrTruncatedBitstream indicates a bit field that runs past the end of its data
+	ErrTruncatedBitstream = errors.New("truncated bitstream")
+	// ErrInvalidExpGolomb indicates an Exp-Golomb code whose value does not fit in 32 bits
+	ErrInvalidExpGolomb = errors.New("invalid Exp-Golomb code")
+)
+
+// bitReader reads MSB-first bit fields from a byte slice
+type bitReader struct {
+	data []byte
+	pos  int // Bit offset of the next read
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+// readBits reads an n-bit unsigned field, n <= 32
+func (r *bitReader) readBits(n int) (uint32, error) {
+	if n > r.bitsLeft() {
+		return 0, ErrTruncatedBitstream
+	}
+	var value uint32
+	for i := 0; i < n; i++ {
+		bit := r.data[r.pos>>3] >> (7 - r.pos&7) & 1
+		value = value<<1 | uint32(bit)
+		r.pos++
+	}
+	return value, nil
+}
+
+// readFlag reads a single bit
+func (r *bitReader) readFlag() (bool, error) {
+	bit, err := r.readBits(1)
+	return bit == 1, err
+}
+
+// skipBits advances past n bits
+func (r *bitReader) skipBits(n int) error {
+	if n > r.bitsLeft() {
+		return ErrTruncatedBitstream
+	}
+	r.pos += n
+	return nil
+}
+
+// bitsLeft returns the number of unread bits
+func (r *bitReader) bitsLeft() int {
+	return len(r.data)*8 - r.pos
+}
+
+// readUE reads an unsigned Exp-Golomb code, ue(v) (ITU-T H.264 section 9.1)
+func (r *bitReader) readUE() (uint32, error) {
+	leadingZeros := 0
+	for {
+		bit, err := r.readBits(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			break
+		}
+		leadingZeros++
+		if leadingZeros > 31 {
+			return 0, ErrInvalidExpGolomb
+		}
+	}
+	suffix, err := r.readBits(leadingZeros)
+	if err != nil {
+		return 0, err
+	}
+	return (1<<leadingZeros - 1) + suffix, nil
+}
+
+// readSE reads a signed Exp-Golomb code, se(v): 1, -1, 2, -2, ... for codes 1, 2, 3, 4, ...
+func (r *bitReader) readSE() (int32, error) {
+	code, err := r.readUE()
+	if err != nil {
+		return 0, err
+	}
+	if code&1 == 1 {
+		return int32(code/2 + 1), nil
+	}
+	return -int32(code / 2), nil
+}
+
+// moreRBSPData reports whether syntax elements remain before the RBSP
+// trailing bits, i.e. the last 1 bit of the data is not the next bit
+func (r *bitReader) moreRBSPData() bool {
+	for i := len(r.data) - 1; i >= 0; i-- {
+		if b := r.data[i]; b != 0 {
+			last := i*8 + 7
+			for b&1 == 0 {
+				b >>= 1
+				last--
+			}
+			return r.pos < last
+		}
+	}
+	return false
+}
+
+// unescapeRBSP removes emulation prevention bytes (0x03 after 0x00 0x00)
+// from a NAL unit payload
+func unescapeRBSP(data []byte) []byte {
+	rbsp := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
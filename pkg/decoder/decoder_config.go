@@ -0,0 +1,359 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	// avcConfigHeaderSize is the avcC size up to numOfSequenceParameterSets
+	avcConfigHeaderSize = 6
+	// hevcConfigHeaderSize is the hvcC size up to numOfArrays
+	hevcConfigHeaderSize = 23
+	// h265GeneralPTLSize is the size of the general part of profile_tier_level
+	h265GeneralPTLSize = 12
+)
+
+var (
+	// ErrMissingParameterSets indicates a decoder configuration record requested without SPS or PPS
+	ErrMissingParameterSets = errors.New("missing parameter sets")
+	// ErrInvalidDecoderConfig indicates an avcC or hvcC record that cannot be parsed
+	ErrInvalidDecoderConfig = errors.New("invalid decoder configuration record")
+)
+
+// avcHighProfiles are the profile_idc values whose avcC carries chroma format
+// and bit depths (ISO/IEC 14496-15 section 5.3.3.1.2)
+var avcHighProfiles = map[uint8]bool{100: true, 110: true, 122: true, 144: true}
+
+// AVCDecoderConfig is the content of an AVCDecoderConfigurationRecord (avcC)
+type AVCDecoderConfig struct {
+	Profile              uint8
+	ProfileCompatibility uint8
+	Level                uint8
+	LengthSize           int
+	SPS                  [][]byte
+	PPS                  [][]byte
+}
+
+// BuildAVCDecoderConfig builds an AVCDecoderConfigurationRecord, the payload
+// of the avcC box (ISO/IEC 14496-15 section 5.3.3.1), from SPS and PPS NAL
+// units without start codes. Samples use 4-byte lengths as written by
+// AnnexBToAVCC.
+func BuildAVCDecoderConfig(sps, pps [][]byte) ([]byte, error) {
+	if len(sps) == 0 || len(pps) == 0 {
+		return nil, fmt.Errorf("%w: %d SPS, %d PPS", ErrMissingParameterSets, len(sps), len(pps))
+	}
+	if len(sps) > 31 || len(pps) > 255 {
+		return nil, fmt.Errorf("%w: %d SPS, %d PPS exceed the avcC limits", ErrInvalidDecoderConfig, len(sps), len(pps))
+	}
+	if len(sps[0]) < 4 {
+		return nil, fmt.Errorf("%w: SPS of %d bytes", ErrMalformedNAL, len(sps[0]))
+	}
+
+	record := []byte{1, sps[0][1], sps[0][2], sps[0][3], 0xFC | (AVCCLengthSize - 1), 0xE0 | byte(len(sps))}
+	var err error
+	if record, err = appendParameterSets(record, sps); err != nil {
+		return nil, err
+	}
+	record = append(record, byte(len(pps)))
+	if record, err = appendParameterSets(record, pps); err != nil {
+		return nil, err
+	}
+
+	if avcHighProfiles[sps[0][1]] {
+		parsed, err := ParseSPS(sps[0])
+		if err != nil {
+			return nil, err
+		}
+		record = append(record,
+			0xFC|byte(parsed.ChromaFormatIDC),
+			0xF8|byte(parsed.BitDepthLuma-8),
+			0xF8|byte(parsed.BitDepthChroma-8),
+			0) // numOfSequenceParameterSetExt
+	}
+	return record, nil
+}
+
+// ParseAVCDecoderConfig parses an AVCDecoderConfigurationRecord. The
+// parameter sets alias record.
+func ParseAVCDecoderConfig(record []byte) (*AVCDecoderConfig, error) {
+	if len(record) < avcConfigHeaderSize || record[0] != 1 {
+		return nil, fmt.Errorf("%w: avcC header", ErrInvalidDecoderConfig)
+	}
+	config := &AVCDecoderConfig{
+		Profile:              record[1],
+		ProfileCompatibility: record[2],
+		Level:                record[3],
+		LengthSize:           int(record[4]&0x03) + 1,
+	}
+	rest := record[avcConfigHeaderSize:]
+	var err error
+	if config.SPS, rest, err = readParameterSets(rest, int(record[5]&0x1F)); err != nil {
+		return nil, fmt.Errorf("%w: avcC SPS: %v", ErrInvalidDecoderConfig, err)
+	}
+	if len(rest) < 1 {
+		return nil, fmt.Errorf("%w: avcC numOfPictureParameterSets missing", ErrInvalidDecoderConfig)
+	}
+	if config.PPS, _, err = readParameterSets(rest[1:], int(rest[0])); err != nil {
+		return nil, fmt.Errorf("%w: avcC PPS: %v", ErrInvalidDecoderConfig, err)
+	}
+	return config, nil
+}
+
+// HEVCDecoderConfig is the content of an HEVCDecoderConfigurationRecord (hvcC)
+type HEVCDecoderConfig struct {
+	ProfileSpace              uint8
+	Tier                      bool
+	ProfileIDC                uint8
+	ProfileCompatibilityFlags uint32
+	ConstraintIndicatorFlags  uint64 // 48 bits
+	Level                     uint8
+	ChromaFormat              uint8
+	BitDepthLuma              int
+	BitDepthChroma            int
+	NumTemporalLayers         int
+	TemporalIDNested          bool
+	LengthSize                int
+	VPS, SPS, PPS             [][]byte
+}
+
+// h265SPSInfo holds the H.265 SPS fields that the hvcC header repeats
+type h265SPSInfo struct {
+	generalPTL        []byte // general_profile_space through general_level_idc
+	maxSubLayers      int
+	temporalIDNesting bool
+	chromaFormat      uint32
+	bitDepthLuma      uint32
+	bitDepthChroma    uint32
+}
+
+// parseH265SPS parses an H.265 SPS NAL unit up to bit_depth_chroma_minus8
+// (ITU-T H.265 section 7.3.2.2)
+func parseH265SPS(nal []byte) (*h265SPSInfo, error) {
+	if len(nal) < h265NALHeaderSize || h265NALType(nal[0]) != h265NALTypeSPS {
+		return nil, fmt.Errorf("%w: not an H.265 SPS", ErrMalformedNAL)
+	}
+	rbsp := unescapeRBSP(nal[h265NALHeaderSize:])
+	if len(rbsp) < 1+h265GeneralPTLSize {
+		return nil, fmt.Errorf("%w: H.265 SPS of %d bytes", ErrMalformedNAL, len(nal))
+	}
+	// sps_video_parameter_set_id, sps_max_sub_layers_minus1, sps_temporal_id_nesting_flag
+	info := &h265SPSInfo{
+		generalPTL:        rbsp[1 : 1+h265GeneralPTLSize],
+		maxSubLayers:      int(rbsp[0]>>1&0x07) + 1,
+		temporalIDNesting: rbsp[0]&0x01 != 0,
+	}
+	if err := info.parse(newBitReader(rbsp[1+h265GeneralPTLSize:])); err != nil {
+		return nil, fmt.Errorf("%w: H.265 SPS: %v", ErrMalformedNAL, err)
+	}
+	return info, nil
+}
+
+// parse reads the sub-layer profile_tier_level fields through bit_depth_chroma_minus8
+func (info *h265SPSInfo) parse(r *bitReader) error {
+	subLayers := info.maxSubLayers - 1
+	profilePresent := make([]bool, subLayers)
+	levelPresent := make([]bool, subLayers)
+	for i := 0; i < subLayers; i++ {
+		flags, err := r.readBits(2)
+		if err != nil {
+			return err
+		}
+		profilePresent[i], levelPresent[i] = flags&2 != 0, flags&1 != 0
+	}
+	if subLayers > 0 {
+		if err := r.skipBits(2 * (8 - subLayers)); err != nil { // reserved_zero_2bits
+			return err
+		}
+	}
+	for i := 0; i < subLayers; i++ {
+		if profilePresent[i] {
+			if err := r.skipBits(88); err != nil {
+				return err
+			}
+		}
+		if levelPresent[i] {
+			if err := r.skipBits(8); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := r.readUE(); err != nil { // sps_seq_parameter_set_id
+		return err
+	}
+	var err error
+	if info.chromaFormat, err = r.readUE(); err != nil {
+		return err
+	}
+	if info.chromaFormat > 3 {
+		return fmt.Errorf("chroma_format_idc %d", info.chromaFormat)
+	}
+	if info.chromaFormat == 3 {
+		if err := r.skipBits(1); err != nil { // separate_colour_plane_flag
+			return err
+		}
+	}
+	for i := 0; i < 2; i++ { // pic_width_in_luma_samples, pic_height_in_luma_samples
+		if _, err := r.readUE(); err != nil {
+			return err
+		}
+	}
+	conformanceWindow, err := r.readFlag()
+	if err != nil {
+		return err
+	}
+	if conformanceWindow {
+		for i := 0; i < 4; i++ {
+			if _, err := r.readUE(); err != nil {
+				return err
+			}
+		}
+	}
+	if info.bitDepthLuma, err = r.readUE(); err != nil {
+		return err
+	}
+	if info.bitDepthChroma, err = r.readUE(); err != nil {
+		return err
+	}
+	// hvcC has 3 bits for each bit depth
+	if info.bitDepthLuma > 7 || info.bitDepthChroma > 7 {
+		return fmt.Errorf("bit depth %d/%d", info.bitDepthLuma+8, info.bitDepthChroma+8)
+	}
+	return nil
+}
+
+// BuildHEVCDecoderConfig builds an HEVCDecoderConfigurationRecord, the
+// payload of the hvcC box (ISO/IEC 14496-15 section 8.3.3.1), from VPS, SPS
+// and PPS NAL units without start codes. The header fields come from the
+// first SPS; samples use 4-byte lengths as written by AnnexBToAVCC.
+func BuildHEVCDecoderConfig(vps, sps, pps [][]byte) ([]byte, error) {
+	if len(vps) == 0 || len(sps) == 0 || len(pps) == 0 {
+		return nil, fmt.Errorf("%w: %d VPS, %d SPS, %d PPS", ErrMissingParameterSets, len(vps), len(sps), len(pps))
+	}
+	info, err := parseH265SPS(sps[0])
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, 0, hevcConfigHeaderSize)
+	record = append(record, 1)
+	record = append(record, info.generalPTL...)
+	nested := byte(0)
+	if info.temporalIDNesting {
+		nested = 1
+	}
+	record = append(record,
+		0xF0, 0x00, // min_spatial_segmentation_idc unknown
+		0xFC, // parallelismType unknown
+		0xFC|byte(info.chromaFormat),
+		0xF8|byte(info.bitDepthLuma),
+		0xF8|byte(info.bitDepthChroma),
+		0x00, 0x00, // avgFrameRate unspecified
+		// constantFrameRate 0, numTemporalLayers, temporalIdNested, lengthSizeMinusOne
+		byte(info.maxSubLayers)<<3|nested<<2|(AVCCLengthSize-1),
+		3) // numOfArrays
+
+	for _, array := range []struct {
+		nalType  byte
+		nalUnits [][]byte
+	}{
+		{h265NALTypeVPS, vps},
+		{h265NALTypeSPS, sps},
+		{h265NALTypePPS, pps},
+	} {
+		if len(array.nalUnits) > 0xFFFF {
+			return nil, fmt.Errorf("%w: %d NAL units of type %d", ErrInvalidDecoderConfig, len(array.nalUnits), array.nalType)
+		}
+		// array_completeness set: no parameter sets of this type in-band
+		record = append(record, 0x80|array.nalType)
+		record = binary.BigEndian.AppendUint16(record, uint16(len(array.nalUnits)))
+		if record, err = appendParameterSets(record, array.nalUnits); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// ParseHEVCDecoderConfig parses an HEVCDecoderConfigurationRecord. Arrays
+// of other NAL unit types (SEI) are skipped; the parameter sets alias record.
+func ParseHEVCDecoderConfig(record []byte) (*HEVCDecoderConfig, error) {
+	if len(record) < hevcConfigHeaderSize || record[0] != 1 {
+		return nil, fmt.Errorf("%w: hvcC header", ErrInvalidDecoderConfig)
+	}
+	config := &HEVCDecoderConfig{
+		ProfileSpace:              record[1] >> 6,
+		Tier:                      record[1]&0x20 != 0,
+		ProfileIDC:                record[1] & 0x1F,
+		ProfileCompatibilityFlags: binary.BigEndian.Uint32(record[2:6]),
+		ConstraintIndicatorFlags:  uint64(binary.BigEndian.Uint16(record[6:8]))<<32 | uint64(binary.BigEndian.Uint32(record[8:12])),
+		Level:                     record[12],
+		ChromaFormat:              record[16] & 0x03,
+		BitDepthLuma:              int(record[17]&0x07) + 8,
+		BitDepthChroma:            int(record[18]&0x07) + 8,
+		NumTemporalLayers:         int(record[21] >> 3 & 0x07),
+		TemporalIDNested:          record[21]&0x04 != 0,
+		LengthSize:                int(record[21]&0x03) + 1,
+	}
+
+	rest := record[hevcConfigHeaderSize:]
+	for i := 0; i < int(record[22]); i++ {
+		if len(rest) < 3 {
+			return nil, fmt.Errorf("%w: hvcC array %d truncated", ErrInvalidDecoderConfig, i)
+		}
+		nalType := rest[0] & 0x3F
+		nalUnits, next, err := readParameterSets(rest[3:], int(binary.BigEndian.Uint16(rest[1:3])))
+		if err != nil {
+			return nil, fmt.Errorf("%w: hvcC array of type %d: %v", ErrInvalidDecoderConfig, nalType, err)
+		}
+		rest = next
+		switch nalType {
+		case h265NALTypeVPS:
+			config.VPS = append(config.VPS, nalUnits...)
+		case h265NALTypeSPS:
+			config.SPS = append(config.SPS, nalUnits...)
+		case h265NALTypePPS:
+			config.PPS = append(config.PPS, nalUnits...)
+		}
+	}
+	return config, nil
+}
+
+// appendParameterSets appends NAL units with 16-bit lengths
+func appendParameterSets(record []byte, nalUnits [][]byte) ([]byte, error) {
+	for _, nal := range nalUnits {
+		if len(nal) == 0 || len(nal) > 0xFFFF {
+			return nil, fmt.Errorf("%w: parameter set of %d bytes", ErrMalformedNAL, len(nal))
+		}
+		record = binary.BigEndian.AppendUint16(record, uint16(len(nal)))
+		record = append(record, nal...)
+	}
+	return record, nil
+}
+
+// readParameterSets reads count NAL units with 16-bit lengths and returns the rest of data
+func readParameterSets(data []byte, count int) (nalUnits [][]byte, rest []byte, err error) {
+	for i := 0; i < count; i++ {
+		if len(data) < 2 {
+			return nil, nil, ErrTruncatedBitstream
+		}
+		length := int(binary.BigEndian.Uint16(data))
+		if len(data)-2 < length {
+			return nil, nil, ErrTruncatedBitstream
+		}
+		nalUnits = append(nalUnits, data[2:2+length])
+		data = data[2+length:]
+	}
+	return nalUnits, data, nil
+}
+
+// AVCDecoderConfig builds the avcC record from the cached SPS and PPS
+func (d *H264Decoder) AVCDecoderConfig() ([]byte, error) {
+	return BuildAVCDecoderConfig(SplitAnnexB(d.spsNAL), SplitAnnexB(d.ppsNAL))
+}
+
+// HEVCDecoderConfig builds the hvcC record from the cached VPS, SPS and PPS
+func (d *H265Decoder) HEVCDecoderConfig() ([]byte, error) {
+	return BuildHEVCDecoderConfig(SplitAnnexB(d.vpsNAL), SplitAnnexB(d.spsNAL), SplitAnnexB(d.ppsNAL))
+}
@@ -0,0 +1,151 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// h265SPS builds an H.265 Main profile SPS at level 3.1 for 1280x720 with
+// the given number of temporal sub-layers
+func h265SPS(subLayers int) []byte {
+	w := &bitWriter{}
+	w.u(4, 0).u(3, uint32(subLayers-1)).bit(true)
+	w.u(2, 0).bit(false).u(5, 1) // general_profile_space, general_tier_flag, Main
+	w.u(32, 0x60000000)          // general_profile_compatibility_flags
+	w.u(16, 0x9000).u(32, 0)     // progressive_source_flag, frame_only_constraint_flag
+	w.u(8, 93)                   // general_level_idc
+	for i := 1; i < subLayers; i++ {
+		w.bit(false).bit(true) // sub-layer level only
+	}
+	if subLayers > 1 {
+		w.u(2*(9-subLayers), 0)
+	}
+	for i := 1; i < subLayers; i++ {
+		w.u(8, 90)
+	}
+	w.ue(0).ue(1)                 // sps_seq_parameter_set_id, chroma_format_idc
+	w.ue(1280).ue(720).bit(false) // no conformance window
+	w.ue(0).ue(0)                 // bit depths
+	w.ue(4)                       // log2_max_pic_order_cnt_lsb_minus4
+	return append([]byte{0x42, 0x01}, w.rbsp()...)
+}
+
+func TestAVCDecoderConfig(t *testing.T) {
+	sps := testSPS()
+	record, err := BuildAVCDecoderConfig([][]byte{sps}, [][]byte{testH264PPS})
+	require.NoError(t, err)
+	expected := []byte{1, 100, 0x00, 40, 0xFF, 0xE1, 0, byte(len(sps))}
+	expected = append(expected, sps...)
+	expected = append(expected, 1, 0, 4)
+	expected = append(expected, testH264PPS...)
+	expected = append(expected, 0xFD, 0xF8, 0xF8, 0) // High profile: 4:2:0, 8 bits
+	assert.Equal(t, expected, record)
+
+	config, err := ParseAVCDecoderConfig(record)
+	require.NoError(t, err)
+	assert.Equal(t, &AVCDecoderConfig{
+		Profile:    100,
+		Level:      40,
+		LengthSize: 4,
+		SPS:        [][]byte{sps},
+		PPS:        [][]byte{testH264PPS},
+	}, config)
+
+	// Baseline has no extension; the SPS is not parsed
+	pps2 := []byte{0x68, 0xce, 0x38, 0x80}
+	record, err = BuildAVCDecoderConfig([][]byte{testH264SPS}, [][]byte{testH264PPS, pps2})
+	require.NoError(t, err)
+	config, err = ParseAVCDecoderConfig(record)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(66), config.Profile)
+	assert.Equal(t, uint8(0xc0), config.ProfileCompatibility)
+	assert.Equal(t, [][]byte{testH264SPS}, config.SPS)
+	assert.Equal(t, [][]byte{testH264PPS, pps2}, config.PPS)
+
+	_, err = BuildAVCDecoderConfig(nil, [][]byte{testH264PPS})
+	assert.ErrorIs(t, err, ErrMissingParameterSets)
+	_, err = BuildAVCDecoderConfig([][]byte{{0x67, 100, 0x00, 40}}, [][]byte{testH264PPS})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+	_, err = BuildAVCDecoderConfig([][]byte{testH264SPS}, [][]byte{{}})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+
+	for _, bad := range [][]byte{nil, {0, 100, 0, 40, 0xFF, 0xE0, 0}, record[:10], record[:len(record)-2]} {
+		_, err = ParseAVCDecoderConfig(bad)
+		assert.ErrorIs(t, err, ErrInvalidDecoderConfig)
+	}
+}
+
+func TestHEVCDecoderConfig(t *testing.T) {
+	for _, subLayers := range []int{1, 3} {
+		sps := h265SPS(subLayers)
+		record, err := BuildHEVCDecoderConfig([][]byte{testH265VPS}, [][]byte{sps}, [][]byte{testH265PPS})
+		require.NoError(t, err)
+		assert.Equal(t, []byte{1, 0x01, 0x60, 0, 0, 0, 0x90, 0, 0, 0, 0, 0, 93}, record[:13])
+		assert.Equal(t, byte(0x80|h265NALTypeVPS), record[23], "complete VPS array")
+
+		config, err := ParseHEVCDecoderConfig(record)
+		require.NoError(t, err)
+		assert.Equal(t, &HEVCDecoderConfig{
+			ProfileIDC:                1,
+			ProfileCompatibilityFlags: 0x60000000,
+			ConstraintIndicatorFlags:  0x900000000000,
+			Level:                     93,
+			ChromaFormat:              1,
+			BitDepthLuma:              8,
+			BitDepthChroma:            8,
+			NumTemporalLayers:         subLayers,
+			TemporalIDNested:          true,
+			LengthSize:                4,
+			VPS:                       [][]byte{testH265VPS},
+			SPS:                       [][]byte{sps},
+			PPS:                       [][]byte{testH265PPS},
+		}, config)
+	}
+
+	_, err := BuildHEVCDecoderConfig(nil, [][]byte{h265SPS(1)}, [][]byte{testH265PPS})
+	assert.ErrorIs(t, err, ErrMissingParameterSets)
+	_, err = BuildHEVCDecoderConfig([][]byte{testH265VPS}, [][]byte{testH265SPS}, [][]byte{testH265PPS})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+
+	record, err := BuildHEVCDecoderConfig([][]byte{testH265VPS}, [][]byte{h265SPS(1)}, [][]byte{testH265PPS})
+	require.NoError(t, err)
+	for _, bad := range [][]byte{nil, record[:22], record[:25], record[:len(record)-1]} {
+		_, err = ParseHEVCDecoderConfig(bad)
+		assert.ErrorIs(t, err, ErrInvalidDecoderConfig)
+	}
+}
+
+func TestDecoderConfigFromCachedParameterSets(t *testing.T) {
+	h264 := NewH264Decoder()
+	_, err := h264.AVCDecoderConfig()
+	assert.ErrorIs(t, err, ErrMissingParameterSets)
+	sprop := base64.StdEncoding.EncodeToString(testSPS()) + "," + base64.StdEncoding.EncodeToString(testH264PPS)
+	require.NoError(t, h264.SetFMTP(map[string]string{"sprop-parameter-sets": sprop}))
+	record, err := h264.AVCDecoderConfig()
+	require.NoError(t, err)
+	config, err := ParseAVCDecoderConfig(record)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{testSPS()}, config.SPS)
+	assert.Equal(t, [][]byte{testH264PPS}, config.PPS)
+
+	h265 := NewH265Decoder()
+	_, err = h265.HEVCDecoderConfig()
+	assert.ErrorIs(t, err, ErrMissingParameterSets)
+	require.NoError(t, h265.SetFMTP(map[string]string{
+		"sprop-vps": base64.StdEncoding.EncodeToString(testH265VPS),
+		"sprop-sps": base64.StdEncoding.EncodeToString(h265SPS(1)),
+		"sprop-pps": base64.StdEncoding.EncodeToString(testH265PPS),
+	}))
+	record, err = h265.HEVCDecoderConfig()
+	require.NoError(t, err)
+	hevc, err := ParseHEVCDecoderConfig(record)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{h265SPS(1)}, hevc.SPS)
+
+	// The SPS/PPS a frame would be muxed with
+	vps, sps, pps := h265.GetParameterSets()
+	assert.Equal(t, annexB(hevc.VPS[0], hevc.SPS[0], hevc.PPS[0]), append(append(append([]byte{}, vps...), sps...), pps...))
+}
@@ -1,8 +1,12 @@
 package decoder
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rtsp-client/pkg/logger"
@@ -12,10 +16,15 @@ import (
 const (
 	// H.264 NAL unit types
 	nalUnitTypeIDR  = 5  // IDR picture (keyframe)
+	nalUnitTypeSEI  = 6  // Supplemental Enhancement Information
 	nalUnitTypeSPS  = 7  // Sequence Parameter Set
 	nalUnitTypePPS  = 8  // Picture Parameter Set
-	nalUnitTypeSTAP = 24 // Single-Time Aggregation Packet A
-	nalUnitTypeFUA  = 28 // Fragmentation Unit A
+	nalUnitTypeSTAP   = 24 // Single-Time Aggregation Packet A
+	nalUnitTypeSTAPB  = 25 // Single-Time Aggregation Packet B (with DON)
+	nalUnitTypeMTAP16 = 26 // Multi-Time Aggregation Packet, 16-bit timestamp offsets
+	nalUnitTypeMTAP24 = 27 // Multi-Time Aggregation Packet, 24-bit timestamp offsets
+	nalUnitTypeFUA    = 28 // Fragmentation Unit A
+	nalUnitTypeFUB    = 29 // Fragmentation Unit B (first fragment, with DON)
 
 	// H.264 start code
 	startCodeSize = 4
@@ -29,12 +38,72 @@ var (
 	startCode = []byte{0x00, 0x00, 0x00, 0x01}
 )
 
+// Codec identifies the coding of a video frame or audio unit
+type Codec int
+
+const (
+	// CodecH264 is H.264/AVC (the zero value)
+	CodecH264 Codec = iota
+	// CodecH265 is H.265/HEVC
+	CodecH265
+	// CodecMJPEG is Motion JPEG: every frame is a complete JFIF image
+	CodecMJPEG
+	// CodecAAC is MPEG-4 AAC carried as mpeg4-generic (RFC 3640)
+	CodecAAC
+	// CodecPCMU is G.711 μ-law
+	CodecPCMU
+	// CodecPCMA is G.711 A-law
+	CodecPCMA
+	// CodecL16 is 16-bit big-endian linear PCM
+	CodecL16
+	// CodecOpus is Opus (RFC 7587); units are single Opus packets
+	CodecOpus
+)
+
+// String returns the RTP encoding name of the codec
+func (c Codec) String() string {
+	switch c {
+	case CodecH265:
+		return "H265"
+	case CodecMJPEG:
+		return "JPEG"
+	case CodecAAC:
+		return "MPEG4-GENERIC"
+	case CodecPCMU:
+		return "PCMU"
+	case CodecPCMA:
+		return "PCMA"
+	case CodecL16:
+		return "L16"
+	case CodecOpus:
+		return "OPUS"
+	default:
+		return "H264"
+	}
+}
+
+// VideoDecoder turns the RTP packets of one video stream into frames
+type VideoDecoder interface {
+	ProcessPacket(packet *rtp.Packet) *Frame
+	Reset()
+	GetStats() DecoderStats
+}
+
 // Frame represents a complete video frame
 type Frame struct {
 	Data        []byte
 	Timestamp   uint32
 	IsKey       bool
 	IsCorrupted bool
+	Codec       Codec // Annex B H.264 unless set; MJPEG frames are JFIF images
+
+	// H.264 only: the frame type from the slice headers, and whether the
+	// frame refers to pictures that were lost or corrupted
+	Type              FrameType
+	IsReferenceBroken bool
+
+	// H.264 only: the SEI messages of the access unit, nil if it has none
+	SEI *SEI
 }
 
 // FrameAssembly represents packets belonging to a single frame (same timestamp)
@@ -55,9 +124,7 @@ type H264Decoder struct {
 	frameMap     map[uint32]*FrameAssembly // Maps timestamp to frame assembly
 
 	// Sequence tracking
-	lastSequence       uint16
-	expectedSequence   uint16
-	sequenceInit       bool
+	source             rtp.SourceState
 
 	// SSRC tracking
 	currentSSRC        uint32
@@ -67,11 +134,29 @@ type H264Decoder struct {
 	spsNAL             []byte
 	ppsNAL             []byte
 
+	// Parsed parameter sets by ID and the properties of the latest SPS
+	spsByID    map[uint32]*SPS
+	ppsByID    map[uint32]*PPS
+	streamInfo *StreamInfo
+
+	// Reference chain state from slice headers
+	references referenceChain
+
+	// SEI user data parsers, and the last pic_timing timecode that partial
+	// timecodes continue from
+	userData     *UserDataRegistry
+	lastTimecode Timecode
+
 	// Statistics
 	stats              DecoderStats
 
 	// Options
 	dropCorruptedFrames bool // If true, don't return corrupted frames
+
+	// Interleaved mode (packetization-mode=2): NAL units are restored to
+	// decoding order instead of being grouped by RTP timestamp
+	interleaved   bool
+	deinterleaver h264Deinterleaver
 }
 
 // DecoderStats tracks decoder statistics
@@ -79,21 +164,79 @@ type DecoderStats struct {
 	TotalFrames      int
 	CorruptedFrames  int
 	PacketLossEvents int
+	PacketsLost      int // Packets missing from sequence gaps
+	DuplicatePackets int
+	SequenceRestarts int // Sender restarts detected without an SSRC change
 	SSRCChanges      int
+
+	// H.264 reference chain tracking
+	FrameNumGaps          int
+	ReferenceBrokenFrames int
 }
 
 // NewH264Decoder creates a new H.264 decoder
 func NewH264Decoder() *H264Decoder {
 	return &H264Decoder{
 		frameMap: make(map[uint32]*FrameAssembly),
+		spsByID:  make(map[uint32]*SPS),
+		ppsByID:  make(map[uint32]*PPS),
+		userData: DefaultUserDataRegistry,
 	}
 }
 
+// SetUserDataRegistry sets the parsers for SEI user_data_unregistered
+// messages; the default is DefaultUserDataRegistry
+func (d *H264Decoder) SetUserDataRegistry(registry *UserDataRegistry) {
+	d.userData = registry
+}
+
 // SetDropCorruptedFrames sets whether to drop corrupted frames instead of returning them
 func (d *H264Decoder) SetDropCorruptedFrames(drop bool) {
 	d.dropCorruptedFrames = drop
 }
 
+// SetFMTP applies the SDP format parameters of an H264 track: the
+// sprop-parameter-sets are cached and parsed into the StreamInfo, and
+// packetization-mode=2 enables DON reordering with the
+// sprop-interleaving-depth and sprop-max-don-diff buffer limits
+func (d *H264Decoder) SetFMTP(fmtp map[string]string) error {
+	if value := fmtp["sprop-parameter-sets"]; value != "" {
+		for _, set := range strings.Split(value, ",") {
+			nal, err := base64.StdEncoding.DecodeString(strings.TrimSpace(set))
+			if err != nil {
+				return fmt.Errorf("%w: sprop-parameter-sets: %v", ErrInvalidParameterSets, err)
+			}
+			d.cacheParameterSet(nal)
+		}
+	}
+
+	params := map[string]int{}
+	for _, name := range []string{"packetization-mode", "sprop-interleaving-depth", "sprop-max-don-diff"} {
+		value := fmtp[name]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+		params[name] = n
+	}
+
+	interleaved := params["packetization-mode"] == h264PacketizationInterleaved
+	if interleaved != d.interleaved {
+		d.Reset()
+	}
+	d.interleaved = interleaved
+	d.deinterleaver.depth = params["sprop-interleaving-depth"]
+	d.deinterleaver.maxDONDiff = params["sprop-max-don-diff"]
+	if interleaved {
+		logger.Info("[H264Decoder] Interleaved mode: interleaving depth %d, max DON diff %d",
+			d.deinterleaver.depth, d.deinterleaver.maxDONDiff)
+	}
+	return nil
+}
+
 // ProcessPacket processes an RTP packet and returns a frame if complete
 func (d *H264Decoder) ProcessPacket(packet *rtp.Packet) *Frame {
 	if packet == nil || len(packet.Payload) == 0 {
@@ -117,29 +260,39 @@ func (d *H264Decoder) ProcessPacket(packet *rtp.Packet) *Frame {
 		// Reset decoder state for new stream
 		d.Reset()
 		d.currentSSRC = packet.SSRC
-		d.sequenceInit = false // Re-initialize sequence tracking
+		d.source.Reset() // Re-initialize sequence tracking
 		d.stats.SSRCChanges++
 		// Clear SPS/PPS on SSRC change - need new ones for new stream
 		d.spsNAL = nil
 		d.ppsNAL = nil
+		d.spsByID = make(map[uint32]*SPS)
+		d.ppsByID = make(map[uint32]*PPS)
+		d.streamInfo = nil
+		d.lastTimecode = Timecode{}
+	}
+
+	// Track sequence numbers: detect loss, duplicates and sender restarts
+	update := d.source.Update(packet.SequenceNumber)
+	switch update.Status {
+	case rtp.SequenceDuplicate:
+		d.stats.DuplicatePackets++
+		logger.Debug("[H264Decoder:ProcessPacket] Dropping duplicate packet seq=%d", packet.SequenceNumber)
+		return nil
+	case rtp.SequenceRestarted:
+		logger.Warn("[H264Decoder] Sequence restarted at seq=%d without SSRC change", packet.SequenceNumber)
+		d.Reset()
+		d.stats.SequenceRestarts++
+	case rtp.SequenceInOrder:
+		if update.Gap > 0 {
+			d.stats.PacketLossEvents++
+			d.stats.PacketsLost += int(update.Gap)
+		}
 	}
 
-	// Initialize sequence tracking on first packet
-	if !d.sequenceInit {
-		d.expectedSequence = packet.SequenceNumber
-		d.lastSequence = packet.SequenceNumber
-		d.sequenceInit = true
-	}
-
-	// Detect packet loss by checking sequence number gap
-	if d.detectPacketLoss(packet.SequenceNumber) {
-		d.stats.PacketLossEvents++
+	if d.interleaved {
+		return d.processInterleaved(packet, update.Status == rtp.SequenceInOrder && update.Gap > 0)
 	}
 
-	// Update sequence tracking
-	d.lastSequence = packet.SequenceNumber
-	d.expectedSequence = packet.SequenceNumber + 1
-
 	// Get or create frame assembly for this timestamp
 	frameAssembly := d.getOrCreateFrameAssembly(packet.Timestamp)
 
@@ -154,10 +307,10 @@ func (d *H264Decoder) ProcessPacket(packet *rtp.Packet) *Frame {
 		frameAssembly.seqMax = packet.SequenceNumber
 	} else {
 		// Update sequence range
-		if sequenceBefore(packet.SequenceNumber, frameAssembly.seqMin) {
+		if rtp.CompareSequence(packet.SequenceNumber, frameAssembly.seqMin) < 0 {
 			frameAssembly.seqMin = packet.SequenceNumber
 		}
-		if sequenceAfter(packet.SequenceNumber, frameAssembly.seqMax) {
+		if rtp.CompareSequence(packet.SequenceNumber, frameAssembly.seqMax) > 0 {
 			frameAssembly.seqMax = packet.SequenceNumber
 		}
 	}
@@ -254,14 +407,14 @@ func (d *H264Decoder) checkFramePacketLoss(fa *FrameAssembly) {
 		seqs = append(seqs, seq)
 	}
 	sort.Slice(seqs, func(i, j int) bool {
-		return sequenceBefore(seqs[i], seqs[j])
+		return rtp.CompareSequence(seqs[i], seqs[j]) < 0
 	})
 
 	// Check for gaps
 	for i := 1; i < len(seqs); i++ {
 		expectedSeq := seqs[i-1] + 1
 		if seqs[i] != expectedSeq {
-			gap := sequenceDifference(expectedSeq, seqs[i])
+			gap := rtp.SequenceDistance(expectedSeq, seqs[i])
 			if gap > 0 && gap < 100 {
 				// Packet loss detected
 				fa.hasPacketLoss = true
@@ -283,7 +436,7 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 		seqs = append(seqs, seq)
 	}
 	sort.Slice(seqs, func(i, j int) bool {
-		return sequenceBefore(seqs[i], seqs[j])
+		return rtp.CompareSequence(seqs[i], seqs[j]) < 0
 	})
 
 	// Reassemble frame
@@ -311,34 +464,37 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 		nalType := payload[0] & 0x1F
 
 		switch nalType {
-		case nalUnitTypeSTAP:
-			// STAP-A: Multiple NAL units in one packet
-			nalUnits := d.unpackSTAPA(payload)
-			for _, nal := range nalUnits {
-				// Extract and cache SPS/PPS
-				extractedType := nal[0] & 0x1F
-				if extractedType == nalUnitTypeSPS {
-					d.spsNAL = append([]byte{}, startCode...)
-					d.spsNAL = append(d.spsNAL, nal...)
-				} else if extractedType == nalUnitTypePPS {
-					d.ppsNAL = append([]byte{}, startCode...)
-					d.ppsNAL = append(d.ppsNAL, nal...)
-				} else if extractedType == nalUnitTypeIDR {
+		case nalUnitTypeSTAP, nalUnitTypeSTAPB, nalUnitTypeMTAP16, nalUnitTypeMTAP24:
+			// Multiple NAL units in one packet; outside interleaved mode
+			// DONs and timestamp offsets are ignored and packet order is kept
+			units, ok := unpackAggregate(payload, packet.Timestamp)
+			if !ok {
+				isCorrupted = true
+			}
+			for _, unit := range units {
+				if d.cacheParameterSet(unit.data) {
 					hasIDR = true
 				}
-
 				frameData = append(frameData, startCode...)
-				frameData = append(frameData, nal...)
+				frameData = append(frameData, unit.data...)
 			}
 
-		case nalUnitTypeFUA:
-			// FU-A fragmentation
+		case nalUnitTypeFUA, nalUnitTypeFUB:
+			// FU-A/FU-B fragmentation; FU-B starts a NAL unit with its DON
 			if len(payload) < 2 {
 				isCorrupted = true
 				continue
 			}
 
 			fuHeader := payload[1]
+			fragmentData := payload[2:]
+			if nalType == nalUnitTypeFUB {
+				if len(fragmentData) < 2 || fuHeader&fuStartBit == 0 {
+					isCorrupted = true
+					continue
+				}
+				fragmentData = fragmentData[2:]
+			}
 			isStart := (fuHeader >> 7) & 0x01
 			isEnd := (fuHeader >> 6) & 0x01
 
@@ -372,10 +528,8 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 				frameData = append(frameData, startCode...)
 				frameData = append(frameData, fuaState.nalHeader)
 
-				// Add payload (skip FU indicator and FU header)
-				if len(payload) > 2 {
-					frameData = append(frameData, payload[2:]...)
-				}
+				// Add payload (skip FU indicator, FU header and DON)
+				frameData = append(frameData, fragmentData...)
 			} else if isEnd == 1 {
 				// End of FU-A
 				if !fuaState.active || !fuaState.started {
@@ -386,10 +540,8 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 				fuaState.ended = true
 				fuaState.active = false
 
-				// Add payload (skip FU indicator and FU header)
-				if len(payload) > 2 {
-					frameData = append(frameData, payload[2:]...)
-				}
+				// Add payload (skip FU indicator, FU header and DON)
+				frameData = append(frameData, fragmentData...)
 			} else {
 				// Middle fragment
 				if !fuaState.active || !fuaState.started {
@@ -397,10 +549,8 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 					continue
 				}
 
-				// Add payload (skip FU indicator and FU header)
-				if len(payload) > 2 {
-					frameData = append(frameData, payload[2:]...)
-				}
+				// Add payload (skip FU indicator, FU header and DON)
+				frameData = append(frameData, fragmentData...)
 			}
 
 		default:
@@ -412,13 +562,7 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 			}
 
 			// Extract and cache SPS/PPS
-			if nalType == nalUnitTypeSPS {
-				d.spsNAL = append([]byte{}, startCode...)
-				d.spsNAL = append(d.spsNAL, payload...)
-			} else if nalType == nalUnitTypePPS {
-				d.ppsNAL = append([]byte{}, startCode...)
-				d.ppsNAL = append(d.ppsNAL, payload...)
-			} else if nalType == nalUnitTypeIDR {
+			if d.cacheParameterSet(payload) {
 				hasIDR = true
 			}
 
@@ -442,8 +586,28 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 	logger.Debug("[H264Decoder:finalizeFrame] Frame assembled: timestamp=%d, size=%d bytes, packets=%d, hasIDR=%t, corrupted=%t", 
 		fa.timestamp, len(frameData), len(fa.packets), hasIDR, isCorrupted)
 
-	// For IDR frames, prepend SPS/PPS if available
-	if hasIDR && len(d.spsNAL) > 0 && len(d.ppsNAL) > 0 {
+	return d.newFrame(frameData, fa.timestamp, hasIDR, isCorrupted)
+}
+
+// newFrame completes an assembled Annex B access unit: keyframes (IDR or
+// recovery point) get the cached SPS/PPS, and corrupted frames are counted
+// and optionally dropped
+func (d *H264Decoder) newFrame(frameData []byte, timestamp uint32, hasIDR, isCorrupted bool) *Frame {
+	// Create frame
+	frame := &Frame{
+		Data:        frameData,
+		Timestamp:   timestamp,
+		IsCorrupted: isCorrupted,
+	}
+
+	// Classify before dropping: a lost reference frame breaks the chain
+	d.trackReferences(frame)
+
+	// Open GOP recovery points are random access points too
+	frame.IsKey = hasIDR || frame.isRecoveryKeyFrame()
+
+	// For keyframes, prepend SPS/PPS if available
+	if frame.IsKey && len(d.spsNAL) > 0 && len(d.ppsNAL) > 0 {
 		// Check if frame already has SPS/PPS
 		hasSPS := d.frameHasNAL(frameData, nalUnitTypeSPS)
 		hasPPS := d.frameHasNAL(frameData, nalUnitTypePPS)
@@ -454,7 +618,7 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 			prepended = append(prepended, d.spsNAL...)
 			prepended = append(prepended, d.ppsNAL...)
 			prepended = append(prepended, frameData...)
-			frameData = prepended
+			frame.Data = prepended
 		}
 	}
 
@@ -464,14 +628,6 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 		return nil
 	}
 
-	// Create frame
-	frame := &Frame{
-		Data:        frameData,
-		Timestamp:   fa.timestamp,
-		IsCorrupted: isCorrupted,
-	}
-	frame.IsKey = frame.IsKeyFrame()
-
 	// Update statistics
 	d.stats.TotalFrames++
 	if frame.IsCorrupted {
@@ -481,35 +637,52 @@ func (d *H264Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
 	return frame
 }
 
-// unpackSTAPA unpacks a STAP-A packet into individual NAL units
-func (d *H264Decoder) unpackSTAPA(payload []byte) [][]byte {
-	if len(payload) < 1 {
-		return nil
+// cacheParameterSet caches a complete SPS or PPS NAL unit and reports whether
+// nal is an IDR slice. Changed parameter sets are parsed and update the StreamInfo.
+func (d *H264Decoder) cacheParameterSet(nal []byte) (isIDR bool) {
+	if len(nal) == 0 {
+		return false
 	}
-
-	var nalUnits [][]byte
-	offset := 1 // Skip STAP-A indicator
-
-	for offset < len(payload) {
-		if offset+2 > len(payload) {
-			break
+	switch nal[0] & 0x1F {
+	case nalUnitTypeSPS:
+		if len(d.spsNAL) > 0 && bytes.Equal(d.spsNAL[startCodeSize:], nal) {
+			return false
 		}
-
-		// Read NAL unit size (2 bytes, big-endian)
-		nalSize := uint16(payload[offset])<<8 | uint16(payload[offset+1])
-		offset += 2
-
-		if offset+int(nalSize) > len(payload) {
-			break
+		d.spsNAL = append(append([]byte{}, startCode...), nal...)
+		sps, err := ParseSPS(nal)
+		if err != nil {
+			logger.Warn("[H264Decoder] Ignoring SPS: %v", err)
+			return false
 		}
-
-		// Extract NAL unit
-		nalUnit := payload[offset : offset+int(nalSize)]
-		nalUnits = append(nalUnits, nalUnit)
-		offset += int(nalSize)
+		d.spsByID[sps.ID] = sps
+		if info := sps.StreamInfo(); d.streamInfo == nil || *d.streamInfo != info {
+			logger.Info("[H264Decoder] Stream info: %s", info)
+			d.streamInfo = &info
+		}
+	case nalUnitTypePPS:
+		if len(d.ppsNAL) > 0 && bytes.Equal(d.ppsNAL[startCodeSize:], nal) {
+			return false
+		}
+		d.ppsNAL = append(append([]byte{}, startCode...), nal...)
+		pps, err := ParsePPS(nal)
+		if err != nil {
+			logger.Warn("[H264Decoder] Ignoring PPS: %v", err)
+			return false
+		}
+		d.ppsByID[pps.ID] = pps
+	case nalUnitTypeIDR:
+		return true
 	}
+	return false
+}
 
-	return nalUnits
+// GetStreamInfo returns the properties of the latest SPS; ok is false until
+// an SPS was received in-band or from sprop-parameter-sets
+func (d *H264Decoder) GetStreamInfo() (info StreamInfo, ok bool) {
+	if d.streamInfo == nil {
+		return StreamInfo{}, false
+	}
+	return *d.streamInfo, true
 }
 
 // frameHasNAL checks if frame contains a specific NAL type
@@ -539,51 +712,6 @@ func (d *H264Decoder) frameHasNAL(frameData []byte, nalType byte) bool {
 	return false
 }
 
-// detectPacketLoss checks if there's a gap in sequence numbers
-func (d *H264Decoder) detectPacketLoss(currentSeq uint16) bool {
-	if !d.sequenceInit {
-		return false
-	}
-
-	// Handle sequence number wraparound (65535 -> 0)
-	expectedSeq := d.expectedSequence
-
-	// Check if current sequence is what we expected
-	if currentSeq != expectedSeq {
-		// Calculate gap size (handling wraparound)
-		gap := sequenceDifference(expectedSeq, currentSeq)
-
-		// If gap is 1-100, it's likely packet loss
-		// If gap > 32000, it's probably wraparound or old packet
-		if gap > 0 && gap < 100 {
-			return true
-		}
-	}
-
-	return false
-}
-
-// sequenceDifference calculates the difference between sequences handling wraparound
-func sequenceDifference(seq1, seq2 uint16) uint16 {
-	if seq2 >= seq1 {
-		return seq2 - seq1
-	}
-	// Wraparound case
-	return (65535 - seq1) + seq2 + 1
-}
-
-// sequenceBefore returns true if seq1 comes before seq2 (wrap-aware)
-func sequenceBefore(seq1, seq2 uint16) bool {
-	diff := sequenceDifference(seq1, seq2)
-	return diff < 32768 // Less than half the space = seq1 before seq2
-}
-
-// sequenceAfter returns true if seq1 comes after seq2 (wrap-aware)
-func sequenceAfter(seq1, seq2 uint16) bool {
-	return sequenceBefore(seq2, seq1)
-}
-
-
 // Reset resets the decoder state
 func (d *H264Decoder) Reset() {
 	// Clear all frame assemblies
@@ -591,6 +719,9 @@ func (d *H264Decoder) Reset() {
 		delete(d.frameMap, k)
 	}
 	d.currentFrame = nil
+	d.deinterleaver.reset()
+	// Frames are lost across a reset; the next IDR starts a clean chain
+	d.references = referenceChain{}
 	// Note: Don't reset SSRC tracking - it persists across frame boundaries
 	// Note: Don't reset SPS/PPS - they remain valid until SSRC changes
 }
@@ -610,8 +741,16 @@ func (d *H264Decoder) ResetStats() {
 	d.stats = DecoderStats{}
 }
 
-// IsKeyFrame checks if the frame contains a keyframe (IDR)
+// IsKeyFrame checks if the frame contains a keyframe (IDR, or IRAP for H.265),
+// or is an H.264 recovery point that decodes on its own (see Frame.SEI).
+// Every JPEG frame is a keyframe.
 func (f *Frame) IsKeyFrame() bool {
+	if f.Codec == CodecMJPEG {
+		return len(f.Data) > 0
+	}
+	if f.isRecoveryKeyFrame() {
+		return true
+	}
 	if len(f.Data) < startCodeSize+1 {
 		return false
 	}
@@ -631,8 +770,11 @@ func (f *Frame) IsKeyFrame() bool {
 				}
 
 				if nalStart < len(f.Data) {
-					nalType := f.Data[nalStart] & 0x1F
-					if nalType == nalUnitTypeIDR {
+					if f.Codec == CodecH265 {
+						if isH265IRAP(h265NALType(f.Data[nalStart])) {
+							return true
+						}
+					} else if f.Data[nalStart]&0x1F == nalUnitTypeIDR {
 						return true
 					}
 				}
@@ -643,6 +785,23 @@ func (f *Frame) IsKeyFrame() bool {
 	return false
 }
 
+// isRecoveryKeyFrame reports whether an H.264 recovery point SEI makes the
+// frame a random access point (open GOP): decoding must be correct from this
+// frame on, i.e. recovery_frame_cnt is 0, or the frame is intra-only. The
+// frames of a gradual intra refresh (recovery_frame_cnt > 0) are not keyframes.
+func (f *Frame) isRecoveryKeyFrame() bool {
+	if f.Codec != CodecH264 || f.SEI == nil || f.SEI.RecoveryPoint == nil {
+		return false
+	}
+	return f.SEI.RecoveryPoint.RecoveryFrameCount == 0 || f.Type == FrameTypeI
+}
+
+// IsDamaged reports whether the frame is corrupted or reference-broken, i.e.
+// whether it decodes with artifacts
+func (f *Frame) IsDamaged() bool {
+	return f.IsCorrupted || f.IsReferenceBroken
+}
+
 // GetTimestampString returns the timestamp as a string for filename
 func (f *Frame) GetTimestampString() string {
 	return fmt.Sprintf("%d", f.Timestamp)
@@ -653,6 +812,8 @@ func (f *Frame) String() string {
 	corrupted := ""
 	if f.IsCorrupted {
 		corrupted = ", CORRUPTED"
+	} else if f.IsReferenceBroken {
+		corrupted = ", REFERENCE-BROKEN"
 	}
 	return fmt.Sprintf("Frame [Timestamp: %d, Size: %d bytes, IsKey: %t%s]",
 		f.Timestamp, len(f.Data), f.IsKey, corrupted)
@@ -0,0 +1,303 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// h264PacketizationInterleaved is packetization-mode=2 (RFC 6184 section 6.4)
+	h264PacketizationInterleaved = 2
+
+	// h264MaxDeinterleaveUnits bounds the deinterleaving buffer if a sender
+	// exceeds its announced interleaving depth
+	h264MaxDeinterleaveUnits = 1024
+)
+
+// h264NALUnit is a NAL unit with the decoding order number (DON) and
+// timestamp it was transmitted with
+type h264NALUnit struct {
+	data      []byte
+	don       uint16
+	timestamp uint32
+	corrupted bool
+}
+
+// isVCL reports whether the unit is a coded slice (NAL unit types 1-5)
+func (u *h264NALUnit) isVCL() bool {
+	nalType := u.data[0] & 0x1F
+	return nalType >= 1 && nalType <= nalUnitTypeIDR
+}
+
+// donDiff returns the distance from DON a to DON b, negative if b precedes a
+// (RFC 6184 section 5.5, modulo 2^16)
+func donDiff(a, b uint16) int {
+	return int(int16(b - a))
+}
+
+// unpackAggregate unpacks a STAP-A, STAP-B, MTAP16 or MTAP24 packet (RFC 6184
+// section 5.7). STAP-B units get consecutive DONs; MTAP units get DONB + DOND
+// and the packet timestamp plus their offset. STAP-A units carry no DON.
+// ok is false if the packet was truncated.
+func unpackAggregate(payload []byte, timestamp uint32) (units []h264NALUnit, ok bool) {
+	if len(payload) < 1 {
+		return nil, false
+	}
+	nalType := payload[0] & 0x1F
+	offset := 1
+
+	var don uint16
+	if nalType != nalUnitTypeSTAP {
+		if len(payload) < offset+2 {
+			return nil, false
+		}
+		don = binary.BigEndian.Uint16(payload[offset:])
+		offset += 2
+	}
+
+	tsOffsetSize := 0
+	switch nalType {
+	case nalUnitTypeMTAP16:
+		tsOffsetSize = 2
+	case nalUnitTypeMTAP24:
+		tsOffsetSize = 3
+	}
+
+	for offset < len(payload) {
+		if offset+2 > len(payload) {
+			return units, false
+		}
+		nalSize := int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+
+		unit := h264NALUnit{don: don, timestamp: timestamp}
+		if tsOffsetSize > 0 {
+			if offset+1+tsOffsetSize > len(payload) {
+				return units, false
+			}
+			unit.don = don + uint16(payload[offset])
+			var tsOffset uint32
+			for _, b := range payload[offset+1 : offset+1+tsOffsetSize] {
+				tsOffset = tsOffset<<8 | uint32(b)
+			}
+			unit.timestamp += tsOffset
+			offset += 1 + tsOffsetSize
+		} else if nalType == nalUnitTypeSTAPB {
+			unit.don = don + uint16(len(units))
+		}
+
+		if nalSize == 0 || offset+nalSize > len(payload) {
+			return units, false
+		}
+		unit.data = payload[offset : offset+nalSize]
+		units = append(units, unit)
+		offset += nalSize
+	}
+	return units, len(units) > 0
+}
+
+// h264Deinterleaver restores decoding order in interleaved mode (RFC 6184
+// section 13). NAL units wait in a buffer sorted by DON and are released once
+// more VCL NAL units than sprop-interleaving-depth are buffered, or the DON span
+// exceeds sprop-max-don-diff. Released units are grouped into access units by
+// timestamp, so an access unit completes when the next one starts.
+type h264Deinterleaver struct {
+	depth      int // sprop-interleaving-depth
+	maxDONDiff int // sprop-max-don-diff; 0 if not announced
+
+	buffer   []h264NALUnit // Sorted by DON, equal DONs in arrival order
+	vclUnits int           // VCL NAL units in buffer
+
+	// NAL unit being reassembled from FU-A/FU-B packets
+	fragment       h264NALUnit
+	fragmentActive bool
+	fragmentSeq    uint16
+
+	lastDON     uint16 // DON of the last unit received, for units sent without one
+	lossPending bool   // Packets were lost; the next unit is marked corrupted
+
+	// Access unit being collected from released units
+	accessUnit []h264NALUnit
+}
+
+// push inserts a unit into the buffer in DON order
+func (b *h264Deinterleaver) push(unit h264NALUnit) {
+	if len(unit.data) == 0 {
+		return
+	}
+	if b.lossPending {
+		unit.corrupted = true
+		b.lossPending = false
+	}
+	b.lastDON = unit.don
+
+	i := sort.Search(len(b.buffer), func(i int) bool {
+		return donDiff(unit.don, b.buffer[i].don) > 0
+	})
+	b.buffer = append(b.buffer, h264NALUnit{})
+	copy(b.buffer[i+1:], b.buffer[i:])
+	b.buffer[i] = unit
+	if unit.isVCL() {
+		b.vclUnits++
+	}
+}
+
+// pop releases the unit with the lowest DON once decoding order is settled
+func (b *h264Deinterleaver) pop() (h264NALUnit, bool) {
+	if len(b.buffer) == 0 {
+		return h264NALUnit{}, false
+	}
+	span := donDiff(b.buffer[0].don, b.buffer[len(b.buffer)-1].don)
+	if b.vclUnits <= b.depth && (b.maxDONDiff == 0 || span <= b.maxDONDiff) && len(b.buffer) <= h264MaxDeinterleaveUnits {
+		return h264NALUnit{}, false
+	}
+
+	unit := b.buffer[0]
+	b.buffer = b.buffer[1:]
+	if unit.isVCL() {
+		b.vclUnits--
+	}
+	return unit, true
+}
+
+// reset drops buffered and partially reassembled units
+func (b *h264Deinterleaver) reset() {
+	b.buffer = nil
+	b.vclUnits = 0
+	b.fragmentActive = false
+	b.lossPending = false
+	b.accessUnit = nil
+}
+
+// processInterleaved depacketizes a packet into the deinterleaving buffer and
+// returns the next access unit completed in decoding order
+func (d *H264Decoder) processInterleaved(packet *rtp.Packet, lost bool) *Frame {
+	b := &d.deinterleaver
+	if lost {
+		b.lossPending = true
+	}
+
+	payload := packet.Payload
+	nalType := payload[0] & 0x1F
+	if nalType != nalUnitTypeFUA && b.fragmentActive {
+		logger.Debug("[H264Decoder:processInterleaved] Fragmented NAL unit DON=%d not ended", b.fragment.don)
+		b.fragment.corrupted = true
+		b.push(b.fragment)
+		b.fragmentActive = false
+	}
+
+	switch nalType {
+	case nalUnitTypeSTAP, nalUnitTypeSTAPB, nalUnitTypeMTAP16, nalUnitTypeMTAP24:
+		units, ok := unpackAggregate(payload, packet.Timestamp)
+		for _, unit := range units {
+			if nalType == nalUnitTypeSTAP {
+				// STAP-A is not allowed in interleaved mode; keep packet order
+				unit.don = b.lastDON + 1
+			}
+			unit.corrupted = !ok
+			b.push(unit)
+		}
+		if !ok {
+			b.lossPending = true
+		}
+
+	case nalUnitTypeFUA, nalUnitTypeFUB:
+		d.pushFragment(packet)
+
+	default:
+		// Single NAL unit packets are not allowed in interleaved mode; keep packet order
+		b.push(h264NALUnit{data: payload, don: b.lastDON + 1, timestamp: packet.Timestamp})
+	}
+
+	for unit, ok := b.pop(); ok; unit, ok = b.pop() {
+		if len(b.accessUnit) > 0 && unit.timestamp != b.accessUnit[0].timestamp {
+			frame := d.finishAccessUnit()
+			b.accessUnit = append(b.accessUnit, unit)
+			if frame != nil {
+				return frame
+			}
+			continue
+		}
+		b.accessUnit = append(b.accessUnit, unit)
+	}
+	return nil
+}
+
+// pushFragment reassembles FU-A/FU-B packets; a FU-B starts a NAL unit and
+// carries its DON, and the following fragments must arrive in sequence
+func (d *H264Decoder) pushFragment(packet *rtp.Packet) {
+	b := &d.deinterleaver
+	payload := packet.Payload
+	if len(payload) < 2 {
+		b.lossPending = true
+		return
+	}
+	fuHeader := payload[1]
+	data := payload[2:]
+
+	if fuHeader&fuStartBit != 0 {
+		if b.fragmentActive {
+			b.fragment.corrupted = true
+			b.push(b.fragment)
+		}
+		don := b.lastDON + 1
+		if payload[0]&0x1F == nalUnitTypeFUB {
+			if len(data) < 2 {
+				b.fragmentActive = false
+				b.lossPending = true
+				return
+			}
+			don = binary.BigEndian.Uint16(data)
+			data = data[2:]
+		}
+		header := payload[0]&0xE0 | fuHeader&0x1F
+		b.fragment = h264NALUnit{data: append([]byte{header}, data...), don: don, timestamp: packet.Timestamp}
+		b.fragmentActive = true
+	} else {
+		if !b.fragmentActive || packet.SequenceNumber != b.fragmentSeq+1 {
+			// The start or a middle fragment was lost
+			if b.fragmentActive {
+				b.fragment.corrupted = true
+				b.push(b.fragment)
+				b.fragmentActive = false
+			}
+			b.lossPending = true
+			return
+		}
+		b.fragment.data = append(b.fragment.data, data...)
+	}
+	b.fragmentSeq = packet.SequenceNumber
+
+	if fuHeader&fuEndBit != 0 {
+		b.push(b.fragment)
+		b.fragmentActive = false
+	}
+}
+
+// finishAccessUnit builds a frame from the collected access unit
+func (d *H264Decoder) finishAccessUnit() *Frame {
+	b := &d.deinterleaver
+	if len(b.accessUnit) == 0 {
+		return nil
+	}
+
+	var frameData []byte
+	var hasIDR, isCorrupted bool
+	for _, unit := range b.accessUnit {
+		if d.cacheParameterSet(unit.data) {
+			hasIDR = true
+		}
+		isCorrupted = isCorrupted || unit.corrupted
+		frameData = append(frameData, startCode...)
+		frameData = append(frameData, unit.data...)
+	}
+	timestamp := b.accessUnit[0].timestamp
+	b.accessUnit = nil
+
+	logger.Debug("[H264Decoder:finishAccessUnit] Access unit assembled: timestamp=%d, size=%d bytes, hasIDR=%t, corrupted=%t",
+		timestamp, len(frameData), hasIDR, isCorrupted)
+	return d.newFrame(frameData, timestamp, hasIDR, isCorrupted)
+}
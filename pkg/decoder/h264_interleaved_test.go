@@ -0,0 +1,170 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testH264SPS    = []byte{0x67, 0x42, 0xc0, 0x1f}
+	testH264PPS    = []byte{0x68, 0xce, 0x3c, 0x80}
+	testH264IDR    = []byte{0x65, 0x88, 0x84, 0x00}
+	testH264PSlice = []byte{0x41, 0x9a, 0x22, 0x10}
+	testH264BSlice = []byte{0x01, 0x9e, 0x41, 0x20}
+)
+
+// h264STAPB builds a STAP-B packet whose first unit has the given DON
+func h264STAPB(don uint16, nalUnits ...[]byte) []byte {
+	payload := []byte{0x19, byte(don >> 8), byte(don)}
+	for _, nal := range nalUnits {
+		payload = append(payload, byte(len(nal)>>8), byte(len(nal)))
+		payload = append(payload, nal...)
+	}
+	return payload
+}
+
+func h264Packet(seq uint16, timestamp uint32, payload []byte) *rtp.Packet {
+	return &rtp.Packet{SequenceNumber: seq, Timestamp: timestamp, SSRC: 0x264, Payload: payload}
+}
+
+func TestUnpackAggregate(t *testing.T) {
+	tests := []struct {
+		name      string
+		payload   []byte
+		expected  []h264NALUnit
+		truncated bool
+	}{
+		{
+			name:    "STAP-A",
+			payload: []byte{0x18, 0x00, 0x02, 0x67, 0x42, 0x00, 0x01, 0x68},
+			expected: []h264NALUnit{
+				{data: []byte{0x67, 0x42}, timestamp: 9000},
+				{data: []byte{0x68}, timestamp: 9000},
+			},
+		},
+		{
+			name:    "STAP-B with consecutive DONs",
+			payload: []byte{0x19, 0xFF, 0xFF, 0x00, 0x01, 0x67, 0x00, 0x01, 0x68},
+			expected: []h264NALUnit{
+				{data: []byte{0x67}, don: 0xFFFF, timestamp: 9000},
+				{data: []byte{0x68}, don: 0, timestamp: 9000},
+			},
+		},
+		{
+			name: "MTAP16",
+			payload: []byte{0x1A, 0x00, 0x10,
+				0x00, 0x01, 0x00, 0x00, 0x00, 0x65,
+				0x00, 0x01, 0x02, 0x0B, 0xB8, 0x01},
+			expected: []h264NALUnit{
+				{data: []byte{0x65}, don: 0x10, timestamp: 9000},
+				{data: []byte{0x01}, don: 0x12, timestamp: 12000},
+			},
+		},
+		{
+			name:     "MTAP24",
+			payload:  []byte{0x1B, 0x00, 0x10, 0x00, 0x01, 0x01, 0x01, 0x00, 0x00, 0x41},
+			expected: []h264NALUnit{{data: []byte{0x41}, don: 0x11, timestamp: 9000 + 65536}},
+		},
+		{
+			name:      "truncated",
+			payload:   []byte{0x19, 0x00, 0x00, 0x00, 0x01, 0x67, 0x00, 0x05, 0x68},
+			expected:  []h264NALUnit{{data: []byte{0x67}, timestamp: 9000}},
+			truncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			units, ok := unpackAggregate(tt.payload, 9000)
+			assert.Equal(t, !tt.truncated, ok)
+			assert.Equal(t, tt.expected, units)
+		})
+	}
+}
+
+func TestH264Decoder_NonInterleavedAggregates(t *testing.T) {
+	d := NewH264Decoder()
+
+	// STAP-B and FU-B outside interleaved mode: DONs are stripped
+	frame := d.ProcessPacket(&rtp.Packet{SequenceNumber: 1, Timestamp: 3000, Marker: true, SSRC: 1,
+		Payload: h264STAPB(7, testH264SPS, testH264PPS, testH264IDR)})
+	require.NotNil(t, frame)
+	assert.Equal(t, annexB(testH264SPS, testH264PPS, testH264IDR), frame.Data)
+	assert.True(t, frame.IsKey)
+
+	d.ProcessPacket(&rtp.Packet{SequenceNumber: 2, Timestamp: 6000, SSRC: 1, Payload: []byte{0x5D, 0x81, 0x00, 0x08, 0x9a, 0x22}})
+	frame = d.ProcessPacket(&rtp.Packet{SequenceNumber: 3, Timestamp: 6000, Marker: true, SSRC: 1, Payload: []byte{0x5C, 0x41, 0x10}})
+	require.NotNil(t, frame)
+	assert.Equal(t, annexB(testH264PSlice), frame.Data)
+	assert.False(t, frame.IsCorrupted)
+}
+
+func TestH264Decoder_Interleaved(t *testing.T) {
+	d := NewH264Decoder()
+	require.NoError(t, d.SetFMTP(map[string]string{"packetization-mode": "2", "sprop-interleaving-depth": "1"}))
+
+	// Decoding order: I (DON 0-2, ts 0), P (DON 3, ts 6000), B (DON 4, ts 3000), P (DON 5, ts 9000)
+	packets := []*rtp.Packet{
+		h264Packet(1, 0, h264STAPB(0, testH264SPS, testH264PPS)),
+		h264Packet(2, 6000, []byte{0x5D, 0x81, 0x00, 0x03, 0x9a}), // FU-B start of the P slice
+		h264Packet(3, 6000, []byte{0x5C, 0x41, 0x22, 0x10}),       // FU-A end
+		h264Packet(4, 0, []byte{0x1A, 0x00, 0x02, // MTAP16 with the IDR and the B slice
+			0x00, 0x04, 0x00, 0x00, 0x00, 0x65, 0x88, 0x84, 0x00,
+			0x00, 0x04, 0x02, 0x0B, 0xB8, 0x01, 0x9e, 0x41, 0x20}),
+		h264Packet(5, 9000, h264STAPB(5, testH264PSlice)),
+		h264Packet(6, 12000, h264STAPB(6, testH264PSlice)),
+	}
+
+	var frames []*Frame
+	for _, packet := range packets {
+		if frame := d.ProcessPacket(packet); frame != nil {
+			frames = append(frames, frame)
+		}
+	}
+
+	require.Len(t, frames, 3)
+	assert.Equal(t, uint32(0), frames[0].Timestamp)
+	assert.Equal(t, annexB(testH264SPS, testH264PPS, testH264IDR), frames[0].Data)
+	assert.True(t, frames[0].IsKey)
+	assert.Equal(t, uint32(6000), frames[1].Timestamp)
+	assert.Equal(t, annexB(testH264PSlice), frames[1].Data)
+	assert.Equal(t, uint32(3000), frames[2].Timestamp)
+	assert.Equal(t, annexB(testH264BSlice), frames[2].Data)
+	for _, frame := range frames {
+		assert.False(t, frame.IsCorrupted)
+	}
+}
+
+func TestH264Decoder_InterleavedLoss(t *testing.T) {
+	d := NewH264Decoder()
+	require.NoError(t, d.SetFMTP(map[string]string{"packetization-mode": "2"}))
+
+	assert.Nil(t, d.ProcessPacket(h264Packet(1, 0, h264STAPB(0, testH264PSlice))))
+	assert.Nil(t, d.ProcessPacket(h264Packet(2, 3000, []byte{0x5D, 0x81, 0x00, 0x01, 0x9a})))
+	// Packet 3, the middle fragment, is lost
+	frame := d.ProcessPacket(h264Packet(4, 3000, []byte{0x5C, 0x41, 0x10}))
+	require.NotNil(t, frame)
+	assert.False(t, frame.IsCorrupted)
+
+	frame = d.ProcessPacket(h264Packet(5, 6000, h264STAPB(2, testH264PSlice)))
+	require.NotNil(t, frame)
+	assert.Equal(t, uint32(3000), frame.Timestamp)
+	assert.True(t, frame.IsCorrupted)
+	assert.Equal(t, 1, d.GetStats().PacketLossEvents)
+}
+
+func TestH264Decoder_SetFMTP(t *testing.T) {
+	d := NewH264Decoder()
+	require.NoError(t, d.SetFMTP(map[string]string{"packetization-mode": "2", "sprop-interleaving-depth": "4", "sprop-max-don-diff": "10"}))
+	assert.True(t, d.interleaved)
+	assert.Equal(t, 4, d.deinterleaver.depth)
+	assert.Equal(t, 10, d.deinterleaver.maxDONDiff)
+
+	require.NoError(t, d.SetFMTP(map[string]string{"packetization-mode": "1"}))
+	assert.False(t, d.interleaved)
+
+	assert.Error(t, d.SetFMTP(map[string]string{"sprop-interleaving-depth": "x"}))
+}
@@ -0,0 +1,161 @@
+package decoder
+
+import (
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// DefaultMTU is the default maximum RTP packet size (header + payload)
+	DefaultMTU = 1400
+
+	// rtpHeaderSize is the fixed RTP header size (no CSRCs or extensions)
+	rtpHeaderSize = 12
+
+	// FU-A start and end bits in the FU header
+	fuStartBit = 0x80
+	fuEndBit   = 0x40
+)
+
+// H264Packetizer splits Annex-B access units into RTP packets (RFC 6184
+// non-interleaved mode): single NAL units, STAP-A aggregates and FU-A fragments.
+type H264Packetizer struct {
+	mtu         int
+	payloadType uint8
+	ssrc        uint32
+	sequence    uint16
+}
+
+// NewH264Packetizer creates a new H.264 packetizer.
+// mtu is the maximum RTP packet size in bytes; values too small to carry an
+// FU-A fragment fall back to DefaultMTU.
+func NewH264Packetizer(payloadType uint8, ssrc uint32, mtu int) *H264Packetizer {
+	if mtu <= rtpHeaderSize+2 {
+		mtu = DefaultMTU
+	}
+	return &H264Packetizer{
+		mtu:         mtu,
+		payloadType: payloadType,
+		ssrc:        ssrc,
+	}
+}
+
+// SetSequenceNumber sets the sequence number of the next packet
+func (p *H264Packetizer) SetSequenceNumber(seq uint16) {
+	p.sequence = seq
+}
+
+// GetSequenceNumber returns the sequence number of the next packet
+func (p *H264Packetizer) GetSequenceNumber() uint16 {
+	return p.sequence
+}
+
+// Packetize converts a frame's Annex-B data into RTP packets.
+// Small consecutive NAL units are aggregated into STAP-A packets, NAL units
+// larger than the MTU are split into FU-A fragments. The marker bit is set
+// on the last packet of the frame.
+func (p *H264Packetizer) Packetize(frame *Frame) []*rtp.Packet {
+	if frame == nil {
+		return nil
+	}
+
+	maxPayload := p.mtu - rtpHeaderSize
+	var payloads [][]byte
+	var pending [][]byte
+	pendingSize := 1 // STAP-A indicator
+
+	flush := func() {
+		switch len(pending) {
+		case 0:
+			return
+		case 1:
+			payloads = append(payloads, pending[0])
+		default:
+			payloads = append(payloads, buildSTAPA(pending, pendingSize))
+		}
+		pending = nil
+		pendingSize = 1
+	}
+
+	for _, nal := range SplitAnnexB(frame.Data) {
+		if len(nal) > maxPayload {
+			flush()
+			payloads = append(payloads, fragmentFUA(nal, maxPayload)...)
+			continue
+		}
+
+		// A lone NAL unit is sent as-is, so only aggregates are held to the STAP-A size
+		if len(pending) > 0 && pendingSize+2+len(nal) > maxPayload {
+			flush()
+		}
+		pending = append(pending, nal)
+		pendingSize += 2 + len(nal)
+	}
+	flush()
+
+	packets := make([]*rtp.Packet, 0, len(payloads))
+	for i, payload := range payloads {
+		packets = append(packets, &rtp.Packet{
+			Version:        2,
+			Marker:         i == len(payloads)-1,
+			PayloadType:    p.payloadType,
+			SequenceNumber: p.sequence,
+			Timestamp:      frame.Timestamp,
+			SSRC:           p.ssrc,
+			Payload:        payload,
+		})
+		p.sequence++
+	}
+
+	return packets
+}
+
+// buildSTAPA aggregates NAL units into a STAP-A payload of the given size
+func buildSTAPA(nalUnits [][]byte, size int) []byte {
+	payload := make([]byte, 1, size)
+
+	// F is the OR of all F bits, NRI the maximum NRI (RFC 6184 section 5.7)
+	var f, nri byte
+	for _, nal := range nalUnits {
+		f |= nal[0] & 0x80
+		if nal[0]&0x60 > nri {
+			nri = nal[0] & 0x60
+		}
+	}
+	payload[0] = f | nri | nalUnitTypeSTAP
+
+	for _, nal := range nalUnits {
+		payload = append(payload, byte(len(nal)>>8), byte(len(nal)))
+		payload = append(payload, nal...)
+	}
+	return payload
+}
+
+// fragmentFUA splits a NAL unit into FU-A payloads of at most maxPayload bytes
+func fragmentFUA(nal []byte, maxPayload int) [][]byte {
+	indicator := nal[0]&0xE0 | nalUnitTypeFUA
+	nalType := nal[0] & 0x1F
+	data := nal[1:]
+	chunkSize := maxPayload - 2
+
+	var payloads [][]byte
+	for offset := 0; offset < len(data); offset += chunkSize {
+		end := offset + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		header := nalType
+		if offset == 0 {
+			header |= fuStartBit
+		}
+		if end == len(data) {
+			header |= fuEndBit
+		}
+
+		payload := make([]byte, 0, 2+end-offset)
+		payload = append(payload, indicator, header)
+		payload = append(payload, data[offset:end]...)
+		payloads = append(payloads, payload)
+	}
+	return payloads
+}
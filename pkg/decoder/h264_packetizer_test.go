@@ -0,0 +1,155 @@
+package decoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// annexB joins NAL units with 4-byte start codes
+func annexB(nalUnits ...[]byte) []byte {
+	var data []byte
+	for _, nal := range nalUnits {
+		data = append(data, startCode...)
+		data = append(data, nal...)
+	}
+	return data
+}
+
+// makeNAL builds a NAL unit of the given header and total size
+func makeNAL(header byte, size int) []byte {
+	nal := make([]byte, size)
+	nal[0] = header
+	for i := 1; i < size; i++ {
+		nal[i] = byte(i%251 + 1) // Never zero, so no accidental start codes
+	}
+	return nal
+}
+
+// TestSplitAnnexB tests NAL unit extraction from Annex-B data
+func TestSplitAnnexB(t *testing.T) {
+	data := []byte{
+		0xFF,                   // Garbage before first start code
+		0x00, 0x00, 0x00, 0x01, // 4-byte start code
+		0x67, 0x42, 0x00,
+		0x00, 0x00, 0x01, // 3-byte start code
+		0x68, 0xCE,
+		0x00, 0x00, // Trailing zero bytes
+		0x00, 0x00, 0x00, 0x01,
+		0x65, 0x88,
+	}
+
+	nalUnits := SplitAnnexB(data)
+	require.Len(t, nalUnits, 3)
+	assert.Equal(t, []byte{0x67, 0x42}, nalUnits[0])
+	assert.Equal(t, []byte{0x68, 0xCE}, nalUnits[1])
+	assert.Equal(t, []byte{0x65, 0x88}, nalUnits[2])
+
+	assert.Empty(t, SplitAnnexB([]byte{0x65, 0x88}))
+}
+
+// TestH264Packetizer_Packetize tests packetization modes
+func TestH264Packetizer_Packetize(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0xC0, 0x1F}
+	pps := []byte{0x68, 0xCE, 0x3C, 0x80}
+
+	tests := []struct {
+		name          string
+		nalUnits      [][]byte
+		mtu           int
+		expectedTypes []byte // NAL type of each packet payload
+	}{
+		{
+			name:          "single NAL unit",
+			nalUnits:      [][]byte{makeNAL(0x41, 100)},
+			mtu:           1200,
+			expectedTypes: []byte{1},
+		},
+		{
+			name:          "parameter sets aggregated into STAP-A",
+			nalUnits:      [][]byte{sps, pps, makeNAL(0x65, 100)},
+			mtu:           1200,
+			expectedTypes: []byte{nalUnitTypeSTAP},
+		},
+		{
+			name:          "large IDR fragmented into FU-A",
+			nalUnits:      [][]byte{sps, pps, makeNAL(0x65, 3000)},
+			mtu:           1200,
+			expectedTypes: []byte{nalUnitTypeSTAP, nalUnitTypeFUA, nalUnitTypeFUA, nalUnitTypeFUA},
+		},
+		{
+			name:          "aggregate split at MTU",
+			nalUnits:      [][]byte{makeNAL(0x41, 60), makeNAL(0x41, 60), makeNAL(0x41, 60)},
+			mtu:           rtpHeaderSize + 1 + 2*62,
+			expectedTypes: []byte{nalUnitTypeSTAP, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packetizer := NewH264Packetizer(96, 0x12345678, tt.mtu)
+			packetizer.SetSequenceNumber(65534)
+
+			frame := &Frame{Data: annexB(tt.nalUnits...), Timestamp: 90000}
+			packets := packetizer.Packetize(frame)
+			require.Len(t, packets, len(tt.expectedTypes))
+
+			for i, packet := range packets {
+				assert.Equal(t, tt.expectedTypes[i], packet.Payload[0]&0x1F)
+				assert.Equal(t, uint16(65534+i), packet.SequenceNumber)
+				assert.Equal(t, uint32(90000), packet.Timestamp)
+				assert.Equal(t, i == len(packets)-1, packet.Marker)
+				assert.LessOrEqual(t, packet.MarshalSize(), tt.mtu)
+			}
+			assert.Equal(t, uint16(65534+len(packets)), packetizer.GetSequenceNumber())
+		})
+	}
+}
+
+// TestH264Packetizer_RoundTrip tests packetize -> marshal -> parse -> decode
+func TestH264Packetizer_RoundTrip(t *testing.T) {
+	frames := []*Frame{
+		{
+			Data:      annexB([]byte{0x67, 0x42, 0xC0, 0x1F}, []byte{0x68, 0xCE, 0x3C, 0x80}, makeNAL(0x65, 5000)),
+			Timestamp: 3000,
+		},
+		{
+			Data:      annexB(makeNAL(0x41, 800)),
+			Timestamp: 6000,
+		},
+		{
+			Data:      annexB(makeNAL(0x06, 20), makeNAL(0x41, 40), makeNAL(0x41, 2500)),
+			Timestamp: 9000,
+		},
+	}
+
+	packetizer := NewH264Packetizer(96, 0xCAFEBABE, 1000)
+	decoder := NewH264Decoder()
+
+	for _, frame := range frames {
+		var decoded *Frame
+		for _, packet := range packetizer.Packetize(frame) {
+			data, err := packet.Marshal()
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(data), 1000)
+
+			parsed, err := rtp.ParsePacket(data)
+			require.NoError(t, err)
+
+			if f := decoder.ProcessPacket(parsed); f != nil {
+				decoded = f
+			}
+		}
+
+		require.NotNil(t, decoded, "frame %d not decoded", frame.Timestamp)
+		assert.Equal(t, frame.Timestamp, decoded.Timestamp)
+		assert.False(t, decoded.IsCorrupted)
+		assert.True(t, bytes.Equal(frame.Data, decoded.Data), "frame %d data mismatch", frame.Timestamp)
+	}
+
+	assert.Equal(t, DefaultMTU, NewH264Packetizer(96, 1, 0).mtu)
+	assert.Nil(t, packetizer.Packetize(nil))
+}
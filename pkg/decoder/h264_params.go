@@ -0,0 +1,511 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMalformedNAL indicates a NAL unit whose syntax cannot be parsed
+	ErrMalformedNAL = errors.New("malformed NAL unit")
+)
+
+// SPS holds the fields of an H.264 sequence parameter set (ITU-T H.264
+// section 7.3.2.1) needed for stream properties and slice header parsing
+type SPS struct {
+	ProfileIDC      uint8
+	ConstraintFlags uint8 // constraint_set0_flag..constraint_set5_flag, MSB first
+	LevelIDC        uint8
+	ID              uint32
+
+	ChromaFormatIDC         uint32 // 0 monochrome, 1 4:2:0, 2 4:2:2, 3 4:4:4
+	SeparateColourPlane     bool
+	BitDepthLuma            int
+	BitDepthChroma          int
+	Log2MaxFrameNum         int
+	PicOrderCntType         uint32
+	Log2MaxPicOrderCntLsb   int  // pic_order_cnt_type 0
+	DeltaPicOrderAlwaysZero bool // pic_order_cnt_type 1
+	MaxNumRefFrames         int
+	FrameMbsOnly            bool
+	PicWidthInMbs           int
+	PicHeightInMapUnits     int
+
+	// Cropped picture size in luma samples
+	Width  int
+	Height int
+
+	// VUI timing; NumUnitsInTick is 0 if absent
+	NumUnitsInTick uint32
+	TimeScale      uint32
+	FixedFrameRate bool
+
+	// VUI HRD and picture structure signalling, needed to parse pic_timing SEI
+	CPBDPBDelaysPresent   bool // NAL or VCL HRD parameters present
+	CPBRemovalDelayLength int
+	DPBOutputDelayLength  int
+	TimeOffsetLength      int
+	PicStructPresent      bool
+}
+
+// highProfiles are the profile_idc values whose SPS carries chroma format, bit
+// depth and scaling matrices
+var highProfiles = map[uint8]bool{100: true, 110: true, 122: true, 244: true, 44: true, 83: true, 86: true, 118: true, 128: true, 138: true, 139: true, 134: true, 135: true}
+
+// ParseSPS parses an H.264 SPS NAL unit, header byte included
+func ParseSPS(nal []byte) (*SPS, error) {
+	if len(nal) < 4 || nal[0]&0x1F != nalUnitTypeSPS {
+		return nil, fmt.Errorf("%w: not an SPS", ErrMalformedNAL)
+	}
+	sps := &SPS{
+		ProfileIDC:      nal[1],
+		ConstraintFlags: nal[2] >> 2,
+		LevelIDC:        nal[3],
+		ChromaFormatIDC: 1,
+		BitDepthLuma:    8,
+		BitDepthChroma:  8,
+	}
+	if err := sps.parse(newBitReader(unescapeRBSP(nal[4:]))); err != nil {
+		return nil, fmt.Errorf("%w: SPS: %v", ErrMalformedNAL, err)
+	}
+	return sps, nil
+}
+
+// parse reads the SPS fields following level_idc
+func (s *SPS) parse(r *bitReader) error {
+	var err error
+	ue := func() uint32 {
+		var v uint32
+		if err == nil {
+			v, err = r.readUE()
+		}
+		return v
+	}
+	se := func() int32 {
+		var v int32
+		if err == nil {
+			v, err = r.readSE()
+		}
+		return v
+	}
+	flag := func() bool {
+		var v bool
+		if err == nil {
+			v, err = r.readFlag()
+		}
+		return v
+	}
+
+	s.ID = ue()
+	if highProfiles[s.ProfileIDC] {
+		s.ChromaFormatIDC = ue()
+		if s.ChromaFormatIDC == 3 {
+			s.SeparateColourPlane = flag()
+		}
+		s.BitDepthLuma = int(ue()) + 8
+		s.BitDepthChroma = int(ue()) + 8
+		flag()      // qpprime_y_zero_transform_bypass_flag
+		if flag() { // seq_scaling_matrix_present_flag
+			lists := 8
+			if s.ChromaFormatIDC == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists && err == nil; i++ {
+				if flag() {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					err = skipScalingList(r, size)
+				}
+			}
+		}
+	}
+
+	s.Log2MaxFrameNum = int(ue()) + 4
+	s.PicOrderCntType = ue()
+	switch s.PicOrderCntType {
+	case 0:
+		s.Log2MaxPicOrderCntLsb = int(ue()) + 4
+	case 1:
+		s.DeltaPicOrderAlwaysZero = flag()
+		se() // offset_for_non_ref_pic
+		se() // offset_for_top_to_bottom_field
+		cycle := ue()
+		for i := uint32(0); i < cycle && err == nil; i++ {
+			se() // offset_for_ref_frame
+		}
+	}
+	s.MaxNumRefFrames = int(ue())
+	flag() // gaps_in_frame_num_value_allowed_flag
+	s.PicWidthInMbs = int(ue()) + 1
+	s.PicHeightInMapUnits = int(ue()) + 1
+	s.FrameMbsOnly = flag()
+	if !s.FrameMbsOnly {
+		flag() // mb_adaptive_frame_field_flag
+	}
+	flag() // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint32
+	if flag() { // frame_cropping_flag
+		cropLeft, cropRight, cropTop, cropBottom = ue(), ue(), ue(), ue()
+	}
+	if err != nil {
+		return err
+	}
+
+	frameHeightInMbs := s.PicHeightInMapUnits
+	if !s.FrameMbsOnly {
+		frameHeightInMbs *= 2
+	}
+	cropUnitX, cropUnitY := 1, 1
+	if !s.SeparateColourPlane && s.ChromaFormatIDC != 0 {
+		if s.ChromaFormatIDC < 3 {
+			cropUnitX = 2 // SubWidthC
+		}
+		if s.ChromaFormatIDC == 1 {
+			cropUnitY = 2 // SubHeightC
+		}
+	}
+	if !s.FrameMbsOnly {
+		cropUnitY *= 2
+	}
+	s.Width = s.PicWidthInMbs*16 - int(cropLeft+cropRight)*cropUnitX
+	s.Height = frameHeightInMbs*16 - int(cropTop+cropBottom)*cropUnitY
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("cropping exceeds picture size")
+	}
+
+	if vuiPresent, vuiErr := r.readFlag(); vuiErr != nil || !vuiPresent {
+		// Some encoders truncate the SPS after the cropping fields
+		return nil
+	}
+	if vuiErr := s.parseVUITiming(r); vuiErr != nil {
+		s.NumUnitsInTick, s.TimeScale, s.FixedFrameRate = 0, 0, false
+	} else if hrdErr := s.parseVUIHRD(r); hrdErr != nil {
+		s.CPBDPBDelaysPresent, s.PicStructPresent = false, false
+	}
+	return nil
+}
+
+// parseVUITiming reads the VUI parameters up to timing_info (ITU-T H.264 Annex E.1.1)
+func (s *SPS) parseVUITiming(r *bitReader) error {
+	if present, err := r.readFlag(); err != nil { // aspect_ratio_info_present_flag
+		return err
+	} else if present {
+		idc, err := r.readBits(8)
+		if err != nil {
+			return err
+		}
+		if idc == 255 { // Extended_SAR: sar_width, sar_height
+			if err := r.skipBits(32); err != nil {
+				return err
+			}
+		}
+	}
+	if present, err := r.readFlag(); err != nil { // overscan_info_present_flag
+		return err
+	} else if present {
+		if err := r.skipBits(1); err != nil {
+			return err
+		}
+	}
+	if present, err := r.readFlag(); err != nil { // video_signal_type_present_flag
+		return err
+	} else if present {
+		if err := r.skipBits(4); err != nil { // video_format, video_full_range_flag
+			return err
+		}
+		colourDescription, err := r.readFlag()
+		if err != nil {
+			return err
+		}
+		if colourDescription {
+			if err := r.skipBits(24); err != nil {
+				return err
+			}
+		}
+	}
+	if present, err := r.readFlag(); err != nil { // chroma_loc_info_present_flag
+		return err
+	} else if present {
+		if _, err := r.readUE(); err != nil {
+			return err
+		}
+		if _, err := r.readUE(); err != nil {
+			return err
+		}
+	}
+
+	timing, err := r.readFlag()
+	if err != nil || !timing {
+		return err
+	}
+	if s.NumUnitsInTick, err = r.readBits(32); err != nil {
+		return err
+	}
+	if s.TimeScale, err = r.readBits(32); err != nil {
+		return err
+	}
+	s.FixedFrameRate, err = r.readFlag()
+	return err
+}
+
+// parseVUIHRD reads the VUI parameters from nal_hrd_parameters_present_flag
+// to pic_struct_present_flag (ITU-T H.264 Annex E.1.1)
+func (s *SPS) parseVUIHRD(r *bitReader) error {
+	for i := 0; i < 2; i++ { // NAL, then VCL HRD parameters
+		present, err := r.readFlag()
+		if err != nil {
+			return err
+		}
+		if present {
+			if err := s.parseHRD(r); err != nil {
+				return err
+			}
+			s.CPBDPBDelaysPresent = true
+		}
+	}
+	if s.CPBDPBDelaysPresent {
+		if err := r.skipBits(1); err != nil { // low_delay_hrd_flag
+			return err
+		}
+	}
+	var err error
+	s.PicStructPresent, err = r.readFlag()
+	return err
+}
+
+// parseHRD reads hrd_parameters() (ITU-T H.264 Annex E.1.2); both sets of
+// HRD parameters carry the same delay lengths
+func (s *SPS) parseHRD(r *bitReader) error {
+	cpbCount, err := r.readUE()
+	if err != nil {
+		return err
+	}
+	if cpbCount > 31 {
+		return fmt.Errorf("cpb_cnt_minus1 %d", cpbCount)
+	}
+	if err := r.skipBits(8); err != nil { // bit_rate_scale, cpb_size_scale
+		return err
+	}
+	for i := uint32(0); i <= cpbCount; i++ {
+		if _, err := r.readUE(); err != nil { // bit_rate_value_minus1
+			return err
+		}
+		if _, err := r.readUE(); err != nil { // cpb_size_value_minus1
+			return err
+		}
+		if err := r.skipBits(1); err != nil { // cbr_flag
+			return err
+		}
+	}
+	lengths := [4]uint32{}
+	for i := range lengths {
+		if lengths[i], err = r.readBits(5); err != nil {
+			return err
+		}
+	}
+	// initial_cpb_removal_delay_length_minus1 is not needed
+	s.CPBRemovalDelayLength = int(lengths[1]) + 1
+	s.DPBOutputDelayLength = int(lengths[2]) + 1
+	s.TimeOffsetLength = int(lengths[3])
+	return nil
+}
+
+// skipScalingList skips a scaling_list() of size 16 or 64 (ITU-T H.264 section 7.3.2.1.1.1)
+func skipScalingList(r *bitReader, size int) error {
+	lastScale, nextScale := int32(8), int32(8)
+	for j := 0; j < size && nextScale != 0; j++ {
+		delta, err := r.readSE()
+		if err != nil {
+			return err
+		}
+		nextScale = (lastScale + delta + 256) % 256
+		if nextScale != 0 {
+			lastScale = nextScale
+		}
+	}
+	return nil
+}
+
+// FrameRate returns the frame rate signalled in the VUI timing info, or 0.
+// H.264 counts field ticks: a frame lasts two ticks.
+func (s *SPS) FrameRate() float64 {
+	if s.NumUnitsInTick == 0 || s.TimeScale == 0 {
+		return 0
+	}
+	return float64(s.TimeScale) / float64(2*s.NumUnitsInTick)
+}
+
+// PPS holds the fields of an H.264 picture parameter set (ITU-T H.264
+// section 7.3.2.2) needed for slice header parsing
+type PPS struct {
+	ID    uint32
+	SPSID uint32
+
+	EntropyCodingModeFlag          bool // CABAC
+	BottomFieldPicOrderInFrame     bool
+	NumSliceGroups                 int
+	SliceGroupMapType              uint32
+	SliceGroupChangeRate           int
+	NumRefIdxL0DefaultActive       int
+	NumRefIdxL1DefaultActive       int
+	WeightedPred                   bool
+	WeightedBipredIDC              uint32
+	PicInitQP                      int
+	DeblockingFilterControlPresent bool
+	ConstrainedIntraPred           bool
+	RedundantPicCntPresent         bool
+	Transform8x8Mode               bool
+}
+
+// ParsePPS parses an H.264 PPS NAL unit, header byte included
+func ParsePPS(nal []byte) (*PPS, error) {
+	if len(nal) < 2 || nal[0]&0x1F != nalUnitTypePPS {
+		return nil, fmt.Errorf("%w: not a PPS", ErrMalformedNAL)
+	}
+	pps := &PPS{}
+	if err := pps.parse(newBitReader(unescapeRBSP(nal[1:]))); err != nil {
+		return nil, fmt.Errorf("%w: PPS: %v", ErrMalformedNAL, err)
+	}
+	return pps, nil
+}
+
+// parse reads the PPS fields; the optional trailing fields are read only as
+// far as transform_8x8_mode_flag
+func (p *PPS) parse(r *bitReader) error {
+	var err error
+	ue := func() uint32 {
+		var v uint32
+		if err == nil {
+			v, err = r.readUE()
+		}
+		return v
+	}
+	se := func() int32 {
+		var v int32
+		if err == nil {
+			v, err = r.readSE()
+		}
+		return v
+	}
+	flag := func() bool {
+		var v bool
+		if err == nil {
+			v, err = r.readFlag()
+		}
+		return v
+	}
+
+	p.ID = ue()
+	p.SPSID = ue()
+	p.EntropyCodingModeFlag = flag()
+	p.BottomFieldPicOrderInFrame = flag()
+	p.NumSliceGroups = int(ue()) + 1
+	if p.NumSliceGroups > 1 {
+		p.SliceGroupMapType = ue()
+		switch p.SliceGroupMapType {
+		case 0:
+			for i := 0; i < p.NumSliceGroups && err == nil; i++ {
+				ue() // run_length_minus1
+			}
+		case 2:
+			for i := 0; i < p.NumSliceGroups-1 && err == nil; i++ {
+				ue() // top_left
+				ue() // bottom_right
+			}
+		case 3, 4, 5:
+			flag() // slice_group_change_direction_flag
+			p.SliceGroupChangeRate = int(ue()) + 1
+		case 6:
+			picSizeInMapUnits := ue() + 1
+			bits := 0
+			for 1<<bits < p.NumSliceGroups {
+				bits++
+			}
+			if err == nil {
+				err = r.skipBits(int(picSizeInMapUnits) * bits)
+			}
+		}
+	}
+	p.NumRefIdxL0DefaultActive = int(ue()) + 1
+	p.NumRefIdxL1DefaultActive = int(ue()) + 1
+	p.WeightedPred = flag()
+	if err == nil {
+		p.WeightedBipredIDC, err = r.readBits(2)
+	}
+	p.PicInitQP = 26 + int(se())
+	se() // pic_init_qs_minus26
+	se() // chroma_qp_index_offset
+	p.DeblockingFilterControlPresent = flag()
+	p.ConstrainedIntraPred = flag()
+	p.RedundantPicCntPresent = flag()
+	if err == nil && r.moreRBSPData() {
+		p.Transform8x8Mode = flag()
+	}
+	return err
+}
+
+// StreamInfo describes an H.264 stream as signalled by its active SPS
+type StreamInfo struct {
+	Profile      uint8 // profile_idc
+	Level        uint8 // level_idc: 31 is level 3.1
+	ChromaFormat uint32
+	BitDepth     int
+	Width        int
+	Height       int
+	FrameRate    float64 // 0 if the SPS carries no VUI timing
+	MaxRefFrames int
+}
+
+// StreamInfo returns the stream properties signalled by the SPS
+func (s *SPS) StreamInfo() StreamInfo {
+	return StreamInfo{
+		Profile:      s.ProfileIDC,
+		Level:        s.LevelIDC,
+		ChromaFormat: s.ChromaFormatIDC,
+		BitDepth:     s.BitDepthLuma,
+		Width:        s.Width,
+		Height:       s.Height,
+		FrameRate:    s.FrameRate(),
+		MaxRefFrames: s.MaxNumRefFrames,
+	}
+}
+
+// ProfileName returns the name of the H.264 profile, e.g. "High"
+func (i StreamInfo) ProfileName() string {
+	switch i.Profile {
+	case 66:
+		return "Baseline"
+	case 77:
+		return "Main"
+	case 88:
+		return "Extended"
+	case 100:
+		return "High"
+	case 110:
+		return "High 10"
+	case 122:
+		return "High 4:2:2"
+	case 244:
+		return "High 4:4:4 Predictive"
+	case 44:
+		return "CAVLC 4:4:4 Intra"
+	}
+	return fmt.Sprintf("profile %d", i.Profile)
+}
+
+// String returns a summary such as "High@4.1 1920x1080 4:2:0 25.00 fps"
+func (i StreamInfo) String() string {
+	chroma := [...]string{"4:0:0", "4:2:0", "4:2:2", "4:4:4"}
+	format := fmt.Sprintf("chroma %d", i.ChromaFormat)
+	if int(i.ChromaFormat) < len(chroma) {
+		format = chroma[i.ChromaFormat]
+	}
+	fps := "unknown fps"
+	if i.FrameRate > 0 {
+		fps = fmt.Sprintf("%.2f fps", i.FrameRate)
+	}
+	return fmt.Sprintf("%s@%d.%d %dx%d %s %s", i.ProfileName(), i.Level/10, i.Level%10, i.Width, i.Height, format, fps)
+}
@@ -0,0 +1,220 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bitWriter builds MSB-first bitstreams for parser tests
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) bit(b bool) *bitWriter {
+	if w.bits%8 == 0 {
+		w.data = append(w.data, 0)
+	}
+	if b {
+		w.data[len(w.data)-1] |= 0x80 >> (w.bits % 8)
+	}
+	w.bits++
+	return w
+}
+
+func (w *bitWriter) u(n int, v uint32) *bitWriter {
+	for i := n - 1; i >= 0; i-- {
+		w.bit(v>>i&1 == 1)
+	}
+	return w
+}
+
+func (w *bitWriter) ue(v uint32) *bitWriter {
+	n := 0
+	for (v+1)>>(n+1) != 0 {
+		n++
+	}
+	return w.u(n, 0).u(n+1, v+1)
+}
+
+func (w *bitWriter) se(v int32) *bitWriter {
+	if v > 0 {
+		return w.ue(uint32(2*v - 1))
+	}
+	return w.ue(uint32(-2 * v))
+}
+
+// rbsp appends the stop bit and alignment and inserts emulation prevention bytes
+func (w *bitWriter) rbsp() []byte {
+	w.bit(true)
+	for w.bits%8 != 0 {
+		w.bit(false)
+	}
+	var escaped []byte
+	zeros := 0
+	for _, b := range w.data {
+		if zeros >= 2 && b <= 0x03 {
+			escaped = append(escaped, 0x03)
+			zeros = 0
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		escaped = append(escaped, b)
+	}
+	return escaped
+}
+
+// testSPS is a High profile 1080p SPS: 1088 lines cropped to 1080, a scaling
+// matrix, and VUI timing for 25 fps whose zero bytes need emulation prevention
+func testSPS() []byte {
+	w := &bitWriter{}
+	w.ue(0)            // seq_parameter_set_id
+	w.ue(1)            // chroma_format_idc
+	w.ue(0).ue(0)      // bit depths
+	w.bit(false)       // qpprime_y_zero_transform_bypass_flag
+	w.bit(true)        // seq_scaling_matrix_present_flag
+	w.bit(true).se(-8) // list 0: delta to 0 ends the list
+	w.u(7, 0)          // lists 1-7 absent
+	w.ue(0)            // log2_max_frame_num_minus4
+	w.ue(0).ue(2)      // pic_order_cnt_type 0, log2_max_pic_order_cnt_lsb_minus4
+	w.ue(4)            // max_num_ref_frames
+	w.bit(false)       // gaps_in_frame_num_value_allowed_flag
+	w.ue(119).ue(67)   // 120x68 macroblocks
+	w.bit(true)        // frame_mbs_only_flag
+	w.bit(true)        // direct_8x8_inference_flag
+	w.bit(true).ue(0).ue(0).ue(0).ue(4)
+	w.bit(true)         // vui_parameters_present_flag
+	w.bit(true).u(8, 1) // aspect_ratio_idc 1:1
+	w.bit(false)        // overscan_info_present_flag
+	w.bit(true).u(4, 5).bit(true).u(24, 0x010101)
+	w.bit(false) // chroma_loc_info_present_flag
+	w.bit(true).u(32, 1).u(32, 50).bit(true)
+	return append([]byte{0x67, 100, 0x00, 40}, w.rbsp()...)
+}
+
+func TestBitReader_ExpGolomb(t *testing.T) {
+	// ue: 0, 1, 2, 3, 7; se: 1, -1, 2
+	r := newBitReader((&bitWriter{}).ue(0).ue(1).ue(2).ue(3).ue(7).se(1).se(-1).se(2).rbsp())
+	for _, expected := range []uint32{0, 1, 2, 3, 7} {
+		v, err := r.readUE()
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+	for _, expected := range []int32{1, -1, 2} {
+		v, err := r.readSE()
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+	assert.False(t, r.moreRBSPData())
+
+	_, err := newBitReader([]byte{0x00}).readUE()
+	assert.ErrorIs(t, err, ErrTruncatedBitstream)
+	_, err = newBitReader(make([]byte, 8)).readUE()
+	assert.ErrorIs(t, err, ErrInvalidExpGolomb)
+}
+
+func TestUnescapeRBSP(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x03}, unescapeRBSP([]byte{0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x03}))
+	assert.Equal(t, []byte{0x00, 0x03, 0x00}, unescapeRBSP([]byte{0x00, 0x03, 0x00}))
+}
+
+func TestParseSPS(t *testing.T) {
+	sps, err := ParseSPS(testSPS())
+	require.NoError(t, err)
+	assert.Equal(t, uint8(100), sps.ProfileIDC)
+	assert.Equal(t, uint8(40), sps.LevelIDC)
+	assert.Equal(t, 1920, sps.Width)
+	assert.Equal(t, 1080, sps.Height)
+	assert.Equal(t, 4, sps.MaxNumRefFrames)
+	assert.Equal(t, 6, sps.Log2MaxPicOrderCntLsb)
+	assert.InDelta(t, 25.0, sps.FrameRate(), 0.001)
+	assert.True(t, sps.FixedFrameRate)
+	assert.Equal(t, "High@4.0 1920x1080 4:2:0 25.00 fps", sps.StreamInfo().String())
+
+	// Baseline, interlaced 576 lines, pic_order_cnt_type 1, no VUI
+	w := &bitWriter{}
+	w.ue(1).ue(12).ue(1)
+	w.bit(true).se(0).se(0).ue(0) // delta_pic_order_always_zero_flag, empty cycle
+	w.ue(1).bit(false).ue(44).ue(17)
+	w.bit(false).bit(false).bit(true) // field coding, no MBAFF
+	w.bit(false).bit(false)           // no cropping, no VUI
+	sps, err = ParseSPS(append([]byte{0x67, 66, 0xC0, 30}, w.rbsp()...))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), sps.ID)
+	assert.Equal(t, 16, sps.Log2MaxFrameNum)
+	assert.Equal(t, uint32(1), sps.PicOrderCntType)
+	assert.True(t, sps.DeltaPicOrderAlwaysZero)
+	assert.Equal(t, uint8(0x30), sps.ConstraintFlags)
+	assert.Equal(t, 720, sps.Width)
+	assert.Equal(t, 576, sps.Height)
+	assert.False(t, sps.FrameMbsOnly)
+	assert.Zero(t, sps.FrameRate())
+
+	_, err = ParseSPS([]byte{0x67, 66, 0, 30})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+	_, err = ParseSPS([]byte{0x68, 0xce, 0x3c, 0x80})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+}
+
+func TestParsePPS(t *testing.T) {
+	w := &bitWriter{}
+	w.ue(2).ue(0).bit(true).bit(false).ue(0) // IDs, CABAC, one slice group
+	w.ue(2).ue(0).bit(true).u(2, 2)          // 3 and 1 reference indices, weighted prediction
+	w.se(-4).se(0).se(0).bit(true).bit(false).bit(false)
+	w.bit(true).bit(false).se(0) // transform_8x8_mode_flag, no scaling matrix
+	pps, err := ParsePPS(append([]byte{0x68}, w.rbsp()...))
+	require.NoError(t, err)
+	assert.Equal(t, &PPS{
+		ID:                             2,
+		EntropyCodingModeFlag:          true,
+		NumSliceGroups:                 1,
+		NumRefIdxL0DefaultActive:       3,
+		NumRefIdxL1DefaultActive:       1,
+		WeightedPred:                   true,
+		WeightedBipredIDC:              2,
+		PicInitQP:                      22,
+		DeblockingFilterControlPresent: true,
+		Transform8x8Mode:               true,
+	}, pps)
+
+	// Baseline PPS without the optional trailing fields
+	pps, err = ParsePPS([]byte{0x68, 0xce, 0x3c, 0x80})
+	require.NoError(t, err)
+	assert.False(t, pps.Transform8x8Mode)
+	assert.Equal(t, 1, pps.NumSliceGroups)
+}
+
+func TestH264Decoder_StreamInfo(t *testing.T) {
+	d := NewH264Decoder()
+	_, ok := d.GetStreamInfo()
+	assert.False(t, ok)
+
+	sprop := base64.StdEncoding.EncodeToString(testSPS()) + "," + base64.StdEncoding.EncodeToString(testH264PPS)
+	require.NoError(t, d.SetFMTP(map[string]string{"sprop-parameter-sets": sprop}))
+	info, ok := d.GetStreamInfo()
+	require.True(t, ok)
+	assert.Equal(t, StreamInfo{Profile: 100, Level: 40, ChromaFormat: 1, BitDepth: 8, Width: 1920, Height: 1080, FrameRate: 25, MaxRefFrames: 4}, info)
+	assert.Equal(t, "High", info.ProfileName())
+
+	// An in-band SPS with a new resolution replaces it
+	w := &bitWriter{}
+	w.ue(0).ue(0).ue(0).ue(0).ue(1).bit(false).ue(39).ue(29)
+	w.bit(true).bit(true).bit(false).bit(false)
+	assert.Nil(t, d.ProcessPacket(h264Packet(1, 3000, append([]byte{0x67, 66, 0xC0, 30}, w.rbsp()...))))
+	idr := h264Packet(2, 3000, testH264IDR)
+	idr.Marker = true
+	require.NotNil(t, d.ProcessPacket(idr))
+	info, ok = d.GetStreamInfo()
+	require.True(t, ok)
+	assert.Equal(t, 640, info.Width)
+	assert.Equal(t, 480, info.Height)
+	assert.Equal(t, "Baseline@3.0 640x480 4:2:0 unknown fps", info.String())
+
+	assert.ErrorIs(t, d.SetFMTP(map[string]string{"sprop-parameter-sets": "!!"}), ErrInvalidParameterSets)
+}
@@ -0,0 +1,340 @@
+package decoder
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+)
+
+const (
+	// H.264 SEI payloadType values (ITU-T H.264 Annex D.1)
+	seiTypePicTiming            = 1
+	seiTypeUserDataUnregistered = 5
+	seiTypeRecoveryPoint        = 6
+
+	// seiUUIDSize is the length of uuid_iso_iec_11578 in user_data_unregistered
+	seiUUIDSize = 16
+)
+
+var (
+	// ErrInvalidUUID indicates a UUID string that is not 32 hex digits with optional dashes
+	ErrInvalidUUID = errors.New("invalid UUID")
+)
+
+// picStructClockTimestamps is NumClockTS for each pic_struct (ITU-T H.264 Table D-1)
+var picStructClockTimestamps = [...]int{1, 1, 1, 2, 2, 3, 3, 2, 3}
+
+// SEI holds the SEI messages of an access unit that H264Decoder interprets
+type SEI struct {
+	RecoveryPoint *RecoveryPoint
+	PicTiming     *PicTiming
+	UserData      []UserData
+}
+
+// RecoveryPoint is a recovery_point SEI message (ITU-T H.264 section D.2.8).
+// Decoding starting at its frame is correct from RecoveryFrameCount frames on.
+type RecoveryPoint struct {
+	RecoveryFrameCount uint32
+	ExactMatch         bool
+	BrokenLink         bool
+}
+
+// PicTiming is a pic_timing SEI message (ITU-T H.264 section D.2.3). The
+// delays are only present if the SPS has HRD parameters, the picture
+// structure and timecodes only if it sets pic_struct_present_flag.
+type PicTiming struct {
+	CPBRemovalDelay uint32
+	DPBOutputDelay  uint32
+	PicStruct       uint8
+	Timecodes       []Timecode
+}
+
+// Timecode is a clock timestamp of a pic_timing SEI message
+type Timecode struct {
+	Hours      int
+	Minutes    int
+	Seconds    int
+	Frames     int
+	DropFrame  bool  // cnt_dropped_flag
+	TimeOffset int32 // In clock ticks of the VUI time scale
+}
+
+// String returns the SMPTE notation, e.g. "10:59:58:24" or "10:59:58;24" for drop-frame
+func (t Timecode) String() string {
+	separator := ":"
+	if t.DropFrame {
+		separator = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", t.Hours, t.Minutes, t.Seconds, separator, t.Frames)
+}
+
+// UserData is a user_data_unregistered SEI message. Vendor, Value and
+// WallClock are set by the parser registered for the UUID, if any.
+type UserData struct {
+	UUID      UUID
+	Payload   []byte
+	Vendor    string
+	Value     interface{}
+	WallClock time.Time
+}
+
+// WallClock returns the first wall-clock time reported by a user data parser
+func (s *SEI) WallClock() (time.Time, bool) {
+	for _, ud := range s.UserData {
+		if !ud.WallClock.IsZero() {
+			return ud.WallClock, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// UUID identifies the originator of a user_data_unregistered SEI message
+type UUID [16]byte
+
+// ParseUUID parses a UUID such as "dc45e9bd-e6d9-48b7-962c-d820d923eeef"
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != len(uuid) {
+		return uuid, fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+	}
+	copy(uuid[:], b)
+	return uuid, nil
+}
+
+// String returns the UUID in 8-4-4-4-12 notation
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// UserDataParseFunc decodes the payload following the UUID of a
+// user_data_unregistered message. A non-zero wallClock is reported as the
+// capture time of the frame.
+type UserDataParseFunc func(payload []byte) (value interface{}, wallClock time.Time, err error)
+
+// userDataParser is a registered vendor parser
+type userDataParser struct {
+	vendor string
+	parse  UserDataParseFunc
+}
+
+// UserDataRegistry maps user_data_unregistered UUIDs to vendor parsers
+type UserDataRegistry struct {
+	mu      sync.RWMutex
+	parsers map[UUID]userDataParser
+}
+
+// x264UUID tags the encoder settings string x264 writes into the first IDR
+var x264UUID = UUID{0xdc, 0x45, 0xe9, 0xbd, 0xe6, 0xd9, 0x48, 0xb7, 0x96, 0x2c, 0xd8, 0x20, 0xd9, 0x23, 0xee, 0xef}
+
+// DefaultUserDataRegistry is used by H264Decoder unless SetUserDataRegistry
+// is called. It knows the x264 encoder settings string.
+var DefaultUserDataRegistry = newDefaultUserDataRegistry()
+
+// NewUserDataRegistry creates an empty registry
+func NewUserDataRegistry() *UserDataRegistry {
+	return &UserDataRegistry{parsers: make(map[UUID]userDataParser)}
+}
+
+func newDefaultUserDataRegistry() *UserDataRegistry {
+	r := NewUserDataRegistry()
+	r.Register(x264UUID, "x264", func(payload []byte) (interface{}, time.Time, error) {
+		return strings.TrimRight(string(payload), "\x00"), time.Time{}, nil
+	})
+	return r
+}
+
+// Register sets the parser for a vendor UUID, replacing any previous one
+func (r *UserDataRegistry) Register(uuid UUID, vendor string, parse UserDataParseFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parsers[uuid] = userDataParser{vendor: vendor, parse: parse}
+}
+
+// decode applies the parser registered for ud.UUID. A failing parser leaves
+// the raw payload in place.
+func (r *UserDataRegistry) decode(ud *UserData) {
+	r.mu.RLock()
+	parser, ok := r.parsers[ud.UUID]
+	r.mu.RUnlock()
+	if !ok {
+		return
+	}
+	ud.Vendor = parser.vendor
+	value, wallClock, err := parser.parse(ud.Payload)
+	if err != nil {
+		logger.Debug("[H264Decoder] Cannot parse %s user data: %v", parser.vendor, err)
+		return
+	}
+	ud.Value, ud.WallClock = value, wallClock
+}
+
+// parseSEI adds the messages of an SEI NAL unit to sei (ITU-T H.264 section
+// 7.3.2.3). pic_timing needs the active SPS and is skipped if sps is nil;
+// messages before a malformed one are kept.
+func (d *H264Decoder) parseSEI(nal []byte, sps *SPS, sei *SEI) error {
+	rbsp := unescapeRBSP(nal[1:])
+	// Messages continue up to the rbsp_trailing_bits byte
+	for len(rbsp) > 0 && !(len(rbsp) == 1 && rbsp[0] == 0x80) {
+		payloadType, n := readSEIValue(rbsp)
+		rbsp = rbsp[n:]
+		payloadSize, m := readSEIValue(rbsp)
+		rbsp = rbsp[m:]
+		if n == 0 || m == 0 || payloadSize > len(rbsp) {
+			return fmt.Errorf("%w: SEI message %d truncated", ErrMalformedNAL, payloadType)
+		}
+		payload := rbsp[:payloadSize]
+		rbsp = rbsp[payloadSize:]
+
+		var err error
+		switch payloadType {
+		case seiTypeRecoveryPoint:
+			sei.RecoveryPoint, err = parseRecoveryPoint(payload)
+		case seiTypePicTiming:
+			if sps != nil && (sps.CPBDPBDelaysPresent || sps.PicStructPresent) {
+				sei.PicTiming, err = parsePicTiming(payload, sps, &d.lastTimecode)
+			}
+		case seiTypeUserDataUnregistered:
+			if len(payload) < seiUUIDSize {
+				err = fmt.Errorf("user data of %d bytes", len(payload))
+				break
+			}
+			ud := UserData{Payload: payload[seiUUIDSize:]}
+			copy(ud.UUID[:], payload)
+			d.userData.decode(&ud)
+			sei.UserData = append(sei.UserData, ud)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: SEI message %d: %v", ErrMalformedNAL, payloadType, err)
+		}
+	}
+	return nil
+}
+
+// readSEIValue reads a payloadType or payloadSize coded as 0xFF bytes and a
+// final byte; n is 0 if data ends first
+func readSEIValue(data []byte) (value, n int) {
+	for n < len(data) {
+		value += int(data[n])
+		n++
+		if data[n-1] != 0xFF {
+			return value, n
+		}
+	}
+	return 0, 0
+}
+
+// parseRecoveryPoint parses a recovery_point SEI payload
+func parseRecoveryPoint(payload []byte) (*RecoveryPoint, error) {
+	r := newBitReader(payload)
+	count, err := r.readUE()
+	if err != nil {
+		return nil, err
+	}
+	flags, err := r.readBits(2) // exact_match_flag, broken_link_flag
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryPoint{RecoveryFrameCount: count, ExactMatch: flags&2 != 0, BrokenLink: flags&1 != 0}, nil
+}
+
+// parsePicTiming parses a pic_timing SEI payload. Timecode fields missing
+// from a partial clock timestamp keep their values from prev, which is
+// updated to the last timecode.
+func parsePicTiming(payload []byte, sps *SPS, prev *Timecode) (*PicTiming, error) {
+	r := newBitReader(payload)
+	pt := &PicTiming{}
+	var err error
+	if sps.CPBDPBDelaysPresent {
+		if pt.CPBRemovalDelay, err = r.readBits(sps.CPBRemovalDelayLength); err != nil {
+			return nil, err
+		}
+		if pt.DPBOutputDelay, err = r.readBits(sps.DPBOutputDelayLength); err != nil {
+			return nil, err
+		}
+	}
+	if !sps.PicStructPresent {
+		return pt, nil
+	}
+	picStruct, err := r.readBits(4)
+	if err != nil {
+		return nil, err
+	}
+	if int(picStruct) >= len(picStructClockTimestamps) {
+		return nil, fmt.Errorf("pic_struct %d", picStruct)
+	}
+	pt.PicStruct = uint8(picStruct)
+
+	for i := 0; i < picStructClockTimestamps[picStruct]; i++ {
+		present, err := r.readFlag() // clock_timestamp_flag
+		if err != nil {
+			return nil, err
+		}
+		if !present {
+			continue
+		}
+		tc, err := parseClockTimestamp(r, sps, *prev)
+		if err != nil {
+			return nil, err
+		}
+		pt.Timecodes = append(pt.Timecodes, tc)
+		*prev = tc
+	}
+	return pt, nil
+}
+
+// parseClockTimestamp reads one clock timestamp of pic_timing, from ct_type
+// to time_offset
+func parseClockTimestamp(r *bitReader, sps *SPS, tc Timecode) (Timecode, error) {
+	// ct_type, nuit_field_based_flag, counting_type, full_timestamp_flag,
+	// discontinuity_flag, cnt_dropped_flag
+	flags, err := r.readBits(11)
+	if err != nil {
+		return tc, err
+	}
+	full := flags&0x04 != 0
+	tc.DropFrame = flags&0x01 != 0
+	frames, err := r.readBits(8)
+	if err != nil {
+		return tc, err
+	}
+	tc.Frames = int(frames)
+
+	// seconds, minutes and hours are each present in a full timestamp, or
+	// nested behind their own flag in a partial one
+	widths := [...]int{6, 6, 5}
+	fields := [...]*int{&tc.Seconds, &tc.Minutes, &tc.Hours}
+	for i := range fields {
+		if !full {
+			present, err := r.readFlag()
+			if err != nil {
+				return tc, err
+			}
+			if !present {
+				break
+			}
+		}
+		value, err := r.readBits(widths[i])
+		if err != nil {
+			return tc, err
+		}
+		*fields[i] = int(value)
+	}
+
+	tc.TimeOffset = 0
+	if n := sps.TimeOffsetLength; n > 0 {
+		offset, err := r.readBits(n)
+		if err != nil {
+			return tc, err
+		}
+		// Two's complement of n bits
+		tc.TimeOffset = int32(offset<<(32-n)) >> (32 - n)
+	}
+	return tc, nil
+}
@@ -0,0 +1,277 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seiMessage is the payloadType and payload of one SEI message
+type seiMessage struct {
+	payloadType int
+	payload     []byte
+}
+
+// h264SEI builds an SEI NAL unit from messages
+func h264SEI(messages ...seiMessage) []byte {
+	w := &bitWriter{}
+	value := func(v int) {
+		for ; v >= 255; v -= 255 {
+			w.u(8, 0xFF)
+		}
+		w.u(8, uint32(v))
+	}
+	for _, m := range messages {
+		value(m.payloadType)
+		value(len(m.payload))
+		for _, b := range m.payload {
+			w.u(8, uint32(b))
+		}
+	}
+	return append([]byte{0x06}, w.rbsp()...)
+}
+
+// recoveryPointSEI builds a recovery_point message
+func recoveryPointSEI(count uint32, exactMatch bool) seiMessage {
+	w := &bitWriter{}
+	w.ue(count).bit(exactMatch).bit(false).u(2, 0)
+	return seiMessage{seiTypeRecoveryPoint, w.data}
+}
+
+// userDataSEI builds a user_data_unregistered message
+func userDataSEI(uuid UUID, payload []byte) seiMessage {
+	return seiMessage{seiTypeUserDataUnregistered, append(uuid[:], payload...)}
+}
+
+// testSPSWithHRD is testSPS with NAL HRD parameters (24-bit delays and time
+// offset) and pic_struct_present_flag
+func testSPSWithHRD() []byte {
+	w := &bitWriter{}
+	w.ue(0).ue(1).ue(0).ue(0).bit(false).bit(false)
+	w.ue(0).ue(0).ue(2).ue(4).bit(false).ue(119).ue(67).bit(true).bit(true)
+	w.bit(true).ue(0).ue(0).ue(0).ue(4) // cropping
+	w.bit(true)                         // vui_parameters_present_flag
+	w.bit(false).bit(false).bit(false).bit(false)
+	w.bit(true).u(32, 1).u(32, 50).bit(true)
+	w.bit(true) // nal_hrd_parameters_present_flag
+	w.ue(0).u(4, 0).u(4, 0).ue(1000).ue(2000).bit(false)
+	w.u(5, 23).u(5, 23).u(5, 23).u(5, 24) // delay and time offset lengths
+	w.bit(false)                          // vcl_hrd_parameters_present_flag
+	w.bit(false)                          // low_delay_hrd_flag
+	w.bit(true)                           // pic_struct_present_flag
+	w.bit(false)                          // bitstream_restriction_flag
+	return append([]byte{0x67, 100, 0x00, 40}, w.rbsp()...)
+}
+
+func TestParseSPS_HRD(t *testing.T) {
+	sps, err := ParseSPS(testSPSWithHRD())
+	require.NoError(t, err)
+	assert.InDelta(t, 25.0, sps.FrameRate(), 0.001)
+	assert.True(t, sps.CPBDPBDelaysPresent)
+	assert.Equal(t, 24, sps.CPBRemovalDelayLength)
+	assert.Equal(t, 24, sps.DPBOutputDelayLength)
+	assert.Equal(t, 24, sps.TimeOffsetLength)
+	assert.True(t, sps.PicStructPresent)
+
+	// VUI ending after the timing info
+	sps, err = ParseSPS(testSPS())
+	require.NoError(t, err)
+	assert.False(t, sps.CPBDPBDelaysPresent)
+	assert.False(t, sps.PicStructPresent)
+}
+
+func TestUUID(t *testing.T) {
+	uuid, err := ParseUUID("DC45E9BD-E6D9-48B7-962C-D820D923EEEF")
+	require.NoError(t, err)
+	assert.Equal(t, x264UUID, uuid)
+	assert.Equal(t, "dc45e9bd-e6d9-48b7-962c-d820d923eeef", uuid.String())
+
+	uuid, err = ParseUUID("dc45e9bde6d948b7962cd820d923eeef")
+	require.NoError(t, err)
+	assert.Equal(t, x264UUID, uuid)
+
+	for _, s := range []string{"", "dc45e9bd", "dc45e9bd-e6d9-48b7-962c-d820d923eeefff", "zz45e9bd-e6d9-48b7-962c-d820d923eeef"} {
+		_, err := ParseUUID(s)
+		assert.ErrorIs(t, err, ErrInvalidUUID, s)
+	}
+}
+
+func TestH264Decoder_ParseSEI(t *testing.T) {
+	vendorUUID := UUID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10}
+	unknownUUID := UUID{0xff}
+	capture := time.Date(2026, 3, 14, 15, 9, 26, 535000000, time.UTC)
+
+	// A camera that sends the capture time as Unix milliseconds
+	registry := NewUserDataRegistry()
+	registry.Register(vendorUUID, "camera", func(payload []byte) (interface{}, time.Time, error) {
+		if len(payload) != 8 {
+			return nil, time.Time{}, errors.New("want 8 bytes")
+		}
+		ms := int64(binary.BigEndian.Uint64(payload))
+		return ms, time.UnixMilli(ms).UTC(), nil
+	})
+	timestamp := binary.BigEndian.AppendUint64(nil, uint64(capture.UnixMilli()))
+
+	d := NewH264Decoder()
+	sei := &SEI{}
+	require.NoError(t, d.parseSEI(h264SEI(
+		recoveryPointSEI(4, true),
+		userDataSEI(x264UUID, []byte("x264 - core 164\x00")),
+		userDataSEI(vendorUUID, timestamp),
+		seiMessage{seiTypePicTiming, []byte{0x12}}, // Skipped without SPS
+	), nil, sei))
+	assert.Equal(t, &RecoveryPoint{RecoveryFrameCount: 4, ExactMatch: true}, sei.RecoveryPoint)
+	assert.Nil(t, sei.PicTiming)
+	require.Len(t, sei.UserData, 2)
+	assert.Equal(t, "x264", sei.UserData[0].Vendor)
+	assert.Equal(t, "x264 - core 164", sei.UserData[0].Value)
+	assert.Equal(t, "", sei.UserData[1].Vendor) // Not in the default registry
+	assert.Equal(t, timestamp, sei.UserData[1].Payload)
+	_, ok := sei.WallClock()
+	assert.False(t, ok)
+
+	d.SetUserDataRegistry(registry)
+	sei = &SEI{}
+	large := make([]byte, 300) // payloadSize coded with a 0xFF byte
+	require.NoError(t, d.parseSEI(h264SEI(
+		userDataSEI(unknownUUID, large),
+		userDataSEI(vendorUUID, []byte{1, 2}),
+		userDataSEI(vendorUUID, timestamp),
+	), nil, sei))
+	require.Len(t, sei.UserData, 3)
+	assert.Equal(t, unknownUUID, sei.UserData[0].UUID)
+	assert.Len(t, sei.UserData[0].Payload, 300)
+	assert.Equal(t, "camera", sei.UserData[1].Vendor)
+	assert.Nil(t, sei.UserData[1].Value) // Parser failed
+	assert.Equal(t, capture.UnixMilli(), sei.UserData[2].Value)
+	wallClock, ok := sei.WallClock()
+	require.True(t, ok)
+	assert.Equal(t, capture, wallClock)
+
+	// Messages before a truncated one are kept
+	sei = &SEI{}
+	nal := h264SEI(recoveryPointSEI(0, false), userDataSEI(vendorUUID, timestamp))
+	err := d.parseSEI(nal[:len(nal)-4], nil, sei)
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+	assert.NotNil(t, sei.RecoveryPoint)
+	assert.Empty(t, sei.UserData)
+
+	err = d.parseSEI(h264SEI(seiMessage{seiTypeUserDataUnregistered, []byte{1, 2, 3}}), nil, &SEI{})
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+}
+
+func TestH264Decoder_ParsePicTiming(t *testing.T) {
+	sps, err := ParseSPS(testSPSWithHRD())
+	require.NoError(t, err)
+
+	// Frame with a full drop-frame timestamp and a negative time offset
+	w := &bitWriter{}
+	w.u(24, 1000).u(24, 2).u(4, 0)
+	w.bit(true).u(2, 0).bit(false).u(5, 0).bit(true).bit(false).bit(true)
+	w.u(8, 24).u(6, 58).u(6, 59).u(5, 10).u(24, 0xFFFFFE)
+	first := seiMessage{seiTypePicTiming, w.data}
+
+	// Top and bottom field: only the second clock timestamp, with seconds only
+	w = &bitWriter{}
+	w.u(24, 1001).u(24, 2).u(4, 3)
+	w.bit(false)
+	w.bit(true).u(2, 0).bit(false).u(5, 0).bit(false).bit(false).bit(false)
+	w.u(8, 0).bit(true).u(6, 59).bit(false).u(24, 0)
+	second := seiMessage{seiTypePicTiming, w.data}
+
+	d := NewH264Decoder()
+	sei := &SEI{}
+	require.NoError(t, d.parseSEI(h264SEI(first), sps, sei))
+	assert.Equal(t, &PicTiming{
+		CPBRemovalDelay: 1000,
+		DPBOutputDelay:  2,
+		Timecodes:       []Timecode{{Hours: 10, Minutes: 59, Seconds: 58, Frames: 24, DropFrame: true, TimeOffset: -2}},
+	}, sei.PicTiming)
+	assert.Equal(t, "10:59:58;24", sei.PicTiming.Timecodes[0].String())
+
+	require.NoError(t, d.parseSEI(h264SEI(second), sps, sei))
+	assert.Equal(t, uint8(3), sei.PicTiming.PicStruct)
+	require.Len(t, sei.PicTiming.Timecodes, 1)
+	assert.Equal(t, "10:59:59:00", sei.PicTiming.Timecodes[0].String())
+
+	// pic_struct 9 and up is reserved
+	w = &bitWriter{}
+	w.u(24, 0).u(24, 0).u(4, 9)
+	assert.ErrorIs(t, d.parseSEI(h264SEI(seiMessage{seiTypePicTiming, w.data}), sps, sei), ErrMalformedNAL)
+}
+
+func TestH264Decoder_RecoveryPoint(t *testing.T) {
+	d := newTestH264Decoder(t)
+	track := func(nals ...[]byte) *Frame {
+		frame := d.newFrame(annexB(nals...), 0, false, false)
+		require.NotNil(t, frame)
+		return frame
+	}
+
+	// Joining an intra-refresh stream: decoding is correct two frames
+	// after the recovery point, which is not a keyframe itself
+	frame := track(h264SEI(recoveryPointSEI(2, false)), h264Slice(0x41, 0, 5))
+	assert.False(t, frame.IsKey)
+	assert.False(t, frame.IsKeyFrame())
+	assert.True(t, frame.IsReferenceBroken)
+	assert.Len(t, SplitAnnexB(frame.Data), 2, "no SPS/PPS prepended")
+	frame = track(h264Slice(0x41, 0, 6))
+	assert.False(t, frame.IsKey)
+	assert.True(t, frame.IsReferenceBroken)
+	frame = track(h264Slice(0x41, 0, 7))
+	assert.False(t, frame.IsReferenceBroken)
+
+	// Open GOP: an I frame with a recovery point repairs a frame_num gap
+	assert.True(t, track(h264Slice(0x41, 0, 10)).IsReferenceBroken)
+	frame = track(h264SEI(recoveryPointSEI(0, true)), h264Slice(0x61, 2, 11))
+	assert.True(t, frame.IsKey)
+	assert.True(t, frame.IsKeyFrame())
+	assert.Equal(t, FrameTypeI, frame.Type)
+	assert.Equal(t, testSPS(), SplitAnnexB(frame.Data)[0], "SPS/PPS prepended")
+	assert.False(t, track(h264Slice(0x41, 0, 12)).IsReferenceBroken)
+
+	// A gap while recovering cancels the recovery
+	assert.False(t, track(h264SEI(recoveryPointSEI(3, false)), h264Slice(0x41, 0, 13)).IsReferenceBroken)
+	assert.True(t, track(h264Slice(0x41, 0, 15)).IsReferenceBroken)
+	assert.True(t, track(h264Slice(0x41, 0, 0)).IsReferenceBroken)
+
+	stats := d.GetStats()
+	assert.Equal(t, 2, stats.FrameNumGaps)
+}
+
+func TestFrame_IsKeyFrameRecoveryPoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		recoveryCount uint32
+		frameType     FrameType
+		codec         Codec
+		isKey         bool
+	}{
+		{"I frame, recovery count 0", 0, FrameTypeI, CodecH264, true},
+		{"P frame, recovery count 0", 0, FrameTypeP, CodecH264, true},
+		{"I frame starting an intra refresh", 3, FrameTypeI, CodecH264, true},
+		{"P frame starting an intra refresh", 3, FrameTypeP, CodecH264, false},
+		{"unclassified frame starting an intra refresh", 3, FrameTypeUnknown, CodecH264, false},
+		{"H.265 frame", 0, FrameTypeI, CodecH265, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := &Frame{
+				Data:  annexB(h264Slice(0x41, 0, 1)),
+				Codec: tt.codec,
+				Type:  tt.frameType,
+				SEI:   &SEI{RecoveryPoint: &RecoveryPoint{RecoveryFrameCount: tt.recoveryCount}},
+			}
+			assert.Equal(t, tt.isKey, frame.IsKeyFrame())
+		})
+	}
+
+	// Without the SEI only IDR slices count
+	assert.False(t, (&Frame{Data: annexB(h264Slice(0x61, 2, 1)), Type: FrameTypeI}).IsKeyFrame())
+	assert.True(t, (&Frame{Data: annexB(h264Slice(0x65, 2, 0))}).IsKeyFrame())
+}
@@ -0,0 +1,279 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rtsp-client/pkg/logger"
+)
+
+const (
+	// H.264 slice_type values modulo 5 (ITU-T H.264 Table 7-6)
+	sliceTypeP  = 0
+	sliceTypeB  = 1
+	sliceTypeI  = 2
+	sliceTypeSP = 3
+	sliceTypeSI = 4
+
+	// sliceHeaderMaxBytes bounds the bytes unescaped for a slice header; the
+	// fields up to pic_order_cnt_lsb fit well within it
+	sliceHeaderMaxBytes = 64
+)
+
+var (
+	// ErrUnknownParameterSet indicates a slice referring to a PPS or SPS that was not received
+	ErrUnknownParameterSet = errors.New("unknown parameter set")
+)
+
+// FrameType classifies a frame by its slice types
+type FrameType int
+
+const (
+	// FrameTypeUnknown means the slice headers could not be parsed
+	FrameTypeUnknown FrameType = iota
+	// FrameTypeI has only I/SI slices
+	FrameTypeI
+	// FrameTypeP has P/SP slices and no B slices
+	FrameTypeP
+	// FrameTypeB has at least one B slice
+	FrameTypeB
+)
+
+// String returns "I", "P", "B" or "?"
+func (t FrameType) String() string {
+	switch t {
+	case FrameTypeI:
+		return "I"
+	case FrameTypeP:
+		return "P"
+	case FrameTypeB:
+		return "B"
+	}
+	return "?"
+}
+
+// sliceHeader holds the leading fields of an H.264 slice header (ITU-T H.264 section 7.3.3)
+type sliceHeader struct {
+	nalRefIDC      uint8
+	idr            bool
+	firstMbInSlice uint32
+	sliceType      uint32 // Modulo 5
+	ppsID          uint32
+	frameNum       uint32
+	maxFrameNum    uint32
+	fieldPic       bool
+	bottomField    bool
+	idrPicID       uint32
+	picOrderCntLsb uint32
+}
+
+// parseSliceHeader parses the slice header of a coded slice NAL unit (type 1
+// or 5) up to pic_order_cnt_lsb, using the parameter sets it refers to
+func parseSliceHeader(nal []byte, spsByID map[uint32]*SPS, ppsByID map[uint32]*PPS) (*sliceHeader, error) {
+	if len(nal) < 2 {
+		return nil, fmt.Errorf("%w: slice too short", ErrMalformedNAL)
+	}
+	nalType := nal[0] & 0x1F
+	if nalType != 1 && nalType != nalUnitTypeIDR {
+		return nil, fmt.Errorf("%w: NAL unit type %d is not a coded slice", ErrMalformedNAL, nalType)
+	}
+	body := nal[1:]
+	if len(body) > sliceHeaderMaxBytes {
+		body = body[:sliceHeaderMaxBytes]
+	}
+
+	h := &sliceHeader{nalRefIDC: nal[0] >> 5 & 0x03, idr: nalType == nalUnitTypeIDR}
+	r := newBitReader(unescapeRBSP(body))
+	var err error
+	if h.firstMbInSlice, err = r.readUE(); err != nil {
+		return nil, fmt.Errorf("%w: slice header: %v", ErrMalformedNAL, err)
+	}
+	if h.sliceType, err = r.readUE(); err != nil {
+		return nil, fmt.Errorf("%w: slice header: %v", ErrMalformedNAL, err)
+	}
+	if h.sliceType > 9 {
+		return nil, fmt.Errorf("%w: slice_type %d", ErrMalformedNAL, h.sliceType)
+	}
+	h.sliceType %= 5
+	if h.ppsID, err = r.readUE(); err != nil {
+		return nil, fmt.Errorf("%w: slice header: %v", ErrMalformedNAL, err)
+	}
+
+	pps, ok := ppsByID[h.ppsID]
+	if !ok {
+		return nil, fmt.Errorf("%w: PPS %d", ErrUnknownParameterSet, h.ppsID)
+	}
+	sps, ok := spsByID[pps.SPSID]
+	if !ok {
+		return nil, fmt.Errorf("%w: SPS %d", ErrUnknownParameterSet, pps.SPSID)
+	}
+
+	if err := h.parseFrameFields(r, sps); err != nil {
+		return nil, fmt.Errorf("%w: slice header: %v", ErrMalformedNAL, err)
+	}
+	return h, nil
+}
+
+// parseFrameFields reads colour_plane_id through pic_order_cnt_lsb
+func (h *sliceHeader) parseFrameFields(r *bitReader, sps *SPS) error {
+	var err error
+	if sps.SeparateColourPlane {
+		if err = r.skipBits(2); err != nil {
+			return err
+		}
+	}
+	h.maxFrameNum = 1 << sps.Log2MaxFrameNum
+	if h.frameNum, err = r.readBits(sps.Log2MaxFrameNum); err != nil {
+		return err
+	}
+	if !sps.FrameMbsOnly {
+		if h.fieldPic, err = r.readFlag(); err != nil {
+			return err
+		}
+		if h.fieldPic {
+			if h.bottomField, err = r.readFlag(); err != nil {
+				return err
+			}
+		}
+	}
+	if h.idr {
+		if h.idrPicID, err = r.readUE(); err != nil {
+			return err
+		}
+	}
+	if sps.PicOrderCntType == 0 {
+		h.picOrderCntLsb, err = r.readBits(sps.Log2MaxPicOrderCntLsb)
+	}
+	return err
+}
+
+// trackReferences classifies a frame from its slice headers and SEI messages
+// and follows the reference chain: after a frame_num gap, or a corrupted
+// reference frame, every following frame is reference-broken until the next
+// IDR or recovery point. Frames before the first of those are
+// reference-broken too. Frames whose slice headers cannot be parsed are left
+// unclassified and do not affect the chain.
+func (d *H264Decoder) trackReferences(frame *Frame) {
+	var first *sliceHeader
+	var seiNALs [][]byte
+	hasP, hasB := false, false
+	for _, nal := range SplitAnnexB(frame.Data) {
+		switch nal[0] & 0x1F {
+		case nalUnitTypeSEI:
+			seiNALs = append(seiNALs, nal)
+			continue
+		case 1, nalUnitTypeIDR:
+		default:
+			continue
+		}
+		h, err := parseSliceHeader(nal, d.spsByID, d.ppsByID)
+		if err != nil {
+			logger.Debug("[H264Decoder:trackReferences] Cannot parse slice header: timestamp=%d: %v", frame.Timestamp, err)
+			continue
+		}
+		if first == nil {
+			first = h
+		}
+		switch h.sliceType {
+		case sliceTypeB:
+			hasB = true
+		case sliceTypeP, sliceTypeSP:
+			hasP = true
+		}
+	}
+	d.parseFrameSEI(frame, seiNALs, first)
+	if first == nil {
+		return
+	}
+
+	switch {
+	case hasB:
+		frame.Type = FrameTypeB
+	case hasP:
+		frame.Type = FrameTypeP
+	default:
+		frame.Type = FrameTypeI
+	}
+
+	refs := &d.references
+	if first.idr {
+		refs.repair(frame, "IDR")
+	} else if refs.haveFrameNum && first.frameNum != refs.prevRefFrameNum &&
+		first.frameNum != (refs.prevRefFrameNum+1)%first.maxFrameNum {
+		d.stats.FrameNumGaps++
+		if !refs.broken {
+			logger.Warn("[H264Decoder] frame_num gap at timestamp=%d: %d after %d, frames are reference-broken until the next IDR or recovery point",
+				frame.Timestamp, first.frameNum, refs.prevRefFrameNum)
+		}
+		refs.broken, refs.recovering = true, false
+	}
+
+	// A recovery point repairs the chain at once, or once frame_num reaches
+	// recovery_frame_cnt frames later
+	if sei := frame.SEI; !first.idr && sei != nil && sei.RecoveryPoint != nil {
+		if count := sei.RecoveryPoint.RecoveryFrameCount; count == 0 {
+			refs.repair(frame, "Recovery point")
+		} else {
+			refs.recovering = true
+			refs.recoveryFrameNum = (first.frameNum + count) % first.maxFrameNum
+		}
+	} else if refs.recovering && first.frameNum == refs.recoveryFrameNum {
+		refs.repair(frame, "Recovery point")
+	}
+
+	// Intra-only frames decode cleanly even though later frames may still
+	// refer to pictures before them
+	frame.IsReferenceBroken = (refs.broken || !refs.synced) && frame.Type != FrameTypeI
+	if frame.IsReferenceBroken {
+		d.stats.ReferenceBrokenFrames++
+	}
+
+	if first.nalRefIDC != 0 {
+		refs.prevRefFrameNum, refs.haveFrameNum = first.frameNum, true
+		if frame.IsCorrupted && !refs.broken {
+			logger.Warn("[H264Decoder] Corrupted reference frame at timestamp=%d, frames are reference-broken until the next IDR or recovery point", frame.Timestamp)
+			refs.broken = true
+		}
+	}
+}
+
+// parseFrameSEI sets frame.SEI from the SEI NAL units of the frame. The SPS
+// for pic_timing is the one the first slice refers to.
+func (d *H264Decoder) parseFrameSEI(frame *Frame, seiNALs [][]byte, first *sliceHeader) {
+	if len(seiNALs) == 0 {
+		return
+	}
+	var sps *SPS
+	if first != nil {
+		sps = d.spsByID[d.ppsByID[first.ppsID].SPSID]
+	}
+	sei := &SEI{}
+	for _, nal := range seiNALs {
+		if err := d.parseSEI(nal, sps, sei); err != nil {
+			logger.Debug("[H264Decoder:parseFrameSEI] timestamp=%d: %v", frame.Timestamp, err)
+		}
+	}
+	if sei.RecoveryPoint != nil || sei.PicTiming != nil || len(sei.UserData) > 0 {
+		frame.SEI = sei
+	}
+}
+
+// referenceChain is the state trackReferences keeps between frames
+type referenceChain struct {
+	synced          bool // An IDR or recovery point was seen
+	broken          bool
+	prevRefFrameNum uint32
+	haveFrameNum    bool
+
+	// Pending recovery point: the chain is repaired at recoveryFrameNum
+	recovering       bool
+	recoveryFrameNum uint32
+}
+
+// repair ends the broken state at a random access point
+func (refs *referenceChain) repair(frame *Frame, cause string) {
+	if refs.broken {
+		logger.Info("[H264Decoder] %s at timestamp=%d repairs the reference chain", cause, frame.Timestamp)
+	}
+	refs.synced, refs.broken, refs.recovering = true, false, false
+}
@@ -0,0 +1,127 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// h264Slice builds a coded slice for the parameter sets of testSPS and
+// testH264PPS: 4-bit frame_num and 6-bit pic_order_cnt_lsb
+func h264Slice(header byte, sliceType, frameNum uint32) []byte {
+	w := &bitWriter{}
+	w.ue(0).ue(sliceType).ue(0).u(4, frameNum)
+	if header&0x1F == nalUnitTypeIDR {
+		w.ue(3) // idr_pic_id
+	}
+	w.u(6, 2*frameNum)
+	w.u(16, 0xBEEF) // Slice data
+	return append([]byte{header}, w.rbsp()...)
+}
+
+// newTestH264Decoder returns a decoder that knows testSPS and testH264PPS
+func newTestH264Decoder(t *testing.T) *H264Decoder {
+	d := NewH264Decoder()
+	d.cacheParameterSet(testSPS())
+	d.cacheParameterSet(testH264PPS)
+	require.Len(t, d.spsByID, 1)
+	require.Len(t, d.ppsByID, 1)
+	return d
+}
+
+func TestParseSliceHeader(t *testing.T) {
+	d := newTestH264Decoder(t)
+
+	h, err := parseSliceHeader(h264Slice(0x65, 7, 0), d.spsByID, d.ppsByID)
+	require.NoError(t, err)
+	assert.Equal(t, &sliceHeader{nalRefIDC: 3, idr: true, sliceType: sliceTypeI, maxFrameNum: 16, idrPicID: 3}, h)
+
+	h, err = parseSliceHeader(h264Slice(0x01, 1, 9), d.spsByID, d.ppsByID)
+	require.NoError(t, err)
+	assert.Equal(t, &sliceHeader{sliceType: sliceTypeB, frameNum: 9, maxFrameNum: 16, picOrderCntLsb: 18}, h)
+
+	_, err = parseSliceHeader(h264Slice(0x41, 0, 1), d.spsByID, map[uint32]*PPS{})
+	assert.ErrorIs(t, err, ErrUnknownParameterSet)
+	_, err = parseSliceHeader(testH264SPS, d.spsByID, d.ppsByID)
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+	_, err = parseSliceHeader([]byte{0x41, 0x00}, d.spsByID, d.ppsByID)
+	assert.ErrorIs(t, err, ErrMalformedNAL)
+}
+
+func TestH264Decoder_TrackReferences(t *testing.T) {
+	d := newTestH264Decoder(t)
+
+	tests := []struct {
+		name      string
+		slices    [][]byte
+		corrupted bool
+		frameType FrameType
+		broken    bool
+	}{
+		{"P before the first IDR", [][]byte{h264Slice(0x41, 5, 9)}, false, FrameTypeP, true},
+		{"IDR", [][]byte{h264Slice(0x65, 7, 0), h264Slice(0x65, 7, 0)}, false, FrameTypeI, false},
+		{"P", [][]byte{h264Slice(0x41, 0, 1)}, false, FrameTypeP, false},
+		{"non-reference B", [][]byte{h264Slice(0x01, 1, 2)}, false, FrameTypeB, false},
+		{"P after B", [][]byte{h264Slice(0x41, 5, 2)}, false, FrameTypeP, false},
+		{"P after a lost frame", [][]byte{h264Slice(0x41, 5, 4)}, false, FrameTypeP, true},
+		{"P with a P and an I slice", [][]byte{h264Slice(0x41, 2, 5), h264Slice(0x41, 0, 5)}, false, FrameTypeP, true},
+		{"non-IDR I", [][]byte{h264Slice(0x61, 2, 6)}, false, FrameTypeI, false},
+		{"P after non-IDR I", [][]byte{h264Slice(0x41, 0, 7)}, false, FrameTypeP, true},
+		{"IDR repairs", [][]byte{h264Slice(0x65, 2, 0)}, false, FrameTypeI, false},
+		{"corrupted P", [][]byte{h264Slice(0x41, 0, 1)}, true, FrameTypeP, false},
+		{"P after corrupted P", [][]byte{h264Slice(0x41, 0, 2)}, false, FrameTypeP, true},
+		{"frame_num wraps", [][]byte{h264Slice(0x65, 2, 0), h264Slice(0x41, 0, 15)}, false, FrameTypeP, false},
+	}
+
+	for _, tt := range tests {
+		frame := &Frame{Data: annexB(tt.slices...), IsCorrupted: tt.corrupted}
+		if tt.name == "frame_num wraps" {
+			// IDR, then frame_num 1..15 and back to 0
+			d.trackReferences(&Frame{Data: annexB(tt.slices[0])})
+			for fn := uint32(1); fn < 16; fn++ {
+				d.trackReferences(&Frame{Data: annexB(h264Slice(0x41, 0, fn))})
+			}
+			frame.Data = annexB(h264Slice(0x41, 0, 0))
+		}
+		d.trackReferences(frame)
+		assert.Equal(t, tt.frameType, frame.Type, tt.name)
+		assert.Equal(t, tt.broken, frame.IsReferenceBroken, tt.name)
+		assert.Equal(t, tt.broken || tt.corrupted, frame.IsDamaged(), tt.name)
+	}
+
+	stats := d.GetStats()
+	assert.Equal(t, 1, stats.FrameNumGaps)
+	assert.Equal(t, 5, stats.ReferenceBrokenFrames)
+
+	// Frames without parseable slice headers are left alone
+	frame := &Frame{Data: annexB([]byte{0x41, 0x9a})}
+	d.trackReferences(frame)
+	assert.Equal(t, FrameTypeUnknown, frame.Type)
+	assert.Equal(t, "?", frame.Type.String())
+}
+
+func TestH264Decoder_ReferenceBrokenAfterLoss(t *testing.T) {
+	d := newTestH264Decoder(t)
+	packet := func(seq uint16, ts uint32, nal []byte) *Frame {
+		p := h264Packet(seq, ts, nal)
+		p.Marker = true
+		return d.ProcessPacket(p)
+	}
+
+	frame := packet(1, 0, h264Slice(0x65, 7, 0))
+	require.NotNil(t, frame)
+	assert.Equal(t, FrameTypeI, frame.Type)
+	require.NotNil(t, packet(2, 3000, h264Slice(0x41, 5, 1)))
+
+	// The frame with frame_num 2 is lost entirely
+	frame = packet(4, 9000, h264Slice(0x41, 5, 3))
+	require.NotNil(t, frame)
+	assert.False(t, frame.IsCorrupted)
+	assert.True(t, frame.IsReferenceBroken)
+	assert.Contains(t, frame.String(), "REFERENCE-BROKEN")
+
+	frame = packet(5, 12000, h264Slice(0x65, 7, 0))
+	require.NotNil(t, frame)
+	assert.False(t, frame.IsDamaged())
+}
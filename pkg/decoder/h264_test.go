@@ -288,3 +288,27 @@ func TestFrame_GetTimestampString(t *testing.T) {
 		})
 	}
 }
+
+func TestH264Decoder_SequenceTracking(t *testing.T) {
+	decoder := NewH264Decoder()
+	packet := func(seq uint16, ts uint32) *rtp.Packet {
+		return &rtp.Packet{Marker: true, Timestamp: ts, SequenceNumber: seq, SSRC: 1, Payload: []byte{0x41, 0x9a}}
+	}
+
+	require.NotNil(t, decoder.ProcessPacket(packet(1, 1000)))
+	require.NotNil(t, decoder.ProcessPacket(packet(2, 2000)))
+	assert.Nil(t, decoder.ProcessPacket(packet(2, 2000)), "duplicate must not emit a frame")
+	require.NotNil(t, decoder.ProcessPacket(packet(5, 5000)))
+
+	// Sender restart without SSRC change
+	decoder.ProcessPacket(packet(40000, 6000))
+	decoder.ProcessPacket(packet(40001, 7000))
+	decoder.ProcessPacket(packet(40002, 8000))
+
+	stats := decoder.GetStats()
+	assert.Equal(t, 1, stats.DuplicatePackets)
+	assert.Equal(t, 1, stats.PacketLossEvents)
+	assert.Equal(t, 2, stats.PacketsLost)
+	assert.Equal(t, 1, stats.SequenceRestarts)
+	assert.Zero(t, stats.SSRCChanges)
+}
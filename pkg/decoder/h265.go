@@ -0,0 +1,340 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// H.265 NAL unit types (ITU-T H.265 Table 7-1, RFC 7798)
+	h265NALTypeBLAWLP  = 16 // First IRAP type
+	h265NALTypeIRAPMax = 23 // Last (reserved) IRAP type
+	h265NALTypeVPS     = 32 // Video Parameter Set
+	h265NALTypeSPS     = 33 // Sequence Parameter Set
+	h265NALTypePPS     = 34 // Picture Parameter Set
+	h265NALTypeAP      = 48 // Aggregation Packet
+	h265NALTypeFU      = 49 // Fragmentation Unit
+	h265NALTypePACI    = 50 // Payload Content Information
+
+	// h265NALHeaderSize is the size of the H.265 NAL unit and payload header
+	h265NALHeaderSize = 2
+)
+
+var (
+	// ErrInvalidParameterSets indicates sprop parameter sets that are not valid base64
+	ErrInvalidParameterSets = errors.New("invalid sprop parameter sets")
+)
+
+// H265Decoder decodes H.265/HEVC RTP packets (RFC 7798) into complete frames:
+// single NAL units, Aggregation Packets and Fragmentation Units, with or
+// without DONL fields.
+type H265Decoder struct {
+	assembler frameAssembler
+
+	// Parameter set caching (Annex B, with start codes)
+	vpsNAL []byte
+	spsNAL []byte
+	ppsNAL []byte
+
+	// donlPresent is set when sprop-max-don-diff > 0: every AP, the first FU
+	// of a NAL unit and every single NAL unit packet carry a DONL field
+	donlPresent bool
+
+	dropCorruptedFrames bool
+}
+
+// NewH265Decoder creates a new H.265 decoder
+func NewH265Decoder() *H265Decoder {
+	return &H265Decoder{
+		assembler: newFrameAssembler("[H265Decoder]"),
+	}
+}
+
+// SetDropCorruptedFrames sets whether to drop corrupted frames instead of returning them
+func (d *H265Decoder) SetDropCorruptedFrames(drop bool) {
+	d.dropCorruptedFrames = drop
+}
+
+// SetFMTP applies the SDP format parameters of an H265 track: sprop-vps,
+// sprop-sps and sprop-pps are cached for keyframes, and sprop-max-don-diff > 0
+// enables DONL parsing
+func (d *H265Decoder) SetFMTP(fmtp map[string]string) error {
+	for _, param := range []struct {
+		name   string
+		target *[]byte
+	}{
+		{"sprop-vps", &d.vpsNAL},
+		{"sprop-sps", &d.spsNAL},
+		{"sprop-pps", &d.ppsNAL},
+	} {
+		value := fmtp[param.name]
+		if value == "" {
+			continue
+		}
+		var annexB []byte
+		for _, set := range strings.Split(value, ",") {
+			nal, err := base64.StdEncoding.DecodeString(strings.TrimSpace(set))
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidParameterSets, param.name, err)
+			}
+			annexB = append(annexB, startCode...)
+			annexB = append(annexB, nal...)
+		}
+		*param.target = annexB
+	}
+
+	if value := fmtp["sprop-max-don-diff"]; value != "" {
+		maxDONDiff, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid sprop-max-don-diff %q: %w", value, err)
+		}
+		d.donlPresent = maxDONDiff > 0
+	}
+	return nil
+}
+
+// SetDONLPresent sets whether packets carry DONL/DOND fields
+func (d *H265Decoder) SetDONLPresent(present bool) {
+	d.donlPresent = present
+}
+
+// GetParameterSets returns the cached VPS, SPS and PPS in Annex B format
+func (d *H265Decoder) GetParameterSets() (vps, sps, pps []byte) {
+	return d.vpsNAL, d.spsNAL, d.ppsNAL
+}
+
+// ProcessPacket processes an RTP packet and returns a frame if complete
+func (d *H265Decoder) ProcessPacket(packet *rtp.Packet) *Frame {
+	if packet == nil || len(packet.Payload) == 0 {
+		return nil
+	}
+
+	logger.Debug("[H265Decoder:ProcessPacket] Processing packet: seq=%d, timestamp=%d, marker=%t, payload=%d bytes",
+		packet.SequenceNumber, packet.Timestamp, packet.Marker, len(packet.Payload))
+
+	added, ssrcChanged := d.assembler.push(packet)
+	if ssrcChanged {
+		// Parameter sets of the old stream do not apply to the new one
+		d.vpsNAL = nil
+		d.spsNAL = nil
+		d.ppsNAL = nil
+	}
+	if !added {
+		return nil
+	}
+
+	for fa := d.assembler.next(packet.Timestamp); fa != nil; fa = d.assembler.next(packet.Timestamp) {
+		if frame := d.finalizeFrame(fa); frame != nil {
+			return frame
+		}
+	}
+	return nil
+}
+
+// finalizeFrame reassembles packets into an Annex B access unit
+func (d *H265Decoder) finalizeFrame(fa *FrameAssembly) *Frame {
+	var frameData []byte
+	isCorrupted := fa.hasPacketLoss
+	var hasIRAP, hasVPS, hasSPS, hasPPS bool
+
+	appendNAL := func(nal []byte) {
+		if len(nal) < h265NALHeaderSize {
+			isCorrupted = true
+			return
+		}
+		annexB := append(append([]byte{}, startCode...), nal...)
+		switch nalType := h265NALType(nal[0]); {
+		case nalType == h265NALTypeVPS:
+			d.vpsNAL, hasVPS = annexB, true
+		case nalType == h265NALTypeSPS:
+			d.spsNAL, hasSPS = annexB, true
+		case nalType == h265NALTypePPS:
+			d.ppsNAL, hasPPS = annexB, true
+		case isH265IRAP(nalType):
+			hasIRAP = true
+		}
+		frameData = append(frameData, annexB...)
+	}
+
+	// fragment is the NAL unit being reassembled from FUs
+	var fragment []byte
+	fragmentActive := false
+
+	for _, seq := range sortedSequences(fa) {
+		payload := fa.packets[seq].Payload
+		if len(payload) < h265NALHeaderSize {
+			isCorrupted = true
+			continue
+		}
+
+		nalType := h265NALType(payload[0])
+		if nalType != h265NALTypeFU && fragmentActive {
+			// FU not properly ended; keep what arrived
+			isCorrupted = true
+			appendNAL(fragment)
+			fragmentActive = false
+		}
+
+		switch nalType {
+		case h265NALTypeAP:
+			nalUnits, ok := d.unpackAP(payload)
+			if !ok {
+				isCorrupted = true
+			}
+			for _, nal := range nalUnits {
+				appendNAL(nal)
+			}
+
+		case h265NALTypeFU:
+			if len(payload) < h265NALHeaderSize+1 {
+				isCorrupted = true
+				continue
+			}
+			fuHeader := payload[2]
+			data := payload[3:]
+
+			if fuHeader&fuStartBit != 0 {
+				if fragmentActive {
+					isCorrupted = true
+					appendNAL(fragment)
+				}
+				if d.donlPresent {
+					if len(data) < 2 {
+						isCorrupted = true
+						fragmentActive = false
+						continue
+					}
+					data = data[2:]
+				}
+				// Rebuild the NAL header: F and LayerId MSB from the payload header, type from the FU header
+				fragment = []byte{payload[0]&0x81 | (fuHeader&0x3F)<<1, payload[1]}
+				fragmentActive = true
+			} else if !fragmentActive {
+				isCorrupted = true
+				continue
+			}
+
+			fragment = append(fragment, data...)
+			if fuHeader&fuEndBit != 0 {
+				appendNAL(fragment)
+				fragmentActive = false
+			}
+
+		case h265NALTypePACI:
+			logger.Debug("[H265Decoder:finalizeFrame] Skipping PACI packet seq=%d", seq)
+
+		default:
+			if d.donlPresent {
+				if len(payload) < h265NALHeaderSize+2 {
+					isCorrupted = true
+					continue
+				}
+				appendNAL(append(append([]byte{}, payload[:h265NALHeaderSize]...), payload[h265NALHeaderSize+2:]...))
+			} else {
+				appendNAL(payload)
+			}
+		}
+	}
+
+	if fragmentActive {
+		isCorrupted = true
+		appendNAL(fragment)
+	}
+
+	if len(frameData) == 0 {
+		logger.Warn("[H265Decoder:finalizeFrame] Frame has no data: timestamp=%d, packets=%d", fa.timestamp, len(fa.packets))
+		return nil
+	}
+
+	// Keyframes must be decodable on their own
+	if hasIRAP && !(hasVPS && hasSPS && hasPPS) && len(d.vpsNAL) > 0 && len(d.spsNAL) > 0 && len(d.ppsNAL) > 0 {
+		prepended := make([]byte, 0, len(d.vpsNAL)+len(d.spsNAL)+len(d.ppsNAL)+len(frameData))
+		prepended = append(prepended, d.vpsNAL...)
+		prepended = append(prepended, d.spsNAL...)
+		prepended = append(prepended, d.ppsNAL...)
+		frameData = append(prepended, frameData...)
+	}
+
+	logger.Debug("[H265Decoder:finalizeFrame] Frame assembled: timestamp=%d, size=%d bytes, packets=%d, irap=%t, corrupted=%t",
+		fa.timestamp, len(frameData), len(fa.packets), hasIRAP, isCorrupted)
+
+	if isCorrupted && d.dropCorruptedFrames {
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	d.assembler.stats.TotalFrames++
+	if isCorrupted {
+		d.assembler.stats.CorruptedFrames++
+	}
+
+	return &Frame{
+		Data:        frameData,
+		Timestamp:   fa.timestamp,
+		IsKey:       hasIRAP,
+		IsCorrupted: isCorrupted,
+		Codec:       CodecH265,
+	}
+}
+
+// unpackAP unpacks an Aggregation Packet; ok is false if it was truncated
+func (d *H265Decoder) unpackAP(payload []byte) (nalUnits [][]byte, ok bool) {
+	offset := h265NALHeaderSize
+	if d.donlPresent {
+		offset += 2 // DONL of the first unit
+	}
+
+	for first := true; offset < len(payload); first = false {
+		if !first && d.donlPresent {
+			offset++ // DOND of the following units
+		}
+		if offset+2 > len(payload) {
+			return nalUnits, false
+		}
+		size := int(binary.BigEndian.Uint16(payload[offset:]))
+		offset += 2
+		if size < h265NALHeaderSize || offset+size > len(payload) {
+			return nalUnits, false
+		}
+		nalUnits = append(nalUnits, payload[offset:offset+size])
+		offset += size
+	}
+	return nalUnits, len(nalUnits) > 0
+}
+
+// Reset clears partially assembled frames; parameter sets stay valid until the SSRC changes
+func (d *H265Decoder) Reset() {
+	d.assembler.reset()
+}
+
+// GetCurrentSSRC returns the current stream SSRC
+func (d *H265Decoder) GetCurrentSSRC() uint32 {
+	return d.assembler.currentSSRC
+}
+
+// GetStats returns decoder statistics
+func (d *H265Decoder) GetStats() DecoderStats {
+	return d.assembler.stats
+}
+
+// ResetStats resets decoder statistics
+func (d *H265Decoder) ResetStats() {
+	d.assembler.stats = DecoderStats{}
+}
+
+// h265NALType extracts the NAL unit type from the first header byte
+func h265NALType(b byte) byte {
+	return (b >> 1) & 0x3F
+}
+
+// isH265IRAP reports whether a NAL unit type is an IRAP picture (BLA, IDR or CRA)
+func isH265IRAP(nalType byte) bool {
+	return nalType >= h265NALTypeBLAWLP && nalType <= h265NALTypeIRAPMax
+}
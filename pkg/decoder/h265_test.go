@@ -0,0 +1,198 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testH265VPS   = []byte{0x40, 0x01, 0x0c, 0x01}
+	testH265SPS   = []byte{0x42, 0x01, 0x01, 0x60}
+	testH265PPS   = []byte{0x44, 0x01, 0xc1, 0x72}
+	testH265IDR   = []byte{0x26, 0x01, 0xaf, 0x10, 0x20}
+	testH265Trail = []byte{0x02, 0x01, 0xd0, 0x30}
+)
+
+// h265AP builds an Aggregation Packet; donl adds a DONL and DOND fields
+func h265AP(donl bool, nalUnits ...[]byte) []byte {
+	payload := []byte{0x60, 0x01}
+	for i, nal := range nalUnits {
+		if donl {
+			if i == 0 {
+				payload = append(payload, 0x00, 0x07)
+			} else {
+				payload = append(payload, 0x00)
+			}
+		}
+		payload = append(payload, byte(len(nal)>>8), byte(len(nal)))
+		payload = append(payload, nal...)
+	}
+	return payload
+}
+
+func h265Packet(seq uint16, timestamp uint32, marker bool, payload []byte) *rtp.Packet {
+	return &rtp.Packet{
+		SequenceNumber: seq,
+		Timestamp:      timestamp,
+		Marker:         marker,
+		SSRC:           0x265,
+		Payload:        payload,
+	}
+}
+
+func TestH265Decoder_ProcessPacket(t *testing.T) {
+	tests := []struct {
+		name      string
+		donl      bool
+		packets   []*rtp.Packet
+		expected  []byte
+		isKey     bool
+		corrupted bool
+	}{
+		{
+			name:     "single NAL unit",
+			packets:  []*rtp.Packet{h265Packet(1, 3000, true, testH265Trail)},
+			expected: annexB(testH265Trail),
+		},
+		{
+			name: "aggregation packet with parameter sets and IDR",
+			packets: []*rtp.Packet{
+				h265Packet(1, 3000, true, h265AP(false, testH265VPS, testH265SPS, testH265PPS, testH265IDR)),
+			},
+			expected: annexB(testH265VPS, testH265SPS, testH265PPS, testH265IDR),
+			isKey:    true,
+		},
+		{
+			name: "fragmentation units",
+			packets: []*rtp.Packet{
+				h265Packet(1, 3000, false, []byte{0x62, 0x01, 0x80 | 19, 0xaf, 0x10}),
+				h265Packet(2, 3000, false, []byte{0x62, 0x01, 19, 0x20}),
+				h265Packet(3, 3000, true, []byte{0x62, 0x01, 0x40 | 19, 0x30}),
+			},
+			expected: annexB([]byte{0x26, 0x01, 0xaf, 0x10, 0x20, 0x30}),
+			isKey:    true,
+		},
+		{
+			name: "fragmentation unit with lost middle",
+			packets: []*rtp.Packet{
+				h265Packet(1, 3000, false, []byte{0x62, 0x01, 0x80 | 1, 0xaa}),
+				h265Packet(3, 3000, true, []byte{0x62, 0x01, 0x40 | 1, 0xcc}),
+			},
+			expected:  annexB([]byte{0x02, 0x01, 0xaa, 0xcc}),
+			corrupted: true,
+		},
+		{
+			name: "fragmentation unit without start",
+			packets: []*rtp.Packet{
+				h265Packet(1, 3000, false, []byte{0x62, 0x01, 1, 0xbb}),
+				h265Packet(2, 3000, true, testH265Trail),
+			},
+			expected:  annexB(testH265Trail),
+			corrupted: true,
+		},
+		{
+			name: "DONL in single NAL, AP and FU",
+			donl: true,
+			packets: []*rtp.Packet{
+				h265Packet(1, 3000, false, h265AP(true, testH265VPS, testH265SPS, testH265PPS)),
+				h265Packet(2, 3000, false, []byte{0x62, 0x01, 0x80 | 19, 0x00, 0x08, 0xaf}),
+				h265Packet(3, 3000, false, []byte{0x62, 0x01, 0x40 | 19, 0x10}),
+				h265Packet(4, 3000, true, []byte{0x02, 0x01, 0x00, 0x09, 0xd0}),
+			},
+			expected: annexB(testH265VPS, testH265SPS, testH265PPS, []byte{0x26, 0x01, 0xaf, 0x10}, []byte{0x02, 0x01, 0xd0}),
+			isKey:    true,
+		},
+		{
+			name:      "truncated aggregation packet",
+			packets:   []*rtp.Packet{h265Packet(1, 3000, true, []byte{0x60, 0x01, 0x00, 0x04, 0x02, 0x01, 0xd0, 0x30, 0x00, 0x09, 0x02})},
+			expected:  annexB(testH265Trail),
+			corrupted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h265 := NewH265Decoder()
+			h265.SetDONLPresent(tt.donl)
+
+			var frame *Frame
+			for _, packet := range tt.packets {
+				if result := h265.ProcessPacket(packet); result != nil {
+					frame = result
+				}
+			}
+
+			require.NotNil(t, frame)
+			assert.Equal(t, tt.expected, frame.Data)
+			assert.Equal(t, tt.isKey, frame.IsKey)
+			assert.Equal(t, tt.isKey, frame.IsKeyFrame())
+			assert.Equal(t, tt.corrupted, frame.IsCorrupted)
+			assert.Equal(t, CodecH265, frame.Codec)
+		})
+	}
+}
+
+func TestH265Decoder_SetFMTP(t *testing.T) {
+	h265 := NewH265Decoder()
+	err := h265.SetFMTP(map[string]string{
+		"sprop-vps":          base64.StdEncoding.EncodeToString(testH265VPS),
+		"sprop-sps":          base64.StdEncoding.EncodeToString(testH265SPS),
+		"sprop-pps":          base64.StdEncoding.EncodeToString(testH265PPS),
+		"sprop-max-don-diff": "0",
+	})
+	require.NoError(t, err)
+
+	vps, sps, pps := h265.GetParameterSets()
+	assert.Equal(t, annexB(testH265VPS), vps)
+	assert.Equal(t, annexB(testH265SPS), sps)
+	assert.Equal(t, annexB(testH265PPS), pps)
+
+	// IRAP frames without in-band parameter sets get the cached ones
+	frame := h265.ProcessPacket(h265Packet(1, 3000, true, testH265IDR))
+	require.NotNil(t, frame)
+	assert.Equal(t, annexB(testH265VPS, testH265SPS, testH265PPS, testH265IDR), frame.Data)
+	assert.True(t, frame.IsKey)
+
+	// Non-IRAP frames are left alone
+	frame = h265.ProcessPacket(h265Packet(2, 6000, true, testH265Trail))
+	require.NotNil(t, frame)
+	assert.Equal(t, annexB(testH265Trail), frame.Data)
+	assert.False(t, frame.IsKey)
+
+	assert.ErrorIs(t, h265.SetFMTP(map[string]string{"sprop-sps": "not base64!"}), ErrInvalidParameterSets)
+	assert.Error(t, h265.SetFMTP(map[string]string{"sprop-max-don-diff": "x"}))
+
+	require.NoError(t, h265.SetFMTP(map[string]string{"sprop-max-don-diff": "2"}))
+	assert.True(t, h265.donlPresent)
+}
+
+func TestH265Decoder_SequenceTracking(t *testing.T) {
+	h265 := NewH265Decoder()
+	require.NotNil(t, h265.ProcessPacket(h265Packet(1, 3000, true, testH265Trail)))
+	require.NotNil(t, h265.ProcessPacket(h265Packet(2, 6000, true, testH265Trail)))
+	assert.Nil(t, h265.ProcessPacket(h265Packet(2, 6000, true, testH265Trail)), "duplicate must not emit a frame")
+	require.NotNil(t, h265.ProcessPacket(h265Packet(5, 9000, true, testH265Trail)))
+
+	stats := h265.GetStats()
+	assert.Equal(t, 3, stats.TotalFrames)
+	assert.Equal(t, 1, stats.DuplicatePackets)
+	assert.Equal(t, 1, stats.PacketLossEvents)
+	assert.Equal(t, 2, stats.PacketsLost)
+}
+
+func TestFrame_IsKeyFrameH265(t *testing.T) {
+	cra := &Frame{Data: annexB([]byte{0x2a, 0x01, 0x00}), Codec: CodecH265}
+	assert.True(t, cra.IsKeyFrame())
+
+	// The same bytes are a non-IDR slice in H.264 terms
+	assert.False(t, (&Frame{Data: annexB([]byte{0x2a, 0x01, 0x00})}).IsKeyFrame())
+
+	trail := &Frame{Data: annexB(testH265Trail), Codec: CodecH265}
+	assert.False(t, trail.IsKeyFrame())
+	assert.Equal(t, "H265", CodecH265.String())
+	assert.Equal(t, "H264", CodecH264.String())
+}
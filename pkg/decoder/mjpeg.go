@@ -0,0 +1,439 @@
+package decoder
+
+import (
+	"encoding/binary"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// PayloadTypeJPEG is the static RTP payload type of JPEG video (RFC 3551)
+	PayloadTypeJPEG = 26
+
+	// RTP/JPEG header sizes (RFC 2435 section 3.1)
+	jpegHeaderSize        = 8
+	jpegRestartHeaderSize = 4
+	jpegQTableHeaderSize  = 4
+
+	// Types 64-127 carry a restart marker header; the low bits select the sampling
+	jpegTypeRestartFlag = 64
+
+	// Q values of 128 and above carry quantization tables in-band
+	jpegQDynamic = 128
+
+	// JPEG markers
+	jpegMarkerSOI = 0xD8
+	jpegMarkerEOI = 0xD9
+	jpegMarkerSOF = 0xC0
+	jpegMarkerDHT = 0xC4
+	jpegMarkerDQT = 0xDB
+	jpegMarkerDRI = 0xDD
+	jpegMarkerSOS = 0xDA
+)
+
+// Standard quantization tables in zig-zag order (RFC 2435 Appendix A)
+var (
+	jpegLumaQuantizer = [64]byte{
+		16, 11, 12, 14, 12, 10, 16, 14,
+		13, 14, 18, 17, 16, 19, 24, 40,
+		26, 24, 22, 22, 24, 49, 35, 37,
+		29, 40, 58, 51, 61, 60, 57, 51,
+		56, 55, 64, 72, 92, 78, 64, 68,
+		87, 69, 55, 56, 80, 109, 81, 87,
+		95, 98, 103, 104, 103, 62, 77, 113,
+		121, 112, 100, 120, 92, 101, 103, 99,
+	}
+	jpegChromaQuantizer = [64]byte{
+		17, 18, 18, 24, 21, 24, 47, 26,
+		26, 47, 99, 66, 56, 66, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+		99, 99, 99, 99, 99, 99, 99, 99,
+	}
+)
+
+// Standard Huffman tables (ITU-T T.81 Annex K.3, RFC 2435 Appendix B)
+var (
+	jpegLumaDCCodeLens = []byte{0, 1, 5, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0, 0, 0}
+	jpegLumaDCSymbols  = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	jpegLumaACCodeLens = []byte{0, 2, 1, 3, 3, 2, 4, 3, 5, 5, 4, 4, 0, 0, 1, 0x7d}
+	jpegLumaACSymbols  = []byte{
+		0x01, 0x02, 0x03, 0x00, 0x04, 0x11, 0x05, 0x12,
+		0x21, 0x31, 0x41, 0x06, 0x13, 0x51, 0x61, 0x07,
+		0x22, 0x71, 0x14, 0x32, 0x81, 0x91, 0xa1, 0x08,
+		0x23, 0x42, 0xb1, 0xc1, 0x15, 0x52, 0xd1, 0xf0,
+		0x24, 0x33, 0x62, 0x72, 0x82, 0x09, 0x0a, 0x16,
+		0x17, 0x18, 0x19, 0x1a, 0x25, 0x26, 0x27, 0x28,
+		0x29, 0x2a, 0x34, 0x35, 0x36, 0x37, 0x38, 0x39,
+		0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48, 0x49,
+		0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59,
+		0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69,
+		0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78, 0x79,
+		0x7a, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89,
+		0x8a, 0x92, 0x93, 0x94, 0x95, 0x96, 0x97, 0x98,
+		0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5, 0xa6, 0xa7,
+		0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4, 0xb5, 0xb6,
+		0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3, 0xc4, 0xc5,
+		0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2, 0xd3, 0xd4,
+		0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda, 0xe1, 0xe2,
+		0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9, 0xea,
+		0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+		0xf9, 0xfa,
+	}
+	jpegChromaDCCodeLens = []byte{0, 3, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 0, 0}
+	jpegChromaDCSymbols  = []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	jpegChromaACCodeLens = []byte{0, 2, 1, 2, 4, 4, 3, 4, 7, 5, 4, 4, 0, 1, 2, 0x77}
+	jpegChromaACSymbols  = []byte{
+		0x00, 0x01, 0x02, 0x03, 0x11, 0x04, 0x05, 0x21,
+		0x31, 0x06, 0x12, 0x41, 0x51, 0x07, 0x61, 0x71,
+		0x13, 0x22, 0x32, 0x81, 0x08, 0x14, 0x42, 0x91,
+		0xa1, 0xb1, 0xc1, 0x09, 0x23, 0x33, 0x52, 0xf0,
+		0x15, 0x62, 0x72, 0xd1, 0x0a, 0x16, 0x24, 0x34,
+		0xe1, 0x25, 0xf1, 0x17, 0x18, 0x19, 0x1a, 0x26,
+		0x27, 0x28, 0x29, 0x2a, 0x35, 0x36, 0x37, 0x38,
+		0x39, 0x3a, 0x43, 0x44, 0x45, 0x46, 0x47, 0x48,
+		0x49, 0x4a, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58,
+		0x59, 0x5a, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68,
+		0x69, 0x6a, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78,
+		0x79, 0x7a, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87,
+		0x88, 0x89, 0x8a, 0x92, 0x93, 0x94, 0x95, 0x96,
+		0x97, 0x98, 0x99, 0x9a, 0xa2, 0xa3, 0xa4, 0xa5,
+		0xa6, 0xa7, 0xa8, 0xa9, 0xaa, 0xb2, 0xb3, 0xb4,
+		0xb5, 0xb6, 0xb7, 0xb8, 0xb9, 0xba, 0xc2, 0xc3,
+		0xc4, 0xc5, 0xc6, 0xc7, 0xc8, 0xc9, 0xca, 0xd2,
+		0xd3, 0xd4, 0xd5, 0xd6, 0xd7, 0xd8, 0xd9, 0xda,
+		0xe2, 0xe3, 0xe4, 0xe5, 0xe6, 0xe7, 0xe8, 0xe9,
+		0xea, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7, 0xf8,
+		0xf9, 0xfa,
+	}
+)
+
+// jpegHeader is the RTP/JPEG main header plus the optional restart and
+// quantization table headers of one packet
+type jpegHeader struct {
+	fragmentOffset  int
+	jpegType        byte
+	q               byte
+	width           int // Pixels
+	height          int // Pixels
+	restartInterval uint16
+	qTables         []byte // In-band tables (Q >= 128, first fragment only)
+	qPrecision      byte   // Bit i set: table i has 16-bit entries
+	data            []byte // Scan data of this fragment
+}
+
+// MJPEGDecoder rebuilds complete JFIF images from RTP/JPEG packets (RFC 2435).
+// JPEG frames are self-contained, so every complete frame is a keyframe.
+type MJPEGDecoder struct {
+	assembler frameAssembler
+
+	// qTables caches in-band quantization tables by Q, since senders may omit
+	// them (length 0) in frames after the first
+	qTables map[byte]mjpegQTables
+
+	dropCorruptedFrames bool
+}
+
+// mjpegQTables is a set of quantization tables with their precision bits
+type mjpegQTables struct {
+	data      []byte
+	precision byte
+}
+
+// NewMJPEGDecoder creates a new RTP/JPEG decoder
+func NewMJPEGDecoder() *MJPEGDecoder {
+	return &MJPEGDecoder{
+		assembler: newFrameAssembler("[MJPEGDecoder]"),
+		qTables:   make(map[byte]mjpegQTables),
+	}
+}
+
+// SetDropCorruptedFrames sets whether to drop corrupted frames instead of returning them
+func (d *MJPEGDecoder) SetDropCorruptedFrames(drop bool) {
+	d.dropCorruptedFrames = drop
+}
+
+// ProcessPacket processes an RTP packet and returns a JPEG frame if complete
+func (d *MJPEGDecoder) ProcessPacket(packet *rtp.Packet) *Frame {
+	if packet == nil || len(packet.Payload) == 0 {
+		return nil
+	}
+
+	logger.Debug("[MJPEGDecoder:ProcessPacket] Processing packet: seq=%d, timestamp=%d, marker=%t, payload=%d bytes",
+		packet.SequenceNumber, packet.Timestamp, packet.Marker, len(packet.Payload))
+
+	added, ssrcChanged := d.assembler.push(packet)
+	if ssrcChanged {
+		d.qTables = make(map[byte]mjpegQTables)
+	}
+	if !added {
+		return nil
+	}
+
+	for fa := d.assembler.next(packet.Timestamp); fa != nil; fa = d.assembler.next(packet.Timestamp) {
+		if frame := d.finalizeFrame(fa); frame != nil {
+			return frame
+		}
+	}
+	return nil
+}
+
+// finalizeFrame joins the fragments of a frame and prepends the JFIF headers
+func (d *MJPEGDecoder) finalizeFrame(fa *FrameAssembly) *Frame {
+	isCorrupted := fa.hasPacketLoss
+	var first *jpegHeader
+	var scan []byte
+
+	for _, seq := range sortedSequences(fa) {
+		header, ok := parseJPEGHeader(fa.packets[seq].Payload)
+		if !ok {
+			isCorrupted = true
+			continue
+		}
+		if header.fragmentOffset == 0 {
+			first = header
+		}
+		if header.fragmentOffset != len(scan) {
+			// A fragment is missing or overlaps
+			isCorrupted = true
+			if header.fragmentOffset < len(scan) {
+				continue
+			}
+		}
+		scan = append(scan, header.data...)
+	}
+
+	if first == nil {
+		logger.Warn("[MJPEGDecoder:finalizeFrame] Dropping frame without first fragment: timestamp=%d, packets=%d", fa.timestamp, len(fa.packets))
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+	if first.jpegType&^jpegTypeRestartFlag > 1 {
+		logger.Warn("[MJPEGDecoder:finalizeFrame] Unsupported RTP/JPEG type %d: timestamp=%d", first.jpegType, fa.timestamp)
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	tables, ok := d.quantizationTables(first)
+	if !ok {
+		logger.Warn("[MJPEGDecoder:finalizeFrame] No quantization tables for Q=%d: timestamp=%d", first.q, fa.timestamp)
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	if isCorrupted && d.dropCorruptedFrames {
+		d.assembler.stats.CorruptedFrames++
+		return nil
+	}
+
+	data := buildJPEGHeaders(first, tables)
+	data = append(data, scan...)
+	if len(scan) < 2 || scan[len(scan)-2] != 0xFF || scan[len(scan)-1] != jpegMarkerEOI {
+		data = append(data, 0xFF, jpegMarkerEOI)
+	}
+
+	logger.Debug("[MJPEGDecoder:finalizeFrame] Frame assembled: timestamp=%d, size=%d bytes, %dx%d, type=%d, q=%d, corrupted=%t",
+		fa.timestamp, len(data), first.width, first.height, first.jpegType, first.q, isCorrupted)
+
+	d.assembler.stats.TotalFrames++
+	if isCorrupted {
+		d.assembler.stats.CorruptedFrames++
+	}
+
+	return &Frame{
+		Data:        data,
+		Timestamp:   fa.timestamp,
+		IsKey:       true,
+		IsCorrupted: isCorrupted,
+		Codec:       CodecMJPEG,
+	}
+}
+
+// quantizationTables returns the tables for the frame's Q: in-band or cached
+// tables for Q >= 128, scaled standard tables otherwise
+func (d *MJPEGDecoder) quantizationTables(header *jpegHeader) (mjpegQTables, bool) {
+	if header.q < jpegQDynamic {
+		if header.q == 0 || header.q > 99 {
+			return mjpegQTables{}, false
+		}
+		return mjpegQTables{data: makeJPEGQuantTables(int(header.q))}, true
+	}
+
+	if len(header.qTables) > 0 {
+		tables := mjpegQTables{data: append([]byte(nil), header.qTables...), precision: header.qPrecision}
+		// Q = 255 tables change from frame to frame and must not be reused
+		if header.q != 255 {
+			d.qTables[header.q] = tables
+		}
+		return tables, true
+	}
+	tables, ok := d.qTables[header.q]
+	return tables, ok
+}
+
+// Reset clears partially assembled frames; cached quantization tables stay valid
+func (d *MJPEGDecoder) Reset() {
+	d.assembler.reset()
+}
+
+// GetCurrentSSRC returns the current stream SSRC
+func (d *MJPEGDecoder) GetCurrentSSRC() uint32 {
+	return d.assembler.currentSSRC
+}
+
+// GetStats returns decoder statistics
+func (d *MJPEGDecoder) GetStats() DecoderStats {
+	return d.assembler.stats
+}
+
+// ResetStats resets decoder statistics
+func (d *MJPEGDecoder) ResetStats() {
+	d.assembler.stats = DecoderStats{}
+}
+
+// parseJPEGHeader parses the RTP/JPEG headers of a packet payload
+func parseJPEGHeader(payload []byte) (*jpegHeader, bool) {
+	if len(payload) < jpegHeaderSize {
+		return nil, false
+	}
+	header := &jpegHeader{
+		fragmentOffset: int(payload[1])<<16 | int(payload[2])<<8 | int(payload[3]),
+		jpegType:       payload[4],
+		q:              payload[5],
+		width:          int(payload[6]) * 8,
+		height:         int(payload[7]) * 8,
+	}
+	offset := jpegHeaderSize
+
+	if header.jpegType&jpegTypeRestartFlag != 0 && header.jpegType < 128 {
+		if len(payload) < offset+jpegRestartHeaderSize {
+			return nil, false
+		}
+		header.restartInterval = binary.BigEndian.Uint16(payload[offset:])
+		offset += jpegRestartHeaderSize
+	}
+
+	if header.q >= jpegQDynamic && header.fragmentOffset == 0 {
+		if len(payload) < offset+jpegQTableHeaderSize {
+			return nil, false
+		}
+		header.qPrecision = payload[offset+1]
+		length := int(binary.BigEndian.Uint16(payload[offset+2:]))
+		offset += jpegQTableHeaderSize
+		if len(payload) < offset+length {
+			return nil, false
+		}
+		header.qTables = payload[offset : offset+length]
+		offset += length
+	}
+
+	header.data = payload[offset:]
+	return header, true
+}
+
+// makeJPEGQuantTables scales the standard tables for a Q factor of 1-99 (RFC 2435 Appendix A)
+func makeJPEGQuantTables(q int) []byte {
+	factor := q
+	if factor < 1 {
+		factor = 1
+	} else if factor > 99 {
+		factor = 99
+	}
+	scale := 200 - factor*2
+	if factor < 50 {
+		scale = 5000 / factor
+	}
+
+	tables := make([]byte, 128)
+	for i := 0; i < 64; i++ {
+		tables[i] = clampQuant((int(jpegLumaQuantizer[i])*scale + 50) / 100)
+		tables[64+i] = clampQuant((int(jpegChromaQuantizer[i])*scale + 50) / 100)
+	}
+	return tables
+}
+
+func clampQuant(value int) byte {
+	if value < 1 {
+		return 1
+	}
+	if value > 255 {
+		return 255
+	}
+	return byte(value)
+}
+
+// buildJPEGHeaders writes SOI, DQT, SOF0, DRI, DHT and SOS for a frame (RFC 2435 Appendix B)
+func buildJPEGHeaders(header *jpegHeader, tables mjpegQTables) []byte {
+	out := []byte{0xFF, jpegMarkerSOI}
+
+	// DQT: one segment per table; a single table is shared by luma and chroma
+	numTables := 0
+	for offset, i := 0, 0; offset < len(tables.data) && i < 4; i++ {
+		size := 64
+		if tables.precision&(1<<i) != 0 {
+			size = 128
+		}
+		if offset+size > len(tables.data) {
+			break
+		}
+		out = appendJPEGSegment(out, jpegMarkerDQT, append([]byte{byte(size/128)<<4 | byte(i)}, tables.data[offset:offset+size]...))
+		offset += size
+		numTables++
+	}
+	chromaTable := byte(1)
+	if numTables < 2 {
+		chromaTable = 0
+	}
+
+	// SOF0: baseline, 8-bit, YCbCr with 4:2:2 (type 0) or 4:2:0 (type 1) luma sampling
+	lumaSampling := byte(0x21)
+	if header.jpegType&^jpegTypeRestartFlag == 1 {
+		lumaSampling = 0x22
+	}
+	out = appendJPEGSegment(out, jpegMarkerSOF, []byte{
+		8,
+		byte(header.height >> 8), byte(header.height),
+		byte(header.width >> 8), byte(header.width),
+		3,
+		1, lumaSampling, 0,
+		2, 0x11, chromaTable,
+		3, 0x11, chromaTable,
+	})
+
+	if header.restartInterval != 0 {
+		out = appendJPEGSegment(out, jpegMarkerDRI, []byte{byte(header.restartInterval >> 8), byte(header.restartInterval)})
+	}
+
+	out = appendHuffmanTable(out, 0x00, jpegLumaDCCodeLens, jpegLumaDCSymbols)
+	out = appendHuffmanTable(out, 0x10, jpegLumaACCodeLens, jpegLumaACSymbols)
+	out = appendHuffmanTable(out, 0x01, jpegChromaDCCodeLens, jpegChromaDCSymbols)
+	out = appendHuffmanTable(out, 0x11, jpegChromaACCodeLens, jpegChromaACSymbols)
+
+	return appendJPEGSegment(out, jpegMarkerSOS, []byte{
+		3,
+		1, 0x00,
+		2, 0x11,
+		3, 0x11,
+		0, 63, 0,
+	})
+}
+
+// appendHuffmanTable appends a DHT segment with one table
+func appendHuffmanTable(out []byte, classAndID byte, codeLens, symbols []byte) []byte {
+	body := make([]byte, 0, 1+len(codeLens)+len(symbols))
+	body = append(body, classAndID)
+	body = append(body, codeLens...)
+	body = append(body, symbols...)
+	return appendJPEGSegment(out, jpegMarkerDHT, body)
+}
+
+// appendJPEGSegment appends a marker segment; the length includes its own two bytes
+func appendJPEGSegment(out []byte, marker byte, body []byte) []byte {
+	out = append(out, 0xFF, marker)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(body)+2))
+	return append(out, body...)
+}
@@ -0,0 +1,178 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testJPEG encodes a 4:2:0 baseline JPEG and returns its quantization tables and scan data
+func testJPEG(t *testing.T, width, height int) (encoded, qTables, scan []byte) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}))
+	encoded = buf.Bytes()
+
+	for offset := 2; offset+4 <= len(encoded); {
+		marker := encoded[offset+1]
+		length := int(binary.BigEndian.Uint16(encoded[offset+2:]))
+		body := encoded[offset+4 : offset+2+length]
+		switch marker {
+		case jpegMarkerDQT:
+			for i := 0; i+65 <= len(body); i += 65 {
+				qTables = append(qTables, body[i+1:i+65]...)
+			}
+		case jpegMarkerSOS:
+			scan = encoded[offset+2+length:]
+			return encoded, qTables, scan
+		}
+		offset += 2 + length
+	}
+	t.Fatal("no SOS in encoded JPEG")
+	return nil, nil, nil
+}
+
+// jpegPackets fragments scan data into RTP/JPEG packets; the first carries the tables
+func jpegPackets(seq uint16, timestamp uint32, jpegType, q byte, width, height int, qTables, scan []byte, fragmentSize int) []*rtp.Packet {
+	var packets []*rtp.Packet
+	for offset := 0; offset < len(scan); offset += fragmentSize {
+		end := offset + fragmentSize
+		if end > len(scan) {
+			end = len(scan)
+		}
+		payload := []byte{0, byte(offset >> 16), byte(offset >> 8), byte(offset), jpegType, q, byte(width / 8), byte(height / 8)}
+		if jpegType&jpegTypeRestartFlag != 0 {
+			payload = append(payload, 0x00, 0x04, 0xFF, 0xFF)
+		}
+		if q >= jpegQDynamic && offset == 0 {
+			payload = append(payload, 0, 0, byte(len(qTables)>>8), byte(len(qTables)))
+			payload = append(payload, qTables...)
+		}
+		payload = append(payload, scan[offset:end]...)
+		packets = append(packets, &rtp.Packet{
+			SequenceNumber: seq + uint16(len(packets)),
+			Timestamp:      timestamp,
+			Marker:         end == len(scan),
+			SSRC:           0x4A50,
+			PayloadType:    PayloadTypeJPEG,
+			Payload:        payload,
+		})
+	}
+	return packets
+}
+
+func processAll(d VideoDecoder, packets []*rtp.Packet) *Frame {
+	var frame *Frame
+	for _, packet := range packets {
+		if result := d.ProcessPacket(packet); result != nil {
+			frame = result
+		}
+	}
+	return frame
+}
+
+func TestMJPEGDecoder_RebuildsJFIF(t *testing.T) {
+	encoded, qTables, scan := testJPEG(t, 64, 48)
+	expected, err := jpeg.Decode(bytes.NewReader(encoded))
+	require.NoError(t, err)
+
+	mjpeg := NewMJPEGDecoder()
+	frame := processAll(mjpeg, jpegPackets(1, 90000, 1, 255, 64, 48, qTables, scan, 100))
+	require.NotNil(t, frame)
+	assert.True(t, frame.IsKey)
+	assert.True(t, frame.IsKeyFrame())
+	assert.False(t, frame.IsCorrupted)
+	assert.Equal(t, CodecMJPEG, frame.Codec)
+	assert.Equal(t, []byte{0xFF, jpegMarkerSOI}, frame.Data[:2])
+
+	decoded, err := jpeg.Decode(bytes.NewReader(frame.Data))
+	require.NoError(t, err)
+	assert.Equal(t, expected.Bounds(), decoded.Bounds())
+	assert.Equal(t, expected, decoded, "rebuilt headers must decode to the same pixels")
+}
+
+func TestMJPEGDecoder_CachedTables(t *testing.T) {
+	_, qTables, scan := testJPEG(t, 32, 32)
+	mjpeg := NewMJPEGDecoder()
+	require.NotNil(t, processAll(mjpeg, jpegPackets(1, 3000, 1, 128, 32, 32, qTables, scan, 1000)))
+
+	// A later frame with Q=128 may omit the tables
+	packets := jpegPackets(2, 6000, 1, 128, 32, 32, nil, scan, 1000)
+	frame := processAll(mjpeg, packets)
+	require.NotNil(t, frame)
+	_, err := jpeg.Decode(bytes.NewReader(frame.Data))
+	assert.NoError(t, err)
+
+	// Q=129 was never announced
+	assert.Nil(t, processAll(mjpeg, jpegPackets(3, 9000, 1, 129, 32, 32, nil, scan, 1000)))
+	assert.Equal(t, 1, mjpeg.GetStats().CorruptedFrames)
+}
+
+func TestMJPEGDecoder_Loss(t *testing.T) {
+	_, qTables, scan := testJPEG(t, 64, 48)
+	packets := jpegPackets(1, 3000, 1, 255, 64, 48, qTables, scan, 100)
+	require.Greater(t, len(packets), 3)
+
+	// Lost middle fragment: the frame is kept but marked corrupted
+	mjpeg := NewMJPEGDecoder()
+	frame := processAll(mjpeg, append(append([]*rtp.Packet{}, packets[:2]...), packets[3:]...))
+	require.NotNil(t, frame)
+	assert.True(t, frame.IsCorrupted)
+
+	// Lost first fragment: no headers can be built
+	mjpeg = NewMJPEGDecoder()
+	assert.Nil(t, processAll(mjpeg, packets[1:]))
+	assert.Equal(t, 1, mjpeg.GetStats().CorruptedFrames)
+
+	mjpeg = NewMJPEGDecoder()
+	mjpeg.SetDropCorruptedFrames(true)
+	assert.Nil(t, processAll(mjpeg, append(append([]*rtp.Packet{}, packets[:2]...), packets[3:]...)))
+}
+
+func TestMJPEGDecoder_StandardTablesAndRestart(t *testing.T) {
+	// Q=50 scales the standard tables by 100%
+	tables := makeJPEGQuantTables(50)
+	assert.Equal(t, jpegLumaQuantizer[:], tables[:64])
+	assert.Equal(t, jpegChromaQuantizer[:], tables[64:])
+	assert.Equal(t, byte(1), makeJPEGQuantTables(99)[0], "entries never drop below 1")
+
+	mjpeg := NewMJPEGDecoder()
+	frame := processAll(mjpeg, jpegPackets(1, 3000, 64, 50, 640, 480, nil, []byte{0x12, 0x34, 0xFF, jpegMarkerEOI}, 1000))
+	require.NotNil(t, frame)
+
+	// DRI carries the restart interval, SOF0 the size and 4:2:2 sampling
+	assert.True(t, bytes.Contains(frame.Data, []byte{0xFF, jpegMarkerDRI, 0x00, 0x04, 0x00, 0x04}))
+	assert.True(t, bytes.Contains(frame.Data, []byte{0xFF, jpegMarkerSOF, 0x00, 0x11, 8, 0x01, 0xE0, 0x02, 0x80, 3, 1, 0x21, 0}))
+	assert.True(t, bytes.HasSuffix(frame.Data, []byte{0x12, 0x34, 0xFF, jpegMarkerEOI}), "EOI is not duplicated")
+
+	// Types other than 0/1 (and 64/65) are not supported
+	assert.Nil(t, processAll(mjpeg, jpegPackets(2, 6000, 3, 50, 64, 48, nil, []byte{0x12}, 1000)))
+}
+
+func TestJPEGHuffmanTables(t *testing.T) {
+	for _, table := range []struct{ codeLens, symbols []byte }{
+		{jpegLumaDCCodeLens, jpegLumaDCSymbols},
+		{jpegLumaACCodeLens, jpegLumaACSymbols},
+		{jpegChromaDCCodeLens, jpegChromaDCSymbols},
+		{jpegChromaACCodeLens, jpegChromaACSymbols},
+	} {
+		total := 0
+		for _, n := range table.codeLens {
+			total += int(n)
+		}
+		assert.Equal(t, total, len(table.symbols))
+	}
+}
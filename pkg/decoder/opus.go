@@ -0,0 +1,167 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// OpusSampleRate is the RTP clock rate of Opus regardless of the encoded bandwidth
+	OpusSampleRate = 48000
+
+	// opusMaxPacketDuration is the longest packet RFC 6716 allows (120 ms)
+	opusMaxPacketDuration = 5760
+	// opusMaxGapFill bounds loss concealment; longer timestamp gaps are discontinuities
+	opusMaxGapFill = 2 * OpusSampleRate
+)
+
+var (
+	// ErrInvalidOpusPacket indicates an Opus packet whose TOC or frame count is malformed
+	ErrInvalidOpusPacket = errors.New("invalid Opus packet")
+)
+
+// opusLossPackets are mono CELT TOC bytes with a zero-length frame, which tells
+// the decoder to conceal a lost frame (RFC 6716 section 3.2.1), longest first
+var opusLossPackets = []struct {
+	toc      byte
+	duration uint32
+}{
+	{31 << 3, 960}, // 20 ms
+	{30 << 3, 480}, // 10 ms
+	{29 << 3, 240}, // 5 ms
+	{28 << 3, 120}, // 2.5 ms
+}
+
+// OpusDecoder depacketizes Opus audio (RFC 7587): every RTP payload is one
+// Opus packet. Timestamp gaps left by lost packets are filled with empty
+// packets that make Opus decoders run packet loss concealment, so durations
+// stay aligned with the RTP clock.
+type OpusDecoder struct {
+	source audioSource
+
+	nextTimestamp uint32 // RTP timestamp expected after the last unit
+	started       bool
+
+	concealedSamples int64
+}
+
+// NewOpusDecoder creates a new Opus decoder
+func NewOpusDecoder() *OpusDecoder {
+	return &OpusDecoder{source: audioSource{tag: "[OpusDecoder]"}}
+}
+
+// ProcessPacket processes an RTP packet and returns its Opus packet, preceded
+// by loss concealment packets when the packet's timestamp skips ahead
+func (d *OpusDecoder) ProcessPacket(packet *rtp.Packet) []*AudioUnit {
+	if packet == nil || len(packet.Payload) == 0 {
+		return nil
+	}
+
+	ok, resync := d.source.push(packet)
+	if !ok {
+		return nil
+	}
+	if resync {
+		d.started = false
+	}
+
+	duration, err := OpusPacketDuration(packet.Payload)
+	if err != nil {
+		logger.Debug("[OpusDecoder] Dropping packet seq=%d: %v", packet.SequenceNumber, err)
+		d.source.stats.CorruptedFrames++
+		return nil
+	}
+
+	var units []*AudioUnit
+	if d.started {
+		gap := int32(packet.Timestamp - d.nextTimestamp)
+		switch {
+		case gap < 0:
+			logger.Debug("[OpusDecoder] Dropping late packet seq=%d (%d samples behind)", packet.SequenceNumber, -gap)
+			return nil
+		case gap > opusMaxGapFill:
+			logger.Warn("[OpusDecoder] Timestamp jumped by %d samples, restarting without concealment", gap)
+		case gap > 0:
+			units = d.lossUnits(uint32(gap))
+		}
+	}
+
+	units = append(units, &AudioUnit{
+		Data:      packet.Payload,
+		Timestamp: packet.Timestamp,
+		Codec:     CodecOpus,
+	})
+	d.nextTimestamp = packet.Timestamp + uint32(duration)
+	d.started = true
+	d.source.stats.TotalFrames++
+	return units
+}
+
+// lossUnits covers a gap after nextTimestamp with concealment packets; a
+// remainder below 2.5 ms is left to the timestamps
+func (d *OpusDecoder) lossUnits(gap uint32) []*AudioUnit {
+	var units []*AudioUnit
+	timestamp := d.nextTimestamp
+	for _, loss := range opusLossPackets {
+		for ; gap >= loss.duration; gap -= loss.duration {
+			units = append(units, &AudioUnit{Data: []byte{loss.toc}, Timestamp: timestamp, Codec: CodecOpus})
+			timestamp += loss.duration
+			d.concealedSamples += int64(loss.duration)
+		}
+	}
+	return units
+}
+
+// GetConcealedSamples returns the number of samples covered by loss concealment packets
+func (d *OpusDecoder) GetConcealedSamples() int64 {
+	return d.concealedSamples
+}
+
+// Reset forgets the expected timestamp; the next packet starts a new run without concealment
+func (d *OpusDecoder) Reset() {
+	d.started = false
+}
+
+// GetStats returns decoder statistics; TotalFrames counts Opus packets passed through
+func (d *OpusDecoder) GetStats() DecoderStats {
+	return d.source.stats
+}
+
+// OpusPacketDuration returns the number of 48 kHz samples in an Opus packet
+// from its TOC byte and frame count (RFC 6716 section 3.1)
+func OpusPacketDuration(packet []byte) (int, error) {
+	if len(packet) == 0 {
+		return 0, fmt.Errorf("%w: empty packet", ErrInvalidOpusPacket)
+	}
+
+	config := packet[0] >> 3
+	var frameSize int
+	switch {
+	case config < 12: // SILK: 10, 20, 40, 60 ms
+		frameSize = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid: 10, 20 ms
+		frameSize = []int{480, 960}[config%2]
+	default: // CELT: 2.5, 5, 10, 20 ms
+		frameSize = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch packet[0] & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, fmt.Errorf("%w: code 3 packet without frame count", ErrInvalidOpusPacket)
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+
+	duration := frames * frameSize
+	if frames == 0 || duration > opusMaxPacketDuration {
+		return 0, fmt.Errorf("%w: %d frames of %d samples", ErrInvalidOpusPacket, frames, frameSize)
+	}
+	return duration, nil
+}
@@ -0,0 +1,87 @@
+package decoder
+
+import (
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func opusPacket(seq uint16, timestamp uint32, payload []byte) *rtp.Packet {
+	return &rtp.Packet{SequenceNumber: seq, Timestamp: timestamp, SSRC: 0x0905, PayloadType: 111, Payload: payload}
+}
+
+func TestOpusPacketDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		packet   []byte
+		expected int
+	}{
+		{"SILK 20 ms", []byte{1 << 3, 0xAA}, 960},
+		{"SILK 60 ms", []byte{11 << 3}, 2880},
+		{"Hybrid 10 ms", []byte{14 << 3}, 480},
+		{"CELT 2.5 ms", []byte{16 << 3}, 120},
+		{"CELT 20 ms two frames", []byte{31<<3 | 1, 0xAA}, 1920},
+		{"CELT 20 ms code 3 with 6 frames", []byte{31<<3 | 3, 6}, 5760},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			duration, err := OpusPacketDuration(tt.packet)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, duration)
+		})
+	}
+
+	for _, packet := range [][]byte{nil, {31<<3 | 3}, {31<<3 | 3, 0}, {31<<3 | 3, 7}} {
+		_, err := OpusPacketDuration(packet)
+		assert.ErrorIs(t, err, ErrInvalidOpusPacket, "%x", packet)
+	}
+}
+
+func TestOpusDecoder_ProcessPacket(t *testing.T) {
+	d := NewOpusDecoder()
+	frame := []byte{31 << 3, 0x11, 0x22} // CELT 20 ms
+
+	units := d.ProcessPacket(opusPacket(1, 960, frame))
+	require.Len(t, units, 1)
+	assert.Equal(t, frame, units[0].Data)
+	assert.Equal(t, CodecOpus, units[0].Codec)
+
+	require.Len(t, d.ProcessPacket(opusPacket(2, 1920, frame)), 1)
+	assert.Empty(t, d.ProcessPacket(opusPacket(2, 1920, frame)), "duplicate")
+
+	// Packets 3 and 4 are lost, along with 7.5 ms the sender skipped
+	units = d.ProcessPacket(opusPacket(5, 4800+360, frame))
+	require.Len(t, units, 5)
+	expected := []struct {
+		data      []byte
+		timestamp uint32
+	}{
+		{[]byte{31 << 3}, 2880},
+		{[]byte{31 << 3}, 3840},
+		{[]byte{29 << 3}, 4800},
+		{[]byte{28 << 3}, 5040},
+		{frame, 5160},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.data, units[i].Data, "unit %d", i)
+		assert.Equal(t, e.timestamp, units[i].Timestamp, "unit %d", i)
+	}
+	assert.Equal(t, int64(2280), d.GetConcealedSamples())
+
+	// Late packets and malformed packets are dropped
+	assert.Empty(t, d.ProcessPacket(opusPacket(3, 2880, frame)))
+	assert.Empty(t, d.ProcessPacket(opusPacket(6, 6120, []byte{31<<3 | 3})))
+
+	// Jumps beyond the concealment limit restart the timeline
+	require.Len(t, d.ProcessPacket(opusPacket(7, 6120+5*OpusSampleRate, frame)), 1)
+
+	stats := d.GetStats()
+	assert.Equal(t, 4, stats.TotalFrames)
+	assert.Equal(t, 1, stats.CorruptedFrames)
+	assert.Equal(t, 1, stats.DuplicatePackets)
+	assert.Equal(t, 1, stats.PacketLossEvents)
+	assert.Equal(t, 2, stats.PacketsLost)
+}
@@ -0,0 +1,241 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// Static RTP payload types of PCM audio (RFC 3551)
+	PayloadTypePCMU      = 0
+	PayloadTypePCMA      = 8
+	PayloadTypeL16Stereo = 10 // 44.1 kHz, 2 channels
+	PayloadTypeL16Mono   = 11 // 44.1 kHz, 1 channel
+)
+
+const (
+	pcmDefaultSampleRate = 8000
+	l16StaticSampleRate  = 44100
+
+	// pcmMaxSilenceSeconds bounds silence insertion; longer timestamp gaps are discontinuities
+	pcmMaxSilenceSeconds = 2
+
+	// G.711 encodings of a zero sample
+	muLawSilence = 0xFF
+	aLawSilence  = 0xD5
+)
+
+var (
+	// ErrUnsupportedAudioCodec indicates a codec the PCM depacketizer or transcoder cannot handle
+	ErrUnsupportedAudioCodec = errors.New("unsupported audio codec")
+)
+
+// PCMDecoder depacketizes G.711 (PCMU/PCMA) and L16 audio. Packets are passed
+// through in arrival order; timestamp gaps from lost packets are filled with
+// silence so the sample count stays aligned with the RTP clock.
+type PCMDecoder struct {
+	codec      Codec
+	sampleRate int
+	channels   int
+	sampleSize int // Bytes per sample and channel
+
+	source audioSource
+
+	nextTimestamp uint32 // RTP timestamp expected after the last unit
+	started       bool
+
+	silenceSamples int64
+}
+
+// NewPCMDecoder creates a decoder for PCMU, PCMA or L16 audio. A zero sample
+// rate or channel count selects 8000 Hz and mono.
+func NewPCMDecoder(codec Codec, sampleRate, channels int) (*PCMDecoder, error) {
+	sampleSize := 1
+	switch codec {
+	case CodecPCMU, CodecPCMA:
+	case CodecL16:
+		sampleSize = 2
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAudioCodec, codec)
+	}
+	if sampleRate <= 0 {
+		sampleRate = pcmDefaultSampleRate
+	}
+	if channels <= 0 {
+		channels = 1
+	}
+	return &PCMDecoder{
+		codec:      codec,
+		sampleRate: sampleRate,
+		channels:   channels,
+		sampleSize: sampleSize,
+		source:     audioSource{tag: "[PCMDecoder]"},
+	}, nil
+}
+
+// PCMCodecForPayloadType returns the codec, sample rate and channels of a static
+// PCM payload type; ok is false for other payload types
+func PCMCodecForPayloadType(payloadType int) (codec Codec, sampleRate, channels int, ok bool) {
+	switch payloadType {
+	case PayloadTypePCMU:
+		return CodecPCMU, pcmDefaultSampleRate, 1, true
+	case PayloadTypePCMA:
+		return CodecPCMA, pcmDefaultSampleRate, 1, true
+	case PayloadTypeL16Stereo:
+		return CodecL16, l16StaticSampleRate, 2, true
+	case PayloadTypeL16Mono:
+		return CodecL16, l16StaticSampleRate, 1, true
+	}
+	return 0, 0, 0, false
+}
+
+// ProcessPacket processes an RTP packet and returns its audio, preceded by a
+// silence unit when the packet's timestamp skips ahead
+func (d *PCMDecoder) ProcessPacket(packet *rtp.Packet) []*AudioUnit {
+	if packet == nil || len(packet.Payload) == 0 {
+		return nil
+	}
+
+	ok, resync := d.source.push(packet)
+	if !ok {
+		return nil
+	}
+	if resync {
+		d.started = false
+	}
+
+	frameSize := d.sampleSize * d.channels
+	samples := len(packet.Payload) / frameSize
+	if samples == 0 {
+		d.source.stats.CorruptedFrames++
+		return nil
+	}
+
+	var units []*AudioUnit
+	if d.started {
+		gap := int32(packet.Timestamp - d.nextTimestamp)
+		switch {
+		case gap < 0:
+			// Its time was already filled with silence or covered by a later packet
+			logger.Debug("[PCMDecoder] Dropping late packet seq=%d (%d samples behind)", packet.SequenceNumber, -gap)
+			return nil
+		case gap > int32(d.sampleRate*pcmMaxSilenceSeconds):
+			logger.Warn("[PCMDecoder] Timestamp jumped by %d samples, restarting without silence", gap)
+		case gap > 0:
+			units = append(units, &AudioUnit{
+				Data:      d.silence(int(gap)),
+				Timestamp: d.nextTimestamp,
+				Codec:     d.codec,
+			})
+			d.silenceSamples += int64(gap)
+		}
+	}
+
+	units = append(units, &AudioUnit{
+		Data:      packet.Payload[:samples*frameSize],
+		Timestamp: packet.Timestamp,
+		Codec:     d.codec,
+	})
+	d.nextTimestamp = packet.Timestamp + uint32(samples)
+	d.started = true
+	d.source.stats.TotalFrames++
+	return units
+}
+
+// silence returns the encoding of the given number of silent samples
+func (d *PCMDecoder) silence(samples int) []byte {
+	data := make([]byte, samples*d.sampleSize*d.channels)
+	fill := byte(0)
+	switch d.codec {
+	case CodecPCMU:
+		fill = muLawSilence
+	case CodecPCMA:
+		fill = aLawSilence
+	}
+	if fill != 0 {
+		for i := range data {
+			data[i] = fill
+		}
+	}
+	return data
+}
+
+// GetFormat returns the codec, sample rate and channel count
+func (d *PCMDecoder) GetFormat() (codec Codec, sampleRate, channels int) {
+	return d.codec, d.sampleRate, d.channels
+}
+
+// GetSilenceSamples returns the number of samples inserted for timestamp gaps
+func (d *PCMDecoder) GetSilenceSamples() int64 {
+	return d.silenceSamples
+}
+
+// Reset forgets the expected timestamp; the next packet starts a new run without silence
+func (d *PCMDecoder) Reset() {
+	d.started = false
+}
+
+// GetStats returns decoder statistics; TotalFrames counts packets passed through
+func (d *PCMDecoder) GetStats() DecoderStats {
+	return d.source.stats
+}
+
+// ToPCM16LE converts PCMU, PCMA or L16 audio to 16-bit little-endian PCM as stored in WAV files
+func ToPCM16LE(codec Codec, data []byte) ([]byte, error) {
+	var decode func(byte) int16
+	switch codec {
+	case CodecPCMU:
+		decode = MuLawToLinear
+	case CodecPCMA:
+		decode = ALawToLinear
+	case CodecL16:
+		out := make([]byte, len(data)&^1)
+		for i := 0; i+1 < len(data); i += 2 {
+			out[i], out[i+1] = data[i+1], data[i]
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAudioCodec, codec)
+	}
+
+	out := make([]byte, 2*len(data))
+	for i, b := range data {
+		sample := decode(b)
+		out[2*i] = byte(sample)
+		out[2*i+1] = byte(sample >> 8)
+	}
+	return out, nil
+}
+
+// MuLawToLinear decodes a G.711 μ-law sample
+func MuLawToLinear(u byte) int16 {
+	u = ^u
+	t := (int32(u&0x0F) << 3) + 0x84
+	t <<= (u & 0x70) >> 4
+	if u&0x80 != 0 {
+		return int16(0x84 - t)
+	}
+	return int16(t - 0x84)
+}
+
+// ALawToLinear decodes a G.711 A-law sample
+func ALawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int32(a&0x0F) << 4
+	switch segment := (a & 0x70) >> 4; segment {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= segment - 1
+	}
+	if a&0x80 != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
@@ -0,0 +1,120 @@
+package decoder
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pcmPacket(seq uint16, timestamp uint32, payload []byte) *rtp.Packet {
+	return &rtp.Packet{SequenceNumber: seq, Timestamp: timestamp, SSRC: 0x711, PayloadType: PayloadTypePCMU, Payload: payload}
+}
+
+func TestPCMDecoder_ProcessPacket(t *testing.T) {
+	pcmu, err := NewPCMDecoder(CodecPCMU, 0, 0)
+	require.NoError(t, err)
+	codec, sampleRate, channels := pcmu.GetFormat()
+	assert.Equal(t, CodecPCMU, codec)
+	assert.Equal(t, 8000, sampleRate)
+	assert.Equal(t, 1, channels)
+
+	audio := bytes.Repeat([]byte{0x42}, 160)
+
+	units := pcmu.ProcessPacket(pcmPacket(1, 8000, audio))
+	require.Len(t, units, 1)
+	assert.Equal(t, audio, units[0].Data)
+	assert.Equal(t, uint32(8000), units[0].Timestamp)
+	assert.Equal(t, CodecPCMU, units[0].Codec)
+
+	require.Len(t, pcmu.ProcessPacket(pcmPacket(2, 8160, audio)), 1)
+	assert.Empty(t, pcmu.ProcessPacket(pcmPacket(2, 8160, audio)), "duplicate")
+
+	// Packets 3 and 4 are lost: 320 samples of μ-law silence come first
+	units = pcmu.ProcessPacket(pcmPacket(5, 8640, audio))
+	require.Len(t, units, 2)
+	assert.Equal(t, uint32(8320), units[0].Timestamp)
+	assert.Equal(t, bytes.Repeat([]byte{muLawSilence}, 320), units[0].Data)
+	assert.Equal(t, audio, units[1].Data)
+	assert.Equal(t, int64(320), pcmu.GetSilenceSamples())
+
+	// A late packet for time already filled is dropped
+	assert.Empty(t, pcmu.ProcessPacket(pcmPacket(3, 8320, audio)))
+
+	// Jumps beyond the silence limit restart the timeline
+	units = pcmu.ProcessPacket(pcmPacket(6, 8800+3*8000, audio))
+	require.Len(t, units, 1)
+	assert.Equal(t, int64(320), pcmu.GetSilenceSamples())
+
+	stats := pcmu.GetStats()
+	assert.Equal(t, 4, stats.TotalFrames)
+	assert.Equal(t, 1, stats.DuplicatePackets)
+	assert.Equal(t, 1, stats.PacketLossEvents)
+	assert.Equal(t, 2, stats.PacketsLost)
+
+	// Reset starts a new run without silence
+	pcmu.Reset()
+	assert.Len(t, pcmu.ProcessPacket(pcmPacket(7, 90000, audio)), 1)
+}
+
+func TestPCMDecoder_L16Stereo(t *testing.T) {
+	l16, err := NewPCMDecoder(CodecL16, 44100, 2)
+	require.NoError(t, err)
+
+	// 3 stereo samples plus a stray byte
+	payload := []byte{0, 1, 0, 2, 0, 3, 0, 4, 0, 5, 0, 6, 0xFF}
+	require.Len(t, l16.ProcessPacket(pcmPacket(1, 0, payload)), 1)
+	units := l16.ProcessPacket(pcmPacket(2, 5, payload))
+	require.Len(t, units, 2)
+	assert.Equal(t, make([]byte, 2*2*2), units[0].Data, "2 stereo samples of zero")
+	assert.Equal(t, payload[:12], units[1].Data)
+
+	_, err = NewPCMDecoder(CodecH264, 8000, 1)
+	assert.ErrorIs(t, err, ErrUnsupportedAudioCodec)
+}
+
+func TestPCMCodecForPayloadType(t *testing.T) {
+	codec, rate, channels, ok := PCMCodecForPayloadType(8)
+	assert.True(t, ok)
+	assert.Equal(t, CodecPCMA, codec)
+	assert.Equal(t, 8000, rate)
+	assert.Equal(t, 1, channels)
+
+	codec, rate, channels, ok = PCMCodecForPayloadType(10)
+	assert.True(t, ok)
+	assert.Equal(t, CodecL16, codec)
+	assert.Equal(t, 44100, rate)
+	assert.Equal(t, 2, channels)
+
+	_, _, _, ok = PCMCodecForPayloadType(96)
+	assert.False(t, ok)
+}
+
+func TestToPCM16LE(t *testing.T) {
+	tests := []struct {
+		name     string
+		codec    Codec
+		input    []byte
+		expected []int16
+	}{
+		{"μ-law silence and extremes", CodecPCMU, []byte{0xFF, 0x7F, 0x00, 0x80}, []int16{0, 0, -32124, 32124}},
+		{"A-law silence and extremes", CodecPCMA, []byte{0xD5, 0x55, 0x2A, 0xAA}, []int16{8, -8, -32256, 32256}},
+		{"L16 big-endian", CodecL16, []byte{0x12, 0x34, 0xFF, 0xFE}, []int16{0x1234, -2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ToPCM16LE(tt.codec, tt.input)
+			require.NoError(t, err)
+			require.Len(t, out, 2*len(tt.expected))
+			for i, expected := range tt.expected {
+				assert.Equal(t, expected, int16(uint16(out[2*i])|uint16(out[2*i+1])<<8), "sample %d", i)
+			}
+		})
+	}
+
+	_, err := ToPCM16LE(CodecAAC, []byte{1})
+	assert.ErrorIs(t, err, ErrUnsupportedAudioCodec)
+}
@@ -0,0 +1,866 @@
+package manager
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/rtsp-client/pkg/rtpdump"
+	"github.com/rtsp-client/pkg/rtsp"
+	"github.com/rtsp-client/pkg/storage"
+)
+
+const (
+	// DefaultMaxConcurrentConnects caps simultaneous RTSP handshakes when no limit is given
+	DefaultMaxConcurrentConnects = 8
+)
+
+var (
+	// ErrStreamExists indicates a stream with the same ID is already managed
+	ErrStreamExists = errors.New("stream already exists")
+	// ErrStreamNotFound indicates no stream with the given ID is managed
+	ErrStreamNotFound = errors.New("stream not found")
+	// ErrInvalidStreamConfig indicates the stream configuration is incomplete
+	ErrInvalidStreamConfig = errors.New("invalid stream configuration")
+	// ErrManagerClosed indicates the manager no longer accepts streams
+	ErrManagerClosed = errors.New("manager is closed")
+)
+
+// StreamState describes where a stream pipeline is in its lifecycle
+type StreamState int
+
+const (
+	// StateConnecting means the pipeline is waiting for a connect slot or doing the RTSP handshake
+	StateConnecting StreamState = iota
+	// StateStreaming means PLAY succeeded and packets are being read
+	StateStreaming
+	// StateBackoff means the last session failed and the pipeline is waiting to restart
+	StateBackoff
+	// StateStopped means the pipeline was stopped by the caller
+	StateStopped
+	// StateFailed means the pipeline gave up after exceeding the retry limit
+	StateFailed
+)
+
+// String returns the state name
+func (s StreamState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateStreaming:
+		return "streaming"
+	case StateBackoff:
+		return "backoff"
+	case StateStopped:
+		return "stopped"
+	case StateFailed:
+		return "failed"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// StreamConfig describes a single camera pipeline
+type StreamConfig struct {
+	ID                string
+	URL               string
+	OutputDir         string        // Defaults to ./frames/<ID>
+	Timeout           time.Duration // RTSP request and packet read timeout
+	SaveJPEG          bool
+	ContinuousDecoder bool
+	RTPDumpFile       string // Record received RTP/RTCP to this rtpdump file across sessions
+	TranscodeAudio    bool   // Store G.711 audio as 16-bit PCM instead of μ-law/A-law
+}
+
+// StreamStatus is a point-in-time snapshot of a stream pipeline
+type StreamStatus struct {
+	ID                  string
+	URL                 string
+	State               StreamState
+	Sessions            int // Successful RTSP handshakes
+	Restarts            int // Sessions attempted after the first one
+	ConsecutiveFailures int
+	LastError           string
+	StartedAt           time.Time // When the current session reached PLAY
+	LastFrameAt         time.Time
+	NextRetryAt         time.Time
+	Decoder             decoder.DecoderStats
+	Storage             storage.StorageStats
+	Video               decoder.StreamInfo // Zero until an H.264 SPS was parsed
+}
+
+// Manager owns many Client + H264Decoder + FrameStorage pipelines.
+// Each pipeline runs in its own goroutine and is restarted independently with
+// exponential backoff; RTSP handshakes are bounded by a shared connect limit.
+type Manager struct {
+	mu           sync.RWMutex
+	streams      map[string]*stream
+	connectSlots chan struct{}
+	retryConfig  *rtsp.RetryConfig
+	closed       bool
+}
+
+// stream is the per-camera pipeline state
+type stream struct {
+	config  StreamConfig
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+	decoder decoder.VideoDecoder // H264Decoder until SDP announces H265
+	storage *storage.FrameStorage
+	dump    *rtpdump.Writer // nil unless RTPDumpFile is set
+
+	audio            decoder.AudioDecoder // nil unless SDP announces a supported audio track
+	audioPayloadType uint8
+
+	mu     sync.Mutex
+	status StreamStatus
+}
+
+// NewManager creates a stream manager that allows at most maxConcurrentConnects
+// RTSP handshakes in flight at the same time
+func NewManager(maxConcurrentConnects int) *Manager {
+	if maxConcurrentConnects <= 0 {
+		maxConcurrentConnects = DefaultMaxConcurrentConnects
+	}
+
+	return &Manager{
+		streams:      make(map[string]*stream),
+		connectSlots: make(chan struct{}, maxConcurrentConnects),
+		retryConfig:  DefaultRetryConfig(),
+	}
+}
+
+// DefaultRetryConfig returns the restart policy used by the manager:
+// 1s initial delay doubling up to 60s, restarting forever
+func DefaultRetryConfig() *rtsp.RetryConfig {
+	return &rtsp.RetryConfig{
+		MaxRetries:   0, // 0 = restart forever
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     60 * time.Second,
+		Multiplier:   2.0,
+	}
+}
+
+// SetRetryConfig sets the restart policy applied to streams started afterwards.
+// MaxRetries bounds consecutive failed sessions; 0 restarts forever.
+func (m *Manager) SetRetryConfig(config *rtsp.RetryConfig) {
+	if config == nil {
+		config = DefaultRetryConfig()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retryConfig = config
+}
+
+// Start validates the configuration, creates the stream's storage and starts its pipeline
+func (m *Manager) Start(config StreamConfig) error {
+	if config.ID == "" {
+		return fmt.Errorf("%w: ID is required", ErrInvalidStreamConfig)
+	}
+	if config.URL == "" {
+		return fmt.Errorf("%w: URL is required for stream %s", ErrInvalidStreamConfig, config.ID)
+	}
+	if _, err := rtsp.NewClient(config.URL, config.Timeout); err != nil {
+		return fmt.Errorf("%w: stream %s: %v", ErrInvalidStreamConfig, config.ID, err)
+	}
+	if config.OutputDir == "" {
+		config.OutputDir = filepath.Join("./frames", config.ID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrManagerClosed
+	}
+	if _, exists := m.streams[config.ID]; exists {
+		return fmt.Errorf("%w: %s", ErrStreamExists, config.ID)
+	}
+
+	frameStorage, err := storage.NewFrameStorageWithOptions(config.OutputDir, config.SaveJPEG, config.ContinuousDecoder)
+	if err != nil {
+		return fmt.Errorf("failed to create storage for stream %s: %w", config.ID, err)
+	}
+
+	var dump *rtpdump.Writer
+	if config.RTPDumpFile != "" {
+		dump, err = rtpdump.Create(config.RTPDumpFile, netip.AddrPort{})
+		if err != nil {
+			frameStorage.Close()
+			return fmt.Errorf("failed to create rtpdump file for stream %s: %w", config.ID, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stream{
+		config:  config,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		decoder: decoder.NewH264Decoder(),
+		storage: frameStorage,
+		dump:    dump,
+		status: StreamStatus{
+			ID:    config.ID,
+			URL:   config.URL,
+			State: StateConnecting,
+		},
+	}
+	m.streams[config.ID] = s
+
+	logger.Info("[Manager] Starting stream %s (%s)", config.ID, config.URL)
+	go m.run(s, m.retryConfig)
+
+	return nil
+}
+
+// Stop stops a stream and waits for its pipeline to exit.
+// A stalled camera delays Stop by at most its read timeout.
+func (m *Manager) Stop(id string) error {
+	m.mu.Lock()
+	s, ok := m.streams[id]
+	if ok {
+		delete(m.streams, id)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStreamNotFound, id)
+	}
+
+	s.cancel()
+	<-s.done
+	logger.Info("[Manager] Stream %s stopped", id)
+	return nil
+}
+
+// StopAll stops every stream in parallel and waits for all of them to exit
+func (m *Manager) StopAll() {
+	m.mu.Lock()
+	streams := make([]*stream, 0, len(m.streams))
+	for id, s := range m.streams {
+		streams = append(streams, s)
+		delete(m.streams, id)
+	}
+	m.mu.Unlock()
+
+	for _, s := range streams {
+		s.cancel()
+	}
+	for _, s := range streams {
+		<-s.done
+	}
+}
+
+// Close stops all streams and rejects further Start calls
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	m.StopAll()
+	return nil
+}
+
+// Status returns the status of a single stream
+func (m *Manager) Status(id string) (StreamStatus, error) {
+	m.mu.RLock()
+	s, ok := m.streams[id]
+	m.mu.RUnlock()
+
+	if !ok {
+		return StreamStatus{}, fmt.Errorf("%w: %s", ErrStreamNotFound, id)
+	}
+	return s.snapshot(), nil
+}
+
+// StatusAll returns the status of every stream, sorted by ID
+func (m *Manager) StatusAll() []StreamStatus {
+	m.mu.RLock()
+	streams := make([]*stream, 0, len(m.streams))
+	for _, s := range m.streams {
+		streams = append(streams, s)
+	}
+	m.mu.RUnlock()
+
+	statuses := make([]StreamStatus, 0, len(streams))
+	for _, s := range streams {
+		statuses = append(statuses, s.snapshot())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].ID < statuses[j].ID
+	})
+	return statuses
+}
+
+// IDs returns the IDs of all managed streams, sorted
+func (m *Manager) IDs() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ids := make([]string, 0, len(m.streams))
+	for id := range m.streams {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// run supervises a single pipeline until it is stopped or gives up
+func (m *Manager) run(s *stream, retryConfig *rtsp.RetryConfig) {
+	defer close(s.done)
+	defer func() {
+		if err := s.storage.Close(); err != nil {
+			logger.Warn("[Manager] Stream %s: error closing storage: %v", s.config.ID, err)
+		}
+		if s.dump != nil {
+			if err := s.dump.Close(); err != nil {
+				logger.Warn("[Manager] Stream %s: error closing rtpdump file: %v", s.config.ID, err)
+			}
+			logger.Info("[Manager] Stream %s: recorded %d packets to %s", s.config.ID, s.dump.Count(), s.config.RTPDumpFile)
+		}
+	}()
+
+	for {
+		err := m.runSession(s)
+		if s.ctx.Err() != nil {
+			s.setState(StateStopped)
+			return
+		}
+
+		failures := s.recordFailure(err)
+		logger.Warn("[Manager] Stream %s session ended: %v (consecutive failures: %d)", s.config.ID, err, failures)
+
+		if retryConfig.MaxRetries > 0 && failures > retryConfig.MaxRetries {
+			logger.Error("[Manager] Stream %s giving up after %d consecutive failures", s.config.ID, failures)
+			s.setState(StateFailed)
+			return
+		}
+
+		delay := calculateBackoff(retryConfig, failures-1)
+		s.setBackoff(delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			s.setState(StateStopped)
+			return
+		}
+	}
+}
+
+// runSession performs one connect/handshake/read cycle and returns why it ended
+func (m *Manager) runSession(s *stream) error {
+	s.setState(StateConnecting)
+
+	if err := m.acquireConnectSlot(s.ctx); err != nil {
+		return err
+	}
+	client, err := m.connect(s)
+	m.releaseConnectSlot()
+	if err != nil {
+		return err
+	}
+	defer m.disconnect(s, client)
+
+	s.recordSessionStart()
+	logger.Info("[Manager] Stream %s is streaming", s.config.ID)
+
+	if client.GetTransportMode() == rtsp.TransportModeUDP {
+		client.StartKeepAlive()
+		go m.readRTCP(s, client)
+	}
+	client.StartReceiverReports()
+
+	s.decoder.Reset()
+	if s.audio != nil {
+		s.audio.Reset()
+	}
+	lossEvents := s.decoder.GetStats().PacketLossEvents
+	var videoSSRC, audioSSRC uint32
+	mapped, audioMapped := false, false
+	for {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+
+		packet, err := client.ReadPacket()
+		if err != nil {
+			return fmt.Errorf("read packet: %w", err)
+		}
+
+		// Audio has its own depacketizer and must not reach the video decoder
+		if s.audio != nil && packet.PayloadType == s.audioPayloadType {
+			// Audio files are named from the audio source's own Sender Reports
+			if !audioMapped || packet.SSRC != audioSSRC {
+				s.storage.SetAudioTimestampMapper(client.GetTimestampMapper(packet.SSRC))
+				audioSSRC, audioMapped = packet.SSRC, true
+			}
+			m.saveAudio(s, packet)
+			continue
+		}
+
+		frame := s.decoder.ProcessPacket(packet)
+
+		// Ask for an IDR instead of waiting for the next periodic one
+		if events := s.decoder.GetStats().PacketLossEvents; events != lossEvents || (frame != nil && frame.IsDamaged()) {
+			lossEvents = events
+			m.requestKeyframe(s, client, packet.SSRC)
+		}
+
+		if frame == nil {
+			continue
+		}
+
+		// Name files from the video source's own Sender Reports
+		if !mapped || packet.SSRC != videoSSRC {
+			s.storage.SetTimestampMapper(client.GetTimestampMapper(packet.SSRC))
+			videoSSRC, mapped = packet.SSRC, true
+		}
+
+		if err := s.storage.SaveFrame(frame); err != nil {
+			logger.Warn("[Manager] Stream %s: failed to save frame: %v", s.config.ID, err)
+			continue
+		}
+		s.recordFrame(s.decoder)
+	}
+}
+
+// saveAudio depacketizes an audio packet and hands its units to storage
+func (m *Manager) saveAudio(s *stream, packet *rtp.Packet) {
+	for _, unit := range s.audio.ProcessPacket(packet) {
+		if err := s.storage.SaveAudio(unit); err != nil {
+			logger.Warn("[Manager] Stream %s: failed to save audio: %v", s.config.ID, err)
+			return
+		}
+	}
+}
+
+// requestKeyframe sends a PLI/FIR if the camera supports it; unsupported and
+// rate-limited requests are expected and only logged at debug level
+func (m *Manager) requestKeyframe(s *stream, client *rtsp.Client, ssrc uint32) {
+	err := client.RequestKeyframe(ssrc)
+	switch {
+	case err == nil:
+		logger.Debug("[Manager] Stream %s: requested keyframe after loss", s.config.ID)
+	case errors.Is(err, rtsp.ErrFeedbackNotSupported), errors.Is(err, rtsp.ErrFeedbackRateLimited):
+		logger.Debug("[Manager] Stream %s: keyframe request skipped: %v", s.config.ID, err)
+	default:
+		logger.Warn("[Manager] Stream %s: keyframe request failed: %v", s.config.ID, err)
+	}
+}
+
+// connect performs the RTSP handshake for a stream
+func (m *Manager) connect(s *stream) (*rtsp.Client, error) {
+	client, err := rtsp.NewClient(s.config.URL, s.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+
+	fail := func(step string, err error) (*rtsp.Client, error) {
+		client.Close()
+		return nil, fmt.Errorf("%s: %w", step, err)
+	}
+
+	if _, err := client.Describe(); err != nil {
+		return fail("DESCRIBE", err)
+	}
+
+	tracks := client.GetNumTracks()
+	if tracks == 0 {
+		tracks = 1
+	}
+	for i := 0; i < tracks; i++ {
+		if err := s.ctx.Err(); err != nil {
+			return fail("SETUP", err)
+		}
+		if err := client.Setup(); err != nil {
+			return fail("SETUP", err)
+		}
+	}
+
+	if s.dump != nil {
+		client.SetPacketRecorder(s.dump)
+	}
+
+	if err := client.Play(); err != nil {
+		return fail("PLAY", err)
+	}
+
+	if track := h265Track(client.GetSDPInfo()); track != nil {
+		m.configureH265(s, track)
+	} else if mjpegTrack(client.GetSDPInfo()) != nil {
+		m.configureMJPEG(s)
+	} else {
+		if sps, pps := spropParameterSets(client.GetSDPInfo()); sps != "" && pps != "" {
+			if err := s.storage.SetSPSPPS(sps, pps); err != nil {
+				logger.Warn("[Manager] Stream %s: invalid sprop-parameter-sets: %v", s.config.ID, err)
+			}
+		}
+		if track := h264Track(client.GetSDPInfo()); track != nil {
+			m.configureH264(s, track)
+		}
+	}
+
+	s.audio = nil
+	if track := aacTrack(client.GetSDPInfo()); track != nil {
+		m.configureAAC(s, track)
+	} else if track := opusTrack(client.GetSDPInfo()); track != nil {
+		m.configureOpus(s, track)
+	} else if track, format := pcmTrack(client.GetSDPInfo()); track != nil {
+		m.configurePCM(s, track, format)
+	}
+
+	return client, nil
+}
+
+// configureAAC routes the AAC track to an AACDecoder and records it next to the video
+func (m *Manager) configureAAC(s *stream, track *rtsp.SDPTrack) {
+	aac := decoder.NewAACDecoder()
+	if err := aac.SetFMTP(track.FMTP); err != nil {
+		logger.Warn("[Manager] Stream %s: invalid AAC format parameters: %v", s.config.ID, err)
+		return
+	}
+	s.audio, s.audioPayloadType = aac, uint8(track.PayloadType)
+
+	if config := aac.GetConfig(); config != nil {
+		if err := s.storage.EnableAACOutput(config); err != nil {
+			logger.Warn("[Manager] Stream %s: cannot record AAC audio: %v", s.config.ID, err)
+		}
+	}
+}
+
+// configureOpus routes the Opus track to an OpusDecoder and records it as Ogg/Opus.
+// The rtpmap always announces 2 channels; sprop-stereo tells what the sender encodes.
+func (m *Manager) configureOpus(s *stream, track *rtsp.SDPTrack) {
+	s.audio, s.audioPayloadType = decoder.NewOpusDecoder(), uint8(track.PayloadType)
+
+	channels := 1
+	if track.FMTP["sprop-stereo"] == "1" {
+		channels = 2
+	}
+	if err := s.storage.EnableOpusOutput(channels); err != nil {
+		logger.Warn("[Manager] Stream %s: cannot record Opus audio: %v", s.config.ID, err)
+	}
+}
+
+// configurePCM routes a G.711 or L16 track to a PCMDecoder and records it as WAV
+func (m *Manager) configurePCM(s *stream, track *rtsp.SDPTrack, format storage.WAVFormat) {
+	pcm, err := decoder.NewPCMDecoder(format.Codec, format.SampleRate, format.Channels)
+	if err != nil {
+		logger.Warn("[Manager] Stream %s: %v", s.config.ID, err)
+		return
+	}
+	s.audio, s.audioPayloadType = pcm, uint8(track.PayloadType)
+
+	format.Transcode = s.config.TranscodeAudio
+	if err := s.storage.EnableWAVOutput(format); err != nil {
+		logger.Warn("[Manager] Stream %s: cannot record %s audio: %v", s.config.ID, format.Codec, err)
+	}
+}
+
+// configureH264 applies the H.264 track's format parameters to the stream's
+// decoder: sprop-parameter-sets for the StreamInfo and interleaved mode
+func (m *Manager) configureH264(s *stream, track *rtsp.SDPTrack) {
+	h264, ok := s.decoder.(*decoder.H264Decoder)
+	if !ok {
+		return
+	}
+	if err := h264.SetFMTP(track.FMTP); err != nil {
+		logger.Warn("[Manager] Stream %s: invalid H.264 format parameters: %v", s.config.ID, err)
+	}
+}
+
+// configureH265 switches the stream to an H265Decoder and passes the SDP parameter sets on
+func (m *Manager) configureH265(s *stream, track *rtsp.SDPTrack) {
+	h265, ok := s.decoder.(*decoder.H265Decoder)
+	if !ok {
+		logger.Info("[Manager] Stream %s: H.265 stream, switching decoder", s.config.ID)
+		h265 = decoder.NewH265Decoder()
+		s.decoder = h265
+	}
+	if err := h265.SetFMTP(track.FMTP); err != nil {
+		logger.Warn("[Manager] Stream %s: invalid H.265 format parameters: %v", s.config.ID, err)
+	}
+
+	first := func(name string) string {
+		value, _, _ := strings.Cut(track.FMTP[name], ",")
+		return strings.TrimSpace(value)
+	}
+	if sps, pps := first("sprop-sps"), first("sprop-pps"); sps != "" && pps != "" {
+		if err := s.storage.SetH265ParameterSets(first("sprop-vps"), sps, pps); err != nil {
+			logger.Warn("[Manager] Stream %s: invalid sprop-vps/sps/pps: %v", s.config.ID, err)
+		}
+	}
+}
+
+// configureMJPEG switches the stream to an MJPEGDecoder; its frames are
+// complete JPEG images and need no parameter sets
+func (m *Manager) configureMJPEG(s *stream) {
+	if _, ok := s.decoder.(*decoder.MJPEGDecoder); !ok {
+		logger.Info("[Manager] Stream %s: Motion JPEG stream, switching decoder", s.config.ID)
+		s.decoder = decoder.NewMJPEGDecoder()
+	}
+}
+
+// disconnect tears the session down when the stream was stopped and closes the client
+func (m *Manager) disconnect(s *stream, client *rtsp.Client) {
+	if s.ctx.Err() != nil {
+		if err := client.Teardown(); err != nil {
+			logger.Debug("[Manager] Stream %s: TEARDOWN failed: %v", s.config.ID, err)
+		}
+	}
+	if err := client.Close(); err != nil {
+		logger.Debug("[Manager] Stream %s: close failed: %v", s.config.ID, err)
+	}
+}
+
+// readRTCP drains the UDP RTCP port; the client records Sender Reports for
+// receiver reports and timestamp mapping as they are read
+func (m *Manager) readRTCP(s *stream, client *rtsp.Client) {
+	for s.ctx.Err() == nil {
+		if _, err := client.ReadRTCP(); errors.Is(err, net.ErrClosed) {
+			return
+		}
+	}
+}
+
+// acquireConnectSlot blocks until a connect slot is free or ctx is cancelled
+func (m *Manager) acquireConnectSlot(ctx context.Context) error {
+	select {
+	case m.connectSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseConnectSlot frees a connect slot taken by acquireConnectSlot
+func (m *Manager) releaseConnectSlot() {
+	<-m.connectSlots
+}
+
+// calculateBackoff returns the restart delay for the given failure attempt (0-based),
+// with up to 20% jitter so cameras that failed together don't reconnect in lockstep
+func calculateBackoff(config *rtsp.RetryConfig, attempt int) time.Duration {
+	multiplier := config.Multiplier
+	if multiplier < 1 {
+		multiplier = 2.0
+	}
+
+	delay := float64(config.InitialDelay) * math.Pow(multiplier, float64(attempt))
+	if config.MaxDelay > 0 && delay > float64(config.MaxDelay) {
+		delay = float64(config.MaxDelay)
+	}
+
+	result := time.Duration(delay)
+	if jitter := int64(result / 5); jitter > 0 {
+		result += time.Duration(rand.Int63n(jitter))
+	}
+	return result
+}
+
+// spropParameterSets returns the base64 SPS and PPS from the first H.264 track in SDP
+func spropParameterSets(info *rtsp.SDPInfo) (string, string) {
+	if info == nil {
+		return "", ""
+	}
+
+	for _, track := range info.Tracks {
+		if !strings.EqualFold(track.Codec, "H264") {
+			continue
+		}
+		sets := strings.Split(track.FMTP["sprop-parameter-sets"], ",")
+		if len(sets) < 2 {
+			continue
+		}
+		if _, err := base64.StdEncoding.DecodeString(sets[0]); err != nil {
+			continue
+		}
+		return sets[0], sets[1]
+	}
+
+	return "", ""
+}
+
+// h264Track returns the first H.264 track in SDP
+func h264Track(info *rtsp.SDPInfo) *rtsp.SDPTrack {
+	if info == nil {
+		return nil
+	}
+	for i := range info.Tracks {
+		if strings.EqualFold(info.Tracks[i].Codec, "H264") {
+			return &info.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// h265Track returns the first H.265 track in SDP
+func h265Track(info *rtsp.SDPInfo) *rtsp.SDPTrack {
+	if info == nil {
+		return nil
+	}
+	for i := range info.Tracks {
+		if strings.EqualFold(info.Tracks[i].Codec, "H265") {
+			return &info.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// aacTrack returns the first mpeg4-generic audio track in SDP
+func aacTrack(info *rtsp.SDPInfo) *rtsp.SDPTrack {
+	if info == nil {
+		return nil
+	}
+	for i := range info.Tracks {
+		if info.Tracks[i].Media == "audio" && strings.EqualFold(info.Tracks[i].Codec, "MPEG4-GENERIC") {
+			return &info.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// opusTrack returns the first Opus audio track in SDP
+func opusTrack(info *rtsp.SDPInfo) *rtsp.SDPTrack {
+	if info == nil {
+		return nil
+	}
+	for i := range info.Tracks {
+		if info.Tracks[i].Media == "audio" && strings.EqualFold(info.Tracks[i].Codec, "opus") {
+			return &info.Tracks[i]
+		}
+	}
+	return nil
+}
+
+// pcmTrack returns the first G.711 or L16 audio track in SDP and its format;
+// static payload types 0, 8, 10 and 11 are recognized without an rtpmap
+func pcmTrack(info *rtsp.SDPInfo) (*rtsp.SDPTrack, storage.WAVFormat) {
+	if info == nil {
+		return nil, storage.WAVFormat{}
+	}
+	for i := range info.Tracks {
+		track := &info.Tracks[i]
+		if track.Media != "audio" {
+			continue
+		}
+		codec, sampleRate, channels, ok := decoder.PCMCodecForPayloadType(track.PayloadType)
+		switch strings.ToUpper(track.Codec) {
+		case "PCMU":
+			codec, ok = decoder.CodecPCMU, true
+		case "PCMA":
+			codec, ok = decoder.CodecPCMA, true
+		case "L16":
+			codec, ok = decoder.CodecL16, true
+		}
+		if !ok {
+			continue
+		}
+		if track.ClockRate > 0 {
+			sampleRate = track.ClockRate
+		}
+		if track.Channels > 0 {
+			channels = track.Channels
+		}
+		if sampleRate == 0 {
+			sampleRate = 8000
+		}
+		if channels == 0 {
+			channels = 1
+		}
+		return track, storage.WAVFormat{Codec: codec, SampleRate: sampleRate, Channels: channels}
+	}
+	return nil, storage.WAVFormat{}
+}
+
+// mjpegTrack returns the first RTP/JPEG video track in SDP; payload type 26
+// is static and often announced without an rtpmap
+func mjpegTrack(info *rtsp.SDPInfo) *rtsp.SDPTrack {
+	if info == nil {
+		return nil
+	}
+	for i := range info.Tracks {
+		track := &info.Tracks[i]
+		if strings.EqualFold(track.Codec, "JPEG") || (track.Media == "video" && track.PayloadType == decoder.PayloadTypeJPEG) {
+			return track
+		}
+	}
+	return nil
+}
+
+// snapshot returns a copy of the stream status including live storage statistics
+func (s *stream) snapshot() StreamStatus {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+
+	status.Storage = s.storage.GetStats()
+	return status
+}
+
+func (s *stream) setState(state StreamState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = state
+}
+
+func (s *stream) setBackoff(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = StateBackoff
+	s.status.NextRetryAt = time.Now().Add(delay)
+	s.status.Restarts++
+}
+
+func (s *stream) recordSessionStart() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = StateStreaming
+	s.status.Sessions++
+	s.status.StartedAt = time.Now()
+	s.status.NextRetryAt = time.Time{}
+}
+
+func (s *stream) recordFailure(err error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.ConsecutiveFailures++
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	return s.status.ConsecutiveFailures
+}
+
+// recordFrame marks the stream healthy: a saved frame resets the failure streak
+func (s *stream) recordFrame(d decoder.VideoDecoder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastFrameAt = time.Now()
+	s.status.ConsecutiveFailures = 0
+	s.status.Decoder = d.GetStats()
+	if h264, ok := d.(*decoder.H264Decoder); ok {
+		s.status.Video, _ = h264.GetStreamInfo()
+	}
+}
@@ -0,0 +1,447 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/rtsp-client/pkg/rtsp"
+	"github.com/rtsp-client/pkg/storage"
+	"github.com/rtsp-client/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetryConfig keeps restart delays short for tests
+func fastRetryConfig(maxRetries int) *rtsp.RetryConfig {
+	return &rtsp.RetryConfig{
+		MaxRetries:   maxRetries,
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     50 * time.Millisecond,
+		Multiplier:   2.0,
+	}
+}
+
+// TestStreamState_String tests state names
+func TestStreamState_String(t *testing.T) {
+	tests := []struct {
+		state    StreamState
+		expected string
+	}{
+		{StateConnecting, "connecting"},
+		{StateStreaming, "streaming"},
+		{StateBackoff, "backoff"},
+		{StateStopped, "stopped"},
+		{StateFailed, "failed"},
+		{StreamState(42), "unknown(42)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.state.String())
+		})
+	}
+}
+
+// TestManager_StartValidation tests stream configuration validation
+func TestManager_StartValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config StreamConfig
+	}{
+		{
+			name:   "missing ID",
+			config: StreamConfig{URL: "rtsp://127.0.0.1/stream"},
+		},
+		{
+			name:   "missing URL",
+			config: StreamConfig{ID: "cam1"},
+		},
+		{
+			name:   "invalid URL scheme",
+			config: StreamConfig{ID: "cam1", URL: "http://127.0.0.1/stream"},
+		},
+	}
+
+	m := NewManager(1)
+	defer m.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.Start(tt.config)
+			assert.ErrorIs(t, err, ErrInvalidStreamConfig)
+		})
+	}
+	assert.Empty(t, m.IDs())
+}
+
+// TestManager_DuplicateAndUnknownStreams tests ID bookkeeping
+func TestManager_DuplicateAndUnknownStreams(t *testing.T) {
+	m := NewManager(2)
+	m.SetRetryConfig(fastRetryConfig(0))
+	defer m.Close()
+
+	config := StreamConfig{
+		ID:        "cam1",
+		URL:       "rtsp://127.0.0.1:1/stream",
+		OutputDir: t.TempDir(),
+		Timeout:   100 * time.Millisecond,
+	}
+	require.NoError(t, m.Start(config))
+
+	err := m.Start(config)
+	assert.ErrorIs(t, err, ErrStreamExists)
+
+	_, err = m.Status("missing")
+	assert.ErrorIs(t, err, ErrStreamNotFound)
+	assert.ErrorIs(t, m.Stop("missing"), ErrStreamNotFound)
+
+	assert.Equal(t, []string{"cam1"}, m.IDs())
+	require.NoError(t, m.Stop("cam1"))
+	assert.Empty(t, m.IDs())
+}
+
+// TestManager_FailingStreamDoesNotBlockOthers tests independent supervision
+func TestManager_FailingStreamDoesNotBlockOthers(t *testing.T) {
+	server, err := test.NewMockRTSPServer()
+	require.NoError(t, err)
+	server.Start()
+	defer server.Stop()
+
+	m := NewManager(2)
+	m.SetRetryConfig(fastRetryConfig(0))
+	defer m.Close()
+
+	require.NoError(t, m.Start(StreamConfig{
+		ID:        "bad",
+		URL:       "rtsp://127.0.0.1:1/stream", // Connection refused
+		OutputDir: t.TempDir(),
+		Timeout:   100 * time.Millisecond,
+	}))
+	require.NoError(t, m.Start(StreamConfig{
+		ID:        "good",
+		URL:       server.URL("/stream"),
+		OutputDir: t.TempDir(),
+		Timeout:   100 * time.Millisecond,
+	}))
+
+	require.Eventually(t, func() bool {
+		bad, err := m.Status("bad")
+		require.NoError(t, err)
+		good, err := m.Status("good")
+		require.NoError(t, err)
+		return bad.Restarts >= 2 && good.Sessions >= 1
+	}, 3*time.Second, 10*time.Millisecond)
+
+	bad, err := m.Status("bad")
+	require.NoError(t, err)
+	assert.Equal(t, 0, bad.Sessions)
+	assert.NotEmpty(t, bad.LastError)
+
+	statuses := m.StatusAll()
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "bad", statuses[0].ID)
+	assert.Equal(t, "good", statuses[1].ID)
+}
+
+// TestManager_MaxRetries tests that a stream gives up after consecutive failures
+func TestManager_MaxRetries(t *testing.T) {
+	m := NewManager(1)
+	m.SetRetryConfig(fastRetryConfig(2))
+	defer m.Close()
+
+	require.NoError(t, m.Start(StreamConfig{
+		ID:        "cam1",
+		URL:       "rtsp://127.0.0.1:1/stream",
+		OutputDir: t.TempDir(),
+		Timeout:   100 * time.Millisecond,
+	}))
+
+	require.Eventually(t, func() bool {
+		status, err := m.Status("cam1")
+		require.NoError(t, err)
+		return status.State == StateFailed
+	}, 3*time.Second, 10*time.Millisecond)
+
+	status, err := m.Status("cam1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	assert.Equal(t, 2, status.Restarts)
+}
+
+// TestManager_StopAll tests stopping every stream promptly
+func TestManager_StopAll(t *testing.T) {
+	m := NewManager(4)
+	m.SetRetryConfig(&rtsp.RetryConfig{InitialDelay: time.Hour, MaxDelay: time.Hour, Multiplier: 2.0})
+
+	for _, id := range []string{"cam1", "cam2", "cam3"} {
+		require.NoError(t, m.Start(StreamConfig{
+			ID:        id,
+			URL:       "rtsp://127.0.0.1:1/stream",
+			OutputDir: t.TempDir(),
+			Timeout:   100 * time.Millisecond,
+		}))
+	}
+
+	// Wait until every stream sits in its (hour-long) backoff
+	require.Eventually(t, func() bool {
+		for _, status := range m.StatusAll() {
+			if status.State != StateBackoff {
+				return false
+			}
+		}
+		return true
+	}, 3*time.Second, 10*time.Millisecond)
+
+	start := time.Now()
+	require.NoError(t, m.Close())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, m.IDs())
+
+	err := m.Start(StreamConfig{ID: "cam4", URL: "rtsp://127.0.0.1:1/stream"})
+	assert.ErrorIs(t, err, ErrManagerClosed)
+}
+
+// TestManager_ConnectSlots tests that connect slots bound concurrent handshakes
+func TestManager_ConnectSlots(t *testing.T) {
+	m := NewManager(1)
+
+	require.NoError(t, m.acquireConnectSlot(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.acquireConnectSlot(ctx), context.DeadlineExceeded)
+
+	m.releaseConnectSlot()
+	require.NoError(t, m.acquireConnectSlot(context.Background()))
+	m.releaseConnectSlot()
+}
+
+// TestCalculateBackoff tests exponential restart delays with jitter
+func TestCalculateBackoff(t *testing.T) {
+	config := &rtsp.RetryConfig{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     1 * time.Second,
+		Multiplier:   2.0,
+	}
+
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		{10, 1 * time.Second},
+	}
+
+	for _, tt := range tests {
+		delay := calculateBackoff(config, tt.attempt)
+		assert.GreaterOrEqual(t, delay, tt.base)
+		assert.Less(t, delay, tt.base+tt.base/5)
+	}
+}
+
+// TestSpropParameterSets tests extracting SPS/PPS from SDP tracks
+func TestSpropParameterSets(t *testing.T) {
+	info := &rtsp.SDPInfo{
+		Tracks: []rtsp.SDPTrack{
+			{Codec: "MPEG4-GENERIC", FMTP: map[string]string{"config": "1190"}},
+			{Codec: "H264", FMTP: map[string]string{"sprop-parameter-sets": "Z0IAKeKQ,aM48gA=="}},
+		},
+	}
+
+	sps, pps := spropParameterSets(info)
+	assert.Equal(t, "Z0IAKeKQ", sps)
+	assert.Equal(t, "aM48gA==", pps)
+
+	sps, pps = spropParameterSets(nil)
+	assert.Empty(t, sps)
+	assert.Empty(t, pps)
+}
+
+// TestConfigureH265 tests switching a stream to H.265 from its SDP track
+// TestConfigureH264 tests applying sprop-parameter-sets and packetization-mode=2 to the decoder
+func TestConfigureH264(t *testing.T) {
+	track := h264Track(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{
+		{Media: "audio", Codec: "PCMU"},
+		{Media: "video", Codec: "H264", FMTP: map[string]string{
+			"packetization-mode":       "2",
+			"sprop-interleaving-depth": "0",
+			"sprop-parameter-sets":     "Z0LAHvQFAeyA,aM48gA==",
+		}},
+	}})
+	require.NotNil(t, track)
+	assert.Nil(t, h264Track(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Codec: "H265"}}}))
+	assert.Nil(t, h264Track(nil))
+
+	h264 := decoder.NewH264Decoder()
+	s := &stream{config: StreamConfig{ID: "cam"}, decoder: h264}
+	NewManager(1).configureH264(s, track)
+
+	// STAP-B packets without marker bits: access units complete when the next one starts
+	stapB := func(seq uint16, ts uint32, don byte) *rtp.Packet {
+		return &rtp.Packet{SequenceNumber: seq, Timestamp: ts, SSRC: 1, Payload: []byte{0x19, 0x00, don, 0x00, 0x02, 0x41, 0x9a}}
+	}
+	assert.Nil(t, h264.ProcessPacket(stapB(1, 0, 0)))
+	frame := h264.ProcessPacket(stapB(2, 3000, 1))
+	require.NotNil(t, frame)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a}, frame.Data)
+
+	// The SPS from SDP is reported in the stream status
+	s.recordFrame(h264)
+	assert.Equal(t, "Baseline@3.0 640x480 4:2:0 unknown fps", s.status.Video.String())
+}
+
+func TestConfigureH265(t *testing.T) {
+	info := &rtsp.SDPInfo{
+		Tracks: []rtsp.SDPTrack{
+			{Codec: "PCMU"},
+			{Codec: "H265", FMTP: map[string]string{
+				"sprop-vps": "QAEMAQ==",
+				"sprop-sps": "QgEBYA==",
+				"sprop-pps": "RAHBcg==",
+			}},
+		},
+	}
+	track := h265Track(info)
+	require.NotNil(t, track)
+	assert.Nil(t, h265Track(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Codec: "H264"}}}))
+	assert.Nil(t, h265Track(nil))
+
+	frameStorage, err := storage.NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+	defer frameStorage.Close()
+
+	s := &stream{config: StreamConfig{ID: "cam"}, decoder: decoder.NewH264Decoder(), storage: frameStorage}
+	NewManager(1).configureH265(s, track)
+
+	h265, ok := s.decoder.(*decoder.H265Decoder)
+	require.True(t, ok)
+	vps, sps, pps := h265.GetParameterSets()
+	assert.NotEmpty(t, vps)
+	assert.NotEmpty(t, sps)
+	assert.NotEmpty(t, pps)
+}
+
+// TestConfigureMJPEG tests switching a stream to Motion JPEG from its SDP track
+func TestConfigureMJPEG(t *testing.T) {
+	assert.NotNil(t, mjpegTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "video", PayloadType: 26}}}))
+	assert.NotNil(t, mjpegTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "video", PayloadType: 96, Codec: "JPEG"}}}))
+	assert.Nil(t, mjpegTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "video", PayloadType: 96, Codec: "H264"}}}))
+	assert.Nil(t, mjpegTrack(nil))
+
+	s := &stream{config: StreamConfig{ID: "cam"}, decoder: decoder.NewH264Decoder()}
+	NewManager(1).configureMJPEG(s)
+	_, ok := s.decoder.(*decoder.MJPEGDecoder)
+	assert.True(t, ok)
+}
+
+// TestConfigureAAC tests routing an mpeg4-generic track to the AAC decoder and audio.aac
+func TestConfigureAAC(t *testing.T) {
+	info := &rtsp.SDPInfo{
+		Tracks: []rtsp.SDPTrack{
+			{Media: "video", Codec: "H264", PayloadType: 96},
+			{Media: "audio", Codec: "mpeg4-generic", PayloadType: 97, FMTP: map[string]string{
+				"mode":             "AAC-hbr",
+				"sizelength":       "13",
+				"indexlength":      "3",
+				"indexdeltalength": "3",
+				"config":           "1408",
+			}},
+		},
+	}
+	track := aacTrack(info)
+	require.NotNil(t, track)
+	assert.Nil(t, aacTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "audio", Codec: "PCMU"}}}))
+	assert.Nil(t, aacTrack(nil))
+
+	outputDir := t.TempDir()
+	frameStorage, err := storage.NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+	defer frameStorage.Close()
+
+	s := &stream{config: StreamConfig{ID: "cam"}, decoder: decoder.NewH264Decoder(), storage: frameStorage}
+	m := NewManager(1)
+	m.configureAAC(s, track)
+	require.NotNil(t, s.audio)
+	assert.Equal(t, uint8(97), s.audioPayloadType)
+
+	m.saveAudio(s, &rtp.Packet{
+		SequenceNumber: 1,
+		Timestamp:      1024,
+		Marker:         true,
+		PayloadType:    97,
+		Payload:        []byte{0x00, 0x10, 0x00, 0x18, 0x21, 0x10, 0x04},
+	})
+	assert.Equal(t, int64(1), frameStorage.GetStats().AudioUnits)
+	_, err = os.Stat(filepath.Join(outputDir, "audio.aac"))
+	assert.NoError(t, err)
+}
+
+// TestConfigureOpus tests routing an Opus track to the Opus decoder and an Ogg/Opus file
+func TestConfigureOpus(t *testing.T) {
+	track := opusTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{
+		{Media: "video", Codec: "H264", PayloadType: 96},
+		{Media: "audio", Codec: "opus", PayloadType: 111, ClockRate: 48000, Channels: 2, FMTP: map[string]string{"sprop-stereo": "1"}},
+	}})
+	require.NotNil(t, track)
+	assert.Nil(t, opusTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "audio", Codec: "PCMU"}}}))
+	assert.Nil(t, opusTrack(nil))
+
+	outputDir := t.TempDir()
+	frameStorage, err := storage.NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+
+	s := &stream{config: StreamConfig{ID: "bridge"}, decoder: decoder.NewH264Decoder(), storage: frameStorage}
+	m := NewManager(1)
+	m.configureOpus(s, track)
+	require.NotNil(t, s.audio)
+	assert.Equal(t, uint8(111), s.audioPayloadType)
+
+	m.saveAudio(s, &rtp.Packet{SequenceNumber: 1, Timestamp: 960, PayloadType: 111, Payload: []byte{0xF8, 0x01}})
+	assert.Equal(t, int64(1), frameStorage.GetStats().AudioUnits)
+	require.NoError(t, frameStorage.Close())
+
+	head, err := os.ReadFile(filepath.Join(outputDir, "audio.opus"))
+	require.NoError(t, err)
+	require.Greater(t, len(head), 28+9)
+	assert.Equal(t, "OpusHead", string(head[28:36]))
+	assert.Equal(t, byte(2), head[37], "sprop-stereo=1")
+}
+
+// TestConfigurePCM tests routing a G.711 track to the PCM decoder and a WAV file
+func TestConfigurePCM(t *testing.T) {
+	track, format := pcmTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{
+		{Media: "video", Codec: "H264", PayloadType: 96},
+		{Media: "audio", PayloadType: 8},
+	}})
+	require.NotNil(t, track)
+	assert.Equal(t, storage.WAVFormat{Codec: decoder.CodecPCMA, SampleRate: 8000, Channels: 1}, format)
+
+	_, format = pcmTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "audio", PayloadType: 97, Codec: "L16", ClockRate: 16000}}})
+	assert.Equal(t, storage.WAVFormat{Codec: decoder.CodecL16, SampleRate: 16000, Channels: 1}, format)
+
+	track, _ = pcmTrack(&rtsp.SDPInfo{Tracks: []rtsp.SDPTrack{{Media: "audio", PayloadType: 97, Codec: "opus"}}})
+	assert.Nil(t, track)
+
+	outputDir := t.TempDir()
+	frameStorage, err := storage.NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+	defer frameStorage.Close()
+
+	s := &stream{config: StreamConfig{ID: "doorbell", TranscodeAudio: true}, decoder: decoder.NewH264Decoder(), storage: frameStorage}
+	m := NewManager(1)
+	m.configurePCM(s, &rtsp.SDPTrack{Media: "audio", PayloadType: 0}, storage.WAVFormat{Codec: decoder.CodecPCMU, SampleRate: 8000, Channels: 1})
+	require.NotNil(t, s.audio)
+	assert.Equal(t, uint8(0), s.audioPayloadType)
+
+	m.saveAudio(s, &rtp.Packet{SequenceNumber: 1, Timestamp: 800, PayloadType: 0, Payload: make([]byte, 160)})
+	assert.Equal(t, int64(1), frameStorage.GetStats().AudioUnits)
+	_, err = os.Stat(filepath.Join(outputDir, "800.wav"))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,231 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86DD
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88A8
+	ipProtocolTCP  = 6
+	ipProtocolUDP  = 17
+	ipv6HopByHop   = 0
+	ipv6Routing    = 43
+	ipv6Fragment   = 44
+	ipv6DestOpts   = 60
+	tcpFlagSYN     = 0x02
+	tcpFlagFIN     = 0x01
+	tcpFlagRST     = 0x04
+	udpHeaderSize  = 8
+	ipv6HeaderSize = 40
+)
+
+// segment is a decoded UDP datagram or TCP segment
+type segment struct {
+	tcp     bool
+	src     netip.AddrPort
+	dst     netip.AddrPort
+	seq     uint32 // TCP only
+	flags   uint8  // TCP only
+	payload []byte
+}
+
+// decodeStatus explains why a frame did not yield a segment
+type decodeStatus int
+
+const (
+	decodeOK decodeStatus = iota
+	decodeUnsupported
+	decodeFragment
+	decodeMalformed
+)
+
+// decodeFrame strips link, network and transport headers from a captured frame
+func decodeFrame(linkType LinkType, data []byte) (segment, decodeStatus) {
+	var network []byte
+	var ipVersion int
+
+	switch linkType {
+	case LinkTypeEthernet:
+		if len(data) < 14 {
+			return segment{}, decodeMalformed
+		}
+		etherType := binary.BigEndian.Uint16(data[12:14])
+		data = data[14:]
+		for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+			if len(data) < 4 {
+				return segment{}, decodeMalformed
+			}
+			etherType = binary.BigEndian.Uint16(data[2:4])
+			data = data[4:]
+		}
+		network, ipVersion = data, etherTypeVersion(etherType)
+
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return segment{}, decodeMalformed
+		}
+		network, ipVersion = data[16:], etherTypeVersion(binary.BigEndian.Uint16(data[14:16]))
+
+	case LinkTypeLinuxSL2:
+		if len(data) < 20 {
+			return segment{}, decodeMalformed
+		}
+		network, ipVersion = data[20:], etherTypeVersion(binary.BigEndian.Uint16(data[0:2]))
+
+	case LinkTypeNull, LinkTypeLoop:
+		if len(data) < 4 {
+			return segment{}, decodeMalformed
+		}
+		// The address family is in the capturing host's byte order for DLT_NULL;
+		// AF_INET is 2 everywhere and AF_INET6 is 24, 28 or 30 depending on the OS
+		family := binary.LittleEndian.Uint32(data[0:4])
+		if linkType == LinkTypeLoop || family > 0xFFFF {
+			family = binary.BigEndian.Uint32(data[0:4])
+		}
+		switch family {
+		case 2:
+			ipVersion = 4
+		case 24, 28, 30:
+			ipVersion = 6
+		}
+		network = data[4:]
+
+	case LinkTypeRaw:
+		if len(data) == 0 {
+			return segment{}, decodeMalformed
+		}
+		network, ipVersion = data, int(data[0]>>4)
+
+	case LinkTypeIPv4:
+		network, ipVersion = data, 4
+
+	case LinkTypeIPv6:
+		network, ipVersion = data, 6
+
+	default:
+		return segment{}, decodeUnsupported
+	}
+
+	switch ipVersion {
+	case 4:
+		return decodeIPv4(network)
+	case 6:
+		return decodeIPv6(network)
+	default:
+		return segment{}, decodeUnsupported
+	}
+}
+
+// etherTypeVersion maps an EtherType to an IP version (0 if not IP)
+func etherTypeVersion(etherType uint16) int {
+	switch etherType {
+	case etherTypeIPv4:
+		return 4
+	case etherTypeIPv6:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// decodeIPv4 decodes an IPv4 packet; fragments are not reassembled
+func decodeIPv4(data []byte) (segment, decodeStatus) {
+	if len(data) < 20 || data[0]>>4 != 4 {
+		return segment{}, decodeMalformed
+	}
+	headerLength := int(data[0]&0x0F) * 4
+	totalLength := int(binary.BigEndian.Uint16(data[2:4]))
+	if headerLength < 20 || totalLength < headerLength || len(data) < headerLength {
+		return segment{}, decodeMalformed
+	}
+	if totalLength < len(data) {
+		data = data[:totalLength] // Drop Ethernet padding
+	}
+
+	flagsAndOffset := binary.BigEndian.Uint16(data[6:8])
+	if flagsAndOffset&0x2000 != 0 || flagsAndOffset&0x1FFF != 0 {
+		return segment{}, decodeFragment
+	}
+
+	src := netip.AddrFrom4([4]byte(data[12:16]))
+	dst := netip.AddrFrom4([4]byte(data[16:20]))
+	return decodeTransport(data[9], src, dst, data[headerLength:])
+}
+
+// decodeIPv6 decodes an IPv6 packet, skipping extension headers
+func decodeIPv6(data []byte) (segment, decodeStatus) {
+	if len(data) < ipv6HeaderSize || data[0]>>4 != 6 {
+		return segment{}, decodeMalformed
+	}
+	payloadLength := int(binary.BigEndian.Uint16(data[4:6]))
+	if ipv6HeaderSize+payloadLength < len(data) {
+		data = data[:ipv6HeaderSize+payloadLength]
+	}
+
+	src := netip.AddrFrom16([16]byte(data[8:24]))
+	dst := netip.AddrFrom16([16]byte(data[24:40]))
+	next := data[6]
+	payload := data[ipv6HeaderSize:]
+
+	for {
+		switch next {
+		case ipv6HopByHop, ipv6Routing, ipv6DestOpts:
+			if len(payload) < 8 {
+				return segment{}, decodeMalformed
+			}
+			length := 8 + int(payload[1])*8
+			if len(payload) < length {
+				return segment{}, decodeMalformed
+			}
+			next, payload = payload[0], payload[length:]
+		case ipv6Fragment:
+			return segment{}, decodeFragment
+		default:
+			return decodeTransport(next, src, dst, payload)
+		}
+	}
+}
+
+// decodeTransport decodes a UDP or TCP header
+func decodeTransport(protocol uint8, src, dst netip.Addr, data []byte) (segment, decodeStatus) {
+	switch protocol {
+	case ipProtocolUDP:
+		if len(data) < udpHeaderSize {
+			return segment{}, decodeMalformed
+		}
+		length := int(binary.BigEndian.Uint16(data[4:6]))
+		if length < udpHeaderSize || length > len(data) {
+			// Truncated by the snap length or malformed; use what was captured
+			length = len(data)
+		}
+		return segment{
+			src:     netip.AddrPortFrom(src, binary.BigEndian.Uint16(data[0:2])),
+			dst:     netip.AddrPortFrom(dst, binary.BigEndian.Uint16(data[2:4])),
+			payload: data[udpHeaderSize:length],
+		}, decodeOK
+
+	case ipProtocolTCP:
+		if len(data) < 20 {
+			return segment{}, decodeMalformed
+		}
+		headerLength := int(data[12]>>4) * 4
+		if headerLength < 20 || headerLength > len(data) {
+			return segment{}, decodeMalformed
+		}
+		return segment{
+			tcp:     true,
+			src:     netip.AddrPortFrom(src, binary.BigEndian.Uint16(data[0:2])),
+			dst:     netip.AddrPortFrom(dst, binary.BigEndian.Uint16(data[2:4])),
+			seq:     binary.BigEndian.Uint32(data[4:8]),
+			flags:   data[13],
+			payload: data[headerLength:],
+		}, decodeOK
+
+	default:
+		return segment{}, decodeUnsupported
+	}
+}
@@ -0,0 +1,345 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// LinkType is a capture link-layer header type (LINKTYPE_* values)
+type LinkType uint16
+
+// Supported link-layer header types
+const (
+	LinkTypeNull     LinkType = 0   // BSD loopback, host-order address family
+	LinkTypeEthernet LinkType = 1   // IEEE 802.3 Ethernet
+	LinkTypeRaw      LinkType = 101 // Raw IPv4 or IPv6
+	LinkTypeLoop     LinkType = 108 // OpenBSD loopback, network-order address family
+	LinkTypeLinuxSLL LinkType = 113 // Linux "cooked" capture v1
+	LinkTypeIPv4     LinkType = 228
+	LinkTypeIPv6     LinkType = 229
+	LinkTypeLinuxSL2 LinkType = 276 // Linux "cooked" capture v2
+)
+
+const (
+	pcapMagicMicro     = 0xA1B2C3D4
+	pcapMagicNano      = 0xA1B23C4D
+	pcapngSectionBlock = 0x0A0D0D0A
+	pcapngByteOrder    = 0x1A2B3C4D
+
+	pcapngInterfaceBlock      = 0x00000001
+	pcapngPacketBlock         = 0x00000002 // Obsolete Packet Block
+	pcapngSimplePacketBlock   = 0x00000003
+	pcapngEnhancedPacketBlock = 0x00000006
+
+	pcapngOptionEnd        = 0
+	pcapngOptionTSResol    = 9
+	pcapngOptionTSOffset   = 14
+	pcapHeaderSize         = 24
+	pcapRecordHeaderSize   = 16
+	pcapMaxRecordSize      = 16 * 1024 * 1024
+	pcapngMinBlockSize     = 12
+	pcapngDefaultTSResol   = 6 // Microseconds
+	pcapngSectionBlockSize = 28
+)
+
+var (
+	// ErrInvalidCapture indicates the input is not a pcap or pcapng file
+	ErrInvalidCapture = errors.New("not a pcap or pcapng capture")
+	// ErrCorruptCapture indicates a truncated or malformed record or block
+	ErrCorruptCapture = errors.New("corrupt capture file")
+)
+
+// Record is one captured link-layer frame
+type Record struct {
+	Time           time.Time
+	LinkType       LinkType
+	Data           []byte // Captured bytes; may be shorter than OriginalLength
+	OriginalLength int
+}
+
+// pcapngInterface holds the per-interface state of a pcapng section
+type pcapngInterface struct {
+	linkType LinkType
+	tsUnits  uint64 // Timestamp units per second
+	tsOffset int64  // Seconds added to every timestamp
+}
+
+// Reader reads records from a classic pcap or a pcapng capture.
+// The format and byte order are detected from the file header.
+type Reader struct {
+	r         *bufio.Reader
+	ng        bool
+	order     binary.ByteOrder
+	linkType  LinkType // Classic pcap only
+	nanos     bool     // Classic pcap only
+	ifaces    []pcapngInterface
+	scratch   []byte
+	blockHead [8]byte
+}
+
+// NewReader detects the capture format and reads the file header
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReaderSize(r, 64*1024)}
+
+	magic, err := reader.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCapture, err)
+	}
+
+	switch {
+	case binary.BigEndian.Uint32(magic) == pcapngSectionBlock:
+		reader.ng = true
+		if err := reader.readSectionHeader(); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case binary.BigEndian.Uint32(magic) == pcapMagicMicro, binary.BigEndian.Uint32(magic) == pcapMagicNano:
+		reader.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(magic) == pcapMagicMicro, binary.LittleEndian.Uint32(magic) == pcapMagicNano:
+		reader.order = binary.LittleEndian
+	default:
+		return nil, fmt.Errorf("%w: magic 0x%x", ErrInvalidCapture, magic)
+	}
+
+	var header [pcapHeaderSize]byte
+	if _, err := io.ReadFull(reader.r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: file header: %v", ErrInvalidCapture, err)
+	}
+	reader.nanos = reader.order.Uint32(header[0:4]) == pcapMagicNano
+	reader.linkType = LinkType(reader.order.Uint32(header[20:24]) & 0xFFFF)
+	return reader, nil
+}
+
+// ReadRecord returns the next captured frame; it returns io.EOF at the end of the file.
+// Record.Data is only valid until the next call.
+func (r *Reader) ReadRecord() (Record, error) {
+	if r.ng {
+		return r.readBlockRecord()
+	}
+
+	var header [pcapRecordHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("%w: truncated record header", ErrCorruptCapture)
+		}
+		return Record{}, err
+	}
+
+	seconds := int64(r.order.Uint32(header[0:4]))
+	fraction := int64(r.order.Uint32(header[4:8]))
+	captured := r.order.Uint32(header[8:12])
+	original := r.order.Uint32(header[12:16])
+	if captured > pcapMaxRecordSize {
+		return Record{}, fmt.Errorf("%w: %d-byte record", ErrCorruptCapture, captured)
+	}
+
+	data, err := r.read(int(captured))
+	if err != nil {
+		return Record{}, err
+	}
+
+	if !r.nanos {
+		fraction *= int64(time.Microsecond)
+	}
+	return Record{
+		Time:           time.Unix(seconds, fraction),
+		LinkType:       r.linkType,
+		Data:           data,
+		OriginalLength: int(original),
+	}, nil
+}
+
+// readBlockRecord reads pcapng blocks until one carries a packet
+func (r *Reader) readBlockRecord() (Record, error) {
+	for {
+		blockType, body, err := r.readBlock()
+		if err != nil {
+			return Record{}, err
+		}
+
+		switch blockType {
+		case pcapngSectionBlock:
+			// A new section resets byte order and interfaces
+			if err := r.parseSectionHeader(body); err != nil {
+				return Record{}, err
+			}
+
+		case pcapngInterfaceBlock:
+			if err := r.parseInterface(body); err != nil {
+				return Record{}, err
+			}
+
+		case pcapngEnhancedPacketBlock:
+			if len(body) < 20 {
+				return Record{}, fmt.Errorf("%w: short enhanced packet block", ErrCorruptCapture)
+			}
+			return r.packetRecord(r.order.Uint32(body[0:4]), body[4:12], body[12:])
+
+		case pcapngPacketBlock:
+			if len(body) < 20 {
+				return Record{}, fmt.Errorf("%w: short packet block", ErrCorruptCapture)
+			}
+			return r.packetRecord(uint32(r.order.Uint16(body[0:2])), body[4:12], body[12:])
+
+		case pcapngSimplePacketBlock:
+			if len(body) < 4 || len(r.ifaces) == 0 {
+				return Record{}, fmt.Errorf("%w: simple packet block without interface", ErrCorruptCapture)
+			}
+			original := int(r.order.Uint32(body[0:4]))
+			data := body[4:]
+			if original < len(data) {
+				data = data[:original]
+			}
+			return Record{LinkType: r.ifaces[0].linkType, Data: data, OriginalLength: original}, nil
+		}
+		// Statistics, name resolution and custom blocks are skipped
+	}
+}
+
+// packetRecord builds a record from the common part of (enhanced) packet blocks
+func (r *Reader) packetRecord(ifaceID uint32, timestamp, rest []byte) (Record, error) {
+	if int(ifaceID) >= len(r.ifaces) {
+		return Record{}, fmt.Errorf("%w: unknown interface %d", ErrCorruptCapture, ifaceID)
+	}
+	iface := r.ifaces[ifaceID]
+
+	captured := r.order.Uint32(rest[0:4])
+	original := r.order.Uint32(rest[4:8])
+	if int(captured) > len(rest)-8 {
+		return Record{}, fmt.Errorf("%w: %d-byte packet in %d-byte block", ErrCorruptCapture, captured, len(rest)-8)
+	}
+
+	ts := uint64(r.order.Uint32(timestamp[0:4]))<<32 | uint64(r.order.Uint32(timestamp[4:8]))
+	seconds := int64(ts/iface.tsUnits) + iface.tsOffset
+	nanos := int64(float64(ts%iface.tsUnits) * 1e9 / float64(iface.tsUnits))
+
+	return Record{
+		Time:           time.Unix(seconds, nanos),
+		LinkType:       iface.linkType,
+		Data:           rest[8 : 8+captured],
+		OriginalLength: int(original),
+	}, nil
+}
+
+// readSectionHeader reads the first Section Header Block
+func (r *Reader) readSectionHeader() error {
+	blockType, body, err := r.readBlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCapture, err)
+	}
+	if blockType != pcapngSectionBlock {
+		return ErrInvalidCapture
+	}
+	return r.parseSectionHeader(body)
+}
+
+// parseSectionHeader resets state for a new section. Byte order was already
+// established from the byte-order magic in readBlock.
+func (r *Reader) parseSectionHeader(body []byte) error {
+	if len(body) < pcapngSectionBlockSize-pcapngMinBlockSize {
+		return fmt.Errorf("%w: short section header", ErrCorruptCapture)
+	}
+	r.ifaces = r.ifaces[:0]
+	return nil
+}
+
+// parseInterface records an Interface Description Block
+func (r *Reader) parseInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("%w: short interface block", ErrCorruptCapture)
+	}
+
+	iface := pcapngInterface{
+		linkType: LinkType(r.order.Uint16(body[0:2])),
+		tsUnits:  uint64(math.Pow10(pcapngDefaultTSResol)),
+	}
+
+	options := body[8:]
+	for len(options) >= 4 {
+		code := r.order.Uint16(options[0:2])
+		length := int(r.order.Uint16(options[2:4]))
+		if code == pcapngOptionEnd || 4+length > len(options) {
+			break
+		}
+		value := options[4 : 4+length]
+
+		switch {
+		case code == pcapngOptionTSResol && length >= 1:
+			resolution := value[0]
+			if resolution&0x80 != 0 {
+				iface.tsUnits = 1 << (resolution & 0x7F)
+			} else {
+				iface.tsUnits = uint64(math.Pow10(int(resolution)))
+			}
+		case code == pcapngOptionTSOffset && length >= 8:
+			iface.tsOffset = int64(r.order.Uint64(value))
+		}
+
+		options = options[4+(length+3)&^3:]
+	}
+	if iface.tsUnits == 0 {
+		iface.tsUnits = uint64(math.Pow10(pcapngDefaultTSResol))
+	}
+
+	r.ifaces = append(r.ifaces, iface)
+	return nil
+}
+
+// readBlock reads one pcapng block and returns its type and body (without the
+// type, length and trailing length fields). The body is valid until the next read.
+func (r *Reader) readBlock() (uint32, []byte, error) {
+	if _, err := io.ReadFull(r.r, r.blockHead[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("%w: truncated block header", ErrCorruptCapture)
+		}
+		return 0, nil, err
+	}
+
+	// The section header's byte-order magic decides how to read its own length
+	if binary.BigEndian.Uint32(r.blockHead[0:4]) == pcapngSectionBlock {
+		bom, err := r.r.Peek(4)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%w: truncated section header", ErrCorruptCapture)
+		}
+		switch {
+		case binary.BigEndian.Uint32(bom) == pcapngByteOrder:
+			r.order = binary.BigEndian
+		case binary.LittleEndian.Uint32(bom) == pcapngByteOrder:
+			r.order = binary.LittleEndian
+		default:
+			return 0, nil, fmt.Errorf("%w: bad byte-order magic", ErrInvalidCapture)
+		}
+	}
+
+	blockType := r.order.Uint32(r.blockHead[0:4])
+	length := r.order.Uint32(r.blockHead[4:8])
+	if length < pcapngMinBlockSize || length%4 != 0 || length > pcapMaxRecordSize {
+		return 0, nil, fmt.Errorf("%w: block length %d", ErrCorruptCapture, length)
+	}
+
+	data, err := r.read(int(length) - 8)
+	if err != nil {
+		return 0, nil, err
+	}
+	if trailer := r.order.Uint32(data[len(data)-4:]); trailer != length {
+		return 0, nil, fmt.Errorf("%w: block length mismatch (%d != %d)", ErrCorruptCapture, trailer, length)
+	}
+	return blockType, data[:len(data)-4], nil
+}
+
+// read reads n bytes into the reusable scratch buffer
+func (r *Reader) read(n int) ([]byte, error) {
+	if cap(r.scratch) < n {
+		r.scratch = make([]byte, n)
+	}
+	data := r.scratch[:n]
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return nil, fmt.Errorf("%w: truncated record: %v", ErrCorruptCapture, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,267 @@
+package pcap
+
+import (
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// Filter selects which RTP and RTCP packets a Source returns
+type Filter struct {
+	SSRC uint32 // Only packets of this SSRC (0 matches any)
+	Port uint16 // Only UDP/TCP traffic from or to this port; UDP RTCP on Port+1 is included (0 matches any)
+}
+
+// RTCPHandler is called for every RTCP packet that passes the filter, once
+// for each packet of a compound packet
+type RTCPHandler func(rtcpPacket rtp.RTCPPacket) error
+
+// CapturedPacket is an RTP packet with its capture metadata
+type CapturedPacket struct {
+	Packet      *rtp.Packet
+	Time        time.Time      // Capture timestamp of the frame that completed the packet
+	Source      netip.AddrPort // Sender address
+	Destination netip.AddrPort
+	Channel     int // Interleaved channel for RTP over TCP, -1 for UDP
+}
+
+// SourceStatistics counts what a Source read from the capture
+type SourceStatistics struct {
+	Records     int // Link-layer frames read
+	RTPPackets  int // RTP packets returned
+	RTCPPackets int // RTCP packets passed to the handler
+	Filtered    int // RTP/RTCP packets rejected by the filter
+	NonRTP      int // UDP datagrams that were not RTP or RTCP
+	Fragments   int // IP fragments (not reassembled)
+	Malformed   int // Frames or packets that failed to decode
+	Unsupported int // Frames of other link types or protocols
+	TCPResyncs  int // Times a TCP stream skipped bytes to find an interleaved frame
+}
+
+// Source replays RTP and RTCP packets from a pcap or pcapng capture. UDP
+// datagrams are classified as RTP or RTCP by payload type (RFC 5761); TCP
+// streams are reassembled and split on RTSP interleaved framing, where even
+// channels carry RTP and odd channels RTCP. Sender Reports update per-SSRC
+// timestamp mappers so frames keep their original wall-clock times.
+type Source struct {
+	reader  *Reader
+	closer  io.Closer
+	filter  Filter
+	streams map[flowKey]*tcpStream
+	queue   []*CapturedPacket
+	last    time.Time
+
+	rtcpHandler      RTCPHandler
+	timestampMappers rtp.TimestampMapperSet
+	stats            SourceStatistics
+}
+
+// NewSource reads a capture from r
+func NewSource(r io.Reader, filter Filter) (*Source, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		reader:  reader,
+		filter:  filter,
+		streams: make(map[flowKey]*tcpStream),
+	}, nil
+}
+
+// OpenSource opens a capture file; Close releases it
+func OpenSource(path string, filter Filter) (*Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+	source, err := NewSource(file, filter)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	source.closer = file
+	logger.Info("[Pcap] Replaying %s", path)
+	return source, nil
+}
+
+// Close closes the capture file opened by OpenSource
+func (s *Source) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// SetRTCPHandler sets the handler for RTCP packets found in the capture
+func (s *Source) SetRTCPHandler(handler RTCPHandler) {
+	s.rtcpHandler = handler
+}
+
+// GetTimestampMapper returns the RTP-to-NTP mapper built from the capture's Sender Reports
+func (s *Source) GetTimestampMapper(ssrc uint32) *rtp.TimestampMapper {
+	return s.timestampMappers.Mapper(ssrc)
+}
+
+// GetStatistics returns what was read so far
+func (s *Source) GetStatistics() SourceStatistics {
+	stats := s.stats
+	for _, stream := range s.streams {
+		stats.TCPResyncs += stream.resyncs
+	}
+	return stats
+}
+
+// ReadPacket returns the next RTP packet, or io.EOF at the end of the capture
+func (s *Source) ReadPacket() (*rtp.Packet, error) {
+	captured, err := s.Next()
+	if err != nil {
+		return nil, err
+	}
+	return captured.Packet, nil
+}
+
+// PacketTime returns the capture timestamp of the packet last returned
+func (s *Source) PacketTime() time.Time {
+	return s.last
+}
+
+// Next returns the next RTP packet with its capture metadata, or io.EOF at the end of the capture
+func (s *Source) Next() (*CapturedPacket, error) {
+	for len(s.queue) == 0 {
+		record, err := s.reader.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		s.stats.Records++
+		s.processRecord(record)
+	}
+
+	captured := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	s.last = captured.Time
+	s.stats.RTPPackets++
+	return captured, nil
+}
+
+// processRecord decodes a captured frame and queues the RTP packets it yields
+func (s *Source) processRecord(record Record) {
+	seg, status := decodeFrame(record.LinkType, record.Data)
+	switch status {
+	case decodeFragment:
+		s.stats.Fragments++
+		return
+	case decodeMalformed:
+		s.stats.Malformed++
+		return
+	case decodeUnsupported:
+		s.stats.Unsupported++
+		return
+	}
+
+	if !seg.tcp {
+		if !s.portMatches(seg, true) {
+			return
+		}
+		s.processDatagram(seg.payload, record.Time, seg.src, seg.dst, -1)
+		return
+	}
+
+	if !s.portMatches(seg, false) {
+		return
+	}
+	key := flowKey{src: seg.src, dst: seg.dst}
+	stream, ok := s.streams[key]
+	if !ok {
+		stream = &tcpStream{}
+		s.streams[key] = stream
+	}
+	for _, frame := range stream.push(seg) {
+		s.processDatagram(frame.payload, record.Time, seg.src, seg.dst, int(frame.channel))
+	}
+	if seg.flags&(tcpFlagFIN|tcpFlagRST) != 0 {
+		delete(s.streams, key)
+	}
+}
+
+// portMatches applies the port filter to a segment
+func (s *Source) portMatches(seg segment, includeRTCP bool) bool {
+	port := s.filter.Port
+	if port == 0 {
+		return true
+	}
+	for _, p := range []uint16{seg.src.Port(), seg.dst.Port()} {
+		if p == port || (includeRTCP && p == port+1) {
+			return true
+		}
+	}
+	return false
+}
+
+// processDatagram classifies one UDP payload or interleaved frame as RTP or RTCP
+func (s *Source) processDatagram(data []byte, at time.Time, src, dst netip.AddrPort, channel int) {
+	if len(data) < 4 || data[0]>>6 != 2 {
+		s.stats.NonRTP++
+		return
+	}
+
+	isRTCP := data[1] >= 192 && data[1] <= 223
+	if channel >= 0 {
+		isRTCP = channel%2 == 1
+	}
+	if isRTCP {
+		s.processRTCP(data)
+		return
+	}
+
+	// Frames are reused by the reader; the packet must own its bytes
+	packet, err := rtp.ParsePacket(append([]byte(nil), data...))
+	if err != nil {
+		s.stats.Malformed++
+		return
+	}
+	if s.filter.SSRC != 0 && packet.SSRC != s.filter.SSRC {
+		s.stats.Filtered++
+		return
+	}
+
+	s.queue = append(s.queue, &CapturedPacket{
+		Packet:      packet,
+		Time:        at,
+		Source:      src,
+		Destination: dst,
+		Channel:     channel,
+	})
+}
+
+// processRTCP records Sender Reports and passes matching RTCP packets to the handler
+func (s *Source) processRTCP(data []byte) {
+	compound, err := rtp.ParseCompoundRTCPPacket(data)
+	if err != nil {
+		s.stats.Malformed++
+		logger.Debug("[Pcap] Skipping malformed RTCP packet: %v", err)
+		return
+	}
+	if s.filter.SSRC != 0 && compound.GetSSRC() != s.filter.SSRC {
+		s.stats.Filtered++
+		return
+	}
+	s.stats.RTCPPackets++
+
+	for _, packet := range compound.Packets {
+		if sr, ok := packet.(*rtp.SenderReport); ok {
+			s.timestampMappers.UpdateFromSR(sr)
+		}
+		if s.rtcpHandler != nil {
+			if err := s.rtcpHandler(packet); err != nil {
+				logger.Warn("[Pcap] RTCP handler error: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testCamera = netip.MustParseAddrPort("192.168.1.10:6970")
+	testClient = netip.MustParseAddrPort("192.168.1.20:50000")
+	testEpoch  = time.Unix(1700000000, 0)
+)
+
+// testFrame is a link-layer frame and its capture time
+type testFrame struct {
+	at   time.Time
+	data []byte
+}
+
+// ethernetIPv4 wraps a transport header and payload in Ethernet and IPv4 headers
+func ethernetIPv4(protocol uint8, src, dst netip.AddrPort, transport []byte) []byte {
+	ip := make([]byte, 20, 20+len(transport))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(transport)))
+	ip[8] = 64
+	ip[9] = protocol
+	src4, dst4 := src.Addr().As4(), dst.Addr().As4()
+	copy(ip[12:16], src4[:])
+	copy(ip[16:20], dst4[:])
+	ip = append(ip, transport...)
+
+	frame := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(frame[12:14], etherTypeIPv4)
+	return append(frame, ip...)
+}
+
+// udpFrame builds an Ethernet/IPv4/UDP frame
+func udpFrame(src, dst netip.AddrPort, payload []byte) []byte {
+	udp := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(udp[0:2], src.Port())
+	binary.BigEndian.PutUint16(udp[2:4], dst.Port())
+	binary.BigEndian.PutUint16(udp[4:6], uint16(8+len(payload)))
+	return ethernetIPv4(ipProtocolUDP, src, dst, append(udp, payload...))
+}
+
+// tcpFrame builds an Ethernet/IPv4/TCP frame
+func tcpFrame(src, dst netip.AddrPort, seq uint32, flags uint8, payload []byte) []byte {
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:2], src.Port())
+	binary.BigEndian.PutUint16(tcp[2:4], dst.Port())
+	binary.BigEndian.PutUint32(tcp[4:8], seq)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	return ethernetIPv4(ipProtocolTCP, src, dst, append(tcp, payload...))
+}
+
+// writePcap writes a little-endian microsecond pcap file
+func writePcap(frames []testFrame) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:4], pcapMagicMicro)
+	binary.LittleEndian.PutUint16(header[4:6], 2)
+	binary.LittleEndian.PutUint16(header[6:8], 4)
+	binary.LittleEndian.PutUint32(header[16:20], 65535)
+	binary.LittleEndian.PutUint32(header[20:24], uint32(LinkTypeEthernet))
+	buf.Write(header)
+
+	for _, frame := range frames {
+		record := make([]byte, 16)
+		binary.LittleEndian.PutUint32(record[0:4], uint32(frame.at.Unix()))
+		binary.LittleEndian.PutUint32(record[4:8], uint32(frame.at.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(record[8:12], uint32(len(frame.data)))
+		binary.LittleEndian.PutUint32(record[12:16], uint32(len(frame.data)))
+		buf.Write(record)
+		buf.Write(frame.data)
+	}
+	return buf.Bytes()
+}
+
+// pcapngBlock encodes a big-endian pcapng block
+func pcapngBlock(blockType uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	length := uint32(12 + len(body))
+	block := binary.BigEndian.AppendUint32(nil, blockType)
+	block = binary.BigEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	return binary.BigEndian.AppendUint32(block, length)
+}
+
+// writePcapng writes a big-endian pcapng file with nanosecond timestamps
+func writePcapng(frames []testFrame) []byte {
+	var buf bytes.Buffer
+
+	shb := binary.BigEndian.AppendUint32(nil, pcapngByteOrder)
+	shb = binary.BigEndian.AppendUint16(shb, 1)
+	shb = binary.BigEndian.AppendUint16(shb, 0)
+	shb = binary.BigEndian.AppendUint64(shb, ^uint64(0))
+	buf.Write(pcapngBlock(pcapngSectionBlock, shb))
+
+	idb := binary.BigEndian.AppendUint16(nil, uint16(LinkTypeEthernet))
+	idb = binary.BigEndian.AppendUint16(idb, 0)
+	idb = binary.BigEndian.AppendUint32(idb, 0)
+	idb = binary.BigEndian.AppendUint16(idb, pcapngOptionTSResol)
+	idb = binary.BigEndian.AppendUint16(idb, 1)
+	idb = append(idb, 9, 0, 0, 0)
+	idb = append(idb, 0, 0, 0, 0) // opt_endofopt
+	buf.Write(pcapngBlock(pcapngInterfaceBlock, idb))
+
+	// An unknown block type must be skipped
+	buf.Write(pcapngBlock(0x00000BAD, []byte{1, 2, 3, 4}))
+
+	for _, frame := range frames {
+		ts := uint64(frame.at.UnixNano())
+		epb := binary.BigEndian.AppendUint32(nil, 0)
+		epb = binary.BigEndian.AppendUint32(epb, uint32(ts>>32))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(ts))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(len(frame.data)))
+		epb = binary.BigEndian.AppendUint32(epb, uint32(len(frame.data)))
+		epb = append(epb, frame.data...)
+		buf.Write(pcapngBlock(pcapngEnhancedPacketBlock, epb))
+	}
+	return buf.Bytes()
+}
+
+// testRTP marshals an RTP packet
+func testRTP(t *testing.T, ssrc uint32, seq uint16) []byte {
+	data, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq) * 3000, SSRC: ssrc, Payload: []byte{0x41, byte(seq)}}).Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+// testSR marshals a Sender Report
+func testSR(t *testing.T, ssrc uint32) []byte {
+	data, err := (&rtp.SenderReport{SSRC: ssrc, NTPTimestamp: rtp.TimeToNTP(testEpoch), RTPTimestamp: 3000}).Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+// readAll drains a source
+func readAll(t *testing.T, source *Source) []*CapturedPacket {
+	var packets []*CapturedPacket
+	for {
+		captured, err := source.Next()
+		if err == io.EOF {
+			return packets
+		}
+		require.NoError(t, err)
+		packets = append(packets, captured)
+	}
+}
+
+// TestSource_UDP tests UDP RTP/RTCP classification, filtering and timestamps in both formats
+func TestSource_UDP(t *testing.T) {
+	other := netip.MustParseAddrPort("192.168.1.10:7000")
+	frames := []testFrame{
+		{testEpoch, udpFrame(testCamera, testClient, testRTP(t, 0xAAAA, 1))},
+		{testEpoch.Add(10 * time.Millisecond), udpFrame(netip.AddrPortFrom(testCamera.Addr(), 6971), netip.AddrPortFrom(testClient.Addr(), 50001), testSR(t, 0xAAAA))},
+		{testEpoch.Add(20 * time.Millisecond), udpFrame(other, testClient, testRTP(t, 0xBBBB, 7))},
+		{testEpoch.Add(30 * time.Millisecond), udpFrame(testCamera, testClient, []byte{0x00, 0x01, 0x02, 0x03, 0x04})}, // Not RTP
+		{testEpoch.Add(40 * time.Millisecond), udpFrame(testCamera, testClient, testRTP(t, 0xAAAA, 2))},
+	}
+
+	for name, data := range map[string][]byte{"pcap": writePcap(frames), "pcapng": writePcapng(frames)} {
+		t.Run(name, func(t *testing.T) {
+			source, err := NewSource(bytes.NewReader(data), Filter{})
+			require.NoError(t, err)
+
+			var rtcp []rtp.RTCPPacket
+			source.SetRTCPHandler(func(packet rtp.RTCPPacket) error {
+				rtcp = append(rtcp, packet)
+				return nil
+			})
+
+			packets := readAll(t, source)
+			require.Len(t, packets, 3)
+			assert.Equal(t, uint16(1), packets[0].Packet.SequenceNumber)
+			assert.Equal(t, testEpoch, packets[0].Time)
+			assert.Equal(t, testCamera, packets[0].Source)
+			assert.Equal(t, -1, packets[0].Channel)
+			assert.Equal(t, uint32(0xBBBB), packets[1].Packet.SSRC)
+			assert.Equal(t, testEpoch.Add(40*time.Millisecond), packets[2].Time)
+			assert.Equal(t, testEpoch.Add(40*time.Millisecond), source.PacketTime())
+
+			require.Len(t, rtcp, 1)
+			assert.True(t, source.GetTimestampMapper(0xAAAA).GetState().Initialized)
+
+			stats := source.GetStatistics()
+			assert.Equal(t, 5, stats.Records)
+			assert.Equal(t, 3, stats.RTPPackets)
+			assert.Equal(t, 1, stats.RTCPPackets)
+			assert.Equal(t, 1, stats.NonRTP)
+		})
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		source, err := NewSource(bytes.NewReader(writePcap(frames)), Filter{SSRC: 0xAAAA, Port: 6970})
+		require.NoError(t, err)
+
+		packets := readAll(t, source)
+		require.Len(t, packets, 2)
+		for _, captured := range packets {
+			assert.Equal(t, uint32(0xAAAA), captured.Packet.SSRC)
+		}
+		assert.Equal(t, 1, source.GetStatistics().RTCPPackets, "RTCP on port+1 passes the port filter")
+
+		source, err = NewSource(bytes.NewReader(writePcap(frames)), Filter{SSRC: 0xBBBB})
+		require.NoError(t, err)
+		assert.Len(t, readAll(t, source), 1)
+		assert.Equal(t, 3, source.GetStatistics().Filtered)
+	})
+}
+
+// TestSource_TCPInterleaved tests TCP reassembly with reordering, retransmission,
+// frames split across segments and RTSP messages between frames
+func TestSource_TCPInterleaved(t *testing.T) {
+	server := netip.MustParseAddrPort("192.168.1.10:554")
+	client := netip.MustParseAddrPort("192.168.1.20:40000")
+
+	var stream []byte
+	stream = append(stream, "RTSP/1.0 200 OK\r\nCSeq: 5\r\nContent-Length: 4\r\n\r\nbody"...)
+	stream = append(stream, interleaved(0, testRTP(t, 0xCCCC, 1))...)
+	stream = append(stream, interleaved(1, testSR(t, 0xCCCC))...)
+	stream = append(stream, interleaved(0, testRTP(t, 0xCCCC, 2))...)
+	stream = append(stream, interleaved(0, testRTP(t, 0xCCCC, 3))...)
+
+	// Cut into 7-byte segments so frames and headers straddle segments
+	const isn = 1000
+	var segments [][2]int
+	for offset := 0; offset < len(stream); offset += 7 {
+		end := offset + 7
+		if end > len(stream) {
+			end = len(stream)
+		}
+		segments = append(segments, [2]int{offset, end})
+	}
+	// Swap two segments and retransmit one
+	segments[3], segments[4] = segments[4], segments[3]
+	segments = append(segments[:6], append([][2]int{segments[2]}, segments[6:]...)...)
+
+	frames := []testFrame{{testEpoch, tcpFrame(server, client, isn-1, tcpFlagSYN, nil)}}
+	for i, seg := range segments {
+		at := testEpoch.Add(time.Duration(i+1) * time.Millisecond)
+		frames = append(frames, testFrame{at, tcpFrame(server, client, isn+uint32(seg[0]), 0, stream[seg[0]:seg[1]])})
+	}
+	// Client-to-server RTSP traffic on the same connection is a separate direction
+	frames = append(frames, testFrame{testEpoch, tcpFrame(client, server, 1, 0, []byte("GET_PARAMETER rtsp://cam/ RTSP/1.0\r\nCSeq: 6\r\n\r\n"))})
+
+	source, err := NewSource(bytes.NewReader(writePcapng(frames)), Filter{Port: 554})
+	require.NoError(t, err)
+
+	packets := readAll(t, source)
+	require.Len(t, packets, 3)
+	for i, captured := range packets {
+		assert.Equal(t, uint16(i+1), captured.Packet.SequenceNumber)
+		assert.Equal(t, 0, captured.Channel)
+		assert.Equal(t, server, captured.Source)
+	}
+	assert.Equal(t, 1, source.GetStatistics().RTCPPackets)
+	assert.True(t, source.GetTimestampMapper(0xCCCC).GetState().Initialized)
+	assert.Zero(t, source.GetStatistics().TCPResyncs)
+}
+
+// TestSource_TCPMidStream tests a capture that starts inside an interleaved frame
+func TestSource_TCPMidStream(t *testing.T) {
+	server := netip.MustParseAddrPort("10.0.0.1:554")
+	client := netip.MustParseAddrPort("10.0.0.2:40000")
+
+	first := interleaved(0, testRTP(t, 1, 1))
+	stream := append(first[5:], interleaved(0, testRTP(t, 1, 2))...)
+
+	source, err := NewSource(bytes.NewReader(writePcap([]testFrame{{testEpoch, tcpFrame(server, client, 5000, 0, stream)}})), Filter{})
+	require.NoError(t, err)
+
+	packets := readAll(t, source)
+	require.Len(t, packets, 1)
+	assert.Equal(t, uint16(2), packets[0].Packet.SequenceNumber)
+	assert.Equal(t, 1, source.GetStatistics().TCPResyncs)
+}
+
+// TestNewReader_Invalid tests rejection of non-capture input and truncated records
+func TestNewReader_Invalid(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("definitely not a capture")))
+	assert.ErrorIs(t, err, ErrInvalidCapture)
+
+	data := writePcap([]testFrame{{testEpoch, udpFrame(testCamera, testClient, testRTP(t, 1, 1))}})
+	reader, err := NewReader(bytes.NewReader(data[:len(data)-5]))
+	require.NoError(t, err)
+	_, err = reader.ReadRecord()
+	assert.ErrorIs(t, err, ErrCorruptCapture)
+}
+
+// interleaved builds an RTSP interleaved frame
+func interleaved(channel uint8, payload []byte) []byte {
+	frame := []byte{'$', channel, byte(len(payload) >> 8), byte(len(payload))}
+	return append(frame, payload...)
+}
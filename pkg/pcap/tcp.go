@@ -0,0 +1,235 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxPendingBytes bounds out-of-order data held per TCP direction; beyond it
+	// the missing bytes are assumed lost by the capture and skipped
+	maxPendingBytes = 1 << 20
+	// maxRTSPMessageSize bounds an RTSP message interleaved with media
+	maxRTSPMessageSize = 64 * 1024
+	interleavedMagic   = '$'
+)
+
+// flowKey identifies one direction of a TCP connection
+type flowKey struct {
+	src netip.AddrPort
+	dst netip.AddrPort
+}
+
+// interleavedFrame is a '$'-framed packet recovered from a TCP stream
+type interleavedFrame struct {
+	channel uint8
+	payload []byte
+}
+
+// tcpStream reassembles one TCP direction and splits it into interleaved frames
+type tcpStream struct {
+	started bool
+	nextSeq uint32
+	pending map[uint32][]byte
+	pendLen int
+	buf     []byte
+	resyncs int // Times the stream skipped data to find the next frame
+	skipped int // Bytes lost to capture gaps
+}
+
+// push adds a segment and returns the complete interleaved frames it made available
+func (s *tcpStream) push(seg segment) []interleavedFrame {
+	if seg.flags&tcpFlagSYN != 0 {
+		s.started = true
+		s.nextSeq = seg.seq + 1
+		s.buf = s.buf[:0]
+		s.pending = nil
+		s.pendLen = 0
+		return nil
+	}
+	if len(seg.payload) == 0 {
+		return nil
+	}
+	if !s.started {
+		// Capture began mid-connection
+		s.started = true
+		s.nextSeq = seg.seq
+	}
+
+	payload := seg.payload
+	offset := int32(seg.seq - s.nextSeq)
+	switch {
+	case offset < 0:
+		// Retransmission overlapping data already delivered
+		if int(-offset) >= len(payload) {
+			return nil
+		}
+		payload = payload[-offset:]
+	case offset > 0:
+		s.hold(seg.seq, payload)
+		if s.pendLen <= maxPendingBytes {
+			return nil
+		}
+		s.skipToPending()
+		return s.frames()
+	}
+
+	s.buf = append(s.buf, payload...)
+	s.nextSeq += uint32(len(payload))
+	s.drainPending()
+	return s.frames()
+}
+
+// hold stores an out-of-order segment
+func (s *tcpStream) hold(seq uint32, payload []byte) {
+	if s.pending == nil {
+		s.pending = make(map[uint32][]byte)
+	}
+	if existing, ok := s.pending[seq]; ok && len(existing) >= len(payload) {
+		return
+	}
+	s.pendLen += len(payload) - len(s.pending[seq])
+	s.pending[seq] = append([]byte(nil), payload...)
+}
+
+// drainPending appends held segments that are now contiguous
+func (s *tcpStream) drainPending() {
+	for len(s.pending) > 0 {
+		progressed := false
+		for seq, payload := range s.pending {
+			offset := int32(seq - s.nextSeq)
+			if offset > 0 {
+				continue
+			}
+			delete(s.pending, seq)
+			s.pendLen -= len(payload)
+			if int(-offset) < len(payload) {
+				s.buf = append(s.buf, payload[-offset:]...)
+				s.nextSeq += uint32(len(payload) + int(offset))
+			}
+			progressed = true
+		}
+		if !progressed {
+			return
+		}
+	}
+}
+
+// skipToPending gives up on missing bytes and continues at the earliest held segment
+func (s *tcpStream) skipToPending() {
+	first := true
+	var earliest uint32
+	for seq := range s.pending {
+		if first || int32(seq-earliest) < 0 {
+			earliest, first = seq, false
+		}
+	}
+
+	s.skipped += int(earliest - s.nextSeq)
+	s.nextSeq = earliest
+	// Whatever was buffered belongs to a frame that can no longer complete
+	s.buf = s.buf[:0]
+	s.resyncs++
+	s.drainPending()
+}
+
+// frames splits buffered bytes into interleaved frames, skipping RTSP messages
+func (s *tcpStream) frames() []interleavedFrame {
+	var frames []interleavedFrame
+	data := s.buf
+
+	for len(data) > 0 {
+		if data[0] == interleavedMagic {
+			if len(data) < 4 {
+				break
+			}
+			length := int(binary.BigEndian.Uint16(data[2:4]))
+			if len(data) < 4+length {
+				break
+			}
+			frames = append(frames, interleavedFrame{
+				channel: data[1],
+				payload: append([]byte(nil), data[4:4+length]...),
+			})
+			data = data[4+length:]
+			continue
+		}
+
+		if isRTSPMessage(data) {
+			size, complete := rtspMessageSize(data)
+			if !complete {
+				if len(data) > maxRTSPMessageSize {
+					data = s.resync(data[1:])
+					continue
+				}
+				break
+			}
+			data = data[size:]
+			continue
+		}
+
+		data = s.resync(data)
+	}
+
+	// Keep the unconsumed tail at the start of the buffer
+	s.buf = append(s.buf[:0], data...)
+	return frames
+}
+
+// resync drops bytes up to the next possible frame start
+func (s *tcpStream) resync(data []byte) []byte {
+	s.resyncs++
+	if i := bytes.IndexByte(data, interleavedMagic); i >= 0 {
+		return data[i:]
+	}
+	return data[:0]
+}
+
+// isRTSPMessage reports whether data starts like an RTSP request or response line
+func isRTSPMessage(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("RTSP/")) {
+		return true
+	}
+	// Requests start with an upper-case method name followed by a space
+	for i, b := range data {
+		if b == ' ' {
+			return i > 0
+		}
+		if b < 'A' || b > 'Z' {
+			if b == '_' {
+				continue
+			}
+			return false
+		}
+		if i > 16 {
+			return false
+		}
+	}
+	return len(data) <= 16
+}
+
+// rtspMessageSize returns the length of the RTSP message at the start of data,
+// including its body, and whether all of it is buffered
+func rtspMessageSize(data []byte) (int, bool) {
+	end := bytes.Index(data, []byte("\r\n\r\n"))
+	if end < 0 {
+		return 0, false
+	}
+	headerSize := end + 4
+
+	contentLength := 0
+	for _, line := range strings.Split(string(data[:end]), "\r\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			contentLength, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+
+	if len(data) < headerSize+contentLength {
+		return 0, false
+	}
+	return headerSize + contentLength, true
+}
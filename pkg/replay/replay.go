@@ -0,0 +1,223 @@
+// Package replay feeds recorded RTP packets through the decoder and frame
+// storage, so captures of misbehaving cameras can be analysed offline.
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/pcap"
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/rtsp-client/pkg/rtpdump"
+	"github.com/rtsp-client/pkg/storage"
+)
+
+var (
+	// ErrNoCaptureFile indicates the replay configuration names no capture
+	ErrNoCaptureFile = errors.New("no capture file to replay")
+)
+
+// TimedReader is a PacketReader that knows when each packet was originally received
+type TimedReader interface {
+	rtp.PacketReader
+	PacketTime() time.Time // Capture time of the packet last returned by ReadPacket
+}
+
+// mapperSource provides RTP-to-NTP mappers from recorded Sender Reports
+type mapperSource interface {
+	GetTimestampMapper(ssrc uint32) *rtp.TimestampMapper
+}
+
+// Options controls how packets are replayed
+type Options struct {
+	// Speed paces packets by their capture times: 1 is real time, 2 twice as
+	// fast. 0 replays as fast as possible. Requires a TimedReader.
+	Speed float64
+}
+
+// Stats summarizes a replay
+type Stats struct {
+	Packets  int
+	Frames   int
+	Duration time.Duration // Capture time between the first and last packet
+	Decoder  decoder.DecoderStats
+	Storage  storage.StorageStats
+}
+
+// Config describes a capture file replay
+type Config struct {
+	CaptureFile       string
+	Filter            pcap.Filter
+	OutputDir         string
+	SaveJPEG          bool
+	ContinuousDecoder bool
+	Codec             decoder.Codec // Video codec of the recorded stream (default H.264)
+	Options           Options
+}
+
+// RunFile replays a pcap/pcapng capture or an rtpdump recording into a new
+// decoder for config.Codec and a FrameStorage. rtpdump files are recognized by
+// their header; only the SSRC part of the filter applies to them.
+func RunFile(ctx context.Context, config Config) (Stats, error) {
+	if config.CaptureFile == "" {
+		return Stats{}, ErrNoCaptureFile
+	}
+
+	isDump, err := isRTPDumpFile(config.CaptureFile)
+	if err != nil {
+		return Stats{}, err
+	}
+	if isDump {
+		player, err := rtpdump.OpenPlayer(config.CaptureFile, rtpdump.PlayerOptions{SSRC: config.Filter.SSRC})
+		if err != nil {
+			return Stats{}, err
+		}
+		defer player.Close()
+
+		stats, err := runWithStorage(ctx, player, config)
+		playerStats := player.GetStatistics()
+		logger.Info("[Replay] %s: %d RTP, %d RTCP, %d filtered, %d malformed",
+			config.CaptureFile, playerStats.RTPPackets, playerStats.RTCPPackets, playerStats.Filtered, playerStats.Malformed)
+		return stats, err
+	}
+
+	source, err := pcap.OpenSource(config.CaptureFile, config.Filter)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer source.Close()
+
+	stats, err := runWithStorage(ctx, source, config)
+	sourceStats := source.GetStatistics()
+	logger.Info("[Replay] %s: %d records, %d RTP, %d RTCP, %d filtered, %d malformed, %d fragments",
+		config.CaptureFile, sourceStats.Records, sourceStats.RTPPackets, sourceStats.RTCPPackets,
+		sourceStats.Filtered, sourceStats.Malformed, sourceStats.Fragments)
+	return stats, err
+}
+
+// runWithStorage creates the configured storage and replays reader into it
+func runWithStorage(ctx context.Context, reader rtp.PacketReader, config Config) (Stats, error) {
+
+	frameStorage, err := storage.NewFrameStorageWithOptions(config.OutputDir, config.SaveJPEG, config.ContinuousDecoder)
+	if err != nil {
+		return Stats{}, err
+	}
+	defer func() {
+		if err := frameStorage.Close(); err != nil {
+			logger.Warn("[Replay] Error closing storage: %v", err)
+		}
+	}()
+
+	var videoDecoder decoder.VideoDecoder = decoder.NewH264Decoder()
+	switch config.Codec {
+	case decoder.CodecH265:
+		videoDecoder = decoder.NewH265Decoder()
+	case decoder.CodecMJPEG:
+		videoDecoder = decoder.NewMJPEGDecoder()
+	}
+	return Run(ctx, reader, videoDecoder, frameStorage, config.Options)
+}
+
+// isRTPDumpFile reports whether the file starts with the rtpdump magic
+func isRTPDumpFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(rtpdump.Magic))
+	n, err := io.ReadFull(file, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("failed to read capture file: %w", err)
+	}
+	return rtpdump.IsRTPDump(magic[:n]), nil
+}
+
+// Run reads packets until the reader is exhausted, decoding frames into frameStorage.
+// Reaching io.EOF is a successful end of replay.
+func Run(ctx context.Context, reader rtp.PacketReader, videoDecoder decoder.VideoDecoder, frameStorage *storage.FrameStorage, options Options) (Stats, error) {
+	var stats Stats
+	timed, _ := reader.(TimedReader)
+	mappers, _ := reader.(mapperSource)
+
+	var first time.Time
+	start := time.Now()
+	var videoSSRC uint32
+	mapped := false
+
+	finish := func(err error) (Stats, error) {
+		stats.Decoder = videoDecoder.GetStats()
+		stats.Storage = frameStorage.GetStats()
+		logger.Info("[Replay] Replayed %d packets into %d frames", stats.Packets, stats.Frames)
+		return stats, err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return finish(err)
+		}
+
+		packet, err := reader.ReadPacket()
+		if errors.Is(err, io.EOF) {
+			return finish(nil)
+		}
+		if err != nil {
+			return finish(fmt.Errorf("read packet: %w", err))
+		}
+		stats.Packets++
+
+		if timed != nil {
+			captured := timed.PacketTime()
+			if first.IsZero() {
+				first = captured
+			}
+			stats.Duration = captured.Sub(first)
+			if options.Speed > 0 {
+				due := start.Add(time.Duration(float64(captured.Sub(first)) / options.Speed))
+				if err := sleepUntil(ctx, due); err != nil {
+					return finish(err)
+				}
+			}
+		}
+
+		frame := videoDecoder.ProcessPacket(packet)
+		if frame == nil {
+			continue
+		}
+
+		// Name files from the recorded Sender Reports of the video source
+		if mappers != nil && (!mapped || packet.SSRC != videoSSRC) {
+			frameStorage.SetTimestampMapper(mappers.GetTimestampMapper(packet.SSRC))
+			videoSSRC, mapped = packet.SSRC, true
+		}
+
+		if err := frameStorage.SaveFrame(frame); err != nil {
+			logger.Warn("[Replay] Failed to save frame: %v", err)
+			continue
+		}
+		stats.Frames++
+	}
+}
+
+// sleepUntil waits until due or until ctx is cancelled
+func sleepUntil(ctx context.Context, due time.Time) error {
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/rtsp-client/pkg/rtpdump"
+	"github.com/rtsp-client/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReader replays fixed packets spaced by a capture interval
+type fakeReader struct {
+	packets  []*rtp.Packet
+	interval time.Duration
+	next     int
+	mapper   *rtp.TimestampMapper
+}
+
+func (r *fakeReader) ReadPacket() (*rtp.Packet, error) {
+	if r.next >= len(r.packets) {
+		return nil, io.EOF
+	}
+	r.next++
+	return r.packets[r.next-1], nil
+}
+
+func (r *fakeReader) PacketTime() time.Time {
+	return time.Unix(1700000000, 0).Add(time.Duration(r.next-1) * r.interval)
+}
+
+func (r *fakeReader) GetTimestampMapper(ssrc uint32) *rtp.TimestampMapper {
+	return r.mapper
+}
+
+// idrPackets returns single-NAL IDR frames, one packet per frame
+func idrPackets(count int) []*rtp.Packet {
+	packets := make([]*rtp.Packet, count)
+	for i := range packets {
+		packets[i] = &rtp.Packet{
+			Marker:         true,
+			PayloadType:    96,
+			SequenceNumber: uint16(i + 1),
+			Timestamp:      uint32(i+1) * 3000,
+			SSRC:           0x1234,
+			Payload:        []byte{0x65, 0x88, byte(i)},
+		}
+	}
+	return packets
+}
+
+func TestRun(t *testing.T) {
+	outputDir := t.TempDir()
+	frameStorage, err := storage.NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+	defer frameStorage.Close()
+
+	mapper := rtp.NewTimestampMapper()
+	mapper.UpdateFromSR(&rtp.SenderReport{SSRC: 0x1234, NTPTimestamp: rtp.TimeToNTP(time.Unix(1700000000, 0)), RTPTimestamp: 0})
+
+	reader := &fakeReader{packets: idrPackets(4), interval: 20 * time.Millisecond, mapper: mapper}
+	start := time.Now()
+	stats, err := Run(context.Background(), reader, decoder.NewH264Decoder(), frameStorage, Options{Speed: 2})
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond, "60 ms of capture at double speed")
+	assert.Equal(t, 4, stats.Packets)
+	assert.Equal(t, 60*time.Millisecond, stats.Duration)
+	assert.Greater(t, stats.Frames, 0)
+	assert.Equal(t, int64(stats.Frames), stats.Storage.TotalFrames)
+
+	// Frames are named from the recorded Sender Report, not the RTP timestamp alone
+	files, err := os.ReadDir(filepath.Join(outputDir, "h264"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	assert.Contains(t, files[0].Name(), "17000000")
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	frameStorage, err := storage.NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+	defer frameStorage.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = Run(ctx, &fakeReader{packets: idrPackets(2)}, decoder.NewH264Decoder(), frameStorage, Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRunFile_Errors(t *testing.T) {
+	_, err := RunFile(context.Background(), Config{})
+	assert.ErrorIs(t, err, ErrNoCaptureFile)
+
+	_, err = RunFile(context.Background(), Config{CaptureFile: filepath.Join(t.TempDir(), "missing.pcap")})
+	assert.Error(t, err)
+}
+
+func TestRunFile_RTPDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "camera.rtp")
+	writer, err := rtpdump.Create(path, netip.AddrPort{})
+	require.NoError(t, err)
+	start := time.Now()
+	for i, packet := range idrPackets(3) {
+		data, err := packet.Marshal()
+		require.NoError(t, err)
+		require.NoError(t, writer.WriteRTP(data, start.Add(time.Duration(i)*40*time.Millisecond)))
+	}
+	require.NoError(t, writer.Close())
+
+	stats, err := RunFile(context.Background(), Config{
+		CaptureFile: path,
+		OutputDir:   t.TempDir(),
+		Options:     Options{Speed: 0},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Packets)
+	assert.Equal(t, 80*time.Millisecond, stats.Duration)
+	assert.Greater(t, stats.Frames, 0)
+}
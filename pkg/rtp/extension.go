@@ -0,0 +1,183 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RTP header extension profiles
+const (
+	// ExtensionProfileOneByte is the RFC 8285 one-byte header profile
+	ExtensionProfileOneByte = 0xBEDE
+	// ExtensionProfileTwoByte is the RFC 8285 two-byte header profile (low 4 bits are appbits)
+	ExtensionProfileTwoByte = 0x1000
+	// ExtensionProfileONVIF is the ONVIF replay header extension profile
+	ExtensionProfileONVIF = 0xABAC
+)
+
+// Well-known header extension URIs (as announced in SDP a=extmap)
+const (
+	ExtensionURIAbsSendTime     = "http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time"
+	ExtensionURIAbsCaptureTime  = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+	ExtensionURITransportWideCC = "http://www.ietf.org/id/draft-holmer-rmcat-transport-wide-cc-extensions-01"
+	ExtensionURITimestampOffset = "urn:ietf:params:rtp-hdrext:toffset"
+	ExtensionURINTP64           = "urn:ietf:params:rtp-hdrext:ntp-64"
+	ExtensionURIAudioLevel      = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+	ExtensionURISDESMid         = "urn:ietf:params:rtp-hdrext:sdes:mid"
+)
+
+var (
+	// ErrInvalidExtMap indicates an SDP a=extmap attribute could not be parsed
+	ErrInvalidExtMap = errors.New("invalid extmap attribute")
+)
+
+// ExtensionElement is a single RFC 8285 header extension element
+type ExtensionElement struct {
+	ID      uint8
+	Payload []byte
+}
+
+// ExtensionMap maps RFC 8285 extension IDs to the URIs negotiated in SDP
+type ExtensionMap map[uint8]string
+
+// ID returns the extension ID negotiated for a URI
+func (m ExtensionMap) ID(uri string) (uint8, bool) {
+	for id, u := range m {
+		if u == uri {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// ParseExtMap parses the value of an SDP a=extmap attribute
+// Example: "3/recvonly http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time"
+func ParseExtMap(value string) (uint8, string, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return 0, "", fmt.Errorf("%w: %q", ErrInvalidExtMap, value)
+	}
+
+	// Strip optional direction (e.g. "3/recvonly")
+	idStr := strings.SplitN(fields[0], "/", 2)[0]
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id < 1 || id > 255 {
+		return 0, "", fmt.Errorf("%w: bad ID in %q", ErrInvalidExtMap, value)
+	}
+
+	return uint8(id), fields[1], nil
+}
+
+// ONVIFReplay holds the ONVIF replay header extension (ONVIF Streaming Spec 6.3)
+type ONVIFReplay struct {
+	NTPTimestamp  uint64 // Absolute wall-clock time of the frame
+	CleanPoint    bool   // C: access unit is a sync point
+	Discontinuity bool   // E: gap precedes this packet
+	Terminal      bool   // D: last packet of the access unit
+	CSeq          uint8  // Low byte of the RTSP CSeq of the PLAY request
+}
+
+// GetExtension returns the payload of an RFC 8285 extension element by ID
+func (p *Packet) GetExtension(id uint8) ([]byte, bool) {
+	for _, ext := range p.Extensions {
+		if ext.ID == id {
+			return ext.Payload, true
+		}
+	}
+	return nil, false
+}
+
+// GetExtensionByURI returns the payload of the extension negotiated for uri in extMap
+func (p *Packet) GetExtensionByURI(extMap ExtensionMap, uri string) ([]byte, bool) {
+	id, ok := extMap.ID(uri)
+	if !ok {
+		return nil, false
+	}
+	return p.GetExtension(id)
+}
+
+// ONVIFReplay returns the ONVIF replay extension if the packet carries one
+func (p *Packet) ONVIFReplay() (ONVIFReplay, bool) {
+	if !p.Extension || p.ExtensionProfile != ExtensionProfileONVIF || len(p.ExtensionPayload) < 12 {
+		return ONVIFReplay{}, false
+	}
+
+	data := p.ExtensionPayload
+	flags := data[8]
+	return ONVIFReplay{
+		NTPTimestamp:  binary.BigEndian.Uint64(data[0:8]),
+		CleanPoint:    flags&0x80 != 0,
+		Discontinuity: flags&0x40 != 0,
+		Terminal:      flags&0x20 != 0,
+		CSeq:          data[9],
+	}, true
+}
+
+// isOneByteProfile reports whether profile is the RFC 8285 one-byte header
+func isOneByteProfile(profile uint16) bool {
+	return profile == ExtensionProfileOneByte
+}
+
+// isTwoByteProfile reports whether profile is the RFC 8285 two-byte header
+func isTwoByteProfile(profile uint16) bool {
+	return profile&0xFFF0 == ExtensionProfileTwoByte
+}
+
+// parseExtensionElements parses RFC 8285 elements from an extension payload.
+// Parsing stops at the first malformed element; elements before it are kept.
+func parseExtensionElements(profile uint16, data []byte) []ExtensionElement {
+	return appendExtensionElements(nil, profile, data)
+}
+
+// appendExtensionElements parses RFC 8285 elements from data and appends them to elements
+func appendExtensionElements(elements []ExtensionElement, profile uint16, data []byte) []ExtensionElement {
+	switch {
+	case isOneByteProfile(profile):
+		for offset := 0; offset < len(data); {
+			b := data[offset]
+			if b == 0 {
+				// Padding byte
+				offset++
+				continue
+			}
+
+			id := b >> 4
+			length := int(b&0x0F) + 1
+			if id == 15 {
+				// Reserved ID: stop processing (RFC 8285 section 4.2)
+				return elements
+			}
+			offset++
+			if offset+length > len(data) {
+				return elements
+			}
+			elements = append(elements, ExtensionElement{ID: id, Payload: data[offset : offset+length]})
+			offset += length
+		}
+
+	case isTwoByteProfile(profile):
+		for offset := 0; offset < len(data); {
+			id := data[offset]
+			if id == 0 {
+				// Padding byte
+				offset++
+				continue
+			}
+			if offset+2 > len(data) {
+				return elements
+			}
+			length := int(data[offset+1])
+			offset += 2
+			if offset+length > len(data) {
+				return elements
+			}
+			elements = append(elements, ExtensionElement{ID: id, Payload: data[offset : offset+length]})
+			offset += length
+		}
+	}
+
+	return elements
+}
@@ -0,0 +1,208 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParsePacket_CSRCAndOneByteExtension tests CSRC lists and RFC 8285 one-byte elements
+func TestParsePacket_CSRCAndOneByteExtension(t *testing.T) {
+	data := []byte{
+		0x92, 0x60, // V=2, P=0, X=1, CC=2, M=0, PT=96
+		0x00, 0x05, // Sequence number: 5
+		0x00, 0x00, 0x03, 0xe8, // Timestamp: 1000
+		0x12, 0x34, 0x56, 0x78, // SSRC
+		0x11, 0x11, 0x11, 0x11, // CSRC 1
+		0x22, 0x22, 0x22, 0x22, // CSRC 2
+		0xBE, 0xDE, 0x00, 0x02, // One-byte profile, 2 words
+		0x32, 0xAA, 0xBB, 0xCC, // ID=3, L=2 (3 bytes)
+		0x50, 0x7F, 0x00, 0x00, // ID=5, L=0 (1 byte), padding
+		0x65, 0x01, // Payload
+	}
+
+	packet, err := ParsePacket(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint32{0x11111111, 0x22222222}, packet.CSRC)
+	assert.True(t, packet.Extension)
+	assert.Equal(t, uint16(ExtensionProfileOneByte), packet.ExtensionProfile)
+	assert.Len(t, packet.ExtensionPayload, 8)
+	require.Len(t, packet.Extensions, 2)
+	assert.Equal(t, []byte{0x65, 0x01}, packet.Payload)
+
+	ext, ok := packet.GetExtension(3)
+	require.True(t, ok)
+	assert.Equal(t, []byte{0xAA, 0xBB, 0xCC}, ext)
+
+	ext, ok = packet.GetExtension(5)
+	require.True(t, ok)
+	assert.Equal(t, []byte{0x7F}, ext)
+
+	_, ok = packet.GetExtension(7)
+	assert.False(t, ok)
+}
+
+// TestParsePacket_TwoByteExtension tests RFC 8285 two-byte elements
+func TestParsePacket_TwoByteExtension(t *testing.T) {
+	data := []byte{
+		0x90, 0x60, // V=2, X=1, CC=0
+		0x00, 0x01,
+		0x00, 0x00, 0x00, 0x01,
+		0xde, 0xad, 0xbe, 0xef,
+		0x10, 0x00, 0x00, 0x02, // Two-byte profile, 2 words
+		0x01, 0x00, // ID=1, empty element
+		0x00,                         // Padding
+		0x10, 0x03, 0x01, 0x02, 0x03, // ID=16, 3 bytes
+		0x41, // Payload
+	}
+
+	packet, err := ParsePacket(data)
+	require.NoError(t, err)
+	require.Len(t, packet.Extensions, 2)
+
+	ext, ok := packet.GetExtension(1)
+	require.True(t, ok)
+	assert.Empty(t, ext)
+
+	ext, ok = packet.GetExtension(16)
+	require.True(t, ok)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, ext)
+	assert.Equal(t, []byte{0x41}, packet.Payload)
+}
+
+// TestParsePacket_ExtensionErrors tests truncated extension headers
+func TestParsePacket_ExtensionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "truncated CSRC list",
+			data: []byte{0x82, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef, 0x11, 0x11},
+		},
+		{
+			name: "truncated extension header",
+			data: []byte{0x90, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef, 0xBE, 0xDE},
+		},
+		{
+			name: "extension length beyond packet",
+			data: []byte{0x90, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef, 0xBE, 0xDE, 0x00, 0x04, 0x10, 0x00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := ParsePacket(tt.data)
+			assert.ErrorIs(t, err, ErrPacketTooShort)
+			assert.Nil(t, packet)
+		})
+	}
+}
+
+// TestParseExtensionElements_Malformed tests that parsing stops at malformed elements
+func TestParseExtensionElements_Malformed(t *testing.T) {
+	// Second element claims 4 bytes but only 1 remains
+	elements := parseExtensionElements(ExtensionProfileOneByte, []byte{0x10, 0xAA, 0x23, 0xBB})
+	require.Len(t, elements, 1)
+	assert.Equal(t, uint8(1), elements[0].ID)
+
+	// ID 15 is reserved and stops processing
+	elements = parseExtensionElements(ExtensionProfileOneByte, []byte{0xF0, 0x00, 0x10, 0xAA})
+	assert.Empty(t, elements)
+
+	// Unknown profiles are not parsed into elements
+	elements = parseExtensionElements(0x1234, []byte{0x10, 0xAA})
+	assert.Empty(t, elements)
+}
+
+// TestParseExtMap tests SDP a=extmap parsing
+func TestParseExtMap(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectedID  uint8
+		expectedURI string
+		expectError bool
+	}{
+		{
+			name:        "simple",
+			value:       "3 " + ExtensionURIAbsSendTime,
+			expectedID:  3,
+			expectedURI: ExtensionURIAbsSendTime,
+		},
+		{
+			name:        "with direction and attributes",
+			value:       "12/recvonly urn:ietf:params:rtp-hdrext:ssrc-audio-level vad=on",
+			expectedID:  12,
+			expectedURI: ExtensionURIAudioLevel,
+		},
+		{
+			name:        "missing URI",
+			value:       "3",
+			expectError: true,
+		},
+		{
+			name:        "ID out of range",
+			value:       "0 urn:x",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, uri, err := ParseExtMap(tt.value)
+			if tt.expectError {
+				assert.ErrorIs(t, err, ErrInvalidExtMap)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedURI, uri)
+		})
+	}
+}
+
+// TestPacket_GetExtensionByURI tests lookups through an SDP extension map
+func TestPacket_GetExtensionByURI(t *testing.T) {
+	packet := &Packet{
+		Extension:        true,
+		ExtensionProfile: ExtensionProfileOneByte,
+		Extensions:       []ExtensionElement{{ID: 3, Payload: []byte{0x01, 0x02, 0x03}}},
+	}
+	extMap := ExtensionMap{3: ExtensionURIAbsSendTime}
+
+	ext, ok := packet.GetExtensionByURI(extMap, ExtensionURIAbsSendTime)
+	require.True(t, ok)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03}, ext)
+
+	_, ok = packet.GetExtensionByURI(extMap, ExtensionURISDESMid)
+	assert.False(t, ok)
+}
+
+// TestPacket_ONVIFReplay tests the ONVIF replay extension
+func TestPacket_ONVIFReplay(t *testing.T) {
+	data := []byte{
+		0x90, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0xde, 0xad, 0xbe, 0xef,
+		0xAB, 0xAC, 0x00, 0x03, // ONVIF profile, 3 words
+		0xE1, 0xF8, 0x92, 0x34, 0x80, 0x00, 0x00, 0x00, // NTP timestamp
+		0xA0, 0x07, 0x00, 0x00, // C=1, E=0, D=1, CSeq=7
+		0x65,
+	}
+
+	packet, err := ParsePacket(data)
+	require.NoError(t, err)
+	assert.Empty(t, packet.Extensions)
+
+	replay, ok := packet.ONVIFReplay()
+	require.True(t, ok)
+	assert.Equal(t, uint64(0xE1F8923480000000), replay.NTPTimestamp)
+	assert.True(t, replay.CleanPoint)
+	assert.False(t, replay.Discontinuity)
+	assert.True(t, replay.Terminal)
+	assert.Equal(t, uint8(7), replay.CSeq)
+
+	_, ok = (&Packet{}).ONVIFReplay()
+	assert.False(t, ok)
+}
@@ -0,0 +1,218 @@
+package rtp
+
+import (
+	"sync"
+)
+
+// FEC receiver limits
+const (
+	// fecMediaHistory is how far behind the newest packet media is kept for recovery
+	fecMediaHistory = 512
+	// fecMaxPending is the most FEC packets held per source awaiting recovery
+	fecMaxPending = 64
+)
+
+// FECStatistics contains RED/ULPFEC receive statistics
+type FECStatistics struct {
+	REDPackets        int // RED packets decapsulated
+	MalformedPackets  int // RED or ULPFEC packets that failed to parse
+	FECPackets        int // ULPFEC packets received
+	MediaPackets      int // Media packets passed through
+	RecoveredPackets  int // Lost media packets rebuilt from FEC
+	DuplicatePackets  int // Media packets already received or recovered
+	UnrecoveredLosses int // Protected packets still missing when their FEC packet expired
+}
+
+// FECReceiver decapsulates RED (RFC 2198) and recovers lost media packets
+// from ULPFEC (RFC 5109) level 0 protection. Every packet read from the
+// network is passed to Push, which returns the media packets to hand on:
+// the packet itself (or the encodings inside a RED packet) followed by any
+// packets recovered because it arrived. Recovered packets may be out of order.
+type FECReceiver struct {
+	mu       sync.Mutex
+	redPT    map[uint8]bool
+	ulpfecPT map[uint8]bool
+	sources  map[uint32]*fecSource
+	stats    FECStatistics
+}
+
+// fecSource is per-SSRC recovery state
+type fecSource struct {
+	media       map[uint16]*Packet
+	newest      uint16
+	initialized bool
+	pending     []*ULPFECPacket
+}
+
+// NewFECReceiver creates a receiver; register payload types with AddREDPayloadType
+// and AddULPFECPayloadType
+func NewFECReceiver() *FECReceiver {
+	return &FECReceiver{
+		redPT:    make(map[uint8]bool),
+		ulpfecPT: make(map[uint8]bool),
+		sources:  make(map[uint32]*fecSource),
+	}
+}
+
+// AddREDPayloadType registers a payload type carrying RED
+func (r *FECReceiver) AddREDPayloadType(pt uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redPT[pt] = true
+}
+
+// AddULPFECPayloadType registers a payload type carrying ULPFEC
+func (r *FECReceiver) AddULPFECPayloadType(pt uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ulpfecPT[pt] = true
+}
+
+// Push processes a received packet and returns the media packets it yields
+func (r *FECReceiver) Push(packet *Packet) []*Packet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.redPT[packet.PayloadType] {
+		return r.handle(packet, nil)
+	}
+
+	inner, err := DecapsulateRED(packet)
+	if err != nil {
+		r.stats.MalformedPackets++
+		return nil
+	}
+	r.stats.REDPackets++
+
+	var out []*Packet
+	for _, p := range inner {
+		out = r.handle(p, out)
+	}
+	return out
+}
+
+// GetStatistics returns receive statistics
+func (r *FECReceiver) GetStatistics() FECStatistics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// Reset forgets all stored packets; statistics are kept
+func (r *FECReceiver) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = make(map[uint32]*fecSource)
+}
+
+// handle processes one media or ULPFEC packet, appending output to out
+func (r *FECReceiver) handle(packet *Packet, out []*Packet) []*Packet {
+	src := r.sources[packet.SSRC]
+	if src == nil {
+		src = &fecSource{media: make(map[uint16]*Packet)}
+		r.sources[packet.SSRC] = src
+	}
+
+	if r.ulpfecPT[packet.PayloadType] {
+		fec, err := ParseULPFEC(packet.Payload)
+		if err != nil {
+			r.stats.MalformedPackets++
+			return out
+		}
+		r.stats.FECPackets++
+		// Copy: the payload may alias a reused receive buffer
+		fec.Payload = append([]byte(nil), fec.Payload...)
+		src.pending = append(src.pending, fec)
+		if len(src.pending) > fecMaxPending {
+			r.expire(src, src.pending[0])
+			src.pending = src.pending[1:]
+		}
+		return r.recover(src, packet.SSRC, out)
+	}
+
+	if _, ok := src.media[packet.SequenceNumber]; ok {
+		r.stats.DuplicatePackets++
+		return out
+	}
+	r.stats.MediaPackets++
+	src.store(packet)
+	out = append(out, packet)
+
+	return r.recover(src, packet.SSRC, out)
+}
+
+// recover repeatedly rebuilds packets that are the only loss covered by a pending FEC packet
+func (r *FECReceiver) recover(src *fecSource, ssrc uint32, out []*Packet) []*Packet {
+	for progress := true; progress; {
+		progress = false
+		kept := src.pending[:0]
+		for _, fec := range src.pending {
+			var missing []uint16
+			var received []*Packet
+			for _, seq := range fec.Protected() {
+				if p, ok := src.media[seq]; ok {
+					received = append(received, p)
+				} else {
+					missing = append(missing, seq)
+				}
+			}
+
+			switch {
+			case len(missing) == 0:
+				// Everything arrived; the FEC packet is no longer needed
+			case len(missing) == 1:
+				if len(received) > 0 {
+					ssrc = received[0].SSRC
+				}
+				packet, err := fec.Recover(missing[0], received, ssrc)
+				if err != nil {
+					r.stats.UnrecoveredLosses++
+					continue
+				}
+				r.stats.RecoveredPackets++
+				src.store(packet)
+				out = append(out, packet)
+				progress = true
+			case src.expired(fec):
+				r.expire(src, fec)
+			default:
+				kept = append(kept, fec)
+			}
+		}
+		src.pending = kept
+	}
+	return out
+}
+
+// expire counts the losses an FEC packet can no longer repair
+func (r *FECReceiver) expire(src *fecSource, fec *ULPFECPacket) {
+	for _, seq := range fec.Protected() {
+		if _, ok := src.media[seq]; !ok {
+			r.stats.UnrecoveredLosses++
+		}
+	}
+}
+
+// store keeps a media packet for recovery and prunes old history
+func (s *fecSource) store(packet *Packet) {
+	s.media[packet.SequenceNumber] = packet
+	if !s.initialized || CompareSequence(packet.SequenceNumber, s.newest) > 0 {
+		s.newest = packet.SequenceNumber
+		s.initialized = true
+	}
+
+	// Prune in batches rather than on every packet
+	if len(s.media) <= 2*fecMediaHistory {
+		return
+	}
+	for seq := range s.media {
+		if SequenceDistance(seq, s.newest) >= fecMediaHistory {
+			delete(s.media, seq)
+		}
+	}
+}
+
+// expired reports whether the packets protected by fec have fallen out of history
+func (s *fecSource) expired(fec *ULPFECPacket) bool {
+	return s.initialized && SequenceDistance(fec.SequenceBase, s.newest) >= fecMediaHistory
+}
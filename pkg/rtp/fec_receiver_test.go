@@ -0,0 +1,107 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redWrap wraps a packet's payload in RED as the primary block
+func redWrap(t *testing.T, p *Packet, redPT uint8) *Packet {
+	payload, err := MarshalRED([]REDBlock{{PayloadType: p.PayloadType, Payload: p.Payload}})
+	require.NoError(t, err)
+	return &Packet{Version: 2, Marker: p.Marker, PayloadType: redPT, SequenceNumber: p.SequenceNumber, Timestamp: p.Timestamp, SSRC: p.SSRC, Payload: payload}
+}
+
+// TestFECReceiver_RecoversFromRED tests RED-encapsulated media and ULPFEC with one loss
+func TestFECReceiver_RecoversFromRED(t *testing.T) {
+	receiver := NewFECReceiver()
+	receiver.AddREDPayloadType(116)
+	receiver.AddULPFECPayloadType(117)
+
+	media := fecTestPackets(100, 4)
+	media[0].CSRC = nil
+	media[1].Padding = false
+	fec, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+	fecPacket := &Packet{Version: 2, PayloadType: 117, SequenceNumber: 104, Timestamp: media[3].Timestamp, SSRC: 0x1234, Payload: fec.Marshal()}
+
+	var out []*Packet
+	for i, p := range media {
+		if i == 2 {
+			continue // lost
+		}
+		out = append(out, receiver.Push(redWrap(t, p, 116))...)
+	}
+	require.Len(t, out, 3)
+
+	out = receiver.Push(redWrap(t, fecPacket, 116))
+	require.Len(t, out, 1)
+	assert.Equal(t, uint16(102), out[0].SequenceNumber)
+	assert.Equal(t, uint8(96), out[0].PayloadType)
+	assert.Equal(t, media[2].Payload, out[0].Payload)
+
+	// The real packet arriving late is a duplicate
+	assert.Empty(t, receiver.Push(redWrap(t, media[2], 116)))
+
+	stats := receiver.GetStatistics()
+	assert.Equal(t, 5, stats.REDPackets)
+	assert.Equal(t, 1, stats.FECPackets)
+	assert.Equal(t, 3, stats.MediaPackets)
+	assert.Equal(t, 1, stats.RecoveredPackets)
+	assert.Equal(t, 1, stats.DuplicatePackets)
+}
+
+// TestFECReceiver_FECBeforeMedia tests recovery once the last needed media packet arrives
+func TestFECReceiver_FECBeforeMedia(t *testing.T) {
+	receiver := NewFECReceiver()
+	receiver.AddULPFECPayloadType(117)
+
+	media := fecTestPackets(1, 3)
+	fec, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+
+	assert.Empty(t, receiver.Push(&Packet{PayloadType: 117, SequenceNumber: 4, SSRC: 0x1234, Payload: fec.Marshal()}))
+	assert.Len(t, receiver.Push(media[0]), 1)
+
+	out := receiver.Push(media[2])
+	require.Len(t, out, 2)
+	assert.Equal(t, uint16(3), out[0].SequenceNumber)
+	assert.Equal(t, uint16(2), out[1].SequenceNumber)
+}
+
+// TestFECReceiver_Unrecoverable tests that two losses are counted when the FEC packet expires
+func TestFECReceiver_Unrecoverable(t *testing.T) {
+	receiver := NewFECReceiver()
+	receiver.AddULPFECPayloadType(117)
+
+	media := fecTestPackets(1, 3)
+	fec, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+
+	receiver.Push(media[0])
+	assert.Empty(t, receiver.Push(&Packet{PayloadType: 117, SequenceNumber: 4, SSRC: 0x1234, Payload: fec.Marshal()}))
+
+	// Media far ahead ages the FEC packet out
+	receiver.Push(&Packet{PayloadType: 96, SequenceNumber: 1 + fecMediaHistory, SSRC: 0x1234, Payload: []byte{1}})
+
+	stats := receiver.GetStatistics()
+	assert.Zero(t, stats.RecoveredPackets)
+	assert.Equal(t, 2, stats.UnrecoveredLosses)
+}
+
+// TestFECReceiver_Malformed tests that unparsable RED and FEC payloads are dropped
+func TestFECReceiver_Malformed(t *testing.T) {
+	receiver := NewFECReceiver()
+	receiver.AddREDPayloadType(116)
+	receiver.AddULPFECPayloadType(117)
+
+	assert.Empty(t, receiver.Push(&Packet{PayloadType: 116, Payload: []byte{0x80}}))
+	assert.Empty(t, receiver.Push(&Packet{PayloadType: 117, Payload: []byte{1, 2}}))
+	assert.Len(t, receiver.Push(&Packet{PayloadType: 96, Payload: []byte{1}}), 1)
+
+	stats := receiver.GetStatistics()
+	assert.Equal(t, 2, stats.MalformedPackets)
+	assert.Equal(t, 1, stats.MediaPackets)
+}
@@ -16,6 +16,10 @@ var (
 	ErrDuplicatePacket = errors.New("duplicate packet detected")
 	// ErrPacketNotReady indicates packet is not ready for retrieval
 	ErrPacketNotReady = errors.New("packet not ready (jitter delay)")
+	// ErrPacketLate indicates a packet arrived after its sequence was already played out
+	ErrPacketLate = errors.New("packet arrived after playout")
+	// ErrSequenceJump indicates a large sequence jump held until the next packet confirms a restart
+	ErrSequenceJump = errors.New("sequence number jump")
 )
 
 // BufferedPacket wraps RTP packet with arrival time
@@ -30,11 +34,18 @@ type JitterBuffer struct {
 	packets          map[uint16]*BufferedPacket
 	maxSize          int
 	maxDelay         time.Duration
+	playoutDelay     time.Duration
 	expectedSeq      uint16
 	initialized      bool
+	released         bool // At least one packet has been handed out
+	source           SourceState
+	pendingJump      *BufferedPacket // Packet after a large jump, awaiting confirmation
 	packetsReceived  int
 	packetsLost      int
 	packetsDuplicate int
+	packetsLate      int
+	retransmitted    int
+	restarts         int
 	lastTimestamp    uint32
 	jitterSum        float64
 	jitterSamples    int
@@ -45,6 +56,9 @@ type BufferStatistics struct {
 	PacketsReceived  int
 	PacketsLost      int
 	PacketsDuplicate int
+	PacketsLate      int
+	Retransmitted    int // Retransmitted packets inserted into gaps
+	Restarts         int // Sender restarts detected without an SSRC change
 	JitterMs         float64
 	BufferSize       int
 }
@@ -52,13 +66,21 @@ type BufferStatistics struct {
 // NewJitterBuffer creates a new jitter buffer
 func NewJitterBuffer(bufferSize int, maxDelay time.Duration) *JitterBuffer {
 	return &JitterBuffer{
-		packets:     make(map[uint16]*BufferedPacket),
-		maxSize:     bufferSize,
-		maxDelay:    maxDelay,
-		initialized: false,
+		packets:      make(map[uint16]*BufferedPacket),
+		maxSize:      bufferSize,
+		maxDelay:     maxDelay,
+		playoutDelay: maxDelay / 4,
+		initialized:  false,
 	}
 }
 
+// SetPlayoutDelay sets how long an in-order packet is held before release (default maxDelay/4)
+func (jb *JitterBuffer) SetPlayoutDelay(delay time.Duration) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+	jb.playoutDelay = delay
+}
+
 // AddPacket adds a packet to the jitter buffer
 func (jb *JitterBuffer) AddPacket(packet *Packet) error {
 	jb.mu.Lock()
@@ -71,6 +93,24 @@ func (jb *JitterBuffer) AddPacket(packet *Packet) error {
 		jb.lastTimestamp = packet.Timestamp
 	}
 
+	switch jb.source.Update(packet.SequenceNumber).Status {
+	case SequenceDuplicate:
+		jb.packetsDuplicate++
+		return ErrDuplicatePacket
+	case SequenceBadJump:
+		// Hold the packet: it is either stray or the first of a restarted sender
+		jb.pendingJump = &BufferedPacket{Packet: packet, ArrivalTime: time.Now()}
+		return ErrSequenceJump
+	case SequenceRestarted:
+		jb.restart(packet.SequenceNumber)
+	}
+
+	// Drop packets whose slot was already played out (or skipped as lost)
+	if jb.released && sequenceCompare(packet.SequenceNumber, jb.expectedSeq) < 0 {
+		jb.packetsLate++
+		return ErrPacketLate
+	}
+
 	// Check for duplicate
 	if _, exists := jb.packets[packet.SequenceNumber]; exists {
 		jb.packetsDuplicate++
@@ -97,6 +137,54 @@ func (jb *JitterBuffer) AddPacket(packet *Packet) error {
 	return nil
 }
 
+// AddRetransmission inserts a retransmitted packet (e.g. restored from RTX)
+// into a gap. Unlike AddPacket it never moves the sequence state: the packet
+// is rejected if it was already played out or skipped, or is still buffered.
+func (jb *JitterBuffer) AddRetransmission(packet *Packet) error {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	if !jb.initialized {
+		return ErrPacketLate
+	}
+	if jb.released && sequenceCompare(packet.SequenceNumber, jb.expectedSeq) < 0 {
+		jb.packetsLate++
+		return ErrPacketLate
+	}
+	if _, exists := jb.packets[packet.SequenceNumber]; exists {
+		jb.packetsDuplicate++
+		return ErrDuplicatePacket
+	}
+
+	if len(jb.packets) >= jb.maxSize {
+		jb.dropOldestPacket()
+	}
+	jb.packets[packet.SequenceNumber] = &BufferedPacket{
+		Packet:      packet,
+		ArrivalTime: time.Now(),
+	}
+	jb.packetsReceived++
+	jb.retransmitted++
+
+	return nil
+}
+
+// restart discards the old stream after the sender restarted its sequence numbers
+// and replays the held packet that started the new one
+func (jb *JitterBuffer) restart(seq uint16) {
+	jb.restarts++
+	jb.packets = make(map[uint16]*BufferedPacket)
+	jb.released = false
+	jb.expectedSeq = seq
+
+	if jb.pendingJump != nil {
+		jb.expectedSeq = jb.pendingJump.Packet.SequenceNumber
+		jb.packets[jb.expectedSeq] = jb.pendingJump
+		jb.packetsReceived++
+		jb.pendingJump = nil
+	}
+}
+
 // GetNextPacket retrieves the next packet in sequence
 func (jb *JitterBuffer) GetNextPacket() (*Packet, error) {
 	jb.mu.Lock()
@@ -155,21 +243,99 @@ func (jb *JitterBuffer) GetNextPacket() (*Packet, error) {
 
 	// Increment expected sequence number
 	jb.expectedSeq++
+	jb.released = true
 
 	return packet, nil
 }
 
+// GetReadyPacket retrieves the next packet whose playout deadline has passed.
+// An in-order packet is released playoutDelay after arrival. When the expected
+// packet is missing, the gap is declared lost once the next buffered packet
+// has waited maxDelay, and playout resumes from that packet.
+func (jb *JitterBuffer) GetReadyPacket(now time.Time) (*Packet, error) {
+	jb.mu.Lock()
+	defer jb.mu.Unlock()
+
+	seq, deadline, ok := jb.nextPlayout()
+	if !ok {
+		return nil, ErrBufferEmpty
+	}
+	if now.Before(deadline) {
+		return nil, ErrPacketNotReady
+	}
+
+	if seq != jb.expectedSeq {
+		if jb.released {
+			jb.packetsLost += int(sequenceDiff(jb.expectedSeq, seq))
+		}
+		jb.expectedSeq = seq
+	}
+
+	packet := jb.packets[seq].Packet
+	delete(jb.packets, seq)
+	jb.expectedSeq++
+	jb.released = true
+
+	return packet, nil
+}
+
+// NextDeadline returns when GetReadyPacket will next be able to release a packet
+func (jb *JitterBuffer) NextDeadline() (time.Time, bool) {
+	jb.mu.RLock()
+	defer jb.mu.RUnlock()
+
+	_, deadline, ok := jb.nextPlayout()
+	return deadline, ok
+}
+
+// nextPlayout returns the sequence number and playout deadline of the next packet
+func (jb *JitterBuffer) nextPlayout() (uint16, time.Time, bool) {
+	if len(jb.packets) == 0 {
+		return 0, time.Time{}, false
+	}
+
+	// Until playout starts, begin from the lowest buffered sequence
+	if !jb.released {
+		seq := jb.findNextAvailableSequence()
+		return seq, jb.packets[seq].ArrivalTime.Add(jb.playoutDelay), true
+	}
+
+	if bp, ok := jb.packets[jb.expectedSeq]; ok {
+		return jb.expectedSeq, bp.ArrivalTime.Add(jb.playoutDelay), true
+	}
+
+	// Expected packet missing: wait up to maxDelay for it before skipping the gap
+	seq := jb.findNextAvailableSequence()
+	return seq, jb.packets[seq].ArrivalTime.Add(jb.maxDelay), true
+}
+
 // DetectGaps detects missing sequence numbers (packet loss)
 func (jb *JitterBuffer) DetectGaps() []uint16 {
 	jb.mu.RLock()
 	defer jb.mu.RUnlock()
 
+	return jb.detectGaps(false)
+}
+
+// DetectMissing is like DetectGaps, but once playout has started it also
+// reports the sequence numbers from the next expected packet up to the
+// lowest buffered one, such as a loss right after the last released packet
+func (jb *JitterBuffer) DetectMissing() []uint16 {
+	jb.mu.RLock()
+	defer jb.mu.RUnlock()
+
+	return jb.detectGaps(jb.released)
+}
+
+// detectGaps returns the sequence numbers missing between buffered packets,
+// starting at expectedSeq if fromExpected is set
+func (jb *JitterBuffer) detectGaps(fromExpected bool) []uint16 {
 	if len(jb.packets) == 0 {
 		return nil
 	}
 
 	// Get all sequence numbers and sort
-	sequences := make([]uint16, 0, len(jb.packets))
+	sequences := make([]uint16, 0, len(jb.packets)+1)
 	for seq := range jb.packets {
 		sequences = append(sequences, seq)
 	}
@@ -178,6 +344,12 @@ func (jb *JitterBuffer) DetectGaps() []uint16 {
 		return sequenceCompare(sequences[i], sequences[j]) < 0
 	})
 
+	// Count the last released packet (expectedSeq-1) as received so the
+	// sequence numbers between it and the lowest buffered packet are reported
+	if fromExpected && sequenceCompare(jb.expectedSeq, sequences[0]) < 0 {
+		sequences = append([]uint16{jb.expectedSeq - 1}, sequences...)
+	}
+
 	// Find gaps
 	gaps := []uint16{}
 	for i := 0; i < len(sequences)-1; i++ {
@@ -211,9 +383,15 @@ func (jb *JitterBuffer) Reset() {
 
 	jb.packets = make(map[uint16]*BufferedPacket)
 	jb.initialized = false
+	jb.released = false
+	jb.source.Reset()
+	jb.pendingJump = nil
 	jb.packetsReceived = 0
 	jb.packetsLost = 0
 	jb.packetsDuplicate = 0
+	jb.packetsLate = 0
+	jb.retransmitted = 0
+	jb.restarts = 0
 	jb.jitterSum = 0
 	jb.jitterSamples = 0
 }
@@ -232,6 +410,9 @@ func (jb *JitterBuffer) GetStatistics() BufferStatistics {
 		PacketsReceived:  jb.packetsReceived,
 		PacketsLost:      jb.packetsLost,
 		PacketsDuplicate: jb.packetsDuplicate,
+		PacketsLate:      jb.packetsLate,
+		Retransmitted:    jb.retransmitted,
+		Restarts:         jb.restarts,
 		JitterMs:         avgJitter,
 		BufferSize:       len(jb.packets),
 	}
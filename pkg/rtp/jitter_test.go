@@ -179,6 +179,28 @@ func TestJitterBuffer_MultipleGaps(t *testing.T) {
 	assert.ElementsMatch(t, []uint16{101, 102, 104}, gaps)
 }
 
+// TestJitterBuffer_DetectMissing tests that losses right after the last released packet are reported
+func TestJitterBuffer_DetectMissing(t *testing.T) {
+	jb := NewJitterBuffer(100, 200*time.Millisecond)
+	now := time.Now()
+
+	// Nothing was released yet, so only gaps between buffered packets count
+	jb.AddPacket(&Packet{SequenceNumber: 65534, Timestamp: 1000})
+	jb.AddPacket(&Packet{SequenceNumber: 65535, Timestamp: 1100})
+	assert.Empty(t, jb.DetectMissing())
+
+	for i := 0; i < 2; i++ {
+		_, err := jb.GetReadyPacket(now.Add(time.Second))
+		require.NoError(t, err)
+	}
+
+	// 0 and 1 are lost across the wrap, 3 between buffered packets
+	jb.AddPacket(&Packet{SequenceNumber: 2, Timestamp: 1400})
+	jb.AddPacket(&Packet{SequenceNumber: 4, Timestamp: 1600})
+	assert.Equal(t, []uint16{3}, jb.DetectGaps())
+	assert.Equal(t, []uint16{0, 1, 3}, jb.DetectMissing())
+}
+
 // TestJitterBuffer_BufferOverflow tests overflow handling
 func TestJitterBuffer_BufferOverflow(t *testing.T) {
 	jb := NewJitterBuffer(5, 100*time.Millisecond) // Small buffer
@@ -414,3 +436,168 @@ func TestCalculatePacketLossRate(t *testing.T) {
 		})
 	}
 }
+
+// TestJitterBuffer_GetReadyPacket_PlayoutDelay tests deadline-based release of in-order packets
+func TestJitterBuffer_GetReadyPacket_PlayoutDelay(t *testing.T) {
+	jb := NewJitterBuffer(100, 200*time.Millisecond)
+	jb.SetPlayoutDelay(50 * time.Millisecond)
+
+	jb.AddPacket(&Packet{SequenceNumber: 11, Timestamp: 1100})
+	jb.AddPacket(&Packet{SequenceNumber: 10, Timestamp: 1000})
+	now := time.Now()
+
+	_, err := jb.GetReadyPacket(now)
+	assert.ErrorIs(t, err, ErrPacketNotReady)
+
+	deadline, ok := jb.NextDeadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, now.Add(50*time.Millisecond), deadline, 10*time.Millisecond)
+
+	later := now.Add(60 * time.Millisecond)
+	p, err := jb.GetReadyPacket(later)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(10), p.SequenceNumber)
+
+	p, err = jb.GetReadyPacket(later)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(11), p.SequenceNumber)
+
+	_, err = jb.GetReadyPacket(later)
+	assert.ErrorIs(t, err, ErrBufferEmpty)
+	_, ok = jb.NextDeadline()
+	assert.False(t, ok)
+}
+
+// TestJitterBuffer_GetReadyPacket_SkipsLostAfterMaxDelay tests gap handling
+func TestJitterBuffer_GetReadyPacket_SkipsLostAfterMaxDelay(t *testing.T) {
+	jb := NewJitterBuffer(100, 100*time.Millisecond)
+	jb.SetPlayoutDelay(0)
+
+	jb.AddPacket(&Packet{SequenceNumber: 65535, Timestamp: 1000})
+	p, err := jb.GetReadyPacket(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(65535), p.SequenceNumber)
+
+	// 0 and 1 are missing
+	jb.AddPacket(&Packet{SequenceNumber: 2, Timestamp: 1300})
+	now := time.Now()
+
+	_, err = jb.GetReadyPacket(now.Add(50 * time.Millisecond))
+	assert.ErrorIs(t, err, ErrPacketNotReady, "gap must be held until maxDelay")
+
+	p, err = jb.GetReadyPacket(now.Add(110 * time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), p.SequenceNumber)
+
+	// A straggler for a skipped slot is rejected as late
+	err = jb.AddPacket(&Packet{SequenceNumber: 1, Timestamp: 1200})
+	assert.ErrorIs(t, err, ErrPacketLate)
+
+	stats := jb.GetStatistics()
+	assert.Equal(t, 2, stats.PacketsLost)
+	assert.Equal(t, 1, stats.PacketsLate)
+	assert.Equal(t, 0, stats.BufferSize)
+}
+
+// TestJitterBuffer_GetReadyPacket_LateFillsGap tests that a reordered packet arriving in time is played
+func TestJitterBuffer_GetReadyPacket_LateFillsGap(t *testing.T) {
+	jb := NewJitterBuffer(100, 100*time.Millisecond)
+	jb.SetPlayoutDelay(0)
+
+	jb.AddPacket(&Packet{SequenceNumber: 1})
+	_, err := jb.GetReadyPacket(time.Now())
+	require.NoError(t, err)
+
+	jb.AddPacket(&Packet{SequenceNumber: 3})
+	_, err = jb.GetReadyPacket(time.Now())
+	assert.ErrorIs(t, err, ErrPacketNotReady)
+
+	jb.AddPacket(&Packet{SequenceNumber: 2})
+	for _, expected := range []uint16{2, 3} {
+		p, err := jb.GetReadyPacket(time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, expected, p.SequenceNumber)
+	}
+	assert.Equal(t, 0, jb.GetStatistics().PacketsLost)
+}
+
+// TestJitterBuffer_DuplicateAfterPlayout tests that a resent packet is a duplicate, not late
+func TestJitterBuffer_DuplicateAfterPlayout(t *testing.T) {
+	jb := NewJitterBuffer(100, 100*time.Millisecond)
+	jb.SetPlayoutDelay(0)
+
+	for _, seq := range []uint16{1, 2, 3} {
+		require.NoError(t, jb.AddPacket(&Packet{SequenceNumber: seq}))
+	}
+	for range []uint16{1, 2, 3} {
+		_, err := jb.GetReadyPacket(time.Now())
+		require.NoError(t, err)
+	}
+
+	assert.ErrorIs(t, jb.AddPacket(&Packet{SequenceNumber: 2}), ErrDuplicatePacket)
+
+	stats := jb.GetStatistics()
+	assert.Equal(t, 1, stats.PacketsDuplicate)
+	assert.Zero(t, stats.PacketsLate)
+}
+
+// TestJitterBuffer_SequenceRestart tests resync when the sender restarts its sequence numbers
+func TestJitterBuffer_SequenceRestart(t *testing.T) {
+	jb := NewJitterBuffer(100, 100*time.Millisecond)
+	jb.SetPlayoutDelay(0)
+
+	for _, seq := range []uint16{100, 101, 102} {
+		require.NoError(t, jb.AddPacket(&Packet{SequenceNumber: seq}))
+	}
+	p, err := jb.GetReadyPacket(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(100), p.SequenceNumber)
+
+	// A single jump is held back
+	assert.ErrorIs(t, jb.AddPacket(&Packet{SequenceNumber: 30000}), ErrSequenceJump)
+	assert.Equal(t, 2, jb.Size())
+
+	// The next packet confirms the restart: the old stream is flushed
+	require.NoError(t, jb.AddPacket(&Packet{SequenceNumber: 30001}))
+	assert.Equal(t, 2, jb.Size())
+
+	for _, expected := range []uint16{30000, 30001} {
+		p, err := jb.GetReadyPacket(time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, expected, p.SequenceNumber)
+	}
+
+	stats := jb.GetStatistics()
+	assert.Equal(t, 1, stats.Restarts)
+	assert.Zero(t, stats.PacketsLost)
+	assert.Zero(t, stats.PacketsLate)
+}
+
+// TestJitterBuffer_AddRetransmission tests filling a gap without disturbing sequence state
+func TestJitterBuffer_AddRetransmission(t *testing.T) {
+	jb := NewJitterBuffer(100, 100*time.Millisecond)
+	jb.SetPlayoutDelay(0)
+
+	assert.ErrorIs(t, jb.AddRetransmission(&Packet{SequenceNumber: 1}), ErrPacketLate, "nothing to fill yet")
+
+	for _, seq := range []uint16{1, 2, 4} {
+		require.NoError(t, jb.AddPacket(&Packet{SequenceNumber: seq}))
+	}
+	p, err := jb.GetReadyPacket(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), p.SequenceNumber)
+
+	assert.ErrorIs(t, jb.AddRetransmission(&Packet{SequenceNumber: 1}), ErrPacketLate)
+	assert.ErrorIs(t, jb.AddRetransmission(&Packet{SequenceNumber: 2}), ErrDuplicatePacket)
+	require.NoError(t, jb.AddRetransmission(&Packet{SequenceNumber: 3}))
+
+	for _, expected := range []uint16{2, 3, 4} {
+		p, err := jb.GetReadyPacket(time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, expected, p.SequenceNumber)
+	}
+
+	stats := jb.GetStatistics()
+	assert.Equal(t, 1, stats.Retransmitted)
+	assert.Zero(t, stats.PacketsLost)
+}
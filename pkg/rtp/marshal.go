@@ -0,0 +1,186 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrBufferTooSmall indicates the destination buffer cannot hold the marshaled packet
+	ErrBufferTooSmall = errors.New("buffer too small")
+	// ErrInvalidPacket indicates packet fields cannot be encoded on the wire
+	ErrInvalidPacket = errors.New("invalid RTP packet")
+)
+
+// MarshalSize returns the number of bytes needed to marshal the packet
+func (p *Packet) MarshalSize() int {
+	size := 12 + len(p.CSRC)*4
+	if p.Extension {
+		size += 4 + p.extensionSize()
+	}
+	size += len(p.Payload)
+	if p.Padding {
+		size += int(p.PaddingSize)
+	}
+	return size
+}
+
+// Marshal serializes the packet into a newly allocated buffer
+func (p *Packet) Marshal() ([]byte, error) {
+	buf := make([]byte, p.MarshalSize())
+	n, err := p.MarshalTo(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// MarshalTo serializes the packet into buf and returns the number of bytes written.
+// When Extensions is set for an RFC 8285 profile the elements are encoded,
+// otherwise ExtensionPayload is written as-is (zero-padded to 32 bits).
+func (p *Packet) MarshalTo(buf []byte) (int, error) {
+	if len(p.CSRC) > 15 {
+		return 0, fmt.Errorf("%w: %d CSRCs (max 15)", ErrInvalidPacket, len(p.CSRC))
+	}
+	if p.PayloadType > 0x7F {
+		return 0, fmt.Errorf("%w: payload type %d", ErrInvalidPacket, p.PayloadType)
+	}
+	if p.Padding && p.PaddingSize == 0 {
+		return 0, fmt.Errorf("%w: padding flag set without padding size", ErrInvalidPacket)
+	}
+	if err := p.validateExtensions(); err != nil {
+		return 0, err
+	}
+
+	size := p.MarshalSize()
+	if len(buf) < size {
+		return 0, fmt.Errorf("%w: need %d bytes, have %d", ErrBufferTooSmall, size, len(buf))
+	}
+
+	// First byte: V(2)=2, P(1), X(1), CC(4)
+	buf[0] = 2<<6 | uint8(len(p.CSRC))
+	if p.Padding {
+		buf[0] |= 1 << 5
+	}
+	if p.Extension {
+		buf[0] |= 1 << 4
+	}
+
+	// Second byte: M(1), PT(7)
+	buf[1] = p.PayloadType
+	if p.Marker {
+		buf[1] |= 1 << 7
+	}
+
+	binary.BigEndian.PutUint16(buf[2:4], p.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[4:8], p.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:12], p.SSRC)
+
+	offset := 12
+	for _, csrc := range p.CSRC {
+		binary.BigEndian.PutUint32(buf[offset:offset+4], csrc)
+		offset += 4
+	}
+
+	if p.Extension {
+		extSize := p.extensionSize()
+		binary.BigEndian.PutUint16(buf[offset:offset+2], p.ExtensionProfile)
+		binary.BigEndian.PutUint16(buf[offset+2:offset+4], uint16(extSize/4))
+		offset += 4
+
+		ext := buf[offset : offset+extSize]
+		for i := range ext {
+			ext[i] = 0
+		}
+		p.writeExtension(ext)
+		offset += extSize
+	}
+
+	offset += copy(buf[offset:], p.Payload)
+
+	if p.Padding {
+		padding := buf[offset : offset+int(p.PaddingSize)]
+		for i := range padding {
+			padding[i] = 0
+		}
+		padding[len(padding)-1] = p.PaddingSize
+		offset += len(padding)
+	}
+
+	return offset, nil
+}
+
+// encodesElements reports whether the extension is built from Extensions
+func (p *Packet) encodesElements() bool {
+	return len(p.Extensions) > 0 && (isOneByteProfile(p.ExtensionProfile) || isTwoByteProfile(p.ExtensionProfile))
+}
+
+// extensionSize returns the extension body size in bytes, rounded up to 32 bits
+func (p *Packet) extensionSize() int {
+	size := len(p.ExtensionPayload)
+	if p.encodesElements() {
+		size = 0
+		for _, ext := range p.Extensions {
+			if isOneByteProfile(p.ExtensionProfile) {
+				size += 1 + len(ext.Payload)
+			} else {
+				size += 2 + len(ext.Payload)
+			}
+		}
+	}
+	return (size + 3) &^ 3
+}
+
+// validateExtensions checks that extension elements fit their profile
+func (p *Packet) validateExtensions() error {
+	if !p.Extension {
+		return nil
+	}
+	if p.extensionSize()/4 > 0xFFFF {
+		return fmt.Errorf("%w: header extension too long", ErrInvalidPacket)
+	}
+	if !p.encodesElements() {
+		return nil
+	}
+
+	for _, ext := range p.Extensions {
+		if isOneByteProfile(p.ExtensionProfile) {
+			if ext.ID < 1 || ext.ID > 14 {
+				return fmt.Errorf("%w: one-byte extension ID %d out of range", ErrInvalidPacket, ext.ID)
+			}
+			if len(ext.Payload) < 1 || len(ext.Payload) > 16 {
+				return fmt.Errorf("%w: one-byte extension %d has %d bytes", ErrInvalidPacket, ext.ID, len(ext.Payload))
+			}
+		} else {
+			if ext.ID == 0 {
+				return fmt.Errorf("%w: two-byte extension ID 0", ErrInvalidPacket)
+			}
+			if len(ext.Payload) > 255 {
+				return fmt.Errorf("%w: two-byte extension %d has %d bytes", ErrInvalidPacket, ext.ID, len(ext.Payload))
+			}
+		}
+	}
+	return nil
+}
+
+// writeExtension writes the extension body into buf (already zeroed)
+func (p *Packet) writeExtension(buf []byte) {
+	if !p.encodesElements() {
+		copy(buf, p.ExtensionPayload)
+		return
+	}
+
+	offset := 0
+	for _, ext := range p.Extensions {
+		if isOneByteProfile(p.ExtensionProfile) {
+			buf[offset] = ext.ID<<4 | uint8(len(ext.Payload)-1)
+			offset++
+		} else {
+			buf[offset] = ext.ID
+			buf[offset+1] = uint8(len(ext.Payload))
+			offset += 2
+		}
+		offset += copy(buf[offset:], ext.Payload)
+	}
+}
@@ -0,0 +1,178 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPacket_Marshal tests serialization round-trips through ParsePacket
+func TestPacket_Marshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *Packet
+	}{
+		{
+			name: "basic",
+			packet: &Packet{
+				Marker:         true,
+				PayloadType:    96,
+				SequenceNumber: 65535,
+				Timestamp:      0xFFFFFFFF,
+				SSRC:           0x12345678,
+				Payload:        []byte{0x65, 0x01, 0x02},
+			},
+		},
+		{
+			name: "CSRCs and padding",
+			packet: &Packet{
+				Padding:        true,
+				PaddingSize:    5,
+				PayloadType:    0,
+				SequenceNumber: 7,
+				Timestamp:      160,
+				SSRC:           1,
+				CSRC:           []uint32{0x11111111, 0x22222222},
+				Payload:        []byte{0xD5, 0xD5, 0xD5},
+			},
+		},
+		{
+			name: "one-byte extension elements",
+			packet: &Packet{
+				Extension:        true,
+				ExtensionProfile: ExtensionProfileOneByte,
+				Extensions: []ExtensionElement{
+					{ID: 3, Payload: []byte{0xAA, 0xBB, 0xCC}},
+					{ID: 5, Payload: []byte{0x7F}},
+				},
+				PayloadType: 96,
+				SSRC:        2,
+				Payload:     []byte{0x41},
+			},
+		},
+		{
+			name: "two-byte extension elements",
+			packet: &Packet{
+				Extension:        true,
+				ExtensionProfile: ExtensionProfileTwoByte,
+				Extensions: []ExtensionElement{
+					{ID: 1, Payload: []byte{}},
+					{ID: 16, Payload: []byte{0x01, 0x02, 0x03}},
+				},
+				PayloadType: 96,
+				SSRC:        3,
+				Payload:     []byte{0x41},
+			},
+		},
+		{
+			name: "raw extension payload",
+			packet: &Packet{
+				Extension:        true,
+				ExtensionProfile: ExtensionProfileONVIF,
+				ExtensionPayload: []byte{0xE1, 0xF8, 0x92, 0x34, 0x80, 0x00, 0x00, 0x00, 0xA0, 0x07, 0x00, 0x00},
+				PayloadType:      96,
+				SSRC:             4,
+				Payload:          []byte{0x41},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.packet.Marshal()
+			require.NoError(t, err)
+			assert.Len(t, data, tt.packet.MarshalSize())
+
+			parsed, err := ParsePacket(data)
+			require.NoError(t, err)
+
+			assert.Equal(t, uint8(2), parsed.Version)
+			assert.Equal(t, tt.packet.Marker, parsed.Marker)
+			assert.Equal(t, tt.packet.PayloadType, parsed.PayloadType)
+			assert.Equal(t, tt.packet.SequenceNumber, parsed.SequenceNumber)
+			assert.Equal(t, tt.packet.Timestamp, parsed.Timestamp)
+			assert.Equal(t, tt.packet.SSRC, parsed.SSRC)
+			assert.Equal(t, tt.packet.CSRC, parsed.CSRC)
+			assert.Equal(t, tt.packet.Padding, parsed.Padding)
+			assert.Equal(t, tt.packet.PaddingSize, parsed.PaddingSize)
+			assert.Equal(t, tt.packet.Extension, parsed.Extension)
+			assert.Equal(t, tt.packet.ExtensionProfile, parsed.ExtensionProfile)
+			assert.Equal(t, tt.packet.Payload, parsed.Payload)
+
+			for _, ext := range tt.packet.Extensions {
+				payload, ok := parsed.GetExtension(ext.ID)
+				require.True(t, ok)
+				assert.Equal(t, ext.Payload, payload)
+			}
+			if len(tt.packet.Extensions) == 0 && tt.packet.Extension {
+				assert.Equal(t, tt.packet.ExtensionPayload, parsed.ExtensionPayload)
+			}
+
+			// Re-marshaling the parsed packet is byte-identical
+			again, err := parsed.Marshal()
+			require.NoError(t, err)
+			assert.Equal(t, data, again)
+		})
+	}
+}
+
+// TestPacket_MarshalTo tests writing into caller-provided buffers
+func TestPacket_MarshalTo(t *testing.T) {
+	packet := &Packet{PayloadType: 96, SequenceNumber: 1, SSRC: 1, Payload: []byte{0x01, 0x02}}
+
+	buf := make([]byte, 64)
+	n, err := packet.MarshalTo(buf)
+	require.NoError(t, err)
+	assert.Equal(t, 14, n)
+	assert.Equal(t, byte(0x80), buf[0])
+	assert.Equal(t, byte(96), buf[1])
+
+	_, err = packet.MarshalTo(make([]byte, 13))
+	assert.ErrorIs(t, err, ErrBufferTooSmall)
+}
+
+// TestPacket_MarshalErrors tests rejection of unencodable packets
+func TestPacket_MarshalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet *Packet
+	}{
+		{
+			name:   "too many CSRCs",
+			packet: &Packet{CSRC: make([]uint32, 16)},
+		},
+		{
+			name:   "payload type out of range",
+			packet: &Packet{PayloadType: 128},
+		},
+		{
+			name:   "padding without size",
+			packet: &Packet{Padding: true},
+		},
+		{
+			name: "one-byte ID out of range",
+			packet: &Packet{
+				Extension:        true,
+				ExtensionProfile: ExtensionProfileOneByte,
+				Extensions:       []ExtensionElement{{ID: 15, Payload: []byte{0x01}}},
+			},
+		},
+		{
+			name: "one-byte element too long",
+			packet: &Packet{
+				Extension:        true,
+				ExtensionProfile: ExtensionProfileOneByte,
+				Extensions:       []ExtensionElement{{ID: 1, Payload: make([]byte, 17)}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.packet.Marshal()
+			assert.ErrorIs(t, err, ErrInvalidPacket)
+			assert.Nil(t, data)
+		})
+	}
+}
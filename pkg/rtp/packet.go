@@ -23,74 +23,117 @@ type Packet struct {
 	SequenceNumber uint16
 	Timestamp      uint32
 	SSRC           uint32
+	CSRC           []uint32 // Contributing sources (set by mixers)
 	Payload        []byte
+	PaddingSize    uint8 // Number of padding bytes (including the count byte) when Padding is set
+
+	// Header extension (present when Extension is true)
+	ExtensionProfile uint16             // "defined by profile" field, e.g. 0xBEDE for RFC 8285 one-byte headers
+	ExtensionPayload []byte             // Raw extension data, without the 4-byte extension header
+	Extensions       []ExtensionElement // Parsed RFC 8285 elements (one-byte or two-byte profiles only)
+
+	// wire aliases the bytes Unmarshal parsed. FEC covers the packet exactly
+	// as sent, which Marshal cannot reproduce for extensions and padding.
+	wire []byte
+}
+
+// PacketReader is a source of RTP packets, such as an RTSP client or a capture file.
+// Readers return io.EOF when a finite source is exhausted.
+type PacketReader interface {
+	ReadPacket() (*Packet, error)
 }
 
 // ParsePacket parses raw bytes into an RTP packet
 func ParsePacket(data []byte) (*Packet, error) {
-	if len(data) < 12 {
-		return nil, ErrPacketTooShort
+	packet := &Packet{}
+	if err := packet.Unmarshal(data); err != nil {
+		return nil, err
 	}
+	return packet, nil
+}
 
-	packet := &Packet{}
+// Unmarshal parses raw bytes into p, reusing its CSRC and Extensions storage.
+// Payload and extension data alias data, so data must outlive the packet.
+func (p *Packet) Unmarshal(data []byte) error {
+	if len(data) < 12 {
+		return ErrPacketTooShort
+	}
 
 	// Parse first byte: V(2), P(1), X(1), CC(4)
-	packet.Version = (data[0] >> 6) & 0x03
-	if packet.Version != 2 {
-		return nil, ErrInvalidVersion
+	p.Version = (data[0] >> 6) & 0x03
+	if p.Version != 2 {
+		return ErrInvalidVersion
 	}
 
-	packet.Padding = (data[0]>>5)&0x01 == 1
-	packet.Extension = (data[0]>>4)&0x01 == 1
+	p.Padding = (data[0]>>5)&0x01 == 1
+	p.Extension = (data[0]>>4)&0x01 == 1
 	csrcCount := data[0] & 0x0F
 
 	// Parse second byte: M(1), PT(7)
-	packet.Marker = (data[1]>>7)&0x01 == 1
-	packet.PayloadType = data[1] & 0x7F
+	p.Marker = (data[1]>>7)&0x01 == 1
+	p.PayloadType = data[1] & 0x7F
 
 	// Parse sequence number (2 bytes)
-	packet.SequenceNumber = binary.BigEndian.Uint16(data[2:4])
+	p.SequenceNumber = binary.BigEndian.Uint16(data[2:4])
 
 	// Parse timestamp (4 bytes)
-	packet.Timestamp = binary.BigEndian.Uint32(data[4:8])
+	p.Timestamp = binary.BigEndian.Uint32(data[4:8])
 
 	// Parse SSRC (4 bytes)
-	packet.SSRC = binary.BigEndian.Uint32(data[8:12])
+	p.SSRC = binary.BigEndian.Uint32(data[8:12])
 
 	// Calculate header size
 	headerSize := 12 + int(csrcCount)*4
 
 	if len(data) < headerSize {
-		return nil, ErrPacketTooShort
+		return ErrPacketTooShort
+	}
+
+	// Parse CSRC identifiers (4 bytes each)
+	p.CSRC = p.CSRC[:0]
+	for i := 0; i < int(csrcCount); i++ {
+		offset := 12 + i*4
+		p.CSRC = append(p.CSRC, binary.BigEndian.Uint32(data[offset:offset+4]))
 	}
 
 	// Handle extension if present
-	if packet.Extension {
+	p.ExtensionProfile = 0
+	p.ExtensionPayload = nil
+	p.Extensions = p.Extensions[:0]
+	if p.Extension {
 		if len(data) < headerSize+4 {
-			return nil, ErrPacketTooShort
+			return ErrPacketTooShort
 		}
-		extensionLength := binary.BigEndian.Uint16(data[headerSize+2:headerSize+4]) * 4
-		headerSize += 4 + int(extensionLength)
-	}
+		p.ExtensionProfile = binary.BigEndian.Uint16(data[headerSize : headerSize+2])
+		extensionLength := int(binary.BigEndian.Uint16(data[headerSize+2:headerSize+4])) * 4
+		extensionStart := headerSize + 4
+		headerSize = extensionStart + extensionLength
 
-	if len(data) < headerSize {
-		return nil, ErrPacketTooShort
+		if len(data) < headerSize {
+			return ErrPacketTooShort
+		}
+
+		p.ExtensionPayload = data[extensionStart:headerSize]
+		p.Extensions = appendExtensionElements(p.Extensions, p.ExtensionProfile, p.ExtensionPayload)
 	}
 
 	// Extract payload
 	payload := data[headerSize:]
 
 	// Handle padding if present
-	if packet.Padding && len(payload) > 0 {
+	p.PaddingSize = 0
+	if p.Padding && len(payload) > 0 {
 		paddingLength := int(payload[len(payload)-1])
 		if paddingLength <= len(payload) {
 			payload = payload[:len(payload)-paddingLength]
+			p.PaddingSize = uint8(paddingLength)
 		}
 	}
 
-	packet.Payload = payload
+	p.Payload = payload
+	p.wire = data
 
-	return packet, nil
+	return nil
 }
 
 // IsKeyFrame checks if the packet contains a keyframe (IDR frame for H.264)
@@ -0,0 +1,67 @@
+package rtp
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultPacketBufferSize fits a standard 1500-byte MTU datagram with headroom
+	DefaultPacketBufferSize = 2048
+	// MaxPacketBufferSize fits the largest UDP datagram or interleaved frame
+	MaxPacketBufferSize = 65535
+)
+
+// PooledPacket is an RTP packet backed by a reusable receive buffer.
+// The packet (and any slice of it) must not be used after Release.
+type PooledPacket struct {
+	Packet
+	buf  []byte
+	pool *PacketPool
+}
+
+// Buffer returns the receive buffer to read the raw packet into
+func (p *PooledPacket) Buffer() []byte {
+	return p.buf
+}
+
+// Release returns the packet and its buffer to the pool
+func (p *PooledPacket) Release() {
+	if p.pool == nil {
+		return
+	}
+	pool := p.pool
+	p.pool = nil
+	pool.pool.Put(p)
+}
+
+// PacketPool recycles packets and their receive buffers to avoid per-packet allocations
+type PacketPool struct {
+	pool       sync.Pool
+	bufferSize int
+}
+
+// NewPacketPool creates a pool whose packets carry bufferSize-byte receive buffers.
+// Sizes outside (0, MaxPacketBufferSize] fall back to DefaultPacketBufferSize.
+func NewPacketPool(bufferSize int) *PacketPool {
+	if bufferSize <= 0 || bufferSize > MaxPacketBufferSize {
+		bufferSize = DefaultPacketBufferSize
+	}
+	return &PacketPool{bufferSize: bufferSize}
+}
+
+// Get returns a packet from the pool, allocating one if the pool is empty
+func (p *PacketPool) Get() *PooledPacket {
+	if pp, ok := p.pool.Get().(*PooledPacket); ok {
+		pp.pool = p
+		return pp
+	}
+	return &PooledPacket{
+		buf:  make([]byte, p.bufferSize),
+		pool: p,
+	}
+}
+
+// GetBufferSize returns the receive buffer size of pooled packets
+func (p *PacketPool) GetBufferSize() int {
+	return p.bufferSize
+}
@@ -0,0 +1,97 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPacketPool tests buffer sizing and release semantics
+func TestPacketPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		expected int
+	}{
+		{"default for zero", 0, DefaultPacketBufferSize},
+		{"default for oversize", MaxPacketBufferSize + 1, DefaultPacketBufferSize},
+		{"jumbo", 9000, 9000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NewPacketPool(tt.size)
+			assert.Equal(t, tt.expected, pool.GetBufferSize())
+
+			pp := pool.Get()
+			assert.Len(t, pp.Buffer(), tt.expected)
+			pp.Release()
+			pp.Release() // Double release is a no-op
+		})
+	}
+}
+
+// TestPacket_UnmarshalReuse tests that Unmarshal resets fields from a previous packet
+func TestPacket_UnmarshalReuse(t *testing.T) {
+	withExtras := &Packet{
+		Padding:          true,
+		PaddingSize:      4,
+		CSRC:             []uint32{1, 2},
+		Extension:        true,
+		ExtensionProfile: ExtensionProfileOneByte,
+		Extensions:       []ExtensionElement{{ID: 1, Payload: []byte{0xAA}}},
+		PayloadType:      96,
+		Payload:          []byte{0x65},
+	}
+	first, err := withExtras.Marshal()
+	require.NoError(t, err)
+
+	plain, err := (&Packet{PayloadType: 96, SequenceNumber: 2, Payload: []byte{0x41}}).Marshal()
+	require.NoError(t, err)
+
+	var packet Packet
+	require.NoError(t, packet.Unmarshal(first))
+	assert.Len(t, packet.CSRC, 2)
+	assert.Len(t, packet.Extensions, 1)
+
+	require.NoError(t, packet.Unmarshal(plain))
+	assert.Empty(t, packet.CSRC)
+	assert.False(t, packet.Extension)
+	assert.Empty(t, packet.Extensions)
+	assert.Nil(t, packet.ExtensionPayload)
+	assert.False(t, packet.Padding)
+	assert.Zero(t, packet.PaddingSize)
+	assert.Equal(t, []byte{0x41}, packet.Payload)
+
+	assert.ErrorIs(t, packet.Unmarshal([]byte{0x80}), ErrPacketTooShort)
+}
+
+func BenchmarkParsePacket(b *testing.B) {
+	data, _ := (&Packet{PayloadType: 96, Payload: make([]byte, 1400)}).Marshal()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParsePacket(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPacket_Unmarshal(b *testing.B) {
+	data, _ := (&Packet{
+		PayloadType:      96,
+		Extension:        true,
+		ExtensionProfile: ExtensionProfileOneByte,
+		Extensions:       []ExtensionElement{{ID: 1, Payload: []byte{0x01, 0x02, 0x03}}},
+		Payload:          make([]byte, 1400),
+	}).Marshal()
+	var packet Packet
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := packet.Unmarshal(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package rtp
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RTCP transmission interval parameters (RFC 3550 section 6.3 and A.7)
+const (
+	// RTCPMinInterval is the minimum average interval between reports
+	RTCPMinInterval = 5 * time.Second
+	// rtcpCompensation corrects the randomized interval for timer reconsideration (e - 3/2)
+	rtcpCompensation = 2.71828 - 1.5
+	// rtcpReceiverShare is the fraction of RTCP bandwidth shared by receivers
+	rtcpReceiverShare = 0.75
+	// rtcpSenderShare is the fraction of RTCP bandwidth shared by senders
+	rtcpSenderShare = 0.25
+)
+
+// ReceptionStats tracks reception statistics for one RTP source, as needed for
+// RTCP receiver report blocks (RFC 3550 sections 6.4.1 and A.3)
+type ReceptionStats struct {
+	mu               sync.Mutex
+	ssrc             uint32
+	clockRate        uint32
+	source           SourceState
+	jitter           uint32 // Interarrival jitter in RTP timestamp units
+	lastRTPTimestamp uint32
+	lastArrival      time.Time
+	lastSR           uint32    // Middle 32 bits of the last SR NTP timestamp
+	lastSRArrival    time.Time // When the last SR was received
+}
+
+// ReceptionStatistics is a snapshot of ReceptionStats
+type ReceptionStatistics struct {
+	SSRC               uint32
+	PacketsReceived    uint32
+	PacketsLost        int32
+	PacketsDuplicate   uint32
+	Restarts           uint32 // Sender restarts detected without an SSRC change
+	ExtendedHighestSeq uint32
+	Jitter             uint32 // RTP timestamp units
+	LastSR             uint32
+	LastSRArrival      time.Time
+}
+
+// NewReceptionStats creates reception statistics for a source
+func NewReceptionStats(ssrc uint32, clockRate uint32) *ReceptionStats {
+	if clockRate == 0 {
+		clockRate = 90000
+	}
+	return &ReceptionStats{
+		ssrc:      ssrc,
+		clockRate: clockRate,
+	}
+}
+
+// Update records a received RTP packet. Packets from a source still on
+// probation, duplicates and unconfirmed sequence jumps are not counted.
+func (s *ReceptionStats) Update(packet *Packet, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.source.Update(packet.SequenceNumber).Valid() {
+		return
+	}
+
+	calculateInterarrivalJitter(&s.jitter, packet.Timestamp, arrival, &s.lastRTPTimestamp, &s.lastArrival, s.clockRate)
+}
+
+// UpdateSR records a Sender Report from the source for LSR/DLSR
+func (s *ReceptionStats) UpdateSR(sr *SenderReport, arrival time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastSR = uint32(sr.NTPTimestamp >> 16)
+	s.lastSRArrival = arrival
+}
+
+// ReportBlock builds a report block and starts a new reporting interval
+func (s *ReceptionStats) ReportBlock(now time.Time) ReportBlock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expectedInterval, receivedInterval := s.source.Interval()
+
+	rb := ReportBlock{
+		SSRC:         s.ssrc,
+		FractionLost: calculateFractionLost(int(expectedInterval), int(receivedInterval)),
+		PacketsLost:  s.source.Lost(),
+		HighestSeq:   s.source.ExtendedMaxSeq(),
+		Jitter:       s.jitter,
+		LSR:          s.lastSR,
+	}
+
+	// DLSR is expressed in units of 1/65536 seconds
+	if !s.lastSRArrival.IsZero() {
+		rb.DLSR = uint32(now.Sub(s.lastSRArrival) * 65536 / time.Second)
+	}
+
+	return rb
+}
+
+// GetStatistics returns a snapshot of the statistics
+func (s *ReceptionStats) GetStatistics() ReceptionStatistics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source := s.source.GetStatistics()
+	return ReceptionStatistics{
+		SSRC:               s.ssrc,
+		PacketsReceived:    source.PacketsReceived,
+		PacketsLost:        source.PacketsLost,
+		PacketsDuplicate:   source.PacketsDuplicate,
+		Restarts:           source.Restarts,
+		ExtendedHighestSeq: source.ExtendedMaxSeq,
+		Jitter:             s.jitter,
+		LastSR:             s.lastSR,
+		LastSRArrival:      s.lastSRArrival,
+	}
+}
+
+// RTCPInterval computes the randomized RTCP transmission interval (RFC 3550 section 6.3.1).
+// rtcpBandwidth is in bytes per second (typically 5% of the session bandwidth),
+// avgRTCPSize is the average compound packet size in bytes including UDP/IP headers.
+func RTCPInterval(members, senders int, rtcpBandwidth, avgRTCPSize float64, weSent, initial bool) time.Duration {
+	minInterval := RTCPMinInterval.Seconds()
+	if initial {
+		minInterval /= 2
+	}
+
+	// Senders and receivers share the RTCP bandwidth separately when senders are few
+	n := float64(members)
+	if senders > 0 && float64(senders) <= float64(members)*rtcpSenderShare {
+		if weSent {
+			rtcpBandwidth *= rtcpSenderShare
+			n = float64(senders)
+		} else {
+			rtcpBandwidth *= rtcpReceiverShare
+			n = float64(members - senders)
+		}
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	interval := minInterval
+	if rtcpBandwidth > 0 {
+		interval = math.Max(avgRTCPSize*n/rtcpBandwidth, minInterval)
+	}
+
+	// Randomize to [0.5, 1.5] times the interval to avoid synchronization
+	interval *= rand.Float64() + 0.5
+	interval /= rtcpCompensation
+
+	return time.Duration(interval * float64(time.Second))
+}
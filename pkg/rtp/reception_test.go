@@ -0,0 +1,112 @@
+package rtp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReceptionStats_ReportBlock tests loss accounting across intervals and wraparound
+func TestReceptionStats_ReportBlock(t *testing.T) {
+	stats := NewReceptionStats(0xCAFE, 90000)
+	start := time.Now()
+
+	receive := func(seqs ...uint16) {
+		for _, seq := range seqs {
+			stats.Update(&Packet{SSRC: 0xCAFE, SequenceNumber: seq, Timestamp: uint32(seq) * 3000}, start.Add(time.Duration(seq)*time.Millisecond))
+		}
+	}
+
+	// 65532 is consumed by probation; first interval: 65533..65535, 0, 2 (1 lost), reordered 65534
+	receive(65532, 65533, 65535, 65534, 0, 2)
+
+	rb := stats.ReportBlock(start)
+	assert.Equal(t, uint32(0xCAFE), rb.SSRC)
+	assert.Equal(t, uint32(1<<16|2), rb.HighestSeq)
+	assert.Equal(t, int32(1), rb.PacketsLost)
+	assert.Equal(t, uint8(256/6), rb.FractionLost)
+	assert.Zero(t, rb.LSR)
+	assert.Zero(t, rb.DLSR)
+
+	// Second interval: no loss, so fraction resets while cumulative loss stays
+	receive(3, 4, 5)
+	rb = stats.ReportBlock(start)
+	assert.Equal(t, int32(1), rb.PacketsLost)
+	assert.Zero(t, rb.FractionLost)
+
+	snapshot := stats.GetStatistics()
+	assert.Equal(t, uint32(8), snapshot.PacketsReceived)
+	assert.Equal(t, uint32(1<<16|5), snapshot.ExtendedHighestSeq)
+}
+
+// TestReceptionStats_LSR tests LSR/DLSR derived from Sender Reports
+func TestReceptionStats_LSR(t *testing.T) {
+	stats := NewReceptionStats(1, 0)
+	arrival := time.Now()
+
+	stats.UpdateSR(&SenderReport{SSRC: 1, NTPTimestamp: 0xE1F8923456789ABC}, arrival)
+	rb := stats.ReportBlock(arrival.Add(1500 * time.Millisecond))
+
+	assert.Equal(t, uint32(0x92345678), rb.LSR)
+	assert.Equal(t, uint32(65536*3/2), rb.DLSR)
+}
+
+// TestReceptionStats_Jitter tests that jitter grows with variance and decays with steady arrivals
+func TestReceptionStats_Jitter(t *testing.T) {
+	stats := NewReceptionStats(1, 90000)
+	start := time.Now()
+
+	// 40 ms frame spacing, one packet 20 ms late
+	arrivals := []time.Duration{0, 40, 100, 120, 160, 200, 240, 280}
+	for i, ms := range arrivals {
+		stats.Update(&Packet{SequenceNumber: uint16(i), Timestamp: uint32(i) * 3600}, start.Add(ms*time.Millisecond))
+	}
+	peak := stats.GetStatistics().Jitter
+	assert.Greater(t, peak, uint32(0))
+
+	for i := len(arrivals); i < 100; i++ {
+		stats.Update(&Packet{SequenceNumber: uint16(i), Timestamp: uint32(i) * 3600}, start.Add(time.Duration(i*40)*time.Millisecond))
+	}
+	assert.Less(t, stats.GetStatistics().Jitter, peak, "jitter must decay with steady arrivals")
+}
+
+// TestReceptionStats_JitterReordered tests that a packet with an earlier
+// timestamp than its predecessor does not wrap the transit difference
+func TestReceptionStats_JitterReordered(t *testing.T) {
+	stats := NewReceptionStats(1, 90000)
+	start := time.Now()
+
+	// 40 ms frame spacing; packet 6 arrives before packet 5
+	order := []int{0, 1, 2, 3, 4, 6, 5, 7, 8}
+	for i, n := range order {
+		stats.Update(&Packet{SequenceNumber: uint16(n), Timestamp: uint32(n) * 3600}, start.Add(time.Duration(i*40)*time.Millisecond))
+	}
+
+	// The swap gives transit differences of one, two and one frame:
+	// at most (3600+7200+3600)/16
+	jitter := stats.ReportBlock(start).Jitter
+	assert.Greater(t, jitter, uint32(0))
+	assert.LessOrEqual(t, jitter, uint32(4*3600/16), "jitter=%d", jitter)
+}
+
+// TestRTCPInterval tests RFC 3550 interval bounds
+func TestRTCPInterval(t *testing.T) {
+	compensation := 2.71828 - 1.5
+	min := time.Duration(float64(RTCPMinInterval) * 0.5 / compensation)
+	max := time.Duration(float64(RTCPMinInterval) * 1.5 / compensation)
+
+	for i := 0; i < 100; i++ {
+		interval := RTCPInterval(2, 1, 12500, 100, false, false)
+		require.GreaterOrEqual(t, interval, min)
+		require.LessOrEqual(t, interval, max)
+
+		initial := RTCPInterval(2, 1, 12500, 100, false, true)
+		require.LessOrEqual(t, initial, max/2)
+	}
+
+	// Large groups are bandwidth bound rather than minimum-interval bound
+	big := RTCPInterval(10000, 1, 1250, 100, false, false)
+	assert.Greater(t, big, 5*RTCPMinInterval)
+}
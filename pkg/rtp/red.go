@@ -0,0 +1,129 @@
+package rtp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidRED indicates a RED payload could not be parsed
+var ErrInvalidRED = errors.New("invalid RED payload")
+
+// REDBlock is one encoding carried in a RED (RFC 2198) payload
+type REDBlock struct {
+	PayloadType     uint8
+	TimestampOffset uint16 // Subtracted from the RTP timestamp; zero for the primary block
+	Payload         []byte
+}
+
+// ParseRED splits a RED payload into its blocks. The primary encoding is
+// the last block. Block payloads alias payload.
+func ParseRED(payload []byte) ([]REDBlock, error) {
+	var blocks []REDBlock
+	var lengths []int
+
+	// Redundant block headers are 4 bytes with the F bit set; the primary header is 1 byte
+	offset := 0
+	for {
+		if offset >= len(payload) {
+			return nil, fmt.Errorf("%w: missing primary block header", ErrInvalidRED)
+		}
+		if payload[offset]&0x80 == 0 {
+			blocks = append(blocks, REDBlock{PayloadType: payload[offset] & 0x7F})
+			offset++
+			break
+		}
+		if offset+4 > len(payload) {
+			return nil, fmt.Errorf("%w: truncated block header", ErrInvalidRED)
+		}
+		header := uint32(payload[offset])<<24 | uint32(payload[offset+1])<<16 | uint32(payload[offset+2])<<8 | uint32(payload[offset+3])
+		blocks = append(blocks, REDBlock{
+			PayloadType:     uint8(header>>24) & 0x7F,
+			TimestampOffset: uint16(header>>10) & 0x3FFF,
+		})
+		lengths = append(lengths, int(header&0x3FF))
+		offset += 4
+	}
+
+	for i, length := range lengths {
+		if offset+length > len(payload) {
+			return nil, fmt.Errorf("%w: block %d length %d exceeds payload", ErrInvalidRED, i, length)
+		}
+		blocks[i].Payload = payload[offset : offset+length]
+		offset += length
+	}
+	blocks[len(blocks)-1].Payload = payload[offset:]
+
+	return blocks, nil
+}
+
+// MarshalRED builds a RED payload; the last block is the primary encoding
+func MarshalRED(blocks []REDBlock) ([]byte, error) {
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: no blocks", ErrInvalidRED)
+	}
+
+	size := 1
+	for i, block := range blocks {
+		if block.PayloadType > 0x7F {
+			return nil, fmt.Errorf("%w: payload type %d", ErrInvalidRED, block.PayloadType)
+		}
+		if i < len(blocks)-1 {
+			if len(block.Payload) > 0x3FF || block.TimestampOffset > 0x3FFF {
+				return nil, fmt.Errorf("%w: redundant block %d too large", ErrInvalidRED, i)
+			}
+			size += 4
+		}
+		size += len(block.Payload)
+	}
+
+	buf := make([]byte, 0, size)
+	for _, block := range blocks[:len(blocks)-1] {
+		buf = append(buf,
+			0x80|block.PayloadType,
+			byte(block.TimestampOffset>>6),
+			byte(block.TimestampOffset<<2)|byte(len(block.Payload)>>8),
+			byte(len(block.Payload)))
+	}
+	primary := blocks[len(blocks)-1]
+	buf = append(buf, primary.PayloadType)
+	for _, block := range blocks {
+		buf = append(buf, block.Payload...)
+	}
+
+	return buf, nil
+}
+
+// DecapsulateRED converts a RED packet into the packets it carries, oldest
+// first. The primary block keeps the RED packet's sequence number, timestamp
+// and marker; redundant blocks are assumed to repeat the immediately
+// preceding packets and get the sequence numbers before it.
+func DecapsulateRED(packet *Packet) ([]*Packet, error) {
+	blocks, err := ParseRED(packet.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := make([]*Packet, len(blocks))
+	for i, block := range blocks {
+		behind := uint16(len(blocks) - 1 - i)
+		packets[i] = &Packet{
+			Version:        2,
+			Marker:         packet.Marker && behind == 0,
+			PayloadType:    block.PayloadType,
+			SequenceNumber: packet.SequenceNumber - behind,
+			Timestamp:      packet.Timestamp - uint32(block.TimestampOffset),
+			SSRC:           packet.SSRC,
+			CSRC:           packet.CSRC,
+			Payload:        block.Payload,
+		}
+	}
+
+	// Header extensions describe the packet as sent, i.e. the primary encoding
+	primary := packets[len(packets)-1]
+	primary.Extension = packet.Extension
+	primary.ExtensionProfile = packet.ExtensionProfile
+	primary.ExtensionPayload = packet.ExtensionPayload
+	primary.Extensions = packet.Extensions
+
+	return packets, nil
+}
@@ -0,0 +1,70 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRED_RoundTrip tests marshaling and parsing primary and redundant blocks
+func TestRED_RoundTrip(t *testing.T) {
+	blocks := []REDBlock{
+		{PayloadType: 111, TimestampOffset: 960, Payload: []byte{1, 2, 3}},
+		{PayloadType: 111, TimestampOffset: 480, Payload: []byte{4}},
+		{PayloadType: 111, Payload: []byte{5, 6}},
+	}
+
+	data, err := MarshalRED(blocks)
+	require.NoError(t, err)
+	assert.Len(t, data, 4+4+1+6)
+
+	parsed, err := ParseRED(data)
+	require.NoError(t, err)
+	assert.Equal(t, blocks, parsed)
+}
+
+// TestParseRED_Errors tests malformed RED payloads
+func TestParseRED_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"only redundant header", []byte{0x80 | 96, 0x00, 0x04, 0x02}},
+		{"truncated header", []byte{0x80 | 96, 0x00}},
+		{"block longer than payload", []byte{0x80 | 96, 0x00, 0x04, 0x10, 96, 0xAA}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRED(tt.data)
+			assert.ErrorIs(t, err, ErrInvalidRED)
+		})
+	}
+}
+
+// TestDecapsulateRED tests the packets produced from a RED packet
+func TestDecapsulateRED(t *testing.T) {
+	payload, err := MarshalRED([]REDBlock{
+		{PayloadType: 96, TimestampOffset: 3000, Payload: []byte{0x41, 0x01}},
+		{PayloadType: 96, Payload: []byte{0x41, 0x02}},
+	})
+	require.NoError(t, err)
+
+	red := &Packet{Version: 2, Marker: true, PayloadType: 116, SequenceNumber: 0, Timestamp: 6000, SSRC: 7, Payload: payload}
+	packets, err := DecapsulateRED(red)
+	require.NoError(t, err)
+	require.Len(t, packets, 2)
+
+	assert.Equal(t, uint16(65535), packets[0].SequenceNumber)
+	assert.Equal(t, uint32(3000), packets[0].Timestamp)
+	assert.False(t, packets[0].Marker)
+
+	assert.Equal(t, uint8(96), packets[1].PayloadType)
+	assert.Equal(t, uint16(0), packets[1].SequenceNumber)
+	assert.Equal(t, uint32(6000), packets[1].Timestamp)
+	assert.Equal(t, uint32(7), packets[1].SSRC)
+	assert.True(t, packets[1].Marker)
+	assert.Equal(t, []byte{0x41, 0x02}, packets[1].Payload)
+}
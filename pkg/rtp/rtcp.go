@@ -3,7 +3,7 @@ package rtp
 import (
 	"encoding/binary"
 	"errors"
-	"sync"
+	"fmt"
 	"time"
 
 	"github.com/rtsp-client/pkg/logger"
@@ -14,15 +14,27 @@ var (
 	ErrRTCPPacketTooShort = errors.New("RTCP packet too short")
 	// ErrInvalidRTCPVersion indicates invalid RTCP version
 	ErrInvalidRTCPVersion = errors.New("invalid RTCP version")
+	// ErrUnknownRTCPPacketType indicates an RTCP packet type or format that is not parsed
+	ErrUnknownRTCPPacketType = errors.New("unknown RTCP packet type")
 )
 
 // RTCP Packet Types
 const (
-	RTCP_SR   = 200 // Sender Report
-	RTCP_RR   = 201 // Receiver Report
-	RTCP_SDES = 202 // Source Description
-	RTCP_BYE  = 203 // Goodbye
-	RTCP_APP  = 204 // Application-Defined
+	RTCP_SR    = 200 // Sender Report
+	RTCP_RR    = 201 // Receiver Report
+	RTCP_SDES  = 202 // Source Description
+	RTCP_BYE   = 203 // Goodbye
+	RTCP_APP   = 204 // Application-Defined
+	RTCP_RTPFB = 205 // Transport-layer feedback (RFC 4585)
+	RTCP_PSFB  = 206 // Payload-specific feedback (RFC 4585)
+	RTCP_XR    = 207 // Extended Report (RFC 3611)
+)
+
+// RTCP feedback message types (FMT field)
+const (
+	RTPFB_NACK = 1 // Generic NACK (RFC 4585 section 6.2.1)
+	PSFB_PLI   = 1 // Picture Loss Indication (RFC 4585 section 6.3.1)
+	PSFB_FIR   = 4 // Full Intra Request (RFC 5104 section 4.3.1)
 )
 
 // SDES Item Types
@@ -67,9 +79,10 @@ func (sr *SenderReport) GetSSRC() uint32 {
 
 // ReceiverReport represents an RTCP RR packet
 type ReceiverReport struct {
-	PacketType  uint8
-	SSRC        uint32
-	ReportBlock *ReportBlock
+	PacketType   uint8
+	SSRC         uint32
+	ReportBlock  *ReportBlock  // First report block, if any
+	ReportBlocks []ReportBlock // All report blocks
 }
 
 // GetPacketType returns the packet type
@@ -144,6 +157,46 @@ func (bye *BYEPacket) GetSSRC() uint32 {
 	return 0
 }
 
+// APPPacket represents an RTCP APP packet
+type APPPacket struct {
+	PacketType uint8
+	Subtype    uint8
+	SSRC       uint32
+	Name       string // Four ASCII characters
+	Data       []byte // Application-dependent data (multiple of 4 bytes)
+}
+
+// GetPacketType returns the packet type
+func (app *APPPacket) GetPacketType() uint8 {
+	return app.PacketType
+}
+
+// GetSSRC returns the SSRC
+func (app *APPPacket) GetSSRC() uint32 {
+	return app.SSRC
+}
+
+// RawRTCPPacket holds an RTCP packet whose type or format is not understood.
+// It is kept so compound packets lose nothing and handlers can inspect it.
+type RawRTCPPacket struct {
+	PacketType uint8
+	Count      uint8  // RC/SC/FMT/subtype field from the header
+	Payload    []byte // Everything after the 4-byte header, without padding
+}
+
+// GetPacketType returns the packet type
+func (raw *RawRTCPPacket) GetPacketType() uint8 {
+	return raw.PacketType
+}
+
+// GetSSRC returns the SSRC that follows the header, if present
+func (raw *RawRTCPPacket) GetSSRC() uint32 {
+	if len(raw.Payload) >= 4 {
+		return binary.BigEndian.Uint32(raw.Payload[0:4])
+	}
+	return 0
+}
+
 // CompoundRTCPPacket represents multiple RTCP packets
 type CompoundRTCPPacket struct {
 	Packets []RTCPPacket
@@ -165,21 +218,82 @@ func (c *CompoundRTCPPacket) GetSSRC() uint32 {
 	return 0
 }
 
-// ParseRTCPPacket parses RTCP packet from bytes
+// FlattenRTCPPacket returns the sub-packets of a compound packet, or the packet itself
+func FlattenRTCPPacket(packet RTCPPacket) []RTCPPacket {
+	if compound, ok := packet.(*CompoundRTCPPacket); ok {
+		return compound.Packets
+	}
+	if packet == nil {
+		return nil
+	}
+	return []RTCPPacket{packet}
+}
+
+// ParseRTCPPacket parses RTCP packet from bytes. Only the first packet of a
+// compound packet is returned; use ParseCompoundRTCPPacket to get all of them.
 func ParseRTCPPacket(data []byte) (RTCPPacket, error) {
+	compound, err := ParseCompoundRTCPPacket(data)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := compound.Packets[0].(*RawRTCPPacket); ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownRTCPPacketType, raw.PacketType)
+	}
+	return compound.Packets[0], nil
+}
+
+// ParseCompoundRTCPPacket splits a compound packet using each header's length field.
+// Unknown types are kept as *RawRTCPPacket, as are malformed packets after the
+// first one; a malformed first packet fails the parse.
+func ParseCompoundRTCPPacket(data []byte) (*CompoundRTCPPacket, error) {
 	if len(data) < 4 {
 		return nil, ErrRTCPPacketTooShort
 	}
 
-	// Parse header
-	version := (data[0] >> 6) & 0x03
-	if version != 2 {
-		return nil, ErrInvalidRTCPVersion
+	compound := &CompoundRTCPPacket{}
+	for len(data) >= 4 {
+		// Parse header
+		version := (data[0] >> 6) & 0x03
+		if version != 2 {
+			if len(compound.Packets) == 0 {
+				return nil, ErrInvalidRTCPVersion
+			}
+			logger.Debug("[RTCP:Parse] Ignoring %d trailing bytes with invalid version", len(data))
+			break
+		}
+
+		// Lenient with length fields that overrun the datagram
+		size := (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+		if size > len(data) {
+			size = len(data)
+		}
+		packetData := data[:size]
+		data = data[size:]
+
+		// Padding is only allowed on the last packet, but strip it wherever it appears
+		if packetData[0]&0x20 != 0 {
+			if padding := int(packetData[len(packetData)-1]); padding > 0 && padding <= len(packetData)-4 {
+				packetData = packetData[:len(packetData)-padding]
+			}
+		}
+
+		packet, err := parseRTCPSubPacket(packetData)
+		if err != nil {
+			if len(compound.Packets) == 0 {
+				return nil, err
+			}
+			logger.Debug("[RTCP:Parse] Keeping malformed packet type %d as raw: %v", packetData[1], err)
+			packet = newRawRTCPPacket(packetData)
+		}
+		compound.Packets = append(compound.Packets, packet)
 	}
 
-	packetType := data[1]
+	return compound, nil
+}
 
-	switch packetType {
+// parseRTCPSubPacket parses one packet of a compound by type
+func parseRTCPSubPacket(data []byte) (RTCPPacket, error) {
+	switch data[1] {
 	case RTCP_SR:
 		return parseSenderReport(data)
 	case RTCP_RR:
@@ -188,9 +302,24 @@ func ParseRTCPPacket(data []byte) (RTCPPacket, error) {
 		return parseSDES(data)
 	case RTCP_BYE:
 		return parseBYE(data)
+	case RTCP_APP:
+		return parseAPP(data)
+	case RTCP_RTPFB, RTCP_PSFB:
+		return parseFeedback(data)
+	case RTCP_XR:
+		return parseExtendedReport(data)
 	default:
-		// Unknown packet type, skip
-		return nil, errors.New("unknown RTCP packet type")
+		// Unknown packet type, keep it for the handler
+		return newRawRTCPPacket(data), nil
+	}
+}
+
+// newRawRTCPPacket wraps a packet that is not parsed further
+func newRawRTCPPacket(data []byte) *RawRTCPPacket {
+	return &RawRTCPPacket{
+		PacketType: data[1],
+		Count:      data[0] & 0x1F,
+		Payload:    data[4:],
 	}
 }
 
@@ -246,11 +375,15 @@ func parseReceiverReport(data []byte) (*ReceiverReport, error) {
 		SSRC:       binary.BigEndian.Uint32(data[4:8]),
 	}
 
-	// Parse report block if present
+	// Parse report blocks if present
 	rc := data[0] & 0x1F
-	if rc > 0 && len(data) >= 32 {
-		rb := parseReportBlock(data[8:32])
-		rr.ReportBlock = &rb
+	offset := 8
+	for i := 0; i < int(rc) && offset+24 <= len(data); i++ {
+		rr.ReportBlocks = append(rr.ReportBlocks, parseReportBlock(data[offset:offset+24]))
+		offset += 24
+	}
+	if len(rr.ReportBlocks) > 0 {
+		rr.ReportBlock = &rr.ReportBlocks[0]
 	}
 
 	return rr, nil
@@ -363,77 +496,19 @@ func parseBYE(data []byte) (*BYEPacket, error) {
 	return bye, nil
 }
 
-// TimestampMapper maps between RTP and NTP timestamps
-type TimestampMapper struct {
-	mu           sync.RWMutex
-	ntpTimestamp uint64
-	rtpTimestamp uint32
-	initialized  bool
-	clockRate    uint32 // Default 90000 for H.264
-}
-
-// NewTimestampMapper creates a new timestamp mapper
-func NewTimestampMapper() *TimestampMapper {
-	return &TimestampMapper{
-		clockRate: 90000, // 90 kHz for H.264
-	}
-}
-
-// UpdateFromSR updates mapping from Sender Report
-func (tm *TimestampMapper) UpdateFromSR(sr *SenderReport) {
-	tm.mu.Lock()
-	defer tm.mu.Unlock()
-
-	oldInitialized := tm.initialized
-	oldRTP := tm.rtpTimestamp
-	oldNTP := tm.ntpTimestamp
-
-	tm.ntpTimestamp = sr.NTPTimestamp
-	tm.rtpTimestamp = sr.RTPTimestamp
-	tm.initialized = true
-	
-	logger.Debug("[TimestampMapper:UpdateFromSR] Mapping updated from RTCP Sender Report: RTP timestamp=%d, NTP timestamp=%d", sr.RTPTimestamp, sr.NTPTimestamp)
-	logger.Debug("[TimestampMapper:UpdateFromSR] Previous state: initialized=%t, RTP=%d, NTP=%d", oldInitialized, oldRTP, oldNTP)
-	logger.Debug("[TimestampMapper:UpdateFromSR] New state: initialized=%t, RTP=%d, NTP=%d", tm.initialized, tm.rtpTimestamp, tm.ntpTimestamp)
-}
-
-// MapperState represents the current state of the timestamp mapper
-type MapperState struct {
-	Initialized  bool
-	RTPTimestamp uint32
-	NTPTimestamp uint64
-	ClockRate    uint32
-}
-
-// GetState returns the current state of the timestamp mapper (for debugging)
-func (tm *TimestampMapper) GetState() MapperState {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-	return MapperState{
-		Initialized:  tm.initialized,
-		RTPTimestamp: tm.rtpTimestamp,
-		NTPTimestamp: tm.ntpTimestamp,
-		ClockRate:    tm.clockRate,
-	}
-}
-
-// RTPToNTP converts RTP timestamp to NTP timestamp
-func (tm *TimestampMapper) RTPToNTP(rtpTime uint32) uint64 {
-	tm.mu.RLock()
-	defer tm.mu.RUnlock()
-
-	if !tm.initialized {
-		return 0
+// parseAPP parses APP packet
+func parseAPP(data []byte) (*APPPacket, error) {
+	if len(data) < 12 {
+		return nil, ErrRTCPPacketTooShort
 	}
 
-	// Calculate RTP difference
-	rtpDiff := int64(rtpTime) - int64(tm.rtpTimestamp)
-
-	// Convert RTP units to NTP units (NTP fraction is 2^32 per second)
-	// RTP is at clockRate Hz (usually 90000)
-	ntpDiff := (rtpDiff << 32) / int64(tm.clockRate)
-
-	return uint64(int64(tm.ntpTimestamp) + ntpDiff)
+	return &APPPacket{
+		PacketType: data[1],
+		Subtype:    data[0] & 0x1F,
+		SSRC:       binary.BigEndian.Uint32(data[4:8]),
+		Name:       string(data[8:12]),
+		Data:       data[12:],
+	}, nil
 }
 
 // NTPToTime converts NTP timestamp to Go time
@@ -495,8 +570,9 @@ func calculateInterarrivalJitter(jitter *uint32, rtpTimestamp uint32, arrivalTim
 		return
 	}
 
-	// Calculate transit time difference
-	rtpDiff := int64(rtpTimestamp - *lastRTPTimestamp)
+	// Calculate transit time difference; the signed 32-bit difference keeps a
+	// reordered (earlier) timestamp from wrapping to a huge positive value
+	rtpDiff := int64(int32(rtpTimestamp - *lastRTPTimestamp))
 	arrivalDiff := arrivalTime.Sub(*lastArrivalTime).Nanoseconds()
 
 	// Convert arrival time to RTP units
@@ -509,8 +585,8 @@ func calculateInterarrivalJitter(jitter *uint32, rtpTimestamp uint32, arrivalTim
 	}
 
 	// Update jitter with smoothing (RFC 3550)
-	// J(i) = J(i-1) + (|D(i-1,i)| - J(i-1))/16
-	*jitter = *jitter + (uint32(d)-*jitter)/16
+	// J(i) = J(i-1) + (|D(i-1,i)| - J(i-1))/16 (signed, so jitter can decay)
+	*jitter = uint32(int64(*jitter) + (d-int64(*jitter))/16)
 
 	*lastRTPTimestamp = rtpTimestamp
 	*lastArrivalTime = arrivalTime
@@ -0,0 +1,196 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// PictureLossIndication asks the media sender for a decoder refresh (RFC 4585 section 6.3.1)
+type PictureLossIndication struct {
+	SenderSSRC uint32
+	MediaSSRC  uint32
+}
+
+// GetPacketType returns the packet type
+func (p *PictureLossIndication) GetPacketType() uint8 {
+	return RTCP_PSFB
+}
+
+// GetSSRC returns the sender SSRC
+func (p *PictureLossIndication) GetSSRC() uint32 {
+	return p.SenderSSRC
+}
+
+// Marshal serializes the PLI
+func (p *PictureLossIndication) Marshal() ([]byte, error) {
+	buf := make([]byte, 12)
+	putRTCPHeader(buf, PSFB_PLI, RTCP_PSFB, len(buf))
+	binary.BigEndian.PutUint32(buf[4:8], p.SenderSSRC)
+	binary.BigEndian.PutUint32(buf[8:12], p.MediaSSRC)
+	return buf, nil
+}
+
+// FIREntry is one Full Intra Request target
+type FIREntry struct {
+	SSRC           uint32
+	SequenceNumber uint8 // Incremented for every new request to the same SSRC
+}
+
+// FullIntraRequest asks the media senders for an intra frame (RFC 5104 section 4.3.1)
+type FullIntraRequest struct {
+	SenderSSRC uint32
+	MediaSSRC  uint32 // Unused by FIR; zero on the wire per RFC 5104
+	Entries    []FIREntry
+}
+
+// GetPacketType returns the packet type
+func (f *FullIntraRequest) GetPacketType() uint8 {
+	return RTCP_PSFB
+}
+
+// GetSSRC returns the sender SSRC
+func (f *FullIntraRequest) GetSSRC() uint32 {
+	return f.SenderSSRC
+}
+
+// Marshal serializes the FIR
+func (f *FullIntraRequest) Marshal() ([]byte, error) {
+	if len(f.Entries) == 0 {
+		return nil, fmt.Errorf("%w: FIR without entries", ErrInvalidRTCPPacket)
+	}
+
+	buf := make([]byte, 12+len(f.Entries)*8)
+	putRTCPHeader(buf, PSFB_FIR, RTCP_PSFB, len(buf))
+	binary.BigEndian.PutUint32(buf[4:8], f.SenderSSRC)
+	binary.BigEndian.PutUint32(buf[8:12], f.MediaSSRC)
+	for i, entry := range f.Entries {
+		offset := 12 + i*8
+		binary.BigEndian.PutUint32(buf[offset:offset+4], entry.SSRC)
+		buf[offset+4] = entry.SequenceNumber
+	}
+	return buf, nil
+}
+
+// NACKPair is one Generic NACK entry: a lost packet ID plus a bitmask of the
+// following 16 packets that were also lost
+type NACKPair struct {
+	PacketID uint16
+	BLP      uint16 // Bit i set means PacketID+i+1 is lost
+}
+
+// PacketList returns every sequence number the pair reports as lost
+func (n NACKPair) PacketList() []uint16 {
+	list := []uint16{n.PacketID}
+	for i := uint16(0); i < 16; i++ {
+		if n.BLP&(1<<i) != 0 {
+			list = append(list, n.PacketID+i+1)
+		}
+	}
+	return list
+}
+
+// NACKPairsFromSequences packs lost sequence numbers into as few NACK pairs as possible.
+// The input is sorted in RTP sequence order (wraparound aware); duplicates are ignored.
+func NACKPairsFromSequences(lost []uint16) []NACKPair {
+	if len(lost) == 0 {
+		return nil
+	}
+
+	sorted := append([]uint16(nil), lost...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sequenceCompare(sorted[i], sorted[j]) < 0
+	})
+
+	var pairs []NACKPair
+	for _, seq := range sorted {
+		if len(pairs) > 0 {
+			last := &pairs[len(pairs)-1]
+			diff := seq - last.PacketID
+			if diff == 0 {
+				continue
+			}
+			if diff <= 16 {
+				last.BLP |= 1 << (diff - 1)
+				continue
+			}
+		}
+		pairs = append(pairs, NACKPair{PacketID: seq})
+	}
+	return pairs
+}
+
+// GenericNACK reports lost RTP packets to the media sender (RFC 4585 section 6.2.1)
+type GenericNACK struct {
+	SenderSSRC uint32
+	MediaSSRC  uint32
+	Pairs      []NACKPair
+}
+
+// GetPacketType returns the packet type
+func (n *GenericNACK) GetPacketType() uint8 {
+	return RTCP_RTPFB
+}
+
+// GetSSRC returns the sender SSRC
+func (n *GenericNACK) GetSSRC() uint32 {
+	return n.SenderSSRC
+}
+
+// Marshal serializes the NACK
+func (n *GenericNACK) Marshal() ([]byte, error) {
+	if len(n.Pairs) == 0 {
+		return nil, fmt.Errorf("%w: NACK without lost packets", ErrInvalidRTCPPacket)
+	}
+
+	buf := make([]byte, 12+len(n.Pairs)*4)
+	putRTCPHeader(buf, RTPFB_NACK, RTCP_RTPFB, len(buf))
+	binary.BigEndian.PutUint32(buf[4:8], n.SenderSSRC)
+	binary.BigEndian.PutUint32(buf[8:12], n.MediaSSRC)
+	for i, pair := range n.Pairs {
+		offset := 12 + i*4
+		binary.BigEndian.PutUint16(buf[offset:offset+2], pair.PacketID)
+		binary.BigEndian.PutUint16(buf[offset+2:offset+4], pair.BLP)
+	}
+	return buf, nil
+}
+
+// parseFeedback parses RTPFB/PSFB packets; unsupported formats are kept raw
+func parseFeedback(data []byte) (RTCPPacket, error) {
+	if len(data) < 12 {
+		return nil, ErrRTCPPacketTooShort
+	}
+
+	fmtType := data[0] & 0x1F
+	senderSSRC := binary.BigEndian.Uint32(data[4:8])
+	mediaSSRC := binary.BigEndian.Uint32(data[8:12])
+	fci := data[12:]
+
+	switch {
+	case data[1] == RTCP_RTPFB && fmtType == RTPFB_NACK:
+		nack := &GenericNACK{SenderSSRC: senderSSRC, MediaSSRC: mediaSSRC}
+		for offset := 0; offset+4 <= len(fci); offset += 4 {
+			nack.Pairs = append(nack.Pairs, NACKPair{
+				PacketID: binary.BigEndian.Uint16(fci[offset : offset+2]),
+				BLP:      binary.BigEndian.Uint16(fci[offset+2 : offset+4]),
+			})
+		}
+		return nack, nil
+
+	case data[1] == RTCP_PSFB && fmtType == PSFB_PLI:
+		return &PictureLossIndication{SenderSSRC: senderSSRC, MediaSSRC: mediaSSRC}, nil
+
+	case data[1] == RTCP_PSFB && fmtType == PSFB_FIR:
+		fir := &FullIntraRequest{SenderSSRC: senderSSRC, MediaSSRC: mediaSSRC}
+		for offset := 0; offset+8 <= len(fci); offset += 8 {
+			fir.Entries = append(fir.Entries, FIREntry{
+				SSRC:           binary.BigEndian.Uint32(fci[offset : offset+4]),
+				SequenceNumber: fci[offset+4],
+			})
+		}
+		return fir, nil
+
+	default:
+		return newRawRTCPPacket(data), nil
+	}
+}
@@ -0,0 +1,115 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPictureLossIndication_Marshal tests PLI wire format
+func TestPictureLossIndication_Marshal(t *testing.T) {
+	data, err := (&PictureLossIndication{SenderSSRC: 0x11111111, MediaSSRC: 0x22222222}).Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x81, 206, 0x00, 0x02,
+		0x11, 0x11, 0x11, 0x11,
+		0x22, 0x22, 0x22, 0x22,
+	}, data)
+}
+
+// TestFullIntraRequest_Marshal tests FIR wire format
+func TestFullIntraRequest_Marshal(t *testing.T) {
+	fir := &FullIntraRequest{
+		SenderSSRC: 0x11111111,
+		Entries:    []FIREntry{{SSRC: 0x22222222, SequenceNumber: 7}},
+	}
+	data, err := fir.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x84, 206, 0x00, 0x04,
+		0x11, 0x11, 0x11, 0x11,
+		0x00, 0x00, 0x00, 0x00,
+		0x22, 0x22, 0x22, 0x22,
+		0x07, 0x00, 0x00, 0x00,
+	}, data)
+
+	_, err = (&FullIntraRequest{}).Marshal()
+	assert.ErrorIs(t, err, ErrInvalidRTCPPacket)
+}
+
+// TestNACKPairsFromSequences tests packing lost sequence numbers into NACK pairs
+func TestNACKPairsFromSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		lost     []uint16
+		expected []NACKPair
+	}{
+		{"empty", nil, nil},
+		{"single", []uint16{100}, []NACKPair{{PacketID: 100}}},
+		{"bitmask", []uint16{100, 101, 103, 116}, []NACKPair{{PacketID: 100, BLP: 0x8005}}},
+		{"overflow to new pair", []uint16{100, 117}, []NACKPair{{PacketID: 100}, {PacketID: 117}}},
+		{"unsorted with duplicates", []uint16{5, 3, 5, 4}, []NACKPair{{PacketID: 3, BLP: 0x0003}}},
+		{"wraparound", []uint16{1, 65535}, []NACKPair{{PacketID: 65535, BLP: 0x0002}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs := NACKPairsFromSequences(tt.lost)
+			assert.Equal(t, tt.expected, pairs)
+		})
+	}
+
+	assert.Equal(t, []uint16{65535, 1}, NACKPair{PacketID: 65535, BLP: 0x0002}.PacketList())
+}
+
+// TestGenericNACK_Marshal tests NACK wire format
+func TestGenericNACK_Marshal(t *testing.T) {
+	nack := &GenericNACK{
+		SenderSSRC: 0x11111111,
+		MediaSSRC:  0x22222222,
+		Pairs:      []NACKPair{{PacketID: 0x0102, BLP: 0x8001}},
+	}
+	data, err := nack.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x81, 205, 0x00, 0x03,
+		0x11, 0x11, 0x11, 0x11,
+		0x22, 0x22, 0x22, 0x22,
+		0x01, 0x02, 0x80, 0x01,
+	}, data)
+
+	_, err = (&GenericNACK{}).Marshal()
+	assert.ErrorIs(t, err, ErrInvalidRTCPPacket)
+}
+
+// TestParseFeedback tests feedback packet parsing round-trips
+func TestParseFeedback(t *testing.T) {
+	packets := []RTCPPacket{
+		&PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2},
+		&FullIntraRequest{SenderSSRC: 1, Entries: []FIREntry{{SSRC: 2, SequenceNumber: 9}, {SSRC: 3, SequenceNumber: 1}}},
+		&GenericNACK{SenderSSRC: 1, MediaSSRC: 2, Pairs: []NACKPair{{PacketID: 10, BLP: 0x0101}}},
+	}
+
+	for _, expected := range packets {
+		data, err := expected.(rtcpMarshaler).Marshal()
+		require.NoError(t, err)
+
+		parsed, err := ParseRTCPPacket(data)
+		require.NoError(t, err)
+		assert.Equal(t, expected, parsed)
+	}
+
+	// Unsupported formats (here REMB, PSFB FMT 15) are kept raw in compounds
+	remb := []byte{0x8F, 206, 0x00, 0x03, 0, 0, 0, 1, 0, 0, 0, 0, 'R', 'E', 'M', 'B'}
+	_, err := ParseRTCPPacket(remb)
+	assert.ErrorIs(t, err, ErrUnknownRTCPPacketType)
+
+	compound, err := ParseCompoundRTCPPacket(remb)
+	require.NoError(t, err)
+	require.Len(t, compound.Packets, 1)
+	raw, ok := compound.Packets[0].(*RawRTCPPacket)
+	require.True(t, ok)
+	assert.Equal(t, uint8(15), raw.Count)
+	assert.Equal(t, uint32(1), raw.GetSSRC())
+}
@@ -0,0 +1,196 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidRTCPPacket indicates RTCP packet fields cannot be encoded on the wire
+	ErrInvalidRTCPPacket = errors.New("invalid RTCP packet")
+)
+
+// rtcpMarshaler is implemented by RTCP packets that can be serialized
+type rtcpMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// putRTCPHeader writes the common RTCP header (V=2, P=0) for a packet of size bytes
+func putRTCPHeader(buf []byte, count uint8, packetType uint8, size int) {
+	buf[0] = 2<<6 | count&0x1F
+	buf[1] = packetType
+	binary.BigEndian.PutUint16(buf[2:4], uint16(size/4-1))
+}
+
+// putReportBlock writes a 24-byte report block
+func putReportBlock(buf []byte, rb ReportBlock) {
+	binary.BigEndian.PutUint32(buf[0:4], rb.SSRC)
+
+	// Fraction lost (8 bits) followed by cumulative lost (24-bit signed)
+	lost := rb.PacketsLost
+	if lost > 0x7FFFFF {
+		lost = 0x7FFFFF
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+	binary.BigEndian.PutUint32(buf[4:8], uint32(rb.FractionLost)<<24|uint32(lost)&0xFFFFFF)
+
+	binary.BigEndian.PutUint32(buf[8:12], rb.HighestSeq)
+	binary.BigEndian.PutUint32(buf[12:16], rb.Jitter)
+	binary.BigEndian.PutUint32(buf[16:20], rb.LSR)
+	binary.BigEndian.PutUint32(buf[20:24], rb.DLSR)
+}
+
+// Marshal serializes the sender report
+func (sr *SenderReport) Marshal() ([]byte, error) {
+	if len(sr.ReportBlocks) > 31 {
+		return nil, fmt.Errorf("%w: %d report blocks (max 31)", ErrInvalidRTCPPacket, len(sr.ReportBlocks))
+	}
+
+	size := 28 + len(sr.ReportBlocks)*24
+	buf := make([]byte, size)
+	putRTCPHeader(buf, uint8(len(sr.ReportBlocks)), RTCP_SR, size)
+	binary.BigEndian.PutUint32(buf[4:8], sr.SSRC)
+	binary.BigEndian.PutUint64(buf[8:16], sr.NTPTimestamp)
+	binary.BigEndian.PutUint32(buf[16:20], sr.RTPTimestamp)
+	binary.BigEndian.PutUint32(buf[20:24], sr.PacketCount)
+	binary.BigEndian.PutUint32(buf[24:28], sr.OctetCount)
+
+	for i, rb := range sr.ReportBlocks {
+		putReportBlock(buf[28+i*24:], rb)
+	}
+	return buf, nil
+}
+
+// Marshal serializes the receiver report.
+// ReportBlocks is used when set, otherwise the single ReportBlock (if any).
+func (rr *ReceiverReport) Marshal() ([]byte, error) {
+	blocks := rr.ReportBlocks
+	if len(blocks) == 0 && rr.ReportBlock != nil {
+		blocks = []ReportBlock{*rr.ReportBlock}
+	}
+	if len(blocks) > 31 {
+		return nil, fmt.Errorf("%w: %d report blocks (max 31)", ErrInvalidRTCPPacket, len(blocks))
+	}
+
+	size := 8 + len(blocks)*24
+	buf := make([]byte, size)
+	putRTCPHeader(buf, uint8(len(blocks)), RTCP_RR, size)
+	binary.BigEndian.PutUint32(buf[4:8], rr.SSRC)
+
+	for i, rb := range blocks {
+		putReportBlock(buf[8+i*24:], rb)
+	}
+	return buf, nil
+}
+
+// Marshal serializes the SDES packet; each chunk is null-terminated and padded to 32 bits
+func (sdes *SDESPacket) Marshal() ([]byte, error) {
+	if len(sdes.Chunks) > 31 {
+		return nil, fmt.Errorf("%w: %d SDES chunks (max 31)", ErrInvalidRTCPPacket, len(sdes.Chunks))
+	}
+
+	buf := make([]byte, 4)
+	for _, chunk := range sdes.Chunks {
+		buf = binary.BigEndian.AppendUint32(buf, chunk.SSRC)
+		for _, item := range chunk.Items {
+			if len(item.Text) > 255 {
+				return nil, fmt.Errorf("%w: SDES item type %d is %d bytes (max 255)", ErrInvalidRTCPPacket, item.Type, len(item.Text))
+			}
+			buf = append(buf, item.Type, uint8(len(item.Text)))
+			buf = append(buf, item.Text...)
+		}
+
+		// END item, then pad the chunk to a 32-bit boundary
+		buf = append(buf, SDES_END)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+
+	putRTCPHeader(buf, uint8(len(sdes.Chunks)), RTCP_SDES, len(buf))
+	return buf, nil
+}
+
+// Marshal serializes the BYE packet
+func (bye *BYEPacket) Marshal() ([]byte, error) {
+	if len(bye.SSRCs) > 31 {
+		return nil, fmt.Errorf("%w: %d SSRCs (max 31)", ErrInvalidRTCPPacket, len(bye.SSRCs))
+	}
+	if len(bye.Reason) > 255 {
+		return nil, fmt.Errorf("%w: BYE reason is %d bytes (max 255)", ErrInvalidRTCPPacket, len(bye.Reason))
+	}
+
+	buf := make([]byte, 4)
+	for _, ssrc := range bye.SSRCs {
+		buf = binary.BigEndian.AppendUint32(buf, ssrc)
+	}
+	if bye.Reason != "" {
+		buf = append(buf, uint8(len(bye.Reason)))
+		buf = append(buf, bye.Reason...)
+		for len(buf)%4 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+
+	putRTCPHeader(buf, uint8(len(bye.SSRCs)), RTCP_BYE, len(buf))
+	return buf, nil
+}
+
+// Marshal serializes all packets back to back into one compound packet.
+// RFC 3550 requires the first packet to be an SR or RR.
+func (c *CompoundRTCPPacket) Marshal() ([]byte, error) {
+	if len(c.Packets) == 0 {
+		return nil, fmt.Errorf("%w: empty compound packet", ErrInvalidRTCPPacket)
+	}
+	switch c.Packets[0].(type) {
+	case *SenderReport, *ReceiverReport:
+	default:
+		return nil, fmt.Errorf("%w: compound packet must start with SR or RR", ErrInvalidRTCPPacket)
+	}
+
+	var buf []byte
+	for _, packet := range c.Packets {
+		m, ok := packet.(rtcpMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T cannot be serialized", ErrInvalidRTCPPacket, packet)
+		}
+		data, err := m.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, data...)
+	}
+	return buf, nil
+}
+
+// Marshal serializes the APP packet
+func (app *APPPacket) Marshal() ([]byte, error) {
+	if len(app.Name) != 4 {
+		return nil, fmt.Errorf("%w: APP name %q is not 4 characters", ErrInvalidRTCPPacket, app.Name)
+	}
+	if len(app.Data)%4 != 0 {
+		return nil, fmt.Errorf("%w: APP data is not 32-bit aligned", ErrInvalidRTCPPacket)
+	}
+
+	buf := make([]byte, 12, 12+len(app.Data))
+	binary.BigEndian.PutUint32(buf[4:8], app.SSRC)
+	copy(buf[8:12], app.Name)
+	buf = append(buf, app.Data...)
+
+	putRTCPHeader(buf, app.Subtype, RTCP_APP, len(buf))
+	return buf, nil
+}
+
+// Marshal re-serializes the raw packet unchanged
+func (raw *RawRTCPPacket) Marshal() ([]byte, error) {
+	if len(raw.Payload)%4 != 0 {
+		return nil, fmt.Errorf("%w: raw packet payload is not 32-bit aligned", ErrInvalidRTCPPacket)
+	}
+
+	buf := make([]byte, 4, 4+len(raw.Payload))
+	buf = append(buf, raw.Payload...)
+	putRTCPHeader(buf, raw.Count, raw.PacketType, len(buf))
+	return buf, nil
+}
@@ -0,0 +1,124 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReceiverReport_Marshal tests RR serialization round-trips
+func TestReceiverReport_Marshal(t *testing.T) {
+	rr := &ReceiverReport{
+		SSRC: 0x12345678,
+		ReportBlocks: []ReportBlock{
+			{SSRC: 0xAAAAAAAA, FractionLost: 25, PacketsLost: 1000, HighestSeq: 0x0001FFFF, Jitter: 42, LSR: 0x34567890, DLSR: 65536},
+			{SSRC: 0xBBBBBBBB, PacketsLost: -3, HighestSeq: 7},
+		},
+	}
+
+	data, err := rr.Marshal()
+	require.NoError(t, err)
+	assert.Len(t, data, 8+2*24)
+
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	parsed, ok := packet.(*ReceiverReport)
+	require.True(t, ok)
+
+	assert.Equal(t, uint8(RTCP_RR), parsed.PacketType)
+	assert.Equal(t, rr.SSRC, parsed.SSRC)
+	assert.Equal(t, rr.ReportBlocks, parsed.ReportBlocks)
+	require.NotNil(t, parsed.ReportBlock)
+	assert.Equal(t, rr.ReportBlocks[0], *parsed.ReportBlock)
+
+	// Single ReportBlock field is used when ReportBlocks is empty
+	data, err = (&ReceiverReport{SSRC: 1, ReportBlock: &ReportBlock{SSRC: 2}}).Marshal()
+	require.NoError(t, err)
+	assert.Len(t, data, 32)
+	assert.Equal(t, byte(0x81), data[0])
+}
+
+// TestSenderReport_Marshal tests SR serialization round-trips
+func TestSenderReport_Marshal(t *testing.T) {
+	sr := &SenderReport{
+		PacketType:   RTCP_SR,
+		SSRC:         0x12345678,
+		NTPTimestamp: 0xE1F8923456789ABC,
+		RTPTimestamp: 90000,
+		PacketCount:  100,
+		OctetCount:   50000,
+		ReportBlocks: []ReportBlock{{SSRC: 9, HighestSeq: 10}},
+	}
+
+	data, err := sr.Marshal()
+	require.NoError(t, err)
+
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	assert.Equal(t, sr, packet)
+}
+
+// TestSDESPacket_Marshal tests SDES serialization and chunk padding
+func TestSDESPacket_Marshal(t *testing.T) {
+	sdes := &SDESPacket{
+		Chunks: []SDESChunk{{
+			SSRC:  0x12345678,
+			Items: []SDESItem{{Type: SDES_CNAME, Text: "user@host"}},
+		}},
+	}
+
+	data, err := sdes.Marshal()
+	require.NoError(t, err)
+	assert.Zero(t, len(data)%4)
+	assert.Equal(t, byte(0x81), data[0])
+	assert.Equal(t, byte(RTCP_SDES), data[1])
+
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	parsed := packet.(*SDESPacket)
+	require.Len(t, parsed.Chunks, 1)
+	assert.Equal(t, sdes.Chunks[0].Items, parsed.Chunks[0].Items)
+
+	_, err = (&SDESPacket{Chunks: []SDESChunk{{Items: []SDESItem{{Type: SDES_CNAME, Text: string(make([]byte, 256))}}}}}).Marshal()
+	assert.ErrorIs(t, err, ErrInvalidRTCPPacket)
+}
+
+// TestBYEPacket_Marshal tests BYE serialization round-trips
+func TestBYEPacket_Marshal(t *testing.T) {
+	bye := &BYEPacket{SSRCs: []uint32{1, 2}, Reason: "done"}
+
+	data, err := bye.Marshal()
+	require.NoError(t, err)
+	assert.Zero(t, len(data)%4)
+
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	parsed := packet.(*BYEPacket)
+	assert.Equal(t, bye.SSRCs, parsed.SSRCs)
+	assert.Equal(t, "done", parsed.Reason)
+}
+
+// TestCompoundRTCPPacket_Marshal tests compound serialization rules
+func TestCompoundRTCPPacket_Marshal(t *testing.T) {
+	compound := &CompoundRTCPPacket{
+		Packets: []RTCPPacket{
+			&ReceiverReport{SSRC: 1},
+			&SDESPacket{Chunks: []SDESChunk{{SSRC: 1, Items: []SDESItem{{Type: SDES_CNAME, Text: "a@b"}}}}},
+		},
+	}
+	data, err := compound.Marshal()
+	require.NoError(t, err)
+	assert.Len(t, data, 8+16)
+
+	// The first sub-packet is the RR
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(RTCP_RR), packet.GetPacketType())
+
+	_, err = (&CompoundRTCPPacket{}).Marshal()
+	assert.ErrorIs(t, err, ErrInvalidRTCPPacket)
+
+	_, err = (&CompoundRTCPPacket{Packets: []RTCPPacket{&BYEPacket{}}}).Marshal()
+	assert.ErrorIs(t, err, ErrInvalidRTCPPacket)
+}
@@ -310,3 +310,89 @@ func TestTimestampMapper(t *testing.T) {
 	expectedDiff := uint64(1 << 32) // 1 second in NTP
 	assert.InDelta(t, float64(expectedDiff), float64(diff), float64(expectedDiff)*0.01)
 }
+
+// TestParseCompoundRTCPPacket tests splitting compound packets and keeping unknown types
+func TestParseCompoundRTCPPacket(t *testing.T) {
+	sr, err := (&SenderReport{SSRC: 0x12345678, NTPTimestamp: 1}).Marshal()
+	require.NoError(t, err)
+	sdes, err := (&SDESPacket{Chunks: []SDESChunk{{SSRC: 0x12345678, Items: []SDESItem{{Type: SDES_CNAME, Text: "cam"}}}}}).Marshal()
+	require.NoError(t, err)
+	app, err := (&APPPacket{Subtype: 3, SSRC: 0x12345678, Name: "TEST", Data: []byte{1, 2, 3, 4}}).Marshal()
+	require.NoError(t, err)
+	unknown := []byte{0x85, 210, 0x00, 0x01, 0xAA, 0xBB, 0xCC, 0xDD}
+
+	var data []byte
+	for _, p := range [][]byte{sr, sdes, unknown, app} {
+		data = append(data, p...)
+	}
+
+	compound, err := ParseCompoundRTCPPacket(data)
+	require.NoError(t, err)
+	require.Len(t, compound.Packets, 4)
+
+	// ParseRTCPPacket keeps returning only the first packet
+	first, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	assert.Equal(t, compound.Packets[0], first)
+
+	assert.IsType(t, &SenderReport{}, compound.Packets[0])
+	assert.IsType(t, &SDESPacket{}, compound.Packets[1])
+
+	raw, ok := compound.Packets[2].(*RawRTCPPacket)
+	require.True(t, ok)
+	assert.Equal(t, uint8(210), raw.GetPacketType())
+	assert.Equal(t, uint8(5), raw.Count)
+	assert.Equal(t, uint32(0xAABBCCDD), raw.GetSSRC())
+
+	parsedApp, ok := compound.Packets[3].(*APPPacket)
+	require.True(t, ok)
+	assert.Equal(t, uint8(3), parsedApp.Subtype)
+	assert.Equal(t, "TEST", parsedApp.Name)
+	assert.Equal(t, []byte{1, 2, 3, 4}, parsedApp.Data)
+
+	// Compound re-serializes byte for byte, including the raw packet
+	out, err := compound.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	assert.Equal(t, compound.Packets, FlattenRTCPPacket(compound))
+	assert.Equal(t, []RTCPPacket{raw}, FlattenRTCPPacket(raw))
+}
+
+// TestParseCompoundRTCPPacket_Malformed tests tolerance of bad sub-packets
+func TestParseCompoundRTCPPacket_Malformed(t *testing.T) {
+	rr := []byte{0x80, 0xC9, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78}
+
+	t.Run("truncated SR after RR is kept raw", func(t *testing.T) {
+		data := append(append([]byte{}, rr...), 0x80, 0xC8, 0x00, 0x01, 0x11, 0x11, 0x11, 0x11)
+		compound, err := ParseCompoundRTCPPacket(data)
+		require.NoError(t, err)
+		require.Len(t, compound.Packets, 2)
+		assert.IsType(t, &RawRTCPPacket{}, compound.Packets[1])
+	})
+
+	t.Run("trailing garbage is ignored", func(t *testing.T) {
+		data := append(append([]byte{}, rr...), 0x00, 0x00, 0x00, 0x00)
+		packet, err := ParseRTCPPacket(data)
+		require.NoError(t, err)
+		assert.IsType(t, &ReceiverReport{}, packet)
+	})
+
+	t.Run("padding on last packet", func(t *testing.T) {
+		data := []byte{0xA0, 0xCB, 0x00, 0x02, 0x12, 0x34, 0x56, 0x78, 0x00, 0x00, 0x00, 0x04}
+		packet, err := ParseRTCPPacket(data)
+		require.NoError(t, err)
+		bye, ok := packet.(*BYEPacket)
+		require.True(t, ok)
+		assert.Empty(t, bye.SSRCs)
+		assert.Empty(t, bye.Reason)
+	})
+
+	t.Run("malformed first packet fails", func(t *testing.T) {
+		_, err := ParseRTCPPacket([]byte{0x80, 0xC8, 0x00, 0x01, 0x11, 0x11, 0x11, 0x11})
+		assert.ErrorIs(t, err, ErrRTCPPacketTooShort)
+
+		_, err = ParseRTCPPacket([]byte{0x40, 0xC8, 0x00, 0x00})
+		assert.ErrorIs(t, err, ErrInvalidRTCPVersion)
+	})
+}
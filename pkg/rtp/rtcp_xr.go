@@ -0,0 +1,299 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// XR report block types (RFC 3611 section 4)
+const (
+	XR_RRTR         = 4 // Receiver Reference Time
+	XR_DLRR         = 5 // Delay since Last Receiver Report
+	XR_STAT_SUMMARY = 6 // Statistics Summary
+	XR_VOIP_METRICS = 7 // VoIP Metrics
+)
+
+// ExtendedReport represents an RTCP XR packet
+type ExtendedReport struct {
+	PacketType uint8
+	SSRC       uint32
+	Blocks     []XRBlock
+}
+
+// GetPacketType returns the packet type
+func (xr *ExtendedReport) GetPacketType() uint8 {
+	return xr.PacketType
+}
+
+// GetSSRC returns the SSRC
+func (xr *ExtendedReport) GetSSRC() uint32 {
+	return xr.SSRC
+}
+
+// XRBlock is one report block of an XR packet
+type XRBlock interface {
+	BlockType() uint8
+}
+
+// ReceiverReferenceTime reports the receiver's wallclock (RFC 3611 section 4.4)
+type ReceiverReferenceTime struct {
+	NTPTimestamp uint64
+}
+
+// BlockType returns XR_RRTR
+func (b *ReceiverReferenceTime) BlockType() uint8 {
+	return XR_RRTR
+}
+
+// DLRRItem is the delay since the last RRTR from one receiver
+type DLRRItem struct {
+	SSRC uint32
+	LRR  uint32 // Middle 32 bits of the last RRTR NTP timestamp
+	DLRR uint32 // Delay since the last RRTR in 1/65536 seconds
+}
+
+// DLRRReport lets receivers compute round-trip time (RFC 3611 section 4.5)
+type DLRRReport struct {
+	Items []DLRRItem
+}
+
+// BlockType returns XR_DLRR
+func (b *DLRRReport) BlockType() uint8 {
+	return XR_DLRR
+}
+
+// StatisticsSummary summarizes a sequence range (RFC 3611 section 4.6).
+// Only the metrics whose flags are set carry meaningful values.
+type StatisticsSummary struct {
+	LossReport      bool  // L: LostPackets valid
+	DuplicateReport bool  // D: DupPackets valid
+	JitterReport    bool  // J: jitter fields valid
+	TTLOrHopLimit   uint8 // ToH: 0 none, 1 IPv4 TTL, 2 IPv6 hop limit
+	SSRC            uint32
+	BeginSeq        uint16
+	EndSeq          uint16
+	LostPackets     uint32
+	DupPackets      uint32
+	MinJitter       uint32
+	MaxJitter       uint32
+	MeanJitter      uint32
+	DevJitter       uint32
+	MinTTL          uint8
+	MaxTTL          uint8
+	MeanTTL         uint8
+	DevTTL          uint8
+}
+
+// BlockType returns XR_STAT_SUMMARY
+func (b *StatisticsSummary) BlockType() uint8 {
+	return XR_STAT_SUMMARY
+}
+
+// VoIPMetrics reports call quality metrics (RFC 3611 section 4.7)
+type VoIPMetrics struct {
+	SSRC           uint32
+	LossRate       uint8 // Fraction lost, in 1/256
+	DiscardRate    uint8 // Fraction discarded by the jitter buffer, in 1/256
+	BurstDensity   uint8
+	GapDensity     uint8
+	BurstDuration  uint16 // Milliseconds
+	GapDuration    uint16 // Milliseconds
+	RoundTripDelay uint16 // Milliseconds
+	EndSystemDelay uint16 // Milliseconds
+	SignalLevel    int8   // dBm0
+	NoiseLevel     int8   // dBm0
+	RERL           uint8  // Residual echo return loss, dB
+	Gmin           uint8
+	RFactor        uint8
+	ExtRFactor     uint8
+	MOSLQ          uint8 // MOS x10
+	MOSCQ          uint8 // MOS x10
+	RXConfig       uint8
+	JBNominal      uint16 // Milliseconds
+	JBMaximum      uint16 // Milliseconds
+	JBAbsMax       uint16 // Milliseconds
+}
+
+// BlockType returns XR_VOIP_METRICS
+func (b *VoIPMetrics) BlockType() uint8 {
+	return XR_VOIP_METRICS
+}
+
+// UnknownXRBlock holds a block type this package does not interpret
+type UnknownXRBlock struct {
+	Type         uint8
+	TypeSpecific uint8
+	Data         []byte
+}
+
+// BlockType returns the block type from the wire
+func (b *UnknownXRBlock) BlockType() uint8 {
+	return b.Type
+}
+
+// parseExtendedReport parses an XR packet. Blocks with an unknown type or a
+// length too short for their type are kept as *UnknownXRBlock.
+func parseExtendedReport(data []byte) (*ExtendedReport, error) {
+	if len(data) < 8 {
+		return nil, ErrRTCPPacketTooShort
+	}
+
+	xr := &ExtendedReport{
+		PacketType: data[1],
+		SSRC:       binary.BigEndian.Uint32(data[4:8]),
+	}
+
+	offset := 8
+	for offset+4 <= len(data) {
+		blockType := data[offset]
+		typeSpecific := data[offset+1]
+		size := (int(binary.BigEndian.Uint16(data[offset+2:offset+4])) + 1) * 4
+		if offset+size > len(data) {
+			return nil, fmt.Errorf("%w: XR block type %d overruns packet", ErrRTCPPacketTooShort, blockType)
+		}
+		body := data[offset+4 : offset+size]
+		offset += size
+
+		xr.Blocks = append(xr.Blocks, parseXRBlock(blockType, typeSpecific, body))
+	}
+
+	return xr, nil
+}
+
+// parseXRBlock parses one XR block body (without its 4-byte header)
+func parseXRBlock(blockType, typeSpecific uint8, body []byte) XRBlock {
+	switch {
+	case blockType == XR_RRTR && len(body) >= 8:
+		return &ReceiverReferenceTime{NTPTimestamp: binary.BigEndian.Uint64(body[0:8])}
+
+	case blockType == XR_DLRR:
+		dlrr := &DLRRReport{}
+		for i := 0; i+12 <= len(body); i += 12 {
+			dlrr.Items = append(dlrr.Items, DLRRItem{
+				SSRC: binary.BigEndian.Uint32(body[i : i+4]),
+				LRR:  binary.BigEndian.Uint32(body[i+4 : i+8]),
+				DLRR: binary.BigEndian.Uint32(body[i+8 : i+12]),
+			})
+		}
+		return dlrr
+
+	case blockType == XR_STAT_SUMMARY && len(body) >= 36:
+		return &StatisticsSummary{
+			LossReport:      typeSpecific&0x80 != 0,
+			DuplicateReport: typeSpecific&0x40 != 0,
+			JitterReport:    typeSpecific&0x20 != 0,
+			TTLOrHopLimit:   (typeSpecific >> 3) & 0x03,
+			SSRC:            binary.BigEndian.Uint32(body[0:4]),
+			BeginSeq:        binary.BigEndian.Uint16(body[4:6]),
+			EndSeq:          binary.BigEndian.Uint16(body[6:8]),
+			LostPackets:     binary.BigEndian.Uint32(body[8:12]),
+			DupPackets:      binary.BigEndian.Uint32(body[12:16]),
+			MinJitter:       binary.BigEndian.Uint32(body[16:20]),
+			MaxJitter:       binary.BigEndian.Uint32(body[20:24]),
+			MeanJitter:      binary.BigEndian.Uint32(body[24:28]),
+			DevJitter:       binary.BigEndian.Uint32(body[28:32]),
+			MinTTL:          body[32],
+			MaxTTL:          body[33],
+			MeanTTL:         body[34],
+			DevTTL:          body[35],
+		}
+
+	case blockType == XR_VOIP_METRICS && len(body) >= 32:
+		return &VoIPMetrics{
+			SSRC:           binary.BigEndian.Uint32(body[0:4]),
+			LossRate:       body[4],
+			DiscardRate:    body[5],
+			BurstDensity:   body[6],
+			GapDensity:     body[7],
+			BurstDuration:  binary.BigEndian.Uint16(body[8:10]),
+			GapDuration:    binary.BigEndian.Uint16(body[10:12]),
+			RoundTripDelay: binary.BigEndian.Uint16(body[12:14]),
+			EndSystemDelay: binary.BigEndian.Uint16(body[14:16]),
+			SignalLevel:    int8(body[16]),
+			NoiseLevel:     int8(body[17]),
+			RERL:           body[18],
+			Gmin:           body[19],
+			RFactor:        body[20],
+			ExtRFactor:     body[21],
+			MOSLQ:          body[22],
+			MOSCQ:          body[23],
+			RXConfig:       body[24],
+			JBNominal:      binary.BigEndian.Uint16(body[26:28]),
+			JBMaximum:      binary.BigEndian.Uint16(body[28:30]),
+			JBAbsMax:       binary.BigEndian.Uint16(body[30:32]),
+		}
+
+	default:
+		return &UnknownXRBlock{Type: blockType, TypeSpecific: typeSpecific, Data: body}
+	}
+}
+
+// Marshal serializes the XR packet
+func (xr *ExtendedReport) Marshal() ([]byte, error) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint32(buf[4:8], xr.SSRC)
+
+	for _, block := range xr.Blocks {
+		start := len(buf)
+		buf = append(buf, block.BlockType(), 0, 0, 0)
+
+		switch b := block.(type) {
+		case *ReceiverReferenceTime:
+			buf = binary.BigEndian.AppendUint64(buf, b.NTPTimestamp)
+
+		case *DLRRReport:
+			for _, item := range b.Items {
+				buf = binary.BigEndian.AppendUint32(buf, item.SSRC)
+				buf = binary.BigEndian.AppendUint32(buf, item.LRR)
+				buf = binary.BigEndian.AppendUint32(buf, item.DLRR)
+			}
+
+		case *StatisticsSummary:
+			var flags uint8
+			if b.LossReport {
+				flags |= 0x80
+			}
+			if b.DuplicateReport {
+				flags |= 0x40
+			}
+			if b.JitterReport {
+				flags |= 0x20
+			}
+			buf[start+1] = flags | (b.TTLOrHopLimit&0x03)<<3
+			buf = binary.BigEndian.AppendUint32(buf, b.SSRC)
+			buf = binary.BigEndian.AppendUint16(buf, b.BeginSeq)
+			buf = binary.BigEndian.AppendUint16(buf, b.EndSeq)
+			for _, v := range []uint32{b.LostPackets, b.DupPackets, b.MinJitter, b.MaxJitter, b.MeanJitter, b.DevJitter} {
+				buf = binary.BigEndian.AppendUint32(buf, v)
+			}
+			buf = append(buf, b.MinTTL, b.MaxTTL, b.MeanTTL, b.DevTTL)
+
+		case *VoIPMetrics:
+			buf = binary.BigEndian.AppendUint32(buf, b.SSRC)
+			buf = append(buf, b.LossRate, b.DiscardRate, b.BurstDensity, b.GapDensity)
+			for _, v := range []uint16{b.BurstDuration, b.GapDuration, b.RoundTripDelay, b.EndSystemDelay} {
+				buf = binary.BigEndian.AppendUint16(buf, v)
+			}
+			buf = append(buf, uint8(b.SignalLevel), uint8(b.NoiseLevel), b.RERL, b.Gmin,
+				b.RFactor, b.ExtRFactor, b.MOSLQ, b.MOSCQ, b.RXConfig, 0)
+			for _, v := range []uint16{b.JBNominal, b.JBMaximum, b.JBAbsMax} {
+				buf = binary.BigEndian.AppendUint16(buf, v)
+			}
+
+		case *UnknownXRBlock:
+			if len(b.Data)%4 != 0 {
+				return nil, fmt.Errorf("%w: XR block type %d data is not 32-bit aligned", ErrInvalidRTCPPacket, b.Type)
+			}
+			buf[start+1] = b.TypeSpecific
+			buf = append(buf, b.Data...)
+
+		default:
+			return nil, fmt.Errorf("%w: unsupported XR block %T", ErrInvalidRTCPPacket, block)
+		}
+
+		binary.BigEndian.PutUint16(buf[start+2:start+4], uint16((len(buf)-start)/4-1))
+	}
+
+	putRTCPHeader(buf, 0, RTCP_XR, len(buf))
+	return buf, nil
+}
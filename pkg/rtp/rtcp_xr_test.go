@@ -0,0 +1,66 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExtendedReport_RoundTrip tests XR serialization and parsing of every block type
+func TestExtendedReport_RoundTrip(t *testing.T) {
+	xr := &ExtendedReport{
+		PacketType: RTCP_XR,
+		SSRC:       0x12345678,
+		Blocks: []XRBlock{
+			&ReceiverReferenceTime{NTPTimestamp: 0xE1F8923456789ABC},
+			&DLRRReport{Items: []DLRRItem{{SSRC: 1, LRR: 2, DLRR: 3}, {SSRC: 4, LRR: 5, DLRR: 6}}},
+			&StatisticsSummary{
+				LossReport: true, JitterReport: true, TTLOrHopLimit: 1,
+				SSRC: 7, BeginSeq: 100, EndSeq: 200, LostPackets: 3,
+				MinJitter: 1, MaxJitter: 90, MeanJitter: 30, DevJitter: 5,
+				MinTTL: 60, MaxTTL: 64, MeanTTL: 62, DevTTL: 1,
+			},
+			&VoIPMetrics{
+				SSRC: 8, LossRate: 12, DiscardRate: 3, BurstDuration: 120, GapDuration: 5000,
+				RoundTripDelay: 80, EndSystemDelay: 40, SignalLevel: -20, NoiseLevel: -70,
+				RERL: 127, Gmin: 16, RFactor: 85, ExtRFactor: 127, MOSLQ: 41, MOSCQ: 40,
+				RXConfig: 0x10, JBNominal: 40, JBMaximum: 80, JBAbsMax: 120,
+			},
+			&UnknownXRBlock{Type: 42, TypeSpecific: 7, Data: []byte{1, 2, 3, 4}},
+		},
+	}
+
+	data, err := xr.Marshal()
+	require.NoError(t, err)
+	assert.Len(t, data, 8+12+28+40+36+8)
+
+	packet, err := ParseRTCPPacket(data)
+	require.NoError(t, err)
+	assert.Equal(t, xr, packet)
+}
+
+// TestParseExtendedReport_Malformed tests XR block length handling
+func TestParseExtendedReport_Malformed(t *testing.T) {
+	// RRTR block with a length too short for its type is kept as unknown
+	short := []byte{
+		0x80, 207, 0x00, 0x03,
+		0x12, 0x34, 0x56, 0x78,
+		XR_RRTR, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x01,
+	}
+	packet, err := ParseRTCPPacket(short)
+	require.NoError(t, err)
+	xr := packet.(*ExtendedReport)
+	require.Len(t, xr.Blocks, 1)
+	assert.IsType(t, &UnknownXRBlock{}, xr.Blocks[0])
+
+	// Block overrunning the packet fails
+	overrun := []byte{
+		0x80, 207, 0x00, 0x02,
+		0x12, 0x34, 0x56, 0x78,
+		XR_RRTR, 0x00, 0x00, 0x02,
+	}
+	_, err = ParseRTCPPacket(overrun)
+	assert.ErrorIs(t, err, ErrRTCPPacketTooShort)
+}
@@ -0,0 +1,183 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidRTX indicates an RTX payload is too short to carry an original sequence number
+var ErrInvalidRTX = errors.New("invalid RTX packet")
+
+// RTXStatistics contains RTX (RFC 4588) receive statistics
+type RTXStatistics struct {
+	Packets      int // RTX packets received
+	Restored     int // Retransmissions restored and passed on
+	Padding      int // RTX packets without a payload (bandwidth probing)
+	Duplicates   int // Packets already received (either copy) or retransmissions too old to use
+	Unassociated int // RTX packets whose media SSRC could not be determined
+	Malformed    int
+}
+
+// DecodeRTX restores the original packet from an RTX packet (RFC 4588 section 4):
+// the first two payload bytes carry the original sequence number. The payload
+// of the returned packet aliases packet's.
+func DecodeRTX(packet *Packet, originalPT uint8, originalSSRC uint32) (*Packet, error) {
+	if len(packet.Payload) < 2 {
+		return nil, fmt.Errorf("%w: payload %d bytes", ErrInvalidRTX, len(packet.Payload))
+	}
+
+	restored := *packet
+	restored.PayloadType = originalPT
+	restored.SSRC = originalSSRC
+	restored.SequenceNumber = binary.BigEndian.Uint16(packet.Payload[0:2])
+	restored.Payload = packet.Payload[2:]
+	restored.Padding = false
+	restored.PaddingSize = 0
+	return &restored, nil
+}
+
+// EncodeRTX builds the RTX packet retransmitting packet with the given RTX
+// payload type, SSRC and sequence number
+func EncodeRTX(packet *Packet, rtxPT uint8, rtxSSRC uint32, rtxSeq uint16) *Packet {
+	rtx := *packet
+	rtx.PayloadType = rtxPT
+	rtx.SSRC = rtxSSRC
+	rtx.SequenceNumber = rtxSeq
+	rtx.Payload = make([]byte, 2+len(packet.Payload))
+	binary.BigEndian.PutUint16(rtx.Payload[0:2], packet.SequenceNumber)
+	copy(rtx.Payload[2:], packet.Payload)
+	return &rtx
+}
+
+// RTXReceiver maps RTX packets back to their original payload type, SSRC and
+// sequence number. Media packets are passed through Push too, so that
+// retransmissions of packets that already arrived are dropped.
+type RTXReceiver struct {
+	mu        sync.Mutex
+	apt       map[uint8]uint8   // RTX payload type to original payload type
+	ssrcs     map[uint32]uint32 // RTX SSRC to media SSRC
+	lastMedia map[uint8]uint32  // Original payload type to the last media SSRC seen
+	sources   map[uint32]*SourceState
+	stats     RTXStatistics
+}
+
+// NewRTXReceiver creates a receiver; register payload types with AddPayloadType
+func NewRTXReceiver() *RTXReceiver {
+	return &RTXReceiver{
+		apt:       make(map[uint8]uint8),
+		ssrcs:     make(map[uint32]uint32),
+		lastMedia: make(map[uint8]uint32),
+		sources:   make(map[uint32]*SourceState),
+	}
+}
+
+// AddPayloadType registers an RTX payload type and its associated original payload type (apt)
+func (r *RTXReceiver) AddPayloadType(rtxPT, apt uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.apt[rtxPT] = apt
+}
+
+// AssociateSSRC maps an RTX SSRC to its media SSRC (e.g. from a=ssrc-group:FID).
+// Without an association, RTX packets are attributed to the last media SSRC
+// seen with the original payload type, or to their own SSRC if it carries media.
+func (r *RTXReceiver) AssociateSSRC(rtxSSRC, mediaSSRC uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ssrcs[rtxSSRC] = mediaSSRC
+}
+
+// IsRTX reports whether packet uses a registered RTX payload type
+func (r *RTXReceiver) IsRTX(packet *Packet) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.apt[packet.PayloadType]
+	return ok
+}
+
+// Push processes a received packet. Media packets are returned unchanged
+// unless they were already received. RTX packets are returned restored with retransmitted set, or nil when they
+// carry no payload, repeat a packet already received, are older than the
+// reordering window, or cannot be associated with a media stream.
+func (r *RTXReceiver) Push(packet *Packet) (restored *Packet, retransmitted bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	apt, ok := r.apt[packet.PayloadType]
+	if !ok {
+		// An original arriving after its retransmission is a duplicate too
+		r.lastMedia[packet.PayloadType] = packet.SSRC
+		if r.source(packet.SSRC).Update(packet.SequenceNumber).Status == SequenceDuplicate {
+			r.stats.Duplicates++
+			return nil, false
+		}
+		return packet, false
+	}
+
+	r.stats.Packets++
+	if len(packet.Payload) == 0 {
+		r.stats.Padding++
+		return nil, true
+	}
+
+	mediaSSRC, ok := r.mediaSSRC(packet.SSRC, apt)
+	if !ok {
+		r.stats.Unassociated++
+		return nil, true
+	}
+
+	restored, err := DecodeRTX(packet, apt, mediaSSRC)
+	if err != nil {
+		r.stats.Malformed++
+		return nil, true
+	}
+
+	// Only packets inside the reordering window that have not arrived are useful
+	source := r.source(mediaSSRC)
+	if source.Valid() {
+		newest := uint16(source.ExtendedMaxSeq())
+		stale := CompareSequence(restored.SequenceNumber, newest) < 0 && SequenceDistance(restored.SequenceNumber, newest) >= MaxMisorder
+		if stale {
+			r.stats.Duplicates++
+			return nil, true
+		}
+		if status := source.Update(restored.SequenceNumber).Status; status != SequenceReordered && status != SequenceInOrder {
+			r.stats.Duplicates++
+			return nil, true
+		}
+	}
+
+	r.stats.Restored++
+	return restored, true
+}
+
+// GetStatistics returns receive statistics
+func (r *RTXReceiver) GetStatistics() RTXStatistics {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+// mediaSSRC resolves the media SSRC an RTX packet belongs to
+func (r *RTXReceiver) mediaSSRC(rtxSSRC uint32, apt uint8) (uint32, bool) {
+	if ssrc, ok := r.ssrcs[rtxSSRC]; ok {
+		return ssrc, true
+	}
+	if _, ok := r.sources[rtxSSRC]; ok {
+		return rtxSSRC, true // Same SSRC as the media stream
+	}
+	ssrc, ok := r.lastMedia[apt]
+	return ssrc, ok
+}
+
+// source returns the sequence state of a media SSRC
+func (r *RTXReceiver) source(ssrc uint32) *SourceState {
+	state := r.sources[ssrc]
+	if state == nil {
+		state = &SourceState{}
+		r.sources[ssrc] = state
+	}
+	return state
+}
@@ -0,0 +1,117 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRTX_RoundTrip tests encoding a retransmission and restoring the original
+func TestRTX_RoundTrip(t *testing.T) {
+	original := &Packet{Version: 2, Marker: true, PayloadType: 96, SequenceNumber: 4321, Timestamp: 9000, SSRC: 0x1111, Payload: []byte{0x65, 1, 2}}
+
+	rtx := EncodeRTX(original, 97, 0x2222, 7)
+	assert.Equal(t, uint8(97), rtx.PayloadType)
+	assert.Equal(t, uint16(7), rtx.SequenceNumber)
+	assert.Equal(t, []byte{0x10, 0xE1, 0x65, 1, 2}, rtx.Payload)
+
+	restored, err := DecodeRTX(rtx, 96, 0x1111)
+	require.NoError(t, err)
+	assert.Equal(t, original, restored)
+
+	_, err = DecodeRTX(&Packet{Payload: []byte{1}}, 96, 0x1111)
+	assert.ErrorIs(t, err, ErrInvalidRTX)
+}
+
+// TestRTXReceiver_Push tests SSRC association and duplicate suppression
+func TestRTXReceiver_Push(t *testing.T) {
+	media := func(seq uint16) *Packet {
+		return &Packet{PayloadType: 96, SequenceNumber: seq, SSRC: 0x1111, Payload: []byte{byte(seq)}}
+	}
+
+	tests := []struct {
+		name      string
+		associate bool
+		rtxSSRC   uint32
+	}{
+		{"FID association", true, 0x2222},
+		{"implicit by apt", false, 0x3333},
+		{"same SSRC", false, 0x1111},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver := NewRTXReceiver()
+			receiver.AddPayloadType(97, 96)
+			if tt.associate {
+				receiver.AssociateSSRC(tt.rtxSSRC, 0x1111)
+			}
+
+			for _, seq := range []uint16{1, 2, 4} {
+				p, retransmitted := receiver.Push(media(seq))
+				require.NotNil(t, p)
+				assert.False(t, retransmitted)
+			}
+			assert.True(t, receiver.IsRTX(EncodeRTX(media(3), 97, tt.rtxSSRC, 100)))
+
+			// Lost packet 3 is restored
+			p, retransmitted := receiver.Push(EncodeRTX(media(3), 97, tt.rtxSSRC, 100))
+			require.NotNil(t, p)
+			assert.True(t, retransmitted)
+			assert.Equal(t, media(3).Payload, p.Payload)
+			assert.Equal(t, uint16(3), p.SequenceNumber)
+			assert.Equal(t, uint32(0x1111), p.SSRC)
+			assert.Equal(t, uint8(96), p.PayloadType)
+
+			// A second retransmission and the late original are both duplicates
+			p, _ = receiver.Push(EncodeRTX(media(3), 97, tt.rtxSSRC, 101))
+			assert.Nil(t, p)
+			p, _ = receiver.Push(media(3))
+			assert.Nil(t, p)
+
+			// Padding-only RTX is dropped
+			p, _ = receiver.Push(&Packet{PayloadType: 97, SequenceNumber: 102, SSRC: tt.rtxSSRC})
+			assert.Nil(t, p)
+
+			stats := receiver.GetStatistics()
+			assert.Equal(t, 3, stats.Packets)
+			assert.Equal(t, 1, stats.Restored)
+			assert.Equal(t, 2, stats.Duplicates)
+			assert.Equal(t, 1, stats.Padding)
+		})
+	}
+}
+
+// TestRTXReceiver_Stale tests that retransmissions outside the reordering window are dropped
+func TestRTXReceiver_Stale(t *testing.T) {
+	receiver := NewRTXReceiver()
+	receiver.AddPayloadType(97, 96)
+
+	for seq := uint16(0); seq < 300; seq++ {
+		if seq != 10 && seq != 11 {
+			receiver.Push(&Packet{PayloadType: 96, SequenceNumber: seq, SSRC: 1, Payload: []byte{1}})
+		}
+	}
+
+	for _, seq := range []uint16{10, 11} {
+		p, _ := receiver.Push(EncodeRTX(&Packet{SequenceNumber: seq, Payload: []byte{1}}, 97, 2, seq))
+		assert.Nil(t, p)
+	}
+	assert.Equal(t, 2, receiver.GetStatistics().Duplicates)
+
+	// Media continues normally afterwards
+	p, _ := receiver.Push(&Packet{PayloadType: 96, SequenceNumber: 300, SSRC: 1, Payload: []byte{1}})
+	assert.NotNil(t, p)
+}
+
+// TestRTXReceiver_Unassociated tests RTX before any media
+func TestRTXReceiver_Unassociated(t *testing.T) {
+	receiver := NewRTXReceiver()
+	receiver.AddPayloadType(97, 96)
+
+	p, retransmitted := receiver.Push(&Packet{PayloadType: 97, SSRC: 2, Payload: []byte{0, 1, 2}})
+	assert.Nil(t, p)
+	assert.True(t, retransmitted)
+	assert.Equal(t, 1, receiver.GetStatistics().Unassociated)
+}
@@ -0,0 +1,291 @@
+package rtp
+
+// RFC 3550 appendix A.1 source validation parameters
+const (
+	// MinSequential is the number of in-order packets needed before a source is valid
+	MinSequential = 2
+	// MaxDropout is the largest forward sequence jump still treated as loss
+	MaxDropout = 3000
+	// MaxMisorder is the largest backward jump still treated as reordering
+	MaxMisorder = 100
+	// seqMod is the size of the 16-bit sequence number space
+	seqMod = 1 << 16
+	// seenWindow is the number of recent sequence numbers remembered for duplicate
+	// detection; it covers MaxMisorder, beyond which old packets are bad jumps anyway
+	seenWindow = 128
+)
+
+// SequenceStatus classifies a packet's sequence number relative to a source's state
+type SequenceStatus int
+
+const (
+	// SequenceInOrder is the next expected packet, or a forward jump (see SequenceUpdate.Gap)
+	SequenceInOrder SequenceStatus = iota
+	// SequenceReordered is a late packet that was not received before
+	SequenceReordered
+	// SequenceDuplicate is a packet that was already received
+	SequenceDuplicate
+	// SequenceProbation is a packet from a source that is not yet validated
+	SequenceProbation
+	// SequenceBadJump is a large jump; it is not counted until the next packet confirms it
+	SequenceBadJump
+	// SequenceRestarted is the packet confirming a sender restart; the state was resynchronized
+	SequenceRestarted
+)
+
+// String returns a readable status name
+func (s SequenceStatus) String() string {
+	switch s {
+	case SequenceInOrder:
+		return "in-order"
+	case SequenceReordered:
+		return "reordered"
+	case SequenceDuplicate:
+		return "duplicate"
+	case SequenceProbation:
+		return "probation"
+	case SequenceBadJump:
+		return "bad-jump"
+	case SequenceRestarted:
+		return "restarted"
+	default:
+		return "unknown"
+	}
+}
+
+// SequenceUpdate is the result of SourceState.Update
+type SequenceUpdate struct {
+	Status   SequenceStatus
+	Extended uint32 // Extended sequence number (cycles + seq) for valid packets
+	Gap      uint16 // Packets skipped immediately before an in-order packet
+}
+
+// Valid reports whether the packet was counted as received
+func (u SequenceUpdate) Valid() bool {
+	return u.Status == SequenceInOrder || u.Status == SequenceReordered || u.Status == SequenceRestarted
+}
+
+// SourceState tracks the sequence numbers of one RTP source as in RFC 3550
+// appendix A.1: probation of new sources, sequence cycle counting, resync after
+// a sender restart, and expected-versus-received loss accounting. Duplicates of
+// recent packets are detected and excluded from the received count.
+// The zero value is ready to use. SourceState is not safe for concurrent use.
+type SourceState struct {
+	initialized   bool
+	maxSeq        uint16
+	cycles        uint32 // Shifted count of sequence number cycles
+	baseSeq       uint32
+	badSeq        uint32 // Last bad sequence number + 1
+	probation     int
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+	duplicates    uint32
+	reordered     uint32
+	restarts      uint32
+	seen          [seenWindow / 64]uint64 // Bitmap of received extended sequence numbers
+}
+
+// SourceStatistics is a snapshot of SourceState counters
+type SourceStatistics struct {
+	Valid            bool   // Probation has completed
+	BaseSeq          uint32 // First valid sequence number
+	ExtendedMaxSeq   uint32 // Highest extended sequence number received
+	PacketsExpected  uint32
+	PacketsReceived  uint32
+	PacketsLost      int32 // Expected minus received, clamped to the 24-bit RTCP range
+	PacketsDuplicate uint32
+	PacketsReordered uint32
+	Restarts         uint32
+}
+
+// Update records a received sequence number and classifies it
+func (s *SourceState) Update(seq uint16) SequenceUpdate {
+	if !s.initialized {
+		s.initSeq(seq)
+		s.maxSeq = seq - 1
+		s.probation = MinSequential
+		s.initialized = true
+	}
+
+	udelta := seq - s.maxSeq
+
+	// New source: require MinSequential packets in sequence
+	if s.probation > 0 {
+		if seq != s.maxSeq+1 {
+			s.probation = MinSequential - 1
+			s.maxSeq = seq
+			return SequenceUpdate{Status: SequenceProbation}
+		}
+		s.probation--
+		s.maxSeq = seq
+		if s.probation > 0 {
+			return SequenceUpdate{Status: SequenceProbation}
+		}
+		s.initSeq(seq)
+		s.markReceived(uint32(seq))
+		return SequenceUpdate{Status: SequenceInOrder, Extended: uint32(seq)}
+	}
+
+	switch {
+	case udelta == 0:
+		s.duplicates++
+		return SequenceUpdate{Status: SequenceDuplicate, Extended: s.extendedMax()}
+
+	case udelta < MaxDropout:
+		// In order, with permissible gap
+		if seq < s.maxSeq {
+			s.cycles += seqMod
+		}
+		gap := udelta - 1
+		s.advanceSeen(uint32(udelta))
+		s.maxSeq = seq
+		s.markReceived(s.extendedMax())
+		return SequenceUpdate{Status: SequenceInOrder, Extended: s.extendedMax(), Gap: gap}
+
+	case udelta <= seqMod-MaxMisorder:
+		// The sequence number made a very large jump
+		if uint32(seq) == s.badSeq {
+			// Two sequential packets: assume the other side restarted without telling us
+			s.initSeq(seq)
+			s.restarts++
+			s.markReceived(uint32(seq))
+			return SequenceUpdate{Status: SequenceRestarted, Extended: uint32(seq)}
+		}
+		s.badSeq = uint32(seq+1) & (seqMod - 1)
+		return SequenceUpdate{Status: SequenceBadJump}
+
+	default:
+		// Duplicate or reordered packet
+		extended := s.extendedMax() - uint32(-udelta)
+		if s.wasReceived(extended) {
+			s.duplicates++
+			return SequenceUpdate{Status: SequenceDuplicate, Extended: extended}
+		}
+		s.reordered++
+		s.markReceived(extended)
+		return SequenceUpdate{Status: SequenceReordered, Extended: extended}
+	}
+}
+
+// Valid reports whether the source has passed probation
+func (s *SourceState) Valid() bool {
+	return s.initialized && s.probation == 0
+}
+
+// ExtendedMaxSeq returns the highest extended sequence number received
+func (s *SourceState) ExtendedMaxSeq() uint32 {
+	return s.extendedMax()
+}
+
+// Expected returns the number of packets expected since the source became valid
+func (s *SourceState) Expected() uint32 {
+	if !s.Valid() {
+		return 0
+	}
+	return s.extendedMax() - s.baseSeq + 1
+}
+
+// Received returns the number of distinct valid packets received
+func (s *SourceState) Received() uint32 {
+	return s.received
+}
+
+// Lost returns the cumulative number of packets lost, clamped to 24 bits (RFC 3550 A.3)
+func (s *SourceState) Lost() int32 {
+	lost := int64(s.Expected()) - int64(s.received)
+	if lost > 0x7FFFFF {
+		lost = 0x7FFFFF
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+	return int32(lost)
+}
+
+// Interval returns the packets expected and received since the previous call,
+// for the RTCP fraction lost (RFC 3550 A.3)
+func (s *SourceState) Interval() (expected, received uint32) {
+	exp := s.Expected()
+	expected = exp - s.expectedPrior
+	received = s.received - s.receivedPrior
+	s.expectedPrior = exp
+	s.receivedPrior = s.received
+	return expected, received
+}
+
+// GetStatistics returns a snapshot of the counters
+func (s *SourceState) GetStatistics() SourceStatistics {
+	return SourceStatistics{
+		Valid:            s.Valid(),
+		BaseSeq:          s.baseSeq,
+		ExtendedMaxSeq:   s.extendedMax(),
+		PacketsExpected:  s.Expected(),
+		PacketsReceived:  s.received,
+		PacketsLost:      s.Lost(),
+		PacketsDuplicate: s.duplicates,
+		PacketsReordered: s.reordered,
+		Restarts:         s.restarts,
+	}
+}
+
+// Reset forgets the source; the next packet starts probation again
+func (s *SourceState) Reset() {
+	*s = SourceState{}
+}
+
+// initSeq resynchronizes to seq as in RFC 3550 init_seq(); lifetime counters are kept
+func (s *SourceState) initSeq(seq uint16) {
+	s.baseSeq = uint32(seq)
+	s.maxSeq = seq
+	s.badSeq = seqMod + 1 // So seq == badSeq is false
+	s.cycles = 0
+	s.received = 0
+	s.receivedPrior = 0
+	s.expectedPrior = 0
+	s.seen = [seenWindow / 64]uint64{}
+}
+
+// extendedMax returns cycles + maxSeq
+func (s *SourceState) extendedMax() uint32 {
+	return s.cycles + uint32(s.maxSeq)
+}
+
+// advanceSeen clears bitmap slots the window slides over when maxSeq advances by delta
+func (s *SourceState) advanceSeen(delta uint32) {
+	if delta >= seenWindow {
+		s.seen = [seenWindow / 64]uint64{}
+		return
+	}
+	for i := uint32(1); i <= delta; i++ {
+		slot := (s.extendedMax() + i) % seenWindow
+		s.seen[slot/64] &^= 1 << (slot % 64)
+	}
+}
+
+// markReceived counts a distinct packet
+func (s *SourceState) markReceived(extended uint32) {
+	slot := extended % seenWindow
+	s.seen[slot/64] |= 1 << (slot % 64)
+	s.received++
+}
+
+// wasReceived reports whether a packet within the window was already received.
+// Packets older than the window are assumed new.
+func (s *SourceState) wasReceived(extended uint32) bool {
+	if s.extendedMax()-extended >= seenWindow {
+		return false
+	}
+	slot := extended % seenWindow
+	return s.seen[slot/64]&(1<<(slot%64)) != 0
+}
+
+// CompareSequence compares two sequence numbers in RTP order (wraparound aware).
+// It returns -1 if a comes before b, 0 if equal and 1 if a comes after b.
+func CompareSequence(a, b uint16) int {
+	return sequenceCompare(a, b)
+}
+
+// SequenceDistance returns how many sequence numbers from must advance to reach to
+func SequenceDistance(from, to uint16) uint16 {
+	return to - from
+}
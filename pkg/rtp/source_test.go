@@ -0,0 +1,146 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSourceState_Update tests sequence classification across the RFC 3550 A.1 cases
+func TestSourceState_Update(t *testing.T) {
+	tests := []struct {
+		name     string
+		seqs     []uint16
+		statuses []SequenceStatus
+		received uint32
+		lost     int32
+		extended uint32
+	}{
+		{
+			name:     "probation then in order",
+			seqs:     []uint16{10, 11, 12},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder},
+			received: 2,
+			extended: 12,
+		},
+		{
+			name:     "probation restarts on gap",
+			seqs:     []uint16{10, 12, 13, 14},
+			statuses: []SequenceStatus{SequenceProbation, SequenceProbation, SequenceInOrder, SequenceInOrder},
+			received: 2,
+			extended: 14,
+		},
+		{
+			name:     "wraparound",
+			seqs:     []uint16{65534, 65535, 0, 1},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder, SequenceInOrder},
+			received: 3,
+			extended: 1<<16 | 1,
+		},
+		{
+			name:     "loss",
+			seqs:     []uint16{1, 2, 5},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder},
+			received: 2,
+			lost:     2,
+			extended: 5,
+		},
+		{
+			name:     "reorder fills gap",
+			seqs:     []uint16{1, 2, 4, 3},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder, SequenceReordered},
+			received: 3,
+			extended: 4,
+		},
+		{
+			name:     "duplicates are not received",
+			seqs:     []uint16{1, 2, 3, 3, 2},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder, SequenceDuplicate, SequenceDuplicate},
+			received: 2,
+			extended: 3,
+		},
+		{
+			name:     "single stray jump is ignored",
+			seqs:     []uint16{1, 2, 20000, 3},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceBadJump, SequenceInOrder},
+			received: 2,
+			extended: 3,
+		},
+		{
+			name:     "restart resyncs",
+			seqs:     []uint16{1, 2, 3, 40000, 40001, 40002},
+			statuses: []SequenceStatus{SequenceProbation, SequenceInOrder, SequenceInOrder, SequenceBadJump, SequenceRestarted, SequenceInOrder},
+			received: 2,
+			extended: 40002,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var state SourceState
+			require.Len(t, tt.statuses, len(tt.seqs))
+			for i, seq := range tt.seqs {
+				assert.Equal(t, tt.statuses[i], state.Update(seq).Status, "seq %d", seq)
+			}
+			assert.True(t, state.Valid())
+			assert.Equal(t, tt.received, state.Received())
+			assert.Equal(t, tt.lost, state.Lost())
+			assert.Equal(t, tt.extended, state.ExtendedMaxSeq())
+		})
+	}
+}
+
+// TestSourceState_Gap tests the gap reported before an in-order packet
+func TestSourceState_Gap(t *testing.T) {
+	var state SourceState
+	state.Update(65530)
+	state.Update(65531)
+
+	update := state.Update(2)
+	assert.Equal(t, SequenceInOrder, update.Status)
+	assert.Equal(t, uint16(6), update.Gap)
+	assert.Equal(t, uint32(1<<16|2), update.Extended)
+	assert.True(t, update.Valid())
+}
+
+// TestSourceState_Statistics tests counters, intervals and reset
+func TestSourceState_Statistics(t *testing.T) {
+	var state SourceState
+	for _, seq := range []uint16{99, 100, 101, 103, 103, 102, 5000, 5001} {
+		state.Update(seq)
+	}
+
+	stats := state.GetStatistics()
+	assert.True(t, stats.Valid)
+	assert.Equal(t, uint32(5001), stats.BaseSeq)
+	assert.Equal(t, uint32(1), stats.PacketsReceived)
+	assert.Equal(t, uint32(1), stats.PacketsDuplicate)
+	assert.Equal(t, uint32(1), stats.PacketsReordered)
+	assert.Equal(t, uint32(1), stats.Restarts)
+
+	state.Update(5003)
+	expected, received := state.Interval()
+	assert.Equal(t, uint32(3), expected)
+	assert.Equal(t, uint32(2), received)
+	expected, received = state.Interval()
+	assert.Zero(t, expected)
+	assert.Zero(t, received)
+
+	state.Reset()
+	assert.False(t, state.Valid())
+	assert.Zero(t, state.Expected())
+	assert.Equal(t, SequenceProbation, state.Update(7).Status)
+}
+
+// TestSourceState_OldPackets tests duplicates within MaxMisorder and very old packets
+func TestSourceState_OldPackets(t *testing.T) {
+	var state SourceState
+	for seq := uint16(0); seq < 2000; seq++ {
+		state.Update(seq)
+	}
+
+	assert.Equal(t, SequenceDuplicate, state.Update(1999-MaxMisorder+1).Status)
+	assert.Equal(t, SequenceBadJump, state.Update(1999-MaxMisorder).Status)
+	assert.Equal(t, uint32(1999), state.Received())
+}
@@ -0,0 +1,553 @@
+package rtp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SRTPProfile identifies an SRTP crypto suite
+type SRTPProfile int
+
+// Supported SRTP crypto suites (RFC 4568 and RFC 7714)
+const (
+	SRTP_AES_CM_128_HMAC_SHA1_80 SRTPProfile = iota + 1
+	SRTP_AES_CM_128_HMAC_SHA1_32
+	SRTP_AEAD_AES_128_GCM
+)
+
+// SRTP key derivation labels (RFC 3711 section 4.3.2)
+const (
+	labelRTPEncryption  = 0x00
+	labelRTPAuth        = 0x01
+	labelRTPSalt        = 0x02
+	labelRTCPEncryption = 0x03
+	labelRTCPAuth       = 0x04
+	labelRTCPSalt       = 0x05
+)
+
+const (
+	srtpAuthKeyLength   = 20
+	srtcpIndexSize      = 4
+	srtcpEncryptedFlag  = 0x80000000
+	srtpReplayWindow    = 64
+	srtcpTagLength      = 10 // SRTCP always uses an 80-bit tag with AES-CM (RFC 4568 section 6.2)
+	srtpGCMTagLength    = 16
+	srtpMaxSRTCPIndex   = 0x7FFFFFFF
+	srtpIndexSpaceRange = 1 << 16
+)
+
+var (
+	// ErrSRTPUnsupportedProfile indicates an unknown crypto suite
+	ErrSRTPUnsupportedProfile = errors.New("unsupported SRTP crypto suite")
+	// ErrSRTPInvalidKey indicates master key or salt of the wrong length
+	ErrSRTPInvalidKey = errors.New("invalid SRTP master key")
+	// ErrSRTPPacketTooShort indicates a protected packet shorter than its header and tag
+	ErrSRTPPacketTooShort = errors.New("SRTP packet too short")
+	// ErrSRTPAuthFailed indicates the authentication tag did not verify
+	ErrSRTPAuthFailed = errors.New("SRTP authentication failed")
+	// ErrSRTPReplay indicates a packet index that was already received or is too old
+	ErrSRTPReplay = errors.New("SRTP replayed packet")
+)
+
+// ParseSRTPProfile maps an SDES crypto suite name to a profile
+func ParseSRTPProfile(suite string) (SRTPProfile, error) {
+	for _, p := range []SRTPProfile{SRTP_AES_CM_128_HMAC_SHA1_80, SRTP_AES_CM_128_HMAC_SHA1_32, SRTP_AEAD_AES_128_GCM} {
+		if strings.EqualFold(suite, p.String()) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrSRTPUnsupportedProfile, suite)
+}
+
+// String returns the SDES crypto suite name
+func (p SRTPProfile) String() string {
+	switch p {
+	case SRTP_AES_CM_128_HMAC_SHA1_80:
+		return "AES_CM_128_HMAC_SHA1_80"
+	case SRTP_AES_CM_128_HMAC_SHA1_32:
+		return "AES_CM_128_HMAC_SHA1_32"
+	case SRTP_AEAD_AES_128_GCM:
+		return "AEAD_AES_128_GCM"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// KeyLength returns the master key length in bytes
+func (p SRTPProfile) KeyLength() int {
+	return 16
+}
+
+// SaltLength returns the master salt length in bytes
+func (p SRTPProfile) SaltLength() int {
+	if p == SRTP_AEAD_AES_128_GCM {
+		return 12
+	}
+	return 14
+}
+
+// rtpTagLength returns the SRTP authentication tag length
+func (p SRTPProfile) rtpTagLength() int {
+	switch p {
+	case SRTP_AES_CM_128_HMAC_SHA1_32:
+		return 4
+	case SRTP_AEAD_AES_128_GCM:
+		return srtpGCMTagLength
+	default:
+		return 10
+	}
+}
+
+// SRTPStatistics contains SRTP/SRTCP receive statistics
+type SRTPStatistics struct {
+	RTPDecrypted  int
+	RTCPDecrypted int
+	AuthFailures  int // Packets rejected by authentication
+	Replayed      int // Packets rejected by replay protection
+}
+
+// srtpSessionKeys are the keys derived for one direction of RTP or RTCP
+type srtpSessionKeys struct {
+	block cipher.Block
+	aead  cipher.AEAD // AES-GCM profiles only
+	auth  []byte      // HMAC-SHA1 key, AES-CM profiles only
+	salt  []byte
+}
+
+// replayWindow is a sliding window over packet indices (RFC 3711 section 3.3.2)
+type replayWindow struct {
+	initialized bool
+	highest     uint64
+	bitmap      uint64 // Bit i set when highest-i was received
+}
+
+// check reports whether index may be accepted
+func (w *replayWindow) check(index uint64) bool {
+	if !w.initialized || index > w.highest {
+		return true
+	}
+	diff := w.highest - index
+	return diff < srtpReplayWindow && w.bitmap&(1<<diff) == 0
+}
+
+// accept records an authenticated index
+func (w *replayWindow) accept(index uint64) {
+	if !w.initialized {
+		w.initialized = true
+		w.highest = index
+		w.bitmap = 1
+		return
+	}
+	if index > w.highest {
+		shift := index - w.highest
+		if shift >= srtpReplayWindow {
+			w.bitmap = 0
+		} else {
+			w.bitmap <<= shift
+		}
+		w.bitmap |= 1
+		w.highest = index
+		return
+	}
+	w.bitmap |= 1 << (w.highest - index)
+}
+
+// srtpStreamState tracks the rollover counter and replay window of one SSRC
+type srtpStreamState struct {
+	roc     uint32
+	lastSeq uint16
+	replay  replayWindow
+}
+
+// estimateIndex guesses the ROC for seq (RFC 3711 appendix A)
+func (s *srtpStreamState) estimateIndex(seq uint16) (uint32, uint64) {
+	roc := s.roc
+	if s.replay.initialized {
+		if s.lastSeq < srtpIndexSpaceRange/2 {
+			if int(seq)-int(s.lastSeq) > srtpIndexSpaceRange/2 && roc > 0 {
+				roc--
+			}
+		} else if int(s.lastSeq)-srtpIndexSpaceRange/2 > int(seq) {
+			roc++
+		}
+	}
+	return roc, uint64(roc)<<16 | uint64(seq)
+}
+
+// update advances the state after an authenticated packet
+func (s *srtpStreamState) update(roc uint32, seq uint16, index uint64) {
+	if !s.replay.initialized || index > s.replay.highest {
+		s.roc = roc
+		s.lastSeq = seq
+	}
+	s.replay.accept(index)
+}
+
+// SRTPContext protects and unprotects SRTP and SRTCP packets with keys
+// derived from one SDES master key (RFC 3711, RFC 7714). It tracks the
+// rollover counter and replay window of every SSRC.
+type SRTPContext struct {
+	mu          sync.Mutex
+	profile     SRTPProfile
+	rtpKeys     srtpSessionKeys
+	rtcpKeys    srtpSessionKeys
+	rtpIn       map[uint32]*srtpStreamState
+	rtcpIn      map[uint32]*replayWindow
+	rtpOut      map[uint32]*srtpStreamState
+	rtcpOutNext uint32 // Next SRTCP index to send
+	stats       SRTPStatistics
+}
+
+// NewSRTPContext derives session keys from a master key and salt
+func NewSRTPContext(profile SRTPProfile, masterKey, masterSalt []byte) (*SRTPContext, error) {
+	switch profile {
+	case SRTP_AES_CM_128_HMAC_SHA1_80, SRTP_AES_CM_128_HMAC_SHA1_32, SRTP_AEAD_AES_128_GCM:
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrSRTPUnsupportedProfile, profile)
+	}
+	if len(masterKey) != profile.KeyLength() || len(masterSalt) != profile.SaltLength() {
+		return nil, fmt.Errorf("%w: %s needs %d-byte key and %d-byte salt, got %d and %d", ErrSRTPInvalidKey,
+			profile, profile.KeyLength(), profile.SaltLength(), len(masterKey), len(masterSalt))
+	}
+
+	ctx := &SRTPContext{
+		profile: profile,
+		rtpIn:   make(map[uint32]*srtpStreamState),
+		rtcpIn:  make(map[uint32]*replayWindow),
+		rtpOut:  make(map[uint32]*srtpStreamState),
+	}
+
+	var err error
+	if ctx.rtpKeys, err = deriveSessionKeys(profile, masterKey, masterSalt, labelRTPEncryption, labelRTPAuth, labelRTPSalt); err != nil {
+		return nil, err
+	}
+	if ctx.rtcpKeys, err = deriveSessionKeys(profile, masterKey, masterSalt, labelRTCPEncryption, labelRTCPAuth, labelRTCPSalt); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// Profile returns the crypto suite
+func (c *SRTPContext) Profile() SRTPProfile {
+	return c.profile
+}
+
+// GetStatistics returns receive statistics
+func (c *SRTPContext) GetStatistics() SRTPStatistics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// DecryptRTP authenticates and decrypts an SRTP packet in place and returns
+// the plain RTP packet (a prefix of data)
+func (c *SRTPContext) DecryptRTP(data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	headerLen, err := rtpHeaderLength(data)
+	if err != nil {
+		return nil, err
+	}
+	tagLen := c.profile.rtpTagLength()
+	if len(data) < headerLen+tagLen {
+		return nil, fmt.Errorf("%w: %d bytes", ErrSRTPPacketTooShort, len(data))
+	}
+
+	ssrc := binary.BigEndian.Uint32(data[8:12])
+	seq := binary.BigEndian.Uint16(data[2:4])
+	state := c.rtpIn[ssrc]
+	if state == nil {
+		state = &srtpStreamState{}
+	}
+	roc, index := state.estimateIndex(seq)
+	if !state.replay.check(index) {
+		c.stats.Replayed++
+		return nil, fmt.Errorf("%w: SSRC 0x%x index %d", ErrSRTPReplay, ssrc, index)
+	}
+
+	var plain []byte
+	if c.profile == SRTP_AEAD_AES_128_GCM {
+		iv := c.rtpGCMIV(ssrc, roc, seq)
+		payload, err := c.rtpKeys.aead.Open(data[headerLen:headerLen], iv, data[headerLen:], data[:headerLen])
+		if err != nil {
+			c.stats.AuthFailures++
+			return nil, ErrSRTPAuthFailed
+		}
+		plain = data[:headerLen+len(payload)]
+	} else {
+		body := data[:len(data)-tagLen]
+		if !hmac.Equal(c.rtpTag(body, roc)[:tagLen], data[len(data)-tagLen:]) {
+			c.stats.AuthFailures++
+			return nil, ErrSRTPAuthFailed
+		}
+		c.rtpCTR(ssrc, index).XORKeyStream(body[headerLen:], body[headerLen:])
+		plain = body
+	}
+
+	if c.rtpIn[ssrc] == nil {
+		c.rtpIn[ssrc] = state
+	}
+	state.update(roc, seq, index)
+	c.stats.RTPDecrypted++
+	return plain, nil
+}
+
+// EncryptRTP protects an RTP packet and returns the SRTP packet in a new buffer
+func (c *SRTPContext) EncryptRTP(data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	headerLen, err := rtpHeaderLength(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ssrc := binary.BigEndian.Uint32(data[8:12])
+	seq := binary.BigEndian.Uint16(data[2:4])
+	state := c.rtpOut[ssrc]
+	if state == nil {
+		state = &srtpStreamState{}
+		c.rtpOut[ssrc] = state
+	} else if seq < state.lastSeq && state.lastSeq-seq > srtpIndexSpaceRange/2 {
+		state.roc++
+	}
+	state.lastSeq = seq
+	index := uint64(state.roc)<<16 | uint64(seq)
+
+	out := make([]byte, len(data), len(data)+c.profile.rtpTagLength())
+	copy(out, data)
+
+	if c.profile == SRTP_AEAD_AES_128_GCM {
+		iv := c.rtpGCMIV(ssrc, state.roc, seq)
+		return c.rtpKeys.aead.Seal(out[:headerLen], iv, out[headerLen:], out[:headerLen]), nil
+	}
+
+	c.rtpCTR(ssrc, index).XORKeyStream(out[headerLen:], out[headerLen:])
+	return append(out, c.rtpTag(out, state.roc)[:c.profile.rtpTagLength()]...), nil
+}
+
+// DecryptRTCP authenticates and decrypts an SRTCP packet in place and returns
+// the plain compound RTCP packet (a prefix of data)
+func (c *SRTPContext) DecryptRTCP(data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// AES-CM appends an 80-bit tag after the index; the GCM tag is part of the ciphertext
+	tagLen := srtcpTagLength
+	minLen := 8 + srtcpIndexSize + srtcpTagLength
+	if c.profile == SRTP_AEAD_AES_128_GCM {
+		tagLen = 0
+		minLen = 8 + srtcpIndexSize + srtpGCMTagLength
+	}
+	if len(data) < minLen {
+		return nil, fmt.Errorf("%w: %d bytes", ErrSRTPPacketTooShort, len(data))
+	}
+
+	trailer := len(data) - tagLen - srtcpIndexSize
+	eIndex := binary.BigEndian.Uint32(data[trailer : trailer+srtcpIndexSize])
+	encrypted := eIndex&srtcpEncryptedFlag != 0
+	index := eIndex & srtpMaxSRTCPIndex
+	ssrc := binary.BigEndian.Uint32(data[4:8])
+
+	window := c.rtcpIn[ssrc]
+	if window == nil {
+		window = &replayWindow{}
+	}
+	if !window.check(uint64(index)) {
+		c.stats.Replayed++
+		return nil, fmt.Errorf("%w: SRTCP SSRC 0x%x index %d", ErrSRTPReplay, ssrc, index)
+	}
+
+	var plain []byte
+	if c.profile == SRTP_AEAD_AES_128_GCM {
+		iv := c.rtcpGCMIV(ssrc, index)
+		var err error
+		if encrypted {
+			aad := append(append([]byte(nil), data[:8]...), data[trailer:]...)
+			var payload []byte
+			payload, err = c.rtcpKeys.aead.Open(data[8:8], iv, data[8:trailer], aad)
+			plain = data[:8+len(payload)]
+		} else {
+			// Authentication only: the whole packet and index are associated data
+			body := trailer - srtpGCMTagLength
+			aad := append(append([]byte(nil), data[:body]...), data[trailer:]...)
+			_, err = c.rtcpKeys.aead.Open(nil, iv, data[body:trailer], aad)
+			plain = data[:body]
+		}
+		if err != nil {
+			c.stats.AuthFailures++
+			return nil, ErrSRTPAuthFailed
+		}
+	} else {
+		authenticated := data[:len(data)-tagLen]
+		mac := hmac.New(sha1.New, c.rtcpKeys.auth)
+		mac.Write(authenticated)
+		if !hmac.Equal(mac.Sum(nil)[:tagLen], data[len(data)-tagLen:]) {
+			c.stats.AuthFailures++
+			return nil, ErrSRTPAuthFailed
+		}
+		plain = data[:trailer]
+		if encrypted {
+			c.ctr(&c.rtcpKeys, ssrc, uint64(index)).XORKeyStream(plain[8:], plain[8:])
+		}
+	}
+
+	if c.rtcpIn[ssrc] == nil {
+		c.rtcpIn[ssrc] = window
+	}
+	window.accept(uint64(index))
+	c.stats.RTCPDecrypted++
+	return plain, nil
+}
+
+// EncryptRTCP protects a compound RTCP packet and returns the SRTCP packet in a new buffer
+func (c *SRTPContext) EncryptRTCP(data []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrSRTPPacketTooShort, len(data))
+	}
+	ssrc := binary.BigEndian.Uint32(data[4:8])
+	index := c.rtcpOutNext
+	c.rtcpOutNext = (c.rtcpOutNext + 1) & srtpMaxSRTCPIndex
+
+	var eIndex [srtcpIndexSize]byte
+	binary.BigEndian.PutUint32(eIndex[:], srtcpEncryptedFlag|index)
+
+	if c.profile == SRTP_AEAD_AES_128_GCM {
+		out := make([]byte, 8, len(data)+srtpGCMTagLength+srtcpIndexSize)
+		copy(out, data[:8])
+		aad := append(append([]byte(nil), data[:8]...), eIndex[:]...)
+		out = c.rtcpKeys.aead.Seal(out, c.rtcpGCMIV(ssrc, index), data[8:], aad)
+		return append(out, eIndex[:]...), nil
+	}
+
+	out := make([]byte, len(data), len(data)+srtcpIndexSize+srtcpTagLength)
+	copy(out, data)
+	c.ctr(&c.rtcpKeys, ssrc, uint64(index)).XORKeyStream(out[8:], out[8:])
+	out = append(out, eIndex[:]...)
+	mac := hmac.New(sha1.New, c.rtcpKeys.auth)
+	mac.Write(out)
+	return append(out, mac.Sum(nil)[:srtcpTagLength]...), nil
+}
+
+// rtpTag computes the HMAC-SHA1 tag over the authenticated portion and ROC
+func (c *SRTPContext) rtpTag(authenticated []byte, roc uint32) []byte {
+	mac := hmac.New(sha1.New, c.rtpKeys.auth)
+	mac.Write(authenticated)
+	var rocBytes [4]byte
+	binary.BigEndian.PutUint32(rocBytes[:], roc)
+	mac.Write(rocBytes[:])
+	return mac.Sum(nil)
+}
+
+// rtpCTR returns the AES-CM keystream for an RTP packet
+func (c *SRTPContext) rtpCTR(ssrc uint32, index uint64) cipher.Stream {
+	return c.ctr(&c.rtpKeys, ssrc, index)
+}
+
+// ctr returns the AES-CM keystream: IV = (salt * 2^16) XOR (SSRC * 2^64) XOR (index * 2^16)
+func (c *SRTPContext) ctr(keys *srtpSessionKeys, ssrc uint32, index uint64) cipher.Stream {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, keys.salt)
+	var ssrcBytes [4]byte
+	binary.BigEndian.PutUint32(ssrcBytes[:], ssrc)
+	for i := 0; i < 4; i++ {
+		iv[4+i] ^= ssrcBytes[i]
+	}
+	for i := 0; i < 6; i++ {
+		iv[8+i] ^= byte(index >> (8 * (5 - i)))
+	}
+	return cipher.NewCTR(keys.block, iv)
+}
+
+// rtpGCMIV builds the RFC 7714 section 8.1 IV
+func (c *SRTPContext) rtpGCMIV(ssrc, roc uint32, seq uint16) []byte {
+	iv := make([]byte, 12)
+	binary.BigEndian.PutUint32(iv[2:6], ssrc)
+	binary.BigEndian.PutUint32(iv[6:10], roc)
+	binary.BigEndian.PutUint16(iv[10:12], seq)
+	for i := range iv {
+		iv[i] ^= c.rtpKeys.salt[i]
+	}
+	return iv
+}
+
+// rtcpGCMIV builds the RFC 7714 section 9.1 IV
+func (c *SRTPContext) rtcpGCMIV(ssrc, index uint32) []byte {
+	iv := make([]byte, 12)
+	binary.BigEndian.PutUint32(iv[2:6], ssrc)
+	binary.BigEndian.PutUint32(iv[8:12], index&srtpMaxSRTCPIndex)
+	for i := range iv {
+		iv[i] ^= c.rtcpKeys.salt[i]
+	}
+	return iv
+}
+
+// deriveSessionKeys derives the encryption, authentication and salt keys for one label set
+func deriveSessionKeys(profile SRTPProfile, masterKey, masterSalt []byte, encLabel, authLabel, saltLabel byte) (srtpSessionKeys, error) {
+	var keys srtpSessionKeys
+
+	encKey, err := srtpKDF(masterKey, masterSalt, encLabel, profile.KeyLength())
+	if err != nil {
+		return keys, err
+	}
+	if keys.block, err = aes.NewCipher(encKey); err != nil {
+		return keys, err
+	}
+	if keys.salt, err = srtpKDF(masterKey, masterSalt, saltLabel, profile.SaltLength()); err != nil {
+		return keys, err
+	}
+
+	if profile == SRTP_AEAD_AES_128_GCM {
+		keys.aead, err = cipher.NewGCM(keys.block)
+		return keys, err
+	}
+	keys.auth, err = srtpKDF(masterKey, masterSalt, authLabel, srtpAuthKeyLength)
+	return keys, err
+}
+
+// srtpKDF is the AES-CM key derivation function (RFC 3711 section 4.3.3) with
+// a key derivation rate of zero
+func srtpKDF(masterKey, masterSalt []byte, label byte, length int) ([]byte, error) {
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSRTPInvalidKey, err)
+	}
+
+	// x = key_id XOR master_salt, with key_id = label || 48-bit zero index
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, masterSalt)
+	iv[7] ^= label
+
+	out := make([]byte, length)
+	cipher.NewCTR(block, iv).XORKeyStream(out, out)
+	return out, nil
+}
+
+// rtpHeaderLength returns the length of the RTP header including CSRCs and extension
+func rtpHeaderLength(data []byte) (int, error) {
+	if len(data) < 12 {
+		return 0, ErrPacketTooShort
+	}
+	length := 12 + int(data[0]&0x0F)*4
+	if data[0]&0x10 != 0 {
+		if len(data) < length+4 {
+			return 0, ErrPacketTooShort
+		}
+		length += 4 + int(binary.BigEndian.Uint16(data[length+2:length+4]))*4
+	}
+	if len(data) < length {
+		return 0, ErrPacketTooShort
+	}
+	return length, nil
+}
@@ -0,0 +1,199 @@
+package rtp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TestSRTPKDF tests key derivation against RFC 3711 appendix B.3
+func TestSRTPKDF(t *testing.T) {
+	masterKey := mustHex(t, "E1F97A0D3E018BE0D64FA32C06DE4139")
+	masterSalt := mustHex(t, "0EC675AD498AFEEBB6960B3AABE6")
+
+	encKey, err := srtpKDF(masterKey, masterSalt, labelRTPEncryption, 16)
+	require.NoError(t, err)
+	assert.Equal(t, mustHex(t, "C61E7A93744F39EE10734AFE3FF7A087"), encKey)
+
+	salt, err := srtpKDF(masterKey, masterSalt, labelRTPSalt, 14)
+	require.NoError(t, err)
+	assert.Equal(t, mustHex(t, "30CBBC08863D8C85D49DB34A9AE1"), salt)
+
+	auth, err := srtpKDF(masterKey, masterSalt, labelRTPAuth, 20)
+	require.NoError(t, err)
+	assert.Equal(t, mustHex(t, "CEBE321F6FF7716B6FD4AB49AF256A156D38BAA4"), auth)
+}
+
+// srtpTestContexts returns sender and receiver contexts sharing a master key
+func srtpTestContexts(t *testing.T, profile SRTPProfile) (*SRTPContext, *SRTPContext) {
+	key := make([]byte, profile.KeyLength())
+	salt := make([]byte, profile.SaltLength())
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	for i := range salt {
+		salt[i] = byte(0xA0 + i)
+	}
+	sender, err := NewSRTPContext(profile, key, salt)
+	require.NoError(t, err)
+	receiver, err := NewSRTPContext(profile, key, salt)
+	require.NoError(t, err)
+	return sender, receiver
+}
+
+// srtpTestPacket marshals an RTP packet with a header extension and CSRC
+func srtpTestPacket(t *testing.T, seq uint16) []byte {
+	data, err := (&Packet{
+		PayloadType:      96,
+		SequenceNumber:   seq,
+		Timestamp:        uint32(seq) * 3000,
+		SSRC:             0xCAFEBABE,
+		CSRC:             []uint32{7},
+		Extension:        true,
+		ExtensionProfile: 0xBEDE,
+		ExtensionPayload: []byte{0x10, 0xAA, 0, 0},
+		Payload:          []byte("secret video payload"),
+	}).Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+// TestSRTP_RTPRoundTrip tests protect/unprotect for every profile, including ROC rollover
+func TestSRTP_RTPRoundTrip(t *testing.T) {
+	for _, profile := range []SRTPProfile{SRTP_AES_CM_128_HMAC_SHA1_80, SRTP_AES_CM_128_HMAC_SHA1_32, SRTP_AEAD_AES_128_GCM} {
+		t.Run(profile.String(), func(t *testing.T) {
+			sender, receiver := srtpTestContexts(t, profile)
+
+			for _, seq := range []uint16{65534, 65535, 0, 1} {
+				plain := srtpTestPacket(t, seq)
+				protected, err := sender.EncryptRTP(plain)
+				require.NoError(t, err)
+				assert.Len(t, protected, len(plain)+profile.rtpTagLength())
+				assert.NotContains(t, string(protected), "secret")
+
+				decrypted, err := receiver.DecryptRTP(protected)
+				require.NoError(t, err, "seq %d", seq)
+				assert.Equal(t, plain, decrypted)
+			}
+
+			assert.Equal(t, uint32(1), receiver.rtpIn[0xCAFEBABE].roc)
+			assert.Equal(t, 4, receiver.GetStatistics().RTPDecrypted)
+		})
+	}
+}
+
+// TestSRTP_RTPRejects tests authentication failures and replays
+func TestSRTP_RTPRejects(t *testing.T) {
+	for _, profile := range []SRTPProfile{SRTP_AES_CM_128_HMAC_SHA1_80, SRTP_AEAD_AES_128_GCM} {
+		t.Run(profile.String(), func(t *testing.T) {
+			sender, receiver := srtpTestContexts(t, profile)
+
+			first, err := sender.EncryptRTP(srtpTestPacket(t, 100))
+			require.NoError(t, err)
+			second, err := sender.EncryptRTP(srtpTestPacket(t, 101))
+			require.NoError(t, err)
+			replay := append([]byte(nil), first...)
+
+			tampered := append([]byte(nil), second...)
+			tampered[len(tampered)-profile.rtpTagLength()-1] ^= 1
+			_, err = receiver.DecryptRTP(tampered)
+			assert.ErrorIs(t, err, ErrSRTPAuthFailed)
+
+			_, err = receiver.DecryptRTP(first)
+			require.NoError(t, err)
+			_, err = receiver.DecryptRTP(second)
+			require.NoError(t, err)
+
+			_, err = receiver.DecryptRTP(replay)
+			assert.ErrorIs(t, err, ErrSRTPReplay)
+
+			_, err = receiver.DecryptRTP(second[:14])
+			assert.Error(t, err)
+
+			stats := receiver.GetStatistics()
+			assert.Equal(t, 1, stats.AuthFailures)
+			assert.Equal(t, 1, stats.Replayed)
+			assert.Equal(t, 2, stats.RTPDecrypted)
+		})
+	}
+}
+
+// TestSRTP_RTCPRoundTrip tests SRTCP protect/unprotect, tampering and replay
+func TestSRTP_RTCPRoundTrip(t *testing.T) {
+	rr, err := (&ReceiverReport{SSRC: 0x1234, ReportBlocks: []ReportBlock{{SSRC: 0xCAFEBABE, HighestSeq: 42}}}).Marshal()
+	require.NoError(t, err)
+
+	for _, profile := range []SRTPProfile{SRTP_AES_CM_128_HMAC_SHA1_80, SRTP_AES_CM_128_HMAC_SHA1_32, SRTP_AEAD_AES_128_GCM} {
+		t.Run(profile.String(), func(t *testing.T) {
+			sender, receiver := srtpTestContexts(t, profile)
+
+			protected, err := sender.EncryptRTCP(rr)
+			require.NoError(t, err)
+			assert.Equal(t, rr[:8], protected[:8], "RTCP header stays in the clear")
+			assert.NotEqual(t, rr[8:], protected[8:len(rr)])
+
+			tampered := append([]byte(nil), protected...)
+			tampered[10] ^= 1
+			_, err = receiver.DecryptRTCP(tampered)
+			assert.ErrorIs(t, err, ErrSRTPAuthFailed)
+
+			replay := append([]byte(nil), protected...)
+			plain, err := receiver.DecryptRTCP(protected)
+			require.NoError(t, err)
+			assert.Equal(t, rr, plain)
+
+			_, err = receiver.DecryptRTCP(replay)
+			assert.ErrorIs(t, err, ErrSRTPReplay)
+
+			// The next index is accepted
+			next, err := sender.EncryptRTCP(rr)
+			require.NoError(t, err)
+			_, err = receiver.DecryptRTCP(next)
+			require.NoError(t, err)
+			assert.Equal(t, 2, receiver.GetStatistics().RTCPDecrypted)
+		})
+	}
+}
+
+// TestSRTP_InvalidConfig tests profile and key validation
+func TestSRTP_InvalidConfig(t *testing.T) {
+	profile, err := ParseSRTPProfile("aes_cm_128_hmac_sha1_32")
+	require.NoError(t, err)
+	assert.Equal(t, SRTP_AES_CM_128_HMAC_SHA1_32, profile)
+
+	_, err = ParseSRTPProfile("F8_128_HMAC_SHA1_80")
+	assert.ErrorIs(t, err, ErrSRTPUnsupportedProfile)
+
+	_, err = NewSRTPContext(SRTP_AEAD_AES_128_GCM, make([]byte, 16), make([]byte, 14))
+	assert.ErrorIs(t, err, ErrSRTPInvalidKey)
+
+	_, err = NewSRTPContext(SRTPProfile(99), make([]byte, 16), make([]byte, 14))
+	assert.ErrorIs(t, err, ErrSRTPUnsupportedProfile)
+}
+
+// TestReplayWindow tests the sliding replay window
+func TestReplayWindow(t *testing.T) {
+	var w replayWindow
+	assert.True(t, w.check(100))
+	w.accept(100)
+	assert.False(t, w.check(100))
+	assert.True(t, w.check(99))
+
+	w.accept(130)
+	assert.True(t, w.check(99))
+	assert.False(t, w.check(100))
+	assert.False(t, w.check(130-srtpReplayWindow), "older than the window")
+
+	w.accept(1000)
+	assert.True(t, w.check(999))
+	assert.False(t, w.check(130))
+}
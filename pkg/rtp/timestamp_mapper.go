@@ -0,0 +1,248 @@
+package rtp
+
+import (
+	"math"
+	"sync"
+
+	"github.com/rtsp-client/pkg/logger"
+)
+
+const (
+	// DefaultClockRate is the RTP clock rate assumed when none is known (H.264 video)
+	DefaultClockRate = 90000
+	// timestampMapperWindow is the number of recent SRs used for the drift regression
+	timestampMapperWindow = 16
+	// minRegressionSpan is the SR time span (seconds) needed before drift is estimated
+	minRegressionSpan = 2.0
+	// maxClockDrift bounds the fitted drift; steeper fits fall back to the nominal clock rate
+	maxClockDrift = 0.005
+	// srDiscontinuity is the prediction error (seconds) at which SR history is discarded
+	srDiscontinuity = 1.0
+	// ntpFractionScale is the number of NTP fraction units per second
+	ntpFractionScale = 1 << 32
+)
+
+// srPoint is one Sender Report mapping with the RTP timestamp unwrapped to 64 bits
+type srPoint struct {
+	rtp int64
+	ntp uint64
+}
+
+// TimestampMapper maps between RTP and NTP timestamps for one RTP source.
+// It fits a line through recent Sender Reports to estimate the sender's clock
+// drift and smooth out SR jitter, and unwraps 32-bit RTP timestamps.
+type TimestampMapper struct {
+	mu           sync.RWMutex
+	ntpTimestamp uint64 // NTP timestamp of the last SR
+	rtpTimestamp uint32 // RTP timestamp of the last SR
+	initialized  bool
+	clockRate    uint32 // Default 90000 for H.264
+	ssrc         uint32
+	points       []srPoint
+	// Fitted mapping relative to the last SR: seconds = intercept + slope*ticks
+	intercept float64
+	slope     float64
+}
+
+// NewTimestampMapper creates a new timestamp mapper for a 90 kHz clock
+func NewTimestampMapper() *TimestampMapper {
+	return NewTimestampMapperWithClockRate(DefaultClockRate)
+}
+
+// NewTimestampMapperWithClockRate creates a timestamp mapper for a clock rate (e.g. from SDP a=rtpmap)
+func NewTimestampMapperWithClockRate(clockRate uint32) *TimestampMapper {
+	if clockRate == 0 {
+		clockRate = DefaultClockRate
+	}
+	return &TimestampMapper{
+		clockRate: clockRate,
+		slope:     1 / float64(clockRate),
+	}
+}
+
+// SetClockRate changes the nominal clock rate; SR history is kept
+func (tm *TimestampMapper) SetClockRate(clockRate uint32) {
+	if clockRate == 0 {
+		return
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.clockRate = clockRate
+	tm.fit()
+}
+
+// UpdateFromSR updates mapping from Sender Report
+func (tm *TimestampMapper) UpdateFromSR(sr *SenderReport) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	extended := int64(sr.RTPTimestamp)
+	if tm.initialized {
+		// Unwrap relative to the last SR; SRs are never 2^31 ticks apart
+		extended = tm.points[len(tm.points)-1].rtp + int64(int32(sr.RTPTimestamp-tm.rtpTimestamp))
+
+		// A large prediction error means the camera reset its clock or timestamps
+		errorSeconds := float64(int64(sr.NTPTimestamp-tm.mapExtended(extended))) / ntpFractionScale
+		if sr.SSRC != tm.ssrc || math.Abs(errorSeconds) > srDiscontinuity {
+			logger.Debug("[TimestampMapper:UpdateFromSR] Discontinuity of %.3fs (SSRC 0x%x -> 0x%x), discarding %d SRs",
+				errorSeconds, tm.ssrc, sr.SSRC, len(tm.points))
+			tm.points = tm.points[:0]
+			extended = int64(sr.RTPTimestamp)
+		}
+	}
+
+	tm.points = append(tm.points, srPoint{rtp: extended, ntp: sr.NTPTimestamp})
+	if len(tm.points) > timestampMapperWindow {
+		tm.points = append(tm.points[:0], tm.points[len(tm.points)-timestampMapperWindow:]...)
+	}
+
+	tm.ssrc = sr.SSRC
+	tm.ntpTimestamp = sr.NTPTimestamp
+	tm.rtpTimestamp = sr.RTPTimestamp
+	tm.initialized = true
+	tm.fit()
+
+	logger.Debug("[TimestampMapper:UpdateFromSR] Mapping updated from RTCP Sender Report: SSRC=0x%x, RTP timestamp=%d, NTP timestamp=%d, reports=%d, drift=%.1fppm",
+		sr.SSRC, sr.RTPTimestamp, sr.NTPTimestamp, len(tm.points), tm.driftPPM())
+}
+
+// MapperState represents the current state of the timestamp mapper
+type MapperState struct {
+	Initialized  bool
+	SSRC         uint32
+	RTPTimestamp uint32
+	NTPTimestamp uint64
+	ClockRate    uint32
+	Reports      int     // Sender Reports in the regression window
+	DriftPPM     float64 // Estimated sender clock drift in parts per million (positive: sender fast)
+}
+
+// GetState returns the current state of the timestamp mapper (for debugging)
+func (tm *TimestampMapper) GetState() MapperState {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return MapperState{
+		Initialized:  tm.initialized,
+		SSRC:         tm.ssrc,
+		RTPTimestamp: tm.rtpTimestamp,
+		NTPTimestamp: tm.ntpTimestamp,
+		ClockRate:    tm.clockRate,
+		Reports:      len(tm.points),
+		DriftPPM:     tm.driftPPM(),
+	}
+}
+
+// RTPToNTP converts RTP timestamp to NTP timestamp. Timestamps up to 2^31 ticks
+// either side of the last SR map correctly across 32-bit wraparound.
+func (tm *TimestampMapper) RTPToNTP(rtpTime uint32) uint64 {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if !tm.initialized {
+		return 0
+	}
+
+	extended := tm.points[len(tm.points)-1].rtp + int64(int32(rtpTime-tm.rtpTimestamp))
+	return tm.mapExtended(extended)
+}
+
+// mapExtended maps an unwrapped RTP timestamp through the fitted line
+func (tm *TimestampMapper) mapExtended(extended int64) uint64 {
+	last := tm.points[len(tm.points)-1]
+	seconds := tm.intercept + tm.slope*float64(extended-last.rtp)
+	return last.ntp + uint64(int64(math.Round(seconds*ntpFractionScale)))
+}
+
+// fit recomputes the mapping by least squares over the SR window, relative to
+// the last SR. Until the window spans minRegressionSpan, or if the fitted drift
+// is implausible, the nominal clock rate is used with an averaged offset.
+func (tm *TimestampMapper) fit() {
+	nominal := 1 / float64(tm.clockRate)
+	tm.slope, tm.intercept = nominal, 0
+	if len(tm.points) < 2 {
+		return
+	}
+
+	last := tm.points[len(tm.points)-1]
+	n := float64(len(tm.points))
+	var sumX, sumY float64
+	xs := make([]float64, len(tm.points))
+	ys := make([]float64, len(tm.points))
+	for i, p := range tm.points {
+		xs[i] = float64(p.rtp - last.rtp)
+		ys[i] = float64(int64(p.ntp-last.ntp)) / ntpFractionScale
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	if -ys[0] >= minRegressionSpan {
+		var sxx, sxy float64
+		for i := range xs {
+			sxx += (xs[i] - meanX) * (xs[i] - meanX)
+			sxy += (xs[i] - meanX) * (ys[i] - meanY)
+		}
+		if sxx > 0 {
+			slope := sxy / sxx
+			if math.Abs(slope/nominal-1) <= maxClockDrift {
+				tm.slope = slope
+				tm.intercept = meanY - slope*meanX
+				return
+			}
+		}
+	}
+
+	tm.intercept = meanY - nominal*meanX
+}
+
+// driftPPM returns how much faster than nominal the sender's RTP clock runs
+func (tm *TimestampMapper) driftPPM() float64 {
+	return (1/(tm.slope*float64(tm.clockRate)) - 1) * 1e6
+}
+
+// TimestampMapperSet keeps one TimestampMapper per SSRC so audio and video
+// sources with different clock rates map independently. The zero value is ready to use.
+type TimestampMapperSet struct {
+	mu      sync.Mutex
+	mappers map[uint32]*TimestampMapper
+}
+
+// Mapper returns the mapper for an SSRC, creating it with the default clock rate if needed.
+// The returned mapper keeps receiving updates from UpdateFromSR.
+func (s *TimestampMapperSet) Mapper(ssrc uint32) *TimestampMapper {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tm, ok := s.mappers[ssrc]
+	if !ok {
+		if s.mappers == nil {
+			s.mappers = make(map[uint32]*TimestampMapper)
+		}
+		tm = NewTimestampMapper()
+		s.mappers[ssrc] = tm
+	}
+	return tm
+}
+
+// SetClockRate sets the clock rate of an SSRC's mapper
+func (s *TimestampMapperSet) SetClockRate(ssrc uint32, clockRate uint32) {
+	s.Mapper(ssrc).SetClockRate(clockRate)
+}
+
+// UpdateFromSR routes a Sender Report to its source's mapper
+func (s *TimestampMapperSet) UpdateFromSR(sr *SenderReport) {
+	s.Mapper(sr.SSRC).UpdateFromSR(sr)
+}
+
+// RTPToNTP converts an RTP timestamp of an SSRC to NTP, or returns 0 if no SR was received yet
+func (s *TimestampMapperSet) RTPToNTP(ssrc uint32, rtpTime uint32) uint64 {
+	s.mu.Lock()
+	tm, ok := s.mappers[ssrc]
+	s.mu.Unlock()
+
+	if !ok {
+		return 0
+	}
+	return tm.RTPToNTP(rtpTime)
+}
@@ -0,0 +1,108 @@
+package rtp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ntpSeconds returns the signed difference a-b in seconds
+func ntpSeconds(a, b uint64) float64 {
+	return float64(int64(a-b)) / (1 << 32)
+}
+
+// TestTimestampMapper_ClockRate tests mapping with a non-video clock rate
+func TestTimestampMapper_ClockRate(t *testing.T) {
+	mapper := NewTimestampMapperWithClockRate(8000)
+	base := TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: base, RTPTimestamp: 1000})
+
+	assert.InDelta(t, 1.0, ntpSeconds(mapper.RTPToNTP(9000), base), 1e-6)
+
+	mapper.SetClockRate(48000)
+	assert.InDelta(t, 1.0, ntpSeconds(mapper.RTPToNTP(49000), base), 1e-6)
+	assert.Equal(t, uint32(48000), mapper.GetState().ClockRate)
+}
+
+// TestTimestampMapper_Wraparound tests RTP timestamps across the 32-bit boundary
+func TestTimestampMapper_Wraparound(t *testing.T) {
+	mapper := NewTimestampMapper()
+	base := TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	start := uint32(0xFFFF0000)
+	mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: base, RTPTimestamp: start})
+
+	// 0x20000 ticks later, past the wrap
+	assert.InDelta(t, float64(0x20000)/90000, ntpSeconds(mapper.RTPToNTP(0x00010000), base), 1e-6)
+	// Slightly before the SR
+	assert.InDelta(t, -1.0, ntpSeconds(mapper.RTPToNTP(start-90000), base), 1e-6)
+
+	// An SR after the wrap continues the same history
+	second := base + 2<<32
+	mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: second, RTPTimestamp: start + 180000})
+	assert.Equal(t, 2, mapper.GetState().Reports)
+	assert.InDelta(t, 3.0, ntpSeconds(mapper.RTPToNTP(start+270000), base), 1e-6)
+}
+
+// TestTimestampMapper_Drift tests drift estimation and SR jitter smoothing
+func TestTimestampMapper_Drift(t *testing.T) {
+	mapper := NewTimestampMapper()
+	base := TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	// Sender clock runs 200 ppm fast; SRs every 5 s carry +/-2 ms of wall-clock jitter
+	const drift = 200e-6
+	jitter := []float64{0.002, -0.001, 0.001, -0.002, 0.002, 0.001, -0.002, -0.001, 0.002, -0.002, 0.001, 0, -0.001, 0.002, -0.002}
+	for i, j := range jitter {
+		seconds := float64(i * 5)
+		rtpTime := uint32(math.Round(seconds * 90000 * (1 + drift)))
+		ntp := base + uint64((seconds+j)*(1<<32))
+		mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: ntp, RTPTimestamp: rtpTime})
+	}
+
+	state := mapper.GetState()
+	assert.Equal(t, len(jitter), state.Reports)
+	assert.InDelta(t, 200, state.DriftPPM, 50)
+
+	// 80 s of sender time maps to 80 wall-clock seconds despite SR jitter
+	// (the nominal rate alone would be 16 ms off)
+	rtpTime := uint32(math.Round(80 * 90000 * (1 + drift)))
+	assert.InDelta(t, 80.0, ntpSeconds(mapper.RTPToNTP(rtpTime), base), 0.004)
+}
+
+// TestTimestampMapper_Discontinuity tests that timestamp jumps discard SR history
+func TestTimestampMapper_Discontinuity(t *testing.T) {
+	mapper := NewTimestampMapper()
+	base := TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	for i := 0; i < 3; i++ {
+		mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: base + uint64(i)<<32, RTPTimestamp: uint32(i * 90000)})
+	}
+	require.Equal(t, 3, mapper.GetState().Reports)
+
+	// Camera restarted its RTP clock
+	restart := base + 3<<32
+	mapper.UpdateFromSR(&SenderReport{SSRC: 1, NTPTimestamp: restart, RTPTimestamp: 12345678})
+	assert.Equal(t, 1, mapper.GetState().Reports)
+	assert.Equal(t, restart, mapper.RTPToNTP(12345678))
+}
+
+// TestTimestampMapperSet tests per-SSRC mapping
+func TestTimestampMapperSet(t *testing.T) {
+	var set TimestampMapperSet
+	base := TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	set.SetClockRate(0xA, 8000)
+	set.UpdateFromSR(&SenderReport{SSRC: 0xA, NTPTimestamp: base, RTPTimestamp: 0})
+	set.UpdateFromSR(&SenderReport{SSRC: 0xB, NTPTimestamp: base, RTPTimestamp: 0})
+
+	assert.InDelta(t, 1.0, ntpSeconds(set.RTPToNTP(0xA, 8000), base), 1e-6)
+	assert.InDelta(t, 1.0, ntpSeconds(set.RTPToNTP(0xB, 90000), base), 1e-6)
+	assert.Zero(t, set.RTPToNTP(0xC, 0))
+
+	// Mapper handles stay live
+	mapper := set.Mapper(0xC)
+	assert.False(t, mapper.GetState().Initialized)
+	set.UpdateFromSR(&SenderReport{SSRC: 0xC, NTPTimestamp: base})
+	assert.True(t, mapper.GetState().Initialized)
+}
@@ -0,0 +1,263 @@
+package rtp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ULPFEC (RFC 5109) header sizes
+const (
+	ulpfecHeaderSize      = 10
+	ulpfecLevelShortSize  = 4 // Protection length + 16-bit mask
+	ulpfecLevelLongSize   = 8 // Protection length + 48-bit mask
+	ulpfecShortMaskLength = 16
+	// ULPFECMaxProtected is the largest number of packets one FEC packet can protect
+	ULPFECMaxProtected = 48
+)
+
+var (
+	// ErrInvalidFEC indicates a ULPFEC payload could not be parsed or built
+	ErrInvalidFEC = errors.New("invalid ULPFEC packet")
+	// ErrFECUnrecoverable indicates the missing packet cannot be rebuilt from an FEC packet
+	ErrFECUnrecoverable = errors.New("packet not recoverable from FEC")
+)
+
+// ULPFECPacket is the level 0 protection carried in a ULPFEC payload (RFC 5109 section 7).
+// Only level 0 is supported; it covers the first ProtectionLength bytes of each payload.
+type ULPFECPacket struct {
+	SequenceBase      uint16
+	Mask              uint64 // Bit i (from the least significant bit) set when SequenceBase+i is protected
+	ProtectionLength  uint16
+	FlagsRecovery     uint8 // XOR of the P, X and CC bits
+	MarkerPTRecovery  uint8 // XOR of the M bit and payload type
+	TimestampRecovery uint32
+	LengthRecovery    uint16
+	Payload           []byte // XOR of the protected packets after their fixed header
+}
+
+// ParseULPFEC parses a ULPFEC payload. Payload aliases data.
+func ParseULPFEC(data []byte) (*ULPFECPacket, error) {
+	if len(data) < ulpfecHeaderSize+ulpfecLevelShortSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidFEC, len(data))
+	}
+	if data[0]&0x80 != 0 {
+		return nil, fmt.Errorf("%w: extension flag set", ErrInvalidFEC)
+	}
+	long := data[0]&0x40 != 0
+
+	fec := &ULPFECPacket{
+		FlagsRecovery:     data[0] & 0x3F,
+		MarkerPTRecovery:  data[1],
+		SequenceBase:      binary.BigEndian.Uint16(data[2:4]),
+		TimestampRecovery: binary.BigEndian.Uint32(data[4:8]),
+		LengthRecovery:    binary.BigEndian.Uint16(data[8:10]),
+	}
+
+	level := data[ulpfecHeaderSize:]
+	fec.ProtectionLength = binary.BigEndian.Uint16(level[0:2])
+	maskBits := ulpfecShortMaskLength
+	levelSize := ulpfecLevelShortSize
+	var mask uint64
+	if long {
+		if len(level) < ulpfecLevelLongSize {
+			return nil, fmt.Errorf("%w: truncated long mask", ErrInvalidFEC)
+		}
+		mask = uint64(binary.BigEndian.Uint16(level[2:4]))<<32 | uint64(binary.BigEndian.Uint32(level[4:8]))
+		maskBits = ULPFECMaxProtected
+		levelSize = ulpfecLevelLongSize
+	} else {
+		mask = uint64(binary.BigEndian.Uint16(level[2:4]))
+	}
+
+	// The wire mask is MSB first: the most significant bit protects SequenceBase
+	for i := 0; i < maskBits; i++ {
+		if mask&(1<<(maskBits-1-i)) != 0 {
+			fec.Mask |= 1 << i
+		}
+	}
+	if fec.Mask == 0 {
+		return nil, fmt.Errorf("%w: empty mask", ErrInvalidFEC)
+	}
+
+	fec.Payload = level[levelSize:]
+	if len(fec.Payload) < int(fec.ProtectionLength) {
+		return nil, fmt.Errorf("%w: payload %d shorter than protection length %d", ErrInvalidFEC, len(fec.Payload), fec.ProtectionLength)
+	}
+	fec.Payload = fec.Payload[:fec.ProtectionLength]
+
+	return fec, nil
+}
+
+// NewULPFECPacket builds level 0 FEC protecting packets (all from one source,
+// spanning at most ULPFECMaxProtected sequence numbers)
+func NewULPFECPacket(packets []*Packet) (*ULPFECPacket, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("%w: nothing to protect", ErrInvalidFEC)
+	}
+
+	base := packets[0].SequenceNumber
+	for _, p := range packets[1:] {
+		if CompareSequence(p.SequenceNumber, base) < 0 {
+			base = p.SequenceNumber
+		}
+	}
+
+	fec := &ULPFECPacket{SequenceBase: base}
+	var bitStrings [][]byte
+	for _, p := range packets {
+		offset := SequenceDistance(base, p.SequenceNumber)
+		if offset >= ULPFECMaxProtected {
+			return nil, fmt.Errorf("%w: sequence %d out of mask range", ErrInvalidFEC, p.SequenceNumber)
+		}
+		fec.Mask |= 1 << offset
+
+		wire, err := p.fecBitString()
+		if err != nil {
+			return nil, err
+		}
+		bitStrings = append(bitStrings, wire)
+		if n := len(wire) - 12; n > int(fec.ProtectionLength) {
+			fec.ProtectionLength = uint16(n)
+		}
+	}
+
+	fec.Payload = make([]byte, fec.ProtectionLength)
+	for _, wire := range bitStrings {
+		fec.xorPacket(wire)
+	}
+
+	return fec, nil
+}
+
+// Marshal serializes the FEC packet as a ULPFEC payload
+func (f *ULPFECPacket) Marshal() []byte {
+	long := f.Mask>>ulpfecShortMaskLength != 0
+	levelSize := ulpfecLevelShortSize
+	maskBits := ulpfecShortMaskLength
+	if long {
+		levelSize = ulpfecLevelLongSize
+		maskBits = ULPFECMaxProtected
+	}
+
+	buf := make([]byte, ulpfecHeaderSize+levelSize+len(f.Payload))
+	buf[0] = f.FlagsRecovery & 0x3F
+	if long {
+		buf[0] |= 0x40
+	}
+	buf[1] = f.MarkerPTRecovery
+	binary.BigEndian.PutUint16(buf[2:4], f.SequenceBase)
+	binary.BigEndian.PutUint32(buf[4:8], f.TimestampRecovery)
+	binary.BigEndian.PutUint16(buf[8:10], f.LengthRecovery)
+
+	var mask uint64
+	for i := 0; i < maskBits; i++ {
+		if f.Mask&(1<<i) != 0 {
+			mask |= 1 << (maskBits - 1 - i)
+		}
+	}
+	level := buf[ulpfecHeaderSize:]
+	binary.BigEndian.PutUint16(level[0:2], uint16(len(f.Payload)))
+	if long {
+		binary.BigEndian.PutUint16(level[2:4], uint16(mask>>32))
+		binary.BigEndian.PutUint32(level[4:8], uint32(mask))
+	} else {
+		binary.BigEndian.PutUint16(level[2:4], uint16(mask))
+	}
+	copy(level[levelSize:], f.Payload)
+
+	return buf
+}
+
+// Protected returns the protected sequence numbers in order
+func (f *ULPFECPacket) Protected() []uint16 {
+	var seqs []uint16
+	for i := 0; i < ULPFECMaxProtected; i++ {
+		if f.Mask&(1<<i) != 0 {
+			seqs = append(seqs, f.SequenceBase+uint16(i))
+		}
+	}
+	return seqs
+}
+
+// Protects reports whether seq is covered by the FEC packet
+func (f *ULPFECPacket) Protects(seq uint16) bool {
+	offset := SequenceDistance(f.SequenceBase, seq)
+	return offset < ULPFECMaxProtected && f.Mask&(1<<offset) != 0
+}
+
+// Recover rebuilds the protected packet with sequence number missing from
+// the other protected packets, which must all be present in received.
+// The recovered packet gets ssrc, since SSRC is not covered by FEC.
+func (f *ULPFECPacket) Recover(missing uint16, received []*Packet, ssrc uint32) (*Packet, error) {
+	if !f.Protects(missing) {
+		return nil, fmt.Errorf("%w: sequence %d not protected", ErrFECUnrecoverable, missing)
+	}
+
+	recovered := &ULPFECPacket{
+		FlagsRecovery:     f.FlagsRecovery,
+		MarkerPTRecovery:  f.MarkerPTRecovery,
+		TimestampRecovery: f.TimestampRecovery,
+		LengthRecovery:    f.LengthRecovery,
+		Payload:           append([]byte(nil), f.Payload...),
+		ProtectionLength:  f.ProtectionLength,
+	}
+
+	seen := map[uint16]bool{}
+	for _, p := range received {
+		if !f.Protects(p.SequenceNumber) || p.SequenceNumber == missing || seen[p.SequenceNumber] {
+			continue
+		}
+		seen[p.SequenceNumber] = true
+		wire, err := p.fecBitString()
+		if err != nil {
+			return nil, err
+		}
+		recovered.xorPacket(wire)
+	}
+	if len(seen) != len(f.Protected())-1 {
+		return nil, fmt.Errorf("%w: %d of %d other packets present", ErrFECUnrecoverable, len(seen), len(f.Protected())-1)
+	}
+
+	if recovered.LengthRecovery > f.ProtectionLength {
+		return nil, fmt.Errorf("%w: length %d exceeds protection length %d", ErrFECUnrecoverable, recovered.LengthRecovery, f.ProtectionLength)
+	}
+
+	wire := make([]byte, 12+int(recovered.LengthRecovery))
+	wire[0] = 2<<6 | recovered.FlagsRecovery
+	wire[1] = recovered.MarkerPTRecovery
+	binary.BigEndian.PutUint16(wire[2:4], missing)
+	binary.BigEndian.PutUint32(wire[4:8], recovered.TimestampRecovery)
+	binary.BigEndian.PutUint32(wire[8:12], ssrc)
+	copy(wire[12:], recovered.Payload)
+
+	packet, err := ParsePacket(wire)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFECUnrecoverable, err)
+	}
+	return packet, nil
+}
+
+// fecBitString returns the packet as sent: the received bytes of a parsed
+// packet, including its original extension and padding bytes, or the
+// marshaled packet for one built locally
+func (p *Packet) fecBitString() ([]byte, error) {
+	if p.wire != nil {
+		return p.wire, nil
+	}
+	return p.Marshal()
+}
+
+// xorPacket folds an RTP packet as sent into the recovery fields
+func (f *ULPFECPacket) xorPacket(wire []byte) {
+	f.FlagsRecovery ^= wire[0] & 0x3F
+	f.MarkerPTRecovery ^= wire[1]
+	f.TimestampRecovery ^= binary.BigEndian.Uint32(wire[4:8])
+	f.LengthRecovery ^= uint16(len(wire) - 12)
+	for i, b := range wire[12:] {
+		if i >= len(f.Payload) {
+			break
+		}
+		f.Payload[i] ^= b
+	}
+}
@@ -0,0 +1,165 @@
+package rtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fecTestPackets returns media packets with varied header fields and sizes
+func fecTestPackets(base uint16, count int) []*Packet {
+	packets := make([]*Packet, count)
+	for i := range packets {
+		payload := make([]byte, 10+i*7)
+		for j := range payload {
+			payload[j] = byte(i*31 + j)
+		}
+		packets[i] = &Packet{
+			Version:        2,
+			PayloadType:    96,
+			SequenceNumber: base + uint16(i),
+			Timestamp:      90000 + uint32(i/2)*3000,
+			SSRC:           0x1234,
+			Marker:         i%2 == 1,
+			Payload:        payload,
+		}
+	}
+	packets[0].CSRC = []uint32{0xAABBCCDD}
+	packets[1].Padding = true
+	packets[1].PaddingSize = 3
+	return packets
+}
+
+// TestULPFEC_RecoverEachPacket tests recovering any single loss, through marshal and parse
+func TestULPFEC_RecoverEachPacket(t *testing.T) {
+	media := fecTestPackets(65533, 5)
+	built, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+
+	fec, err := ParseULPFEC(built.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{65533, 65534, 65535, 0, 1}, fec.Protected())
+
+	for lost := range media {
+		var received []*Packet
+		for i, p := range media {
+			if i != lost {
+				received = append(received, p)
+			}
+		}
+
+		recovered, err := fec.Recover(media[lost].SequenceNumber, received, 0x1234)
+		require.NoError(t, err, "lost index %d", lost)
+
+		want, err := media[lost].Marshal()
+		require.NoError(t, err)
+		got, err := recovered.Marshal()
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "lost index %d", lost)
+	}
+}
+
+// TestULPFEC_LongMask tests masks beyond 16 packets
+func TestULPFEC_LongMask(t *testing.T) {
+	media := fecTestPackets(100, 2)
+	media[1].SequenceNumber = 140
+
+	built, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+	data := built.Marshal()
+	assert.NotZero(t, data[0]&0x40, "L bit must be set")
+
+	fec, err := ParseULPFEC(data)
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{100, 140}, fec.Protected())
+	assert.True(t, fec.Protects(140))
+	assert.False(t, fec.Protects(101))
+
+	recovered, err := fec.Recover(140, media[:1], 0x1234)
+	require.NoError(t, err)
+	assert.Equal(t, media[1].Payload, recovered.Payload)
+}
+
+// TestULPFEC_ExtensionAndPadding tests that FEC covers packets as received:
+// an RFC 8285 extension with a padding byte between elements and non-zero
+// padding bytes are not reproduced by Marshal
+func TestULPFEC_ExtensionAndPadding(t *testing.T) {
+	wire := []byte{
+		0xB0, 96, 0, 10, 0, 0, 0x03, 0xE8, 0, 0, 0x12, 0x34, // V=2, P, X
+		0xBE, 0xDE, 0, 2, 0x10, 0x01, 0x00, 0x21, 0x02, 0x03, 0x00, 0x00,
+		0x65, 0x88, 0x84, 0x00, 0x21, // Payload
+		0xAA, 0xAA, 0x03, // Padding
+	}
+	withExtension, err := ParsePacket(wire)
+	require.NoError(t, err)
+	marshaled, err := withExtension.Marshal()
+	require.NoError(t, err)
+	require.NotEqual(t, wire, marshaled)
+
+	plain, err := ParsePacket([]byte{0x80, 96, 0, 11, 0, 0, 0x03, 0xE8, 0, 0, 0x12, 0x34, 0x41, 0x9A})
+	require.NoError(t, err)
+
+	built, err := NewULPFECPacket([]*Packet{withExtension, plain})
+	require.NoError(t, err)
+	fec, err := ParseULPFEC(built.Marshal())
+	require.NoError(t, err)
+
+	recovered, err := fec.Recover(10, []*Packet{plain}, 0x1234)
+	require.NoError(t, err)
+	assert.Equal(t, wire, recovered.wire)
+	assert.Equal(t, withExtension.Payload, recovered.Payload)
+	assert.Equal(t, withExtension.ExtensionPayload, recovered.ExtensionPayload)
+	assert.Equal(t, uint8(3), recovered.PaddingSize)
+
+	recovered, err = fec.Recover(11, []*Packet{withExtension}, 0x1234)
+	require.NoError(t, err)
+	assert.False(t, recovered.Extension)
+	assert.False(t, recovered.Padding)
+	assert.Equal(t, plain.Payload, recovered.Payload)
+}
+
+// TestULPFEC_Unrecoverable tests recovery preconditions
+func TestULPFEC_Unrecoverable(t *testing.T) {
+	media := fecTestPackets(10, 3)
+	fec, err := NewULPFECPacket(media)
+	require.NoError(t, err)
+
+	_, err = fec.Recover(10, media[1:2], 0x1234)
+	assert.ErrorIs(t, err, ErrFECUnrecoverable, "two packets missing")
+
+	_, err = fec.Recover(20, media, 0x1234)
+	assert.ErrorIs(t, err, ErrFECUnrecoverable, "not protected")
+
+	_, err = NewULPFECPacket([]*Packet{{SequenceNumber: 1}, {SequenceNumber: 60}})
+	assert.ErrorIs(t, err, ErrInvalidFEC)
+}
+
+// TestParseULPFEC_Errors tests malformed ULPFEC payloads
+func TestParseULPFEC_Errors(t *testing.T) {
+	valid, err := NewULPFECPacket(fecTestPackets(1, 2))
+	require.NoError(t, err)
+	data := valid.Marshal()
+
+	extension := append([]byte(nil), data...)
+	extension[0] |= 0x80
+	noMask := append([]byte(nil), data...)
+	noMask[12], noMask[13] = 0, 0
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", data[:8]},
+		{"extension flag", extension},
+		{"empty mask", noMask},
+		{"truncated payload", data[:len(data)-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseULPFEC(tt.data)
+			assert.ErrorIs(t, err, ErrInvalidFEC)
+		})
+	}
+}
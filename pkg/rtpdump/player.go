@@ -0,0 +1,179 @@
+package rtpdump
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// RTCPHandler is called for every recorded RTCP packet during playback, once
+// for each packet of a compound packet
+type RTCPHandler func(rtcpPacket rtp.RTCPPacket) error
+
+// PlayerOptions controls playback
+type PlayerOptions struct {
+	// Speed paces ReadPacket by the recorded offsets: 1 is the original pacing,
+	// 2 twice as fast. 0 returns packets as fast as possible.
+	Speed float64
+	SSRC  uint32 // Only play this SSRC (0 plays all)
+}
+
+// PlayerStatistics counts what a Player read
+type PlayerStatistics struct {
+	RTPPackets  int
+	RTCPPackets int
+	Filtered    int // Packets of other SSRCs
+	Malformed   int // Records that did not parse as RTP or RTCP
+}
+
+// Player plays an rtpdump recording back through the same ReadPacket interface
+// as rtsp.Client. Recorded RTCP goes to the RTCP handler and Sender Reports
+// update per-SSRC timestamp mappers, so frames keep their original names.
+type Player struct {
+	reader  *Reader
+	closer  io.Closer
+	options PlayerOptions
+	started time.Time // Wall-clock time of the first packet when pacing
+	first   time.Duration
+	last    time.Time
+
+	rtcpHandler      RTCPHandler
+	timestampMappers rtp.TimestampMapperSet
+	stats            PlayerStatistics
+}
+
+// NewPlayer plays the recording read from r
+func NewPlayer(r io.Reader, options PlayerOptions) (*Player, error) {
+	reader, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Player{reader: reader, options: options}, nil
+}
+
+// OpenPlayer opens an rtpdump file for playback; Close releases it
+func OpenPlayer(path string, options PlayerOptions) (*Player, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rtpdump file: %w", err)
+	}
+	player, err := NewPlayer(file, options)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	player.closer = file
+	logger.Info("[RTPDump] Playing %s (recorded from %s)", path, player.reader.Header().Source)
+	return player, nil
+}
+
+// Close closes the file opened by OpenPlayer
+func (p *Player) Close() error {
+	if p.closer == nil {
+		return nil
+	}
+	return p.closer.Close()
+}
+
+// Header returns the recording's file header
+func (p *Player) Header() Header {
+	return p.reader.Header()
+}
+
+// SetRTCPHandler sets the handler for recorded RTCP packets
+func (p *Player) SetRTCPHandler(handler RTCPHandler) {
+	p.rtcpHandler = handler
+}
+
+// GetTimestampMapper returns the RTP-to-NTP mapper built from recorded Sender Reports
+func (p *Player) GetTimestampMapper(ssrc uint32) *rtp.TimestampMapper {
+	return p.timestampMappers.Mapper(ssrc)
+}
+
+// GetStatistics returns what was played so far
+func (p *Player) GetStatistics() PlayerStatistics {
+	return p.stats
+}
+
+// PacketTime returns the original receive time of the packet last returned
+func (p *Player) PacketTime() time.Time {
+	return p.last
+}
+
+// ReadPacket returns the next recorded RTP packet, or io.EOF at the end of the recording
+func (p *Player) ReadPacket() (*rtp.Packet, error) {
+	for {
+		record, err := p.reader.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+
+		if record.RTCP {
+			p.playRTCP(record.Data)
+			continue
+		}
+
+		// The record buffer is reused; the packet must own its bytes
+		packet, err := rtp.ParsePacket(append([]byte(nil), record.Data...))
+		if err != nil {
+			p.stats.Malformed++
+			continue
+		}
+		if p.options.SSRC != 0 && packet.SSRC != p.options.SSRC {
+			p.stats.Filtered++
+			continue
+		}
+
+		p.pace(record.Offset)
+		p.last = p.reader.Header().Start.Add(record.Offset)
+		p.stats.RTPPackets++
+		return packet, nil
+	}
+}
+
+// pace sleeps until a record is due at the configured speed
+func (p *Player) pace(offset time.Duration) {
+	if p.options.Speed <= 0 {
+		return
+	}
+	if p.started.IsZero() {
+		p.started = time.Now()
+		p.first = offset
+		return
+	}
+
+	due := p.started.Add(time.Duration(float64(offset-p.first) / p.options.Speed))
+	if wait := time.Until(due); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+// playRTCP records Sender Reports and passes the packet to the handler
+func (p *Player) playRTCP(data []byte) {
+	compound, err := rtp.ParseCompoundRTCPPacket(data)
+	if err != nil {
+		p.stats.Malformed++
+		logger.Debug("[RTPDump] Skipping malformed RTCP record: %v", err)
+		return
+	}
+	if p.options.SSRC != 0 && compound.GetSSRC() != p.options.SSRC {
+		p.stats.Filtered++
+		return
+	}
+	p.stats.RTCPPackets++
+
+	for _, packet := range compound.Packets {
+		if sr, ok := packet.(*rtp.SenderReport); ok {
+			p.timestampMappers.UpdateFromSR(sr)
+		}
+		if p.rtcpHandler != nil {
+			if err := p.rtcpHandler(packet); err != nil {
+				logger.Warn("[RTPDump] RTCP handler error: %v", err)
+			}
+		}
+	}
+}
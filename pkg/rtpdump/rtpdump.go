@@ -0,0 +1,282 @@
+// Package rtpdump reads and writes the rtpdump (RTPPlay) format of rtptools:
+// a text line "#!rtpplay1.0 address/port", a 16-byte binary file header and
+// one record per packet carrying its millisecond offset from the start.
+package rtpdump
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Magic starts every rtpdump file
+	Magic = "#!rtpplay1.0 "
+
+	fileHeaderSize   = 16
+	recordHeaderSize = 8
+	maxLineLength    = 256
+	maxPacketSize    = 0xFFFF - recordHeaderSize
+)
+
+var (
+	// ErrInvalidFile indicates the input does not start with an rtpdump header
+	ErrInvalidFile = errors.New("not an rtpdump file")
+	// ErrCorruptFile indicates a truncated or malformed record
+	ErrCorruptFile = errors.New("corrupt rtpdump file")
+	// ErrPacketTooLarge indicates a packet that does not fit a 16-bit record length
+	ErrPacketTooLarge = errors.New("packet too large for rtpdump record")
+)
+
+// Header is the rtpdump file header
+type Header struct {
+	Start  time.Time      // Recording start; record offsets are relative to it
+	Source netip.AddrPort // Address the packets were received from
+}
+
+// Record is one recorded RTP or RTCP packet
+type Record struct {
+	Offset time.Duration // Time since Header.Start (millisecond resolution)
+	RTCP   bool
+	Data   []byte
+}
+
+// Writer records packets in rtpdump format. It is safe for concurrent use, so
+// RTP and RTCP read on different goroutines can share one file.
+type Writer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	start  time.Time
+	buf    []byte
+	count  int
+}
+
+// NewWriter writes the file header for a recording that starts at start
+func NewWriter(w io.Writer, source netip.AddrPort, start time.Time) (*Writer, error) {
+	addr := source.Addr()
+	if !addr.IsValid() {
+		addr = netip.IPv4Unspecified()
+	}
+
+	header := []byte(fmt.Sprintf("%s%s/%d\n", Magic, addr, source.Port()))
+	fileHeader := make([]byte, fileHeaderSize)
+	putFileHeader(fileHeader, start, addr, source.Port())
+	if _, err := w.Write(append(header, fileHeader...)); err != nil {
+		return nil, fmt.Errorf("failed to write rtpdump header: %w", err)
+	}
+
+	return &Writer{w: w, start: start}, nil
+}
+
+// Create creates an rtpdump file; Close closes it
+func Create(path string, source netip.AddrPort) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rtpdump file: %w", err)
+	}
+	writer, err := NewWriter(file, source, time.Now())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	writer.closer = file
+	return writer, nil
+}
+
+// WriteRTP records an RTP packet received at the given time
+func (w *Writer) WriteRTP(data []byte, at time.Time) error {
+	return w.write(data, at, false)
+}
+
+// WriteRTCP records an RTCP packet received at the given time
+func (w *Writer) WriteRTCP(data []byte, at time.Time) error {
+	return w.write(data, at, true)
+}
+
+// Count returns the number of records written
+func (w *Writer) Count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// Close closes the file opened by Create
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closer == nil {
+		return nil
+	}
+	err := w.closer.Close()
+	w.closer = nil
+	return err
+}
+
+// write appends one record; RTCP records have a zero packet length as in rtptools
+func (w *Writer) write(data []byte, at time.Time, rtcp bool) error {
+	if len(data) > maxPacketSize {
+		return fmt.Errorf("%w: %d bytes", ErrPacketTooLarge, len(data))
+	}
+
+	offset := at.Sub(w.start) / time.Millisecond
+	if offset < 0 {
+		offset = 0
+	}
+	plen := uint16(len(data))
+	if rtcp {
+		plen = 0
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = w.buf[:0]
+	w.buf = binary.BigEndian.AppendUint16(w.buf, uint16(recordHeaderSize+len(data)))
+	w.buf = binary.BigEndian.AppendUint16(w.buf, plen)
+	w.buf = binary.BigEndian.AppendUint32(w.buf, uint32(offset))
+	w.buf = append(w.buf, data...)
+	if _, err := w.w.Write(w.buf); err != nil {
+		return err
+	}
+	w.count++
+	return nil
+}
+
+// Reader reads records from an rtpdump file
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	buf    []byte
+}
+
+// NewReader reads and validates the file header
+func NewReader(r io.Reader) (*Reader, error) {
+	reader := &Reader{r: bufio.NewReader(r)}
+
+	line, err := reader.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, Magic) {
+		return nil, ErrInvalidFile
+	}
+	source, err := parseAddress(strings.TrimSpace(strings.TrimPrefix(line, Magic)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+
+	var header [fileHeaderSize]byte
+	if _, err := io.ReadFull(reader.r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: file header: %v", ErrCorruptFile, err)
+	}
+	seconds := binary.BigEndian.Uint32(header[0:4])
+	micros := binary.BigEndian.Uint32(header[4:8])
+
+	reader.header = Header{
+		Start:  time.Unix(int64(seconds), int64(micros)*int64(time.Microsecond)),
+		Source: source,
+	}
+	return reader, nil
+}
+
+// Header returns the file header
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// ReadRecord returns the next record, or io.EOF at the end of the file.
+// Record.Data is only valid until the next call.
+func (r *Reader) ReadRecord() (Record, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("%w: truncated record header", ErrCorruptFile)
+		}
+		return Record{}, err
+	}
+
+	length := int(binary.BigEndian.Uint16(header[0:2]))
+	plen := binary.BigEndian.Uint16(header[2:4])
+	offset := binary.BigEndian.Uint32(header[4:8])
+	if length < recordHeaderSize {
+		return Record{}, fmt.Errorf("%w: record length %d", ErrCorruptFile, length)
+	}
+
+	if cap(r.buf) < length-recordHeaderSize {
+		r.buf = make([]byte, length-recordHeaderSize)
+	}
+	data := r.buf[:length-recordHeaderSize]
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Record{}, fmt.Errorf("%w: truncated record: %v", ErrCorruptFile, err)
+	}
+
+	// plen is the original packet length; RTP packets may have been recorded truncated
+	if plen != 0 && int(plen) < len(data) {
+		data = data[:plen]
+	}
+
+	return Record{
+		Offset: time.Duration(offset) * time.Millisecond,
+		RTCP:   plen == 0,
+		Data:   data,
+	}, nil
+}
+
+// IsRTPDump reports whether data starts with the rtpdump magic
+func IsRTPDump(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// readLine reads the text line of the file header
+func (r *Reader) readLine() (string, error) {
+	var line []byte
+	for len(line) < maxLineLength {
+		b, err := r.r.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if b == '\n' {
+			return string(line), nil
+		}
+		line = append(line, b)
+	}
+	return "", fmt.Errorf("%w: header line too long", ErrInvalidFile)
+}
+
+// putFileHeader encodes the binary file header: start time, source address and port
+func putFileHeader(buf []byte, start time.Time, addr netip.Addr, port uint16) {
+	binary.BigEndian.PutUint32(buf[0:4], uint32(start.Unix()))
+	binary.BigEndian.PutUint32(buf[4:8], uint32(start.Nanosecond()/1000))
+	if addr.Is4() {
+		ip := addr.As4()
+		copy(buf[8:12], ip[:])
+	}
+	binary.BigEndian.PutUint16(buf[12:14], port)
+}
+
+// parseAddress parses the "address/port" of the header line
+func parseAddress(value string) (netip.AddrPort, error) {
+	host, portText, ok := strings.Cut(value, "/")
+	if !ok {
+		return netip.AddrPort{}, fmt.Errorf("malformed address %q", value)
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	port, err := strconv.ParseUint(portText, 10, 16)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(addr, uint16(port)), nil
+}
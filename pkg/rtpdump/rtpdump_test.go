@@ -0,0 +1,171 @@
+package rtpdump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStart = time.Unix(1700000000, 250000*int64(time.Microsecond))
+
+// rtpData marshals a minimal RTP packet
+func rtpData(t *testing.T, seq uint16, ssrc uint32) []byte {
+	t.Helper()
+	packet := &rtp.Packet{
+		Version:        2,
+		Marker:         true,
+		PayloadType:    96,
+		SequenceNumber: seq,
+		Timestamp:      uint32(seq) * 3000,
+		SSRC:           ssrc,
+		Payload:        []byte{0x65, 0x88, byte(seq)},
+	}
+	data, err := packet.Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+// senderReport builds an SR that maps RTP timestamp 0 to at
+func senderReport(ssrc uint32, at time.Time) []byte {
+	data := make([]byte, 28)
+	data[0] = 0x80
+	data[1] = 200
+	binary.BigEndian.PutUint16(data[2:4], 6)
+	binary.BigEndian.PutUint32(data[4:8], ssrc)
+	binary.BigEndian.PutUint64(data[8:16], rtp.TimeToNTP(at))
+	return data
+}
+
+func TestWriterReader_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	source := netip.AddrPortFrom(netip.MustParseAddr("192.168.1.20"), 5004)
+	writer, err := NewWriter(&buf, source, testStart)
+	require.NoError(t, err)
+
+	first := rtpData(t, 1, 0x1234)
+	rtcp := senderReport(0x1234, testStart)
+	require.NoError(t, writer.WriteRTP(first, testStart.Add(5*time.Millisecond)))
+	require.NoError(t, writer.WriteRTCP(rtcp, testStart.Add(40*time.Millisecond)))
+	require.NoError(t, writer.WriteRTP(rtpData(t, 2, 0x1234), testStart.Add(1500*time.Millisecond)))
+	assert.Equal(t, 3, writer.Count())
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("#!rtpplay1.0 192.168.1.20/5004\n")))
+	assert.True(t, IsRTPDump(buf.Bytes()))
+
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, source, reader.Header().Source)
+	assert.True(t, testStart.Equal(reader.Header().Start))
+
+	record, err := reader.ReadRecord()
+	require.NoError(t, err)
+	assert.False(t, record.RTCP)
+	assert.Equal(t, 5*time.Millisecond, record.Offset)
+	assert.Equal(t, first, record.Data)
+
+	record, err = reader.ReadRecord()
+	require.NoError(t, err)
+	assert.True(t, record.RTCP, "RTCP records have a zero packet length")
+	assert.Equal(t, rtcp, record.Data)
+
+	record, err = reader.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, 1500*time.Millisecond, record.Offset)
+
+	_, err = reader.ReadRecord()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReader_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"empty", nil, ErrInvalidFile},
+		{"pcap magic", []byte{0xd4, 0xc3, 0xb2, 0xa1, 0x02, 0x00, 0x04, 0x00, '\n'}, ErrInvalidFile},
+		{"bad address", []byte("#!rtpplay1.0 camera\n"), ErrInvalidFile},
+		{"truncated file header", []byte("#!rtpplay1.0 10.0.0.1/5004\n\x00\x01"), ErrCorruptFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	// A record shorter than its own header
+	var buf bytes.Buffer
+	_, err := NewWriter(&buf, netip.AddrPort{}, testStart)
+	require.NoError(t, err)
+	buf.Write([]byte{0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	reader, err := NewReader(&buf)
+	require.NoError(t, err)
+	_, err = reader.ReadRecord()
+	assert.ErrorIs(t, err, ErrCorruptFile)
+}
+
+func TestWriter_PacketTooLarge(t *testing.T) {
+	writer, err := NewWriter(io.Discard, netip.AddrPort{}, testStart)
+	require.NoError(t, err)
+	assert.ErrorIs(t, writer.WriteRTP(make([]byte, 70000), testStart), ErrPacketTooLarge)
+	assert.Equal(t, 0, writer.Count())
+}
+
+func TestPlayer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "camera.rtp")
+	writer, err := Create(path, netip.AddrPortFrom(netip.MustParseAddr("10.0.0.5"), 6970))
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, writer.WriteRTCP(senderReport(0x1234, start), start))
+	require.NoError(t, writer.WriteRTP(rtpData(t, 1, 0x1234), start))
+	require.NoError(t, writer.WriteRTP(rtpData(t, 1, 0x9999), start.Add(10*time.Millisecond)))
+	require.NoError(t, writer.WriteRTP(rtpData(t, 2, 0x1234), start.Add(60*time.Millisecond)))
+	require.NoError(t, writer.Close())
+
+	player, err := OpenPlayer(path, PlayerOptions{Speed: 2, SSRC: 0x1234})
+	require.NoError(t, err)
+	defer player.Close()
+
+	var reports int
+	player.SetRTCPHandler(func(rtcpPacket rtp.RTCPPacket) error {
+		reports++
+		return nil
+	})
+
+	began := time.Now()
+	var sequences []uint16
+	for {
+		packet, err := player.ReadPacket()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		sequences = append(sequences, packet.SequenceNumber)
+		assert.Equal(t, uint32(0x1234), packet.SSRC)
+	}
+
+	assert.Equal(t, []uint16{1, 2}, sequences)
+	assert.GreaterOrEqual(t, time.Since(began), 25*time.Millisecond, "60 ms of recording at double speed")
+	assert.Equal(t, 1, reports)
+	assert.Equal(t, PlayerStatistics{RTPPackets: 2, RTCPPackets: 1, Filtered: 1}, player.GetStatistics())
+	assert.Equal(t, netip.AddrPortFrom(netip.MustParseAddr("10.0.0.5"), 6970), player.Header().Source)
+	assert.Equal(t, 60*time.Millisecond, player.PacketTime().Sub(player.Header().Start))
+
+	// The recorded Sender Report maps RTP timestamps to wall-clock time
+	mapper := player.GetTimestampMapper(0x1234)
+	require.NotNil(t, mapper)
+	assert.Equal(t, rtp.TimeToNTP(start), mapper.RTPToNTP(0))
+}
+
+func TestPlayer_ImplementsPacketReader(t *testing.T) {
+	var _ rtp.PacketReader = (*Player)(nil)
+}
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rtsp-client/pkg/logger"
@@ -28,7 +29,8 @@ var (
 	ErrInvalidResponse = errors.New("invalid RTSP response")
 )
 
-// ClientRTCPHandler is called when an RTCP packet is received by the client
+// ClientRTCPHandler is called when an RTCP packet is received by the client,
+// once for each packet of a compound packet
 type ClientRTCPHandler func(rtcpPacket rtp.RTCPPacket) error
 
 // Client represents an RTSP client
@@ -69,6 +71,26 @@ type Client struct {
 	payloadTypeInit     bool
 	rtcpHandler         ClientRTCPHandler // Handler for RTCP packets
 	rtcpHandlerMu       sync.RWMutex
+	interleavedReader   *InterleavedReader // Reused across TCP reads
+	receiveBuffer       []byte             // Scratch buffer for ReadPacket
+	jitterBuffer        *rtp.JitterBuffer  // Optional UDP reordering stage
+	packetsReceived     atomic.Uint64      // RTP packets read from the network
+	receiverReportStop  chan struct{}
+	receptionMu         sync.Mutex
+	reception           map[uint32]*receptionSource // Reception statistics by remote SSRC
+	localSSRC           uint32                      // SSRC used in RTCP reports
+	feedbackMu          sync.Mutex
+	feedback            feedbackState // PLI/FIR rate limiting and NACK history
+	timestampMappers    rtp.TimestampMapperSet // RTP-to-NTP mapping per remote SSRC
+	fecReceiver         *rtp.FECReceiver       // RED/ULPFEC recovery when advertised in SDP
+	rtxReceiver         *rtp.RTXReceiver       // RTX restoration when advertised in SDP
+	repairQueue         []*rtp.Packet          // Repaired media packets not yet returned
+	srtpSetup           bool                   // SETUP in progress requests RTP/SAVP
+	srtpMu              sync.Mutex
+	srtpContexts        map[int]*rtp.SRTPContext    // SRTP contexts by SDP track index
+	srtpSSRCs           map[uint32]*rtp.SRTPContext // Context that authenticated each remote SSRC
+	recorder            PacketRecorder              // Optional copy of received packets (e.g. rtpdump)
+	recorderMu          sync.RWMutex
 }
 
 // SDPInfo captures parsed SDP metadata for aggregate and track-level details.
@@ -86,6 +108,71 @@ type SDPTrack struct {
 	ClockRate   int
 	Channels    int
 	FMTP        map[string]string
+	ExtMap      rtp.ExtensionMap // RTP header extension IDs from a=extmap
+	Feedback    []string         // RTCP feedback for PayloadType from a=rtcp-fb, e.g. "nack", "nack pli", "ccm fir"
+	Formats     []SDPFormat      // Every payload format in the media section, including RED/FEC/RTX
+	SSRCGroups  []SDPSSRCGroup   // a=ssrc-group, e.g. FID pairing a media SSRC with its RTX SSRC
+	Protocol    string           // Transport protocol from the m= line, e.g. RTP/AVP or RTP/SAVP
+	Crypto      []SDPCrypto      // SDES keys from a=crypto (RFC 4568)
+}
+
+// SDPCrypto is an a=crypto attribute carrying an inline SRTP master key and salt
+type SDPCrypto struct {
+	Tag   int
+	Suite string
+	Key   []byte // Base64-decoded inline key||salt
+}
+
+// SDPSSRCGroup is an a=ssrc-group attribute (RFC 5576)
+type SDPSSRCGroup struct {
+	Semantics string
+	SSRCs     []uint32
+}
+
+// SDPFormat describes one payload type of a media section (a=rtpmap and a=fmtp)
+type SDPFormat struct {
+	PayloadType int
+	Codec       string
+	ClockRate   int
+	Channels    int
+	FMTP        map[string]string
+	Feedback    []string // RTCP feedback from a=rtcp-fb for this payload type or *
+}
+
+// FormatsByCodec returns the formats whose encoding name matches codec (case-insensitive)
+func (t SDPTrack) FormatsByCodec(codec string) []SDPFormat {
+	var formats []SDPFormat
+	for _, f := range t.Formats {
+		if strings.EqualFold(f.Codec, codec) {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// isRepairCodec reports whether an encoding only carries redundancy for another format
+func isRepairCodec(codec string) bool {
+	switch strings.ToLower(codec) {
+	case "red", "ulpfec", "rtx", "flexfec", "flexfec-03":
+		return true
+	}
+	return false
+}
+
+// SupportsFeedback reports whether the track advertises an RTCP feedback type
+// (e.g. "nack") with an optional parameter (e.g. "pli"); an empty param matches
+// only the bare type.
+func (t SDPTrack) SupportsFeedback(fbType, param string) bool {
+	want := fbType
+	if param != "" {
+		want += " " + param
+	}
+	for _, fb := range t.Feedback {
+		if strings.EqualFold(fb, want) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewClient creates a new RTSP client
@@ -181,6 +268,8 @@ func (c *Client) Describe() (string, error) {
 		c.sdpInfo = info
 		c.aggregateControl = info.AggregateControl
 		c.trackIndex = 0
+		c.configureFEC(info)
+		c.configureRTX(info)
 	}
 
 	return body, nil
@@ -190,6 +279,15 @@ func (c *Client) Describe() (string, error) {
 func (c *Client) Setup() error {
 	c.cseq++
 	setupURL := c.nextSetupURL()
+	setupTrack := c.trackIndex
+
+	// Tracks offered as RTP/SAVP are set up with the SDES key from a=crypto
+	srtpContext, err := c.setupSRTPContext()
+	if err != nil {
+		return err
+	}
+	c.srtpSetup = srtpContext != nil
+	defer func() { c.srtpSetup = false }()
 	
 	// Calculate channel numbers for this track (each track gets 2 channels: RTP and RTCP)
 	channelBase := uint8(c.trackIndex * 2)
@@ -199,6 +297,9 @@ func (c *Client) Setup() error {
 	var request string
 	if c.transportMode == TransportModeTCP {
 		request = buildRequestWithTCPTransportAndChannels("SETUP", setupURL, c.cseq, "", c.rtpChannel, c.rtcpChannel)
+		if c.srtpSetup {
+			request = secureTransport(request)
+		}
 		// Add authentication if needed
 		if c.authChallenge != nil && c.hasCredentials() {
 			authHeader := c.generateAuthHeader("SETUP", setupURL)
@@ -260,6 +361,10 @@ func (c *Client) Setup() error {
 		}
 	}
 
+	if srtpContext != nil {
+		c.addSRTPContext(setupTrack, srtpContext)
+	}
+
 	if c.sdpInfo != nil && len(c.sdpInfo.Tracks) > 0 && c.trackIndex < len(c.sdpInfo.Tracks)-1 {
 		c.trackIndex++
 	}
@@ -297,90 +402,32 @@ func (c *Client) Play() error {
 	return nil
 }
 
-// ReadPacket reads an RTP packet from the stream
+// ReadPacket reads an RTP packet from the stream.
+// The returned packet owns its memory; use ReadPacketInto to avoid per-packet allocations.
 func (c *Client) ReadPacket() (*rtp.Packet, error) {
-	if c.transportMode == TransportModeTCP {
-		if c.conn == nil {
-			return nil, fmt.Errorf("not connected")
-		}
-
-		if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
-			return nil, err
-		}
-
-		for {
-			frame, err := c.ReadInterleavedPacket()
-			if err != nil {
-				return nil, err
-			}
-
-			channel := frame.Channel
-			// Accept RTP packets from any track (even-numbered channels: 0, 2, 4, ...)
-			// RTCP packets are on odd-numbered channels: 1, 3, 5, ...
-			if channel%2 == 0 {
-				// This is an RTP channel
-				packet, err := rtp.ParsePacket(frame.Payload)
-				if err != nil {
-					return nil, fmt.Errorf("failed to parse RTP packet: %w", err)
-				}
-				// Log RTP header details immediately after parsing
-				logger.Debug("[RTP:ReadPacket:TCP] RTP Header: Version=%d, Padding=%t, Extension=%t, Marker=%t, PayloadType=%d, SeqNum=%d, Timestamp=%d, SSRC=0x%x, PayloadSize=%d bytes, Channel=%d",
-					packet.Version, packet.Padding, packet.Extension, packet.Marker, packet.PayloadType,
-					packet.SequenceNumber, packet.Timestamp, packet.SSRC, len(packet.Payload), channel)
-				c.validatePayloadType(packet)
-				return packet, nil
-			}
-
-			// This is an RTCP channel (odd-numbered)
-			logger.Debug("[RTP:ReadPacket:TCP] RTCP packet detected on channel %d (size=%d bytes), routing to RTCP handler", channel, len(frame.Payload))
-			rtcpPacket, err := rtp.ParseRTCPPacket(frame.Payload)
-			if err != nil {
-				logger.Warn("[RTP:ReadPacket:TCP] Failed to parse RTCP packet: %v, continuing to look for RTP packet", err)
-				continue
-			}
-			
-			// Route RTCP packet to handler if registered
-			c.rtcpHandlerMu.RLock()
-			handler := c.rtcpHandler
-			c.rtcpHandlerMu.RUnlock()
-			
-			if handler != nil {
-				if err := handler(rtcpPacket); err != nil {
-					logger.Warn("[RTP:ReadPacket:TCP] RTCP handler error: %v", err)
-				}
-			} else {
-				logger.Debug("[RTP:ReadPacket:TCP] RTCP packet received but no handler registered (type=%d, SSRC=0x%x)", rtcpPacket.GetPacketType(), rtcpPacket.GetSSRC())
-			}
-			
-			// Continue to look for RTP packet
-			continue
-		}
+	if c.jitterBufferActive() {
+		return c.readJitterBuffered()
 	}
-
-	if c.rtpConn == nil {
-		return nil, errors.New("RTP connection not established")
+	if c.repairActive() {
+		return c.readRepaired()
 	}
 
-	buffer := make([]byte, 2048)
-	c.rtpConn.SetReadDeadline(time.Now().Add(c.timeout))
+	if c.receiveBuffer == nil {
+		c.receiveBuffer = make([]byte, rtp.MaxPacketBufferSize)
+	}
 
-	n, err := c.rtpConn.Read(buffer)
+	data, channel, err := c.readRTP(c.receiveBuffer)
 	if err != nil {
-		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-			return nil, err
-		}
 		return nil, err
 	}
 
-	packet, err := rtp.ParsePacket(buffer[:n])
+	// Copy out of the shared receive buffer so callers may retain the packet
+	packet, err := rtp.ParsePacket(append([]byte(nil), data...))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse RTP packet: %w", err)
 	}
-	// Log RTP header details immediately after parsing
-	logger.Debug("[RTP:ReadPacket:UDP] RTP Header: Version=%d, Padding=%t, Extension=%t, Marker=%t, PayloadType=%d, SeqNum=%d, Timestamp=%d, SSRC=0x%x, PayloadSize=%d bytes",
-		packet.Version, packet.Padding, packet.Extension, packet.Marker, packet.PayloadType,
-		packet.SequenceNumber, packet.Timestamp, packet.SSRC, len(packet.Payload))
-
+	c.logRTPHeader(packet, channel)
+	c.recordReception(packet, channel, time.Now())
 	c.validatePayloadType(packet)
 
 	return packet, nil
@@ -396,8 +443,18 @@ func (c *Client) SetRTCPHandler(handler ClientRTCPHandler) {
 }
 
 // ReadRTCP reads an RTCP packet from the stream
-// Returns the parsed RTCP packet or error
+// Returns the first packet of the compound packet or error
 func (c *Client) ReadRTCP() (rtp.RTCPPacket, error) {
+	compound, err := c.ReadCompoundRTCP()
+	if err != nil {
+		return nil, err
+	}
+	return compound.Packets[0], nil
+}
+
+// ReadCompoundRTCP reads a compound RTCP packet from the stream
+// Returns all packets it contains or error
+func (c *Client) ReadCompoundRTCP() (*rtp.CompoundRTCPPacket, error) {
 	if c.transportMode == TransportModeTCP {
 		if c.conn == nil {
 			return nil, fmt.Errorf("not connected")
@@ -417,12 +474,19 @@ func (c *Client) ReadRTCP() (rtp.RTCPPacket, error) {
 			// RTCP packets are on odd-numbered channels: 1, 3, 5, ...
 			if channel%2 == 1 {
 				// This is an RTCP channel
-				rtcpPacket, err := rtp.ParseRTCPPacket(frame.Payload)
+				data, err := c.unprotectRTCP(frame.Payload)
+				if err != nil {
+					logger.Warn("[RTCP:ReadRTCP:TCP] Dropping SRTCP packet: %v", err)
+					continue
+				}
+				c.recordRTCPData(data)
+				compound, err := rtp.ParseCompoundRTCPPacket(data)
 				if err != nil {
 					logger.Warn("[RTCP:ReadRTCP:TCP] Failed to parse RTCP packet: %v", err)
 					continue
 				}
-				return rtcpPacket, nil
+				c.recordRTCP(compound)
+				return compound, nil
 			}
 
 			// Skip RTP frames (even channels)
@@ -446,12 +510,19 @@ func (c *Client) ReadRTCP() (rtp.RTCPPacket, error) {
 		return nil, err
 	}
 
-	rtcpPacket, err := rtp.ParseRTCPPacket(buffer[:n])
+	data, err := c.unprotectRTCP(buffer[:n])
+	if err != nil {
+		return nil, fmt.Errorf("failed to unprotect SRTCP packet: %w", err)
+	}
+	c.recordRTCPData(data)
+
+	compound, err := rtp.ParseCompoundRTCPPacket(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse RTCP packet: %w", err)
 	}
+	c.recordRTCP(compound)
 
-	return rtcpPacket, nil
+	return compound, nil
 }
 
 // Teardown sends TEARDOWN request to stop streaming
@@ -476,6 +547,12 @@ func (c *Client) Teardown() error {
 	return nil
 }
 
+// GetSDPInfo returns the SDP metadata parsed from the last DESCRIBE response,
+// or nil if DESCRIBE has not succeeded yet
+func (c *Client) GetSDPInfo() *SDPInfo {
+	return c.sdpInfo
+}
+
 // Close closes all connections
 // GetNumTracks returns the number of tracks in the SDP
 func (c *Client) GetNumTracks() int {
@@ -777,11 +854,49 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 
 	var current *SDPTrack
 	currentPayloadType := -1
+	sessionExtMap := rtp.ExtensionMap{}
+	var feedbackLines [][2]string // a=rtcp-fb payload type and feedback of the current section
+
+	// formatFor returns the current section's format entry for a payload type
+	formatFor := func(pt int) *SDPFormat {
+		for i := range current.Formats {
+			if current.Formats[i].PayloadType == pt {
+				return &current.Formats[i]
+			}
+		}
+		current.Formats = append(current.Formats, SDPFormat{PayloadType: pt, FMTP: make(map[string]string)})
+		return &current.Formats[len(current.Formats)-1]
+	}
 
 	flushCurrent := func() {
 		if current == nil {
 			return
 		}
+		// A RED/FEC/RTX format listed first is not the media codec; use the first real one
+		if isRepairCodec(current.Codec) {
+			for _, f := range current.Formats {
+				if f.Codec != "" && !isRepairCodec(f.Codec) {
+					current.PayloadType = f.PayloadType
+					current.Codec = f.Codec
+					current.ClockRate = f.ClockRate
+					current.Channels = f.Channels
+					current.FMTP = f.FMTP
+					break
+				}
+			}
+		}
+		// Feedback is resolved once the media format is known, since RED may be listed first
+		for _, line := range feedbackLines {
+			for i := range current.Formats {
+				if line[0] == "*" || line[0] == strconv.Itoa(current.Formats[i].PayloadType) {
+					current.Formats[i].Feedback = append(current.Formats[i].Feedback, line[1])
+				}
+			}
+			if line[0] == "*" || line[0] == strconv.Itoa(current.PayloadType) {
+				current.Feedback = append(current.Feedback, line[1])
+			}
+		}
+		feedbackLines = nil
 		if current.ControlURL != "" {
 			info.Tracks = append(info.Tracks, *current)
 		}
@@ -812,6 +927,19 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 			continue
 		}
 
+		if strings.HasPrefix(line, "a=extmap:") {
+			id, uri, err := rtp.ParseExtMap(strings.TrimPrefix(line, "a=extmap:"))
+			if err != nil {
+				continue
+			}
+			if current == nil {
+				sessionExtMap[id] = uri
+			} else {
+				current.ExtMap[id] = uri
+			}
+			continue
+		}
+
 		if strings.HasPrefix(line, "m=") {
 			flushCurrent()
 			parts := strings.Fields(strings.TrimPrefix(line, "m="))
@@ -825,10 +953,17 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 			}
 			current = &SDPTrack{
 				Media:       media,
+				Protocol:    parts[2],
 				PayloadType: pt,
 				FMTP:        make(map[string]string),
+				ExtMap:      rtp.ExtensionMap{},
 			}
 			currentPayloadType = pt
+			for _, field := range parts[3:] {
+				if fmtPT, err := strconv.Atoi(field); err == nil {
+					formatFor(fmtPT)
+				}
+			}
 			continue
 		}
 
@@ -841,6 +976,17 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 			parts := strings.SplitN(payloadAndCodec, " ", 2)
 			if len(parts) == 2 {
 				pt, err := strconv.Atoi(parts[0])
+				if err == nil {
+					format := formatFor(pt)
+					codecParts := strings.Split(parts[1], "/")
+					format.Codec = codecParts[0]
+					if len(codecParts) > 1 {
+						format.ClockRate, _ = strconv.Atoi(codecParts[1])
+					}
+					if len(codecParts) > 2 {
+						format.Channels, _ = strconv.Atoi(codecParts[2])
+					}
+				}
 				if err == nil && (currentPayloadType == -1 || pt == currentPayloadType) {
 					codecParts := strings.Split(parts[1], "/")
 					current.Codec = codecParts[0]
@@ -859,13 +1005,44 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 			continue
 		}
 
+		if strings.HasPrefix(line, "a=ssrc-group:") {
+			parts := strings.Fields(strings.TrimPrefix(line, "a=ssrc-group:"))
+			if len(parts) >= 2 {
+				group := SDPSSRCGroup{Semantics: parts[0]}
+				for _, field := range parts[1:] {
+					if ssrc, err := strconv.ParseUint(field, 10, 32); err == nil {
+						group.SSRCs = append(group.SSRCs, uint32(ssrc))
+					}
+				}
+				current.SSRCGroups = append(current.SSRCGroups, group)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "a=crypto:") {
+			if crypto, ok := parseSDPCrypto(strings.TrimPrefix(line, "a=crypto:")); ok {
+				current.Crypto = append(current.Crypto, crypto)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "a=rtcp-fb:") {
+			parts := strings.Fields(strings.TrimPrefix(line, "a=rtcp-fb:"))
+			if len(parts) >= 2 {
+				feedbackLines = append(feedbackLines, [2]string{parts[0], strings.ToLower(strings.Join(parts[1:], " "))})
+			}
+			continue
+		}
+
 		if strings.HasPrefix(line, "a=fmtp:") {
 			fmtpLine := strings.TrimPrefix(line, "a=fmtp:")
 			parts := strings.SplitN(fmtpLine, " ", 2)
 			if len(parts) == 2 {
 				pt, err := strconv.Atoi(parts[0])
-				if err == nil && (currentPayloadType == -1 || pt == currentPayloadType) {
+				if err == nil {
 					pairs := strings.Split(parts[1], ";")
+					format := formatFor(pt)
+					primary := currentPayloadType == -1 || pt == currentPayloadType
 					for _, pair := range pairs {
 						pair = strings.TrimSpace(pair)
 						if pair == "" {
@@ -873,7 +1050,11 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 						}
 						kv := strings.SplitN(pair, "=", 2)
 						if len(kv) == 2 {
-							current.FMTP[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+							key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+							format.FMTP[key] = value
+							if primary {
+								current.FMTP[key] = value
+							}
 						}
 					}
 				}
@@ -884,6 +1065,15 @@ func parseSDPInfo(sdp, contentBase, requestURL string) *SDPInfo {
 
 	flushCurrent()
 
+	// Session-level extmap attributes apply to every media section that doesn't override them
+	for i := range info.Tracks {
+		for id, uri := range sessionExtMap {
+			if _, ok := info.Tracks[i].ExtMap[id]; !ok {
+				info.Tracks[i].ExtMap[id] = uri
+			}
+		}
+	}
+
 	if info.AggregateControl != "" {
 		info.AggregateControl = resolveControlURL(contentBase, requestURL, info.AggregateControl)
 	}
@@ -958,6 +1148,9 @@ func (c *Client) buildRequestWithAuth(method, url string, cseq int, session stri
 	} else {
 		request = buildRequest(method, url, cseq, session)
 	}
+	if method == "SETUP" && c.srtpSetup {
+		request = secureTransport(request)
+	}
 
 	// Add authentication header if we have credentials and challenge
 	if c.authChallenge != nil && c.hasCredentials() {
@@ -1064,6 +1257,8 @@ func (c *Client) retryWithAuth(method string, headers map[string]string) (string
 		c.sdpInfo = info
 		c.aggregateControl = info.AggregateControl
 		c.trackIndex = 0
+		c.configureFEC(info)
+		c.configureRTX(info)
 	}
 
 	return body, nil
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rtsp-client/pkg/rtp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -316,6 +317,157 @@ a=control:trackID=1`
 	assert.Equal(t, "5", audio.FMTP["streamtype"])
 }
 
+func TestParseSDPInfoExtMap(t *testing.T) {
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+a=extmap:1 urn:ietf:params:rtp-hdrext:sdes:mid
+m=video 0 RTP/AVP 96
+a=rtpmap:96 H264/90000
+a=extmap:3/recvonly http://www.webrtc.org/experiments/rtp-hdrext/abs-send-time
+a=extmap:1 urn:ietf:params:rtp-hdrext:toffset
+a=control:trackID=0
+m=audio 0 RTP/AVP 0
+a=rtpmap:0 PCMU/8000
+a=control:trackID=1`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 2)
+
+	video := info.Tracks[0]
+	assert.Equal(t, rtp.ExtensionMap{
+		1: rtp.ExtensionURITimestampOffset,
+		3: rtp.ExtensionURIAbsSendTime,
+	}, video.ExtMap, "media-level extmap overrides session-level ID")
+
+	audio := info.Tracks[1]
+	assert.Equal(t, rtp.ExtensionMap{1: rtp.ExtensionURISDESMid}, audio.ExtMap)
+}
+
+func TestParseSDPInfoRTCPFeedback(t *testing.T) {
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+m=video 0 RTP/AVP 96
+a=rtpmap:96 H264/90000
+a=rtcp-fb:96 nack
+a=rtcp-fb:96 nack pli
+a=rtcp-fb:* ccm fir
+a=rtcp-fb:97 goog-remb
+a=control:trackID=0
+m=audio 0 RTP/AVP 0
+a=rtpmap:0 PCMU/8000
+a=control:trackID=1`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 2)
+
+	video := info.Tracks[0]
+	assert.Equal(t, []string{"nack", "nack pli", "ccm fir"}, video.Feedback)
+	assert.True(t, video.SupportsFeedback("nack", ""))
+	assert.True(t, video.SupportsFeedback("nack", "pli"))
+	assert.True(t, video.SupportsFeedback("CCM", "FIR"))
+	assert.False(t, video.SupportsFeedback("goog-remb", ""))
+
+	assert.Empty(t, info.Tracks[1].Feedback)
+	assert.False(t, info.Tracks[1].SupportsFeedback("nack", ""))
+}
+
+func TestParseSDPInfoRTCPFeedbackRedundancyFirst(t *testing.T) {
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+m=video 0 RTP/AVP 116 96 117
+a=rtpmap:116 red/90000
+a=rtpmap:96 H264/90000
+a=rtpmap:117 ulpfec/90000
+a=rtcp-fb:96 nack
+a=rtcp-fb:96 nack pli
+a=rtcp-fb:* ccm fir
+a=rtcp-fb:116 goog-remb
+a=control:trackID=0`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 1)
+
+	video := info.Tracks[0]
+	assert.Equal(t, 96, video.PayloadType)
+	assert.Equal(t, []string{"nack", "nack pli", "ccm fir"}, video.Feedback)
+	assert.True(t, video.SupportsFeedback("nack", "pli"))
+	assert.False(t, video.SupportsFeedback("goog-remb", ""))
+
+	red := video.FormatsByCodec("red")
+	require.Len(t, red, 1)
+	assert.Equal(t, []string{"ccm fir", "goog-remb"}, red[0].Feedback)
+	h264 := video.FormatsByCodec("H264")
+	require.Len(t, h264, 1)
+	assert.Equal(t, []string{"nack", "nack pli", "ccm fir"}, h264[0].Feedback)
+}
+
+func TestParseSDPInfoFormats(t *testing.T) {
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+m=video 0 RTP/AVP 116 96 117
+a=rtpmap:96 H264/90000
+a=fmtp:96 packetization-mode=1
+a=rtpmap:116 red/90000
+a=rtpmap:117 ulpfec/90000
+a=control:trackID=0`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 1)
+
+	// RED listed first must not be taken as the media codec
+	video := info.Tracks[0]
+	assert.Equal(t, 96, video.PayloadType)
+	assert.Equal(t, "H264", video.Codec)
+	assert.Equal(t, 90000, video.ClockRate)
+	assert.Equal(t, "1", video.FMTP["packetization-mode"])
+
+	require.Len(t, video.Formats, 3)
+	assert.Equal(t, []int{116, 96, 117}, []int{video.Formats[0].PayloadType, video.Formats[1].PayloadType, video.Formats[2].PayloadType})
+	assert.Equal(t, "1", video.Formats[1].FMTP["packetization-mode"])
+
+	red := video.FormatsByCodec("RED")
+	require.Len(t, red, 1)
+	assert.Equal(t, 116, red[0].PayloadType)
+	assert.Empty(t, video.FormatsByCodec("rtx"))
+}
+
+func TestParseSDPInfoRTX(t *testing.T) {
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+m=video 0 RTP/AVP 96 97
+a=rtpmap:96 H264/90000
+a=rtpmap:97 rtx/90000
+a=fmtp:97 apt=96;rtx-time=3000
+a=ssrc-group:FID 11111 22222
+a=control:trackID=0`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 1)
+
+	video := info.Tracks[0]
+	assert.Empty(t, video.FMTP, "RTX fmtp must not leak into the media format")
+	rtx := video.FormatsByCodec("rtx")
+	require.Len(t, rtx, 1)
+	assert.Equal(t, "96", rtx[0].FMTP["apt"])
+	assert.Equal(t, "3000", rtx[0].FMTP["rtx-time"])
+	assert.Equal(t, []SDPSSRCGroup{{Semantics: "FID", SSRCs: []uint32{11111, 22222}}}, video.SSRCGroups)
+}
+
 func TestParseSDPInfoFallbackToRequestURL(t *testing.T) {
 	sdp := `v=0
 o=- 0 0 IN IP4 127.0.0.1
@@ -0,0 +1,54 @@
+package rtsp
+
+import (
+	"strings"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// configureFEC enables RED/ULPFEC recovery when any track advertises it
+func (c *Client) configureFEC(info *SDPInfo) {
+	c.fecReceiver = nil
+	c.repairQueue = nil
+
+	var receiver *rtp.FECReceiver
+	for _, track := range info.Tracks {
+		for _, format := range track.Formats {
+			if format.PayloadType < 0 || format.PayloadType > 0x7F {
+				continue
+			}
+			pt := uint8(format.PayloadType)
+			switch strings.ToLower(format.Codec) {
+			case "red":
+				if receiver == nil {
+					receiver = rtp.NewFECReceiver()
+				}
+				receiver.AddREDPayloadType(pt)
+				logger.Info("[Client] RED enabled: track=%s, payload type=%d", track.Media, pt)
+			case "ulpfec":
+				if receiver == nil {
+					receiver = rtp.NewFECReceiver()
+				}
+				receiver.AddULPFECPayloadType(pt)
+				logger.Info("[Client] ULPFEC recovery enabled: track=%s, payload type=%d", track.Media, pt)
+			}
+		}
+	}
+	c.fecReceiver = receiver
+}
+
+// DisableFEC turns off RED/ULPFEC processing; RED and FEC packets are then returned as-is
+func (c *Client) DisableFEC() {
+	c.fecReceiver = nil
+	c.repairQueue = nil
+}
+
+// decodeFEC returns the media packets carried by or recovered because of packet
+func (c *Client) decodeFEC(packet *rtp.Packet) []*rtp.Packet {
+	if c.fecReceiver == nil {
+		return []*rtp.Packet{packet}
+	}
+
+	return c.fecReceiver.Push(packet)
+}
@@ -0,0 +1,73 @@
+package rtsp
+
+import (
+	"testing"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClient_FECRecovery tests RED decapsulation and ULPFEC recovery on the read path
+func TestClient_FECRecovery(t *testing.T) {
+	media := make([]*rtp.Packet, 3)
+	for i := range media {
+		media[i] = &rtp.Packet{PayloadType: 96, SequenceNumber: uint16(10 + i), Timestamp: 3000, SSRC: 0xdeadbeef, Marker: i == 2, Payload: []byte{0x41, byte(i), byte(i * 2)}}
+	}
+	fec, err := rtp.NewULPFECPacket(media)
+	require.NoError(t, err)
+
+	red := func(p *rtp.Packet) []byte {
+		payload, err := rtp.MarshalRED([]rtp.REDBlock{{PayloadType: p.PayloadType, Payload: p.Payload}})
+		require.NoError(t, err)
+		data, err := (&rtp.Packet{PayloadType: 116, Marker: p.Marker, SequenceNumber: p.SequenceNumber, Timestamp: p.Timestamp, SSRC: p.SSRC, Payload: payload}).Marshal()
+		require.NoError(t, err)
+		return BuildInterleavedFrame(0, data)
+	}
+	fecPacket := &rtp.Packet{PayloadType: 117, SequenceNumber: 13, Timestamp: 3000, SSRC: 0xdeadbeef, Payload: fec.Marshal()}
+
+	// Packet 11 is lost
+	conn := newMockConn(red(media[0]), red(media[2]), red(fecPacket))
+	client := newTCPTestClient(conn)
+	client.configureFEC(&SDPInfo{Tracks: []SDPTrack{{
+		Media:       "video",
+		PayloadType: 96,
+		Formats: []SDPFormat{
+			{PayloadType: 96, Codec: "H264", ClockRate: 90000},
+			{PayloadType: 116, Codec: "red", ClockRate: 90000},
+			{PayloadType: 117, Codec: "ulpfec", ClockRate: 90000},
+		},
+	}}})
+
+	var seqs []uint16
+	for i := 0; i < 2; i++ {
+		p, err := client.ReadPacket()
+		require.NoError(t, err)
+		assert.Equal(t, uint8(96), p.PayloadType)
+		seqs = append(seqs, p.SequenceNumber)
+	}
+
+	packet := &rtp.Packet{}
+	buf := make([]byte, rtp.MaxPacketBufferSize)
+	require.NoError(t, client.ReadPacketInto(packet, buf))
+	seqs = append(seqs, packet.SequenceNumber)
+	assert.Equal(t, media[1].Payload, packet.Payload)
+	assert.Equal(t, []uint16{10, 12, 11}, seqs)
+
+	stats := client.GetStats()
+	assert.True(t, stats.FECEnabled)
+	assert.Equal(t, 3, stats.FEC.REDPackets)
+	assert.Equal(t, 1, stats.FEC.RecoveredPackets)
+
+	client.DisableFEC()
+	assert.False(t, client.GetStats().FECEnabled)
+}
+
+// TestClient_ConfigureFECWithoutRepairFormats tests that plain streams keep the direct read path
+func TestClient_ConfigureFECWithoutRepairFormats(t *testing.T) {
+	client := newTCPTestClient(newMockConn())
+	client.configureFEC(&SDPInfo{Tracks: []SDPTrack{{
+		Formats: []SDPFormat{{PayloadType: 96, Codec: "H264", ClockRate: 90000}},
+	}}})
+	assert.Nil(t, client.fecReceiver)
+}
@@ -0,0 +1,216 @@
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// DefaultKeyframeRequestInterval is the minimum time between PLI/FIR requests for one source
+	DefaultKeyframeRequestInterval = time.Second
+	// nackRetryInterval is how long a NACKed sequence number is not requested again
+	nackRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrFeedbackNotSupported indicates the SDP does not advertise the required a=rtcp-fb type
+	ErrFeedbackNotSupported = errors.New("RTCP feedback not supported by server")
+	// ErrFeedbackRateLimited indicates a keyframe request was suppressed by the rate limit
+	ErrFeedbackRateLimited = errors.New("RTCP feedback rate limited")
+)
+
+// feedbackState holds RTCP feedback bookkeeping
+type feedbackState struct {
+	keyframeInterval time.Duration
+	lastKeyframe     map[uint32]time.Time // Last PLI/FIR per media SSRC
+	firSequence      map[uint32]uint8     // FIR command sequence number per media SSRC
+	nackEnabled      bool
+	nackSent         map[uint16]time.Time // When each sequence number was last NACKed
+}
+
+// SetKeyframeRequestInterval sets the minimum time between keyframe requests for one source
+func (c *Client) SetKeyframeRequestInterval(interval time.Duration) {
+	c.feedbackMu.Lock()
+	defer c.feedbackMu.Unlock()
+	c.feedback.keyframeInterval = interval
+}
+
+// SetNACKEnabled enables generic NACKs for gaps the jitter buffer detects.
+// NACKs are only sent if the SDP advertises a=rtcp-fb nack and the jitter buffer is enabled.
+func (c *Client) SetNACKEnabled(enabled bool) {
+	c.feedbackMu.Lock()
+	defer c.feedbackMu.Unlock()
+	c.feedback.nackEnabled = enabled
+	c.feedback.nackSent = nil
+}
+
+// RequestKeyframe asks the sender of mediaSSRC for a new IDR frame. A PLI is sent
+// if the SDP advertises "nack pli", otherwise a FIR if it advertises "ccm fir".
+// Requests closer together than the keyframe request interval return ErrFeedbackRateLimited.
+func (c *Client) RequestKeyframe(mediaSSRC uint32) error {
+	track, channel, err := c.feedbackTarget(mediaSSRC)
+	if err != nil {
+		return err
+	}
+
+	usePLI := track.SupportsFeedback("nack", "pli")
+	if !usePLI && !track.SupportsFeedback("ccm", "fir") {
+		return fmt.Errorf("%w: no PLI or FIR for payload type %d", ErrFeedbackNotSupported, track.PayloadType)
+	}
+
+	now := time.Now()
+	c.feedbackMu.Lock()
+	interval := c.feedback.keyframeInterval
+	if interval == 0 {
+		interval = DefaultKeyframeRequestInterval
+	}
+	if last, ok := c.feedback.lastKeyframe[mediaSSRC]; ok && now.Sub(last) < interval {
+		c.feedbackMu.Unlock()
+		return ErrFeedbackRateLimited
+	}
+	if c.feedback.lastKeyframe == nil {
+		c.feedback.lastKeyframe = make(map[uint32]time.Time)
+		c.feedback.firSequence = make(map[uint32]uint8)
+	}
+	c.feedback.lastKeyframe[mediaSSRC] = now
+	firSequence := c.feedback.firSequence[mediaSSRC]
+	if !usePLI {
+		c.feedback.firSequence[mediaSSRC]++
+	}
+	c.feedbackMu.Unlock()
+
+	localSSRC := c.GetLocalSSRC()
+	var fb rtp.RTCPPacket
+	if usePLI {
+		fb = &rtp.PictureLossIndication{SenderSSRC: localSSRC, MediaSSRC: mediaSSRC}
+	} else {
+		fb = &rtp.FullIntraRequest{
+			SenderSSRC: localSSRC,
+			Entries:    []rtp.FIREntry{{SSRC: mediaSSRC, SequenceNumber: firSequence}},
+		}
+	}
+
+	if err := c.sendFeedback(channel, fb); err != nil {
+		return err
+	}
+	if usePLI {
+		logger.Info("[Client:Feedback] Sent PLI for SSRC 0x%x", mediaSSRC)
+	} else {
+		logger.Info("[Client:Feedback] Sent FIR #%d for SSRC 0x%x", firSequence, mediaSSRC)
+	}
+	return nil
+}
+
+// SendNACK reports lost sequence numbers of mediaSSRC with a generic NACK
+func (c *Client) SendNACK(mediaSSRC uint32, lost []uint16) error {
+	if len(lost) == 0 {
+		return nil
+	}
+
+	track, channel, err := c.feedbackTarget(mediaSSRC)
+	if err != nil {
+		return err
+	}
+	if !track.SupportsFeedback("nack", "") {
+		return fmt.Errorf("%w: no generic NACK for payload type %d", ErrFeedbackNotSupported, track.PayloadType)
+	}
+
+	nack := &rtp.GenericNACK{
+		SenderSSRC: c.GetLocalSSRC(),
+		MediaSSRC:  mediaSSRC,
+		Pairs:      rtp.NACKPairsFromSequences(lost),
+	}
+	if err := c.sendFeedback(channel, nack); err != nil {
+		return err
+	}
+	logger.Debug("[Client:Feedback] Sent NACK for %d packets of SSRC 0x%x", len(lost), mediaSSRC)
+	return nil
+}
+
+// nackJitterGaps NACKs packets missing from the jitter buffer, including any
+// right after the last released one, that were not requested recently
+func (c *Client) nackJitterGaps(mediaSSRC uint32) {
+	c.feedbackMu.Lock()
+	if !c.feedback.nackEnabled {
+		c.feedbackMu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	var lost []uint16
+	for _, seq := range c.jitterBuffer.DetectMissing() {
+		if sent, ok := c.feedback.nackSent[seq]; ok && now.Sub(sent) < nackRetryInterval {
+			continue
+		}
+		lost = append(lost, seq)
+	}
+	if len(lost) > 0 {
+		if c.feedback.nackSent == nil {
+			c.feedback.nackSent = make(map[uint16]time.Time)
+		}
+		for _, seq := range lost {
+			c.feedback.nackSent[seq] = now
+		}
+	}
+	// Forget old entries so the sequence space can wrap
+	for seq, sent := range c.feedback.nackSent {
+		if now.Sub(sent) > time.Second {
+			delete(c.feedback.nackSent, seq)
+		}
+	}
+	c.feedbackMu.Unlock()
+
+	if err := c.SendNACK(mediaSSRC, lost); err != nil && !errors.Is(err, ErrFeedbackNotSupported) {
+		logger.Warn("[Client:Feedback] Failed to send NACK: %v", err)
+	}
+}
+
+// feedbackTarget returns the SDP track and RTCP channel for a media source
+func (c *Client) feedbackTarget(mediaSSRC uint32) (*SDPTrack, uint8, error) {
+	c.receptionMu.Lock()
+	source, ok := c.reception[mediaSSRC]
+	c.receptionMu.Unlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown media SSRC 0x%x", mediaSSRC)
+	}
+
+	track := c.trackForPayloadType(source.payloadType)
+	if track == nil {
+		return nil, 0, fmt.Errorf("%w: no SDP track for payload type %d", ErrFeedbackNotSupported, source.payloadType)
+	}
+
+	channel := uint8(0)
+	if c.transportMode == TransportModeTCP {
+		channel = source.rtcpChannel
+	}
+	return track, channel, nil
+}
+
+// sendFeedback sends a feedback message in a compound packet led by an empty RR and SDES CNAME
+// (RFC 4585 section 3.1). The RR carries no report blocks so reporting intervals are unaffected.
+func (c *Client) sendFeedback(channel uint8, fb rtp.RTCPPacket) error {
+	ssrc := c.GetLocalSSRC()
+	compound := &rtp.CompoundRTCPPacket{
+		Packets: []rtp.RTCPPacket{
+			&rtp.ReceiverReport{PacketType: rtp.RTCP_RR, SSRC: ssrc},
+			&rtp.SDESPacket{
+				PacketType: rtp.RTCP_SDES,
+				Chunks: []rtp.SDESChunk{{
+					SSRC:  ssrc,
+					Items: []rtp.SDESItem{{Type: rtp.SDES_CNAME, Text: rtcpCNAME(ssrc)}},
+				}},
+			},
+			fb,
+		},
+	}
+
+	data, err := compound.Marshal()
+	if err != nil {
+		return err
+	}
+	return c.sendRTCP(channel, data)
+}
@@ -0,0 +1,207 @@
+package rtsp
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFeedbackTestClient returns a TCP client that has received one packet from SSRC 0xdeadbeef
+func newFeedbackTestClient(t *testing.T, feedback ...string) (*Client, *mockConn) {
+	conn := newMockConn(BuildInterleavedFrame(0, testRTPPacket(t, 100)))
+	client := newTCPTestClient(conn)
+	client.sdpInfo = &SDPInfo{Tracks: []SDPTrack{{PayloadType: 96, ClockRate: 90000, Feedback: feedback}}}
+
+	_, err := client.ReadPacket()
+	require.NoError(t, err)
+	return client, conn
+}
+
+// lastRTCPPacket reads the next interleaved frame and returns its channel and final compound sub-packet
+func lastRTCPPacket(t *testing.T, conn *mockConn) (uint8, []byte) {
+	frame, err := NewInterleavedReader(&conn.writeBuf).ReadFrame()
+	require.NoError(t, err)
+
+	data := frame.Payload
+	require.Equal(t, uint8(rtp.RTCP_RR), data[1], "compound must start with RR")
+	for {
+		size := (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+		require.LessOrEqual(t, size, len(data))
+		if size == len(data) {
+			return frame.Channel, data
+		}
+		data = data[size:]
+	}
+}
+
+// TestClient_RequestKeyframe tests PLI/FIR selection and rate limiting
+func TestClient_RequestKeyframe(t *testing.T) {
+	t.Run("PLI when advertised", func(t *testing.T) {
+		client, conn := newFeedbackTestClient(t, "nack", "nack pli", "ccm fir")
+
+		require.NoError(t, client.RequestKeyframe(0xdeadbeef))
+		channel, pli := lastRTCPPacket(t, conn)
+		assert.Equal(t, uint8(1), channel)
+		assert.Equal(t, byte(0x80|rtp.PSFB_PLI), pli[0])
+		assert.Equal(t, byte(rtp.RTCP_PSFB), pli[1])
+		assert.Equal(t, client.GetLocalSSRC(), binary.BigEndian.Uint32(pli[4:8]))
+		assert.Equal(t, uint32(0xdeadbeef), binary.BigEndian.Uint32(pli[8:12]))
+
+		assert.ErrorIs(t, client.RequestKeyframe(0xdeadbeef), ErrFeedbackRateLimited)
+		assert.Zero(t, conn.writeBuf.Len())
+
+		client.SetKeyframeRequestInterval(time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		assert.NoError(t, client.RequestKeyframe(0xdeadbeef))
+	})
+
+	t.Run("FIR fallback increments sequence", func(t *testing.T) {
+		client, conn := newFeedbackTestClient(t, "ccm fir")
+		client.SetKeyframeRequestInterval(time.Nanosecond)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, client.RequestKeyframe(0xdeadbeef))
+			_, fir := lastRTCPPacket(t, conn)
+			assert.Equal(t, byte(0x80|rtp.PSFB_FIR), fir[0])
+			assert.Equal(t, uint32(0xdeadbeef), binary.BigEndian.Uint32(fir[12:16]))
+			assert.Equal(t, byte(i), fir[16])
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	t.Run("not advertised", func(t *testing.T) {
+		client, conn := newFeedbackTestClient(t, "nack")
+		assert.ErrorIs(t, client.RequestKeyframe(0xdeadbeef), ErrFeedbackNotSupported)
+		assert.Zero(t, conn.writeBuf.Len())
+	})
+
+	t.Run("unknown source", func(t *testing.T) {
+		client, _ := newFeedbackTestClient(t, "nack pli")
+		assert.Error(t, client.RequestKeyframe(0x12345678))
+	})
+}
+
+// TestClient_SendNACK tests generic NACK generation
+func TestClient_SendNACK(t *testing.T) {
+	client, conn := newFeedbackTestClient(t, "nack")
+
+	require.NoError(t, client.SendNACK(0xdeadbeef, []uint16{43, 45}))
+	_, nack := lastRTCPPacket(t, conn)
+	assert.Equal(t, byte(0x80|rtp.RTPFB_NACK), nack[0])
+	assert.Equal(t, byte(rtp.RTCP_RTPFB), nack[1])
+	assert.Equal(t, uint16(43), binary.BigEndian.Uint16(nack[12:14]))
+	assert.Equal(t, uint16(0x0002), binary.BigEndian.Uint16(nack[14:16]))
+
+	assert.NoError(t, client.SendNACK(0xdeadbeef, nil))
+
+	noNACK, _ := newFeedbackTestClient(t, "nack pli")
+	assert.ErrorIs(t, noNACK.SendNACK(0xdeadbeef, []uint16{1}), ErrFeedbackNotSupported)
+}
+
+// TestClient_JitterBufferNACK tests that jitter buffer gaps are NACKed once per retry interval
+func TestClient_JitterBufferNACK(t *testing.T) {
+	rtpSocket, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer rtpSocket.Close()
+	rtcpSocket, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer rtcpSocket.Close()
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := newUDPTestClient(rtpSocket)
+	client.host = "127.0.0.1"
+	client.rtcpConn = rtcpSocket
+	client.serverPorts = []int{0, server.LocalAddr().(*net.UDPAddr).Port}
+	client.sdpInfo = &SDPInfo{Tracks: []SDPTrack{{PayloadType: 96, Feedback: []string{"nack"}}}}
+	client.EnableJitterBuffer(64, 10*time.Millisecond, 500*time.Millisecond)
+	client.SetNACKEnabled(true)
+
+	sender, err := net.DialUDP("udp", nil, rtpSocket.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer sender.Close()
+	for _, seq := range []uint16{10, 11, 13} {
+		data, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: seq, SSRC: 0xdeadbeef, Payload: []byte{0x65}}).Marshal()
+		require.NoError(t, err)
+		_, err = sender.Write(data)
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ReadPacket()
+		require.NoError(t, err)
+	}
+
+	buf := make([]byte, 1500)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := server.ReadFrom(buf)
+	require.NoError(t, err)
+	nack := buf[n-16 : n]
+	assert.Equal(t, byte(rtp.RTCP_RTPFB), nack[1])
+	assert.Equal(t, uint16(12), binary.BigEndian.Uint16(nack[12:14]))
+
+	// The same gap is not NACKed again within the retry interval
+	server.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err = server.ReadFrom(buf)
+	assert.Error(t, err)
+}
+
+// TestClient_JitterBufferNACKAfterRelease tests that a loss right after the last released packet is NACKed
+func TestClient_JitterBufferNACKAfterRelease(t *testing.T) {
+	rtpSocket, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer rtpSocket.Close()
+	rtcpSocket, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer rtcpSocket.Close()
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	client := newUDPTestClient(rtpSocket)
+	client.host = "127.0.0.1"
+	client.rtcpConn = rtcpSocket
+	client.serverPorts = []int{0, server.LocalAddr().(*net.UDPAddr).Port}
+	client.sdpInfo = &SDPInfo{Tracks: []SDPTrack{{PayloadType: 96, Feedback: []string{"nack"}}}}
+	client.EnableJitterBuffer(64, 10*time.Millisecond, 100*time.Millisecond)
+	client.SetNACKEnabled(true)
+
+	sender, err := net.DialUDP("udp", nil, rtpSocket.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer sender.Close()
+	send := func(seq uint16) {
+		data, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: seq, SSRC: 0xdeadbeef, Payload: []byte{0x65}}).Marshal()
+		require.NoError(t, err)
+		_, err = sender.Write(data)
+		require.NoError(t, err)
+	}
+
+	// Play out 10 and 11, leaving the buffer empty
+	for _, seq := range []uint16{10, 11} {
+		send(seq)
+		packet, err := client.ReadPacket()
+		require.NoError(t, err)
+		require.Equal(t, seq, packet.SequenceNumber)
+	}
+
+	// 12 is lost; 13 is the only buffered packet, so there is no gap between buffered packets
+	send(13)
+	packet, err := client.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(13), packet.SequenceNumber)
+
+	buf := make([]byte, 1500)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := server.ReadFrom(buf)
+	require.NoError(t, err)
+	nack := buf[n-16 : n]
+	assert.Equal(t, byte(rtp.RTCP_RTPFB), nack[1])
+	assert.Equal(t, uint16(12), binary.BigEndian.Uint16(nack[12:14]))
+	assert.Equal(t, uint16(0), binary.BigEndian.Uint16(nack[14:16]))
+}
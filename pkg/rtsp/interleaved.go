@@ -2,12 +2,18 @@ package rtsp
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
 )
 
+var (
+	// ErrPacketTooLarge indicates a received packet does not fit the caller's buffer
+	ErrPacketTooLarge = errors.New("packet larger than receive buffer")
+)
+
 // TransportMode defines the transport mode for RTSP
 type TransportMode int
 
@@ -43,6 +49,7 @@ type RTCPHandler func(payload []byte)
 // InterleavedReader reads interleaved frames from a stream
 type InterleavedReader struct {
 	reader io.Reader
+	header [4]byte
 }
 
 // ChannelDemux demultiplexes interleaved channels to RTP/RTCP handlers
@@ -63,20 +70,11 @@ func NewInterleavedReader(reader io.Reader) *InterleavedReader {
 // ReadFrame reads a single interleaved frame
 func (r *InterleavedReader) ReadFrame() (*InterleavedFrame, error) {
 	// Read header: $ + channel (1 byte) + length (2 bytes big-endian)
-	header := make([]byte, 4)
-	_, err := io.ReadFull(r.reader, header)
+	channel, length, err := r.readHeader()
 	if err != nil {
 		return nil, err
 	}
 
-	// Check for $ prefix
-	if header[0] != '$' {
-		return nil, fmt.Errorf("invalid interleaved frame: missing $ prefix")
-	}
-
-	channel := header[1]
-	length := binary.BigEndian.Uint16(header[2:4])
-
 	// Read payload
 	payload := make([]byte, length)
 	if length > 0 {
@@ -93,6 +91,44 @@ func (r *InterleavedReader) ReadFrame() (*InterleavedFrame, error) {
 	}, nil
 }
 
+// ReadFrameInto reads a single interleaved frame, storing its payload in buf.
+// The returned payload aliases buf. Frames larger than buf are discarded and
+// reported as ErrPacketTooLarge so the stream stays aligned.
+func (r *InterleavedReader) ReadFrameInto(buf []byte) (uint8, []byte, error) {
+	channel, length, err := r.readHeader()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if int(length) > len(buf) {
+		if _, err := io.CopyN(io.Discard, r.reader, int64(length)); err != nil {
+			return 0, nil, fmt.Errorf("failed to skip payload: %w", err)
+		}
+		return channel, nil, fmt.Errorf("%w: %d bytes, buffer is %d", ErrPacketTooLarge, length, len(buf))
+	}
+
+	payload := buf[:length]
+	if _, err := io.ReadFull(r.reader, payload); err != nil {
+		return 0, nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+
+	return channel, payload, nil
+}
+
+// readHeader reads the 4-byte interleaved header: $ + channel + length
+func (r *InterleavedReader) readHeader() (uint8, uint16, error) {
+	if _, err := io.ReadFull(r.reader, r.header[:]); err != nil {
+		return 0, 0, err
+	}
+
+	// Check for $ prefix
+	if r.header[0] != '$' {
+		return 0, 0, fmt.Errorf("invalid interleaved frame: missing $ prefix")
+	}
+
+	return r.header[1], binary.BigEndian.Uint16(r.header[2:4]), nil
+}
+
 // ParseInterleavedFrame parses an interleaved frame from raw bytes
 func ParseInterleavedFrame(data []byte) (*InterleavedFrame, error) {
 	if len(data) < 4 {
@@ -204,7 +240,7 @@ func ParseTransportHeader(transport string) *TransportInfo {
 	info := &TransportInfo{}
 
 	// Check if TCP transport
-	if strings.Contains(transport, "RTP/AVP/TCP") || strings.Contains(transport, "interleaved") {
+	if strings.Contains(transport, "RTP/AVP/TCP") || strings.Contains(transport, "RTP/SAVP/TCP") || strings.Contains(transport, "interleaved") {
 		info.IsTCP = true
 	}
 
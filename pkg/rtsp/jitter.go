@@ -0,0 +1,143 @@
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// ClientStats contains client receive statistics
+type ClientStats struct {
+	PacketsReceived     uint64               // RTP packets read from the network
+	JitterBufferEnabled bool                 // Whether UDP packets pass through the jitter buffer
+	JitterBuffer        rtp.BufferStatistics // Jitter buffer statistics (zero when disabled)
+	FECEnabled          bool                 // Whether RED/ULPFEC recovery is active
+	FEC                 rtp.FECStatistics    // RED/ULPFEC statistics (zero when disabled)
+	RTXEnabled          bool                 // Whether RTX retransmissions are restored
+	RTX                 rtp.RTXStatistics    // RTX statistics (zero when disabled)
+	SRTPEnabled         bool                 // Whether any track was set up as RTP/SAVP
+	SRTP                rtp.SRTPStatistics   // SRTP/SRTCP statistics summed over tracks
+}
+
+// EnableJitterBuffer inserts a jitter buffer between the UDP socket and ReadPacket.
+// Packets are released in sequence order playoutDelay after arrival; a missing
+// packet is waited for up to maxDelay before it is declared lost and skipped.
+// TCP interleaved streams are already ordered and bypass the buffer.
+// Call before reading starts.
+func (c *Client) EnableJitterBuffer(size int, playoutDelay, maxDelay time.Duration) {
+	jb := rtp.NewJitterBuffer(size, maxDelay)
+	jb.SetPlayoutDelay(playoutDelay)
+	c.jitterBuffer = jb
+	logger.Info("[Client] Jitter buffer enabled: size=%d, playoutDelay=%v, maxDelay=%v", size, playoutDelay, maxDelay)
+}
+
+// DisableJitterBuffer removes the jitter buffer; buffered packets are discarded
+func (c *Client) DisableJitterBuffer() {
+	c.jitterBuffer = nil
+}
+
+// GetStats returns client receive statistics
+func (c *Client) GetStats() ClientStats {
+	stats := ClientStats{
+		PacketsReceived: c.packetsReceived.Load(),
+	}
+	if jb := c.jitterBuffer; jb != nil {
+		stats.JitterBufferEnabled = true
+		stats.JitterBuffer = jb.GetStatistics()
+	}
+	if fec := c.fecReceiver; fec != nil {
+		stats.FECEnabled = true
+		stats.FEC = fec.GetStatistics()
+	}
+	if rtx := c.rtxReceiver; rtx != nil {
+		stats.RTXEnabled = true
+		stats.RTX = rtx.GetStatistics()
+	}
+	if c.srtpActive() {
+		stats.SRTPEnabled = true
+		stats.SRTP = c.srtpStatistics()
+	}
+	return stats
+}
+
+// jitterBufferActive reports whether reads go through the jitter buffer
+func (c *Client) jitterBufferActive() bool {
+	return c.jitterBuffer != nil && c.transportMode == TransportModeUDP
+}
+
+// readJitterBuffered feeds UDP packets into the jitter buffer until one is due for playout
+func (c *Client) readJitterBuffered() (*rtp.Packet, error) {
+	if c.receiveBuffer == nil {
+		c.receiveBuffer = make([]byte, rtp.MaxPacketBufferSize)
+	}
+
+	readDeadline := time.Now().Add(c.timeout)
+	for {
+		if packet, err := c.jitterBuffer.GetReadyPacket(time.Now()); err == nil {
+			c.validatePayloadType(packet)
+			return packet, nil
+		}
+
+		// Wake up at the next playout deadline even if nothing arrives
+		deadline := readDeadline
+		if next, ok := c.jitterBuffer.NextDeadline(); ok && next.Before(deadline) {
+			deadline = next
+		}
+
+		data, err := c.readDatagram(c.receiveBuffer, deadline)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && time.Now().Before(readDeadline) {
+				continue
+			}
+			return nil, err
+		}
+		c.packetsReceived.Add(1)
+
+		if data, err = c.unprotectRTP(data); err != nil {
+			logger.Debug("[RTP:ReadPacket:UDP] Dropping SRTP packet: %v", err)
+			continue
+		}
+		c.recordRTPData(data)
+
+		// Copy out of the shared receive buffer; the packet outlives this read
+		packet, err := rtp.ParsePacket(append([]byte(nil), data...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RTP packet: %w", err)
+		}
+		c.logRTPHeader(packet, 0)
+		c.recordReception(packet, 0, time.Now())
+
+		packets, retransmitted := c.repairPackets(packet)
+		for _, media := range packets {
+			add := c.jitterBuffer.AddPacket
+			if retransmitted {
+				add = c.jitterBuffer.AddRetransmission
+			}
+			if err := add(media); err != nil {
+				logger.Debug("[RTP:ReadPacket:UDP] Jitter buffer rejected packet seq=%d: %v", media.SequenceNumber, err)
+			}
+		}
+		if len(packets) > 0 {
+			c.nackJitterGaps(packets[0].SSRC)
+		}
+	}
+}
+
+// readJitterBufferedInto copies the next jitter-buffered packet into buf
+func (c *Client) readJitterBufferedInto(packet *rtp.Packet, buf []byte) error {
+	buffered, err := c.readJitterBuffered()
+	if err != nil {
+		return err
+	}
+
+	n, err := buffered.MarshalTo(buf)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPacketTooLarge, err)
+	}
+	return packet.Unmarshal(buf[:n])
+}
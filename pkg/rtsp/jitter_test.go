@@ -0,0 +1,68 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClient_JitterBufferUDP tests reordering and loss skipping on the UDP path
+func TestClient_JitterBufferUDP(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer sender.Close()
+
+	send := func(seq uint16) {
+		data, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq) * 3000, SSRC: 1, Payload: []byte{0x41}}).Marshal()
+		require.NoError(t, err)
+		_, err = sender.Write(data)
+		require.NoError(t, err)
+	}
+
+	client := newUDPTestClient(conn)
+	client.EnableJitterBuffer(64, 10*time.Millisecond, 80*time.Millisecond)
+
+	// Reordered on the wire, 4 never arrives
+	for _, seq := range []uint16{1, 3, 2, 5} {
+		send(seq)
+	}
+
+	var got []uint16
+	for i := 0; i < 4; i++ {
+		packet, err := client.ReadPacket()
+		require.NoError(t, err)
+		got = append(got, packet.SequenceNumber)
+	}
+	assert.Equal(t, []uint16{1, 2, 3, 5}, got)
+
+	stats := client.GetStats()
+	assert.True(t, stats.JitterBufferEnabled)
+	assert.Equal(t, uint64(4), stats.PacketsReceived)
+	assert.Equal(t, 4, stats.JitterBuffer.PacketsReceived)
+	assert.Equal(t, 1, stats.JitterBuffer.PacketsLost)
+
+	// ReadPacketInto copies released packets into the caller's buffer
+	send(6)
+	var packet rtp.Packet
+	require.NoError(t, client.ReadPacketInto(&packet, make([]byte, rtp.DefaultPacketBufferSize)))
+	assert.Equal(t, uint16(6), packet.SequenceNumber)
+	assert.Equal(t, []byte{0x41}, packet.Payload)
+
+	// Timeout still applies when nothing is buffered
+	client.timeout = 50 * time.Millisecond
+	_, err = client.ReadPacket()
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+
+	client.DisableJitterBuffer()
+	assert.False(t, client.GetStats().JitterBufferEnabled)
+}
@@ -0,0 +1,177 @@
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// ReadPacketInto reads the next RTP packet into packet using buf as the receive buffer.
+// It does not allocate: packet.Payload and extension data alias buf, so neither
+// may be reused until the caller is done with the packet. buf should hold
+// rtp.MaxPacketBufferSize bytes for TCP interleaved streams and at least the
+// path MTU for UDP (jumbo frames need a correspondingly larger buffer).
+// Packets that do not fit buf return ErrPacketTooLarge. With the jitter
+// buffer or RTX/RED/FEC repair enabled, packets are copied into buf (this path allocates).
+//
+// Typical use with a pool:
+//
+//	pp := pool.Get()
+//	err := client.ReadPacketInto(&pp.Packet, pp.Buffer())
+//	...
+//	pp.Release()
+func (c *Client) ReadPacketInto(packet *rtp.Packet, buf []byte) error {
+	if c.jitterBufferActive() {
+		return c.readJitterBufferedInto(packet, buf)
+	}
+	if c.repairActive() {
+		return c.readRepairedInto(packet, buf)
+	}
+
+	data, channel, err := c.readRTP(buf)
+	if err != nil {
+		return err
+	}
+
+	if err := packet.Unmarshal(data); err != nil {
+		return fmt.Errorf("failed to parse RTP packet: %w", err)
+	}
+	c.logRTPHeader(packet, channel)
+	c.recordReception(packet, channel, time.Now())
+	c.validatePayloadType(packet)
+
+	return nil
+}
+
+// readRTP reads raw RTP bytes into buf, routing interleaved RTCP to the RTCP handler.
+// It returns the RTP data (a slice of buf) and the interleaved channel (0 for UDP).
+func (c *Client) readRTP(buf []byte) ([]byte, uint8, error) {
+	if c.transportMode == TransportModeTCP {
+		if c.conn == nil {
+			return nil, 0, fmt.Errorf("not connected")
+		}
+
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, 0, err
+		}
+
+		if c.interleavedReader == nil || c.interleavedReader.reader != c.conn {
+			c.interleavedReader = NewInterleavedReader(c.conn)
+		}
+
+		for {
+			channel, payload, err := c.interleavedReader.ReadFrameInto(buf)
+			if err != nil {
+				return nil, 0, err
+			}
+
+			// Accept RTP packets from any track (even-numbered channels: 0, 2, 4, ...)
+			// RTCP packets are on odd-numbered channels: 1, 3, 5, ...
+			if channel%2 == 0 {
+				c.packetsReceived.Add(1)
+				data, err := c.unprotectRTP(payload)
+				if err != nil {
+					logger.Debug("[RTP:ReadPacket:TCP] Dropping SRTP packet on channel %d: %v", channel, err)
+					continue
+				}
+				c.recordRTPData(data)
+				return data, channel, nil
+			}
+
+			// This is an RTCP channel (odd-numbered)
+			logger.Debug("[RTP:ReadPacket:TCP] RTCP packet detected on channel %d (size=%d bytes), routing to RTCP handler", channel, len(payload))
+			c.handleRTCP(payload)
+		}
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	for {
+		data, err := c.readDatagram(buf, deadline)
+		if err != nil {
+			return nil, 0, err
+		}
+		c.packetsReceived.Add(1)
+
+		data, err = c.unprotectRTP(data)
+		if err != nil {
+			logger.Debug("[RTP:ReadPacket:UDP] Dropping SRTP packet: %v", err)
+			continue
+		}
+		c.recordRTPData(data)
+		return data, 0, nil
+	}
+}
+
+// readDatagram reads one UDP RTP datagram into buf before deadline
+func (c *Client) readDatagram(buf []byte, deadline time.Time) ([]byte, error) {
+	if c.rtpConn == nil {
+		return nil, errors.New("RTP connection not established")
+	}
+
+	c.rtpConn.SetReadDeadline(deadline)
+
+	n, err := c.rtpConn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	// A full buffer means the datagram was probably truncated by the kernel
+	if n == len(buf) && len(buf) < rtp.MaxPacketBufferSize {
+		return nil, fmt.Errorf("%w: datagram filled %d-byte buffer", ErrPacketTooLarge, len(buf))
+	}
+
+	return buf[:n], nil
+}
+
+// handleRTCP parses an interleaved RTCP packet and routes it to the registered handler
+func (c *Client) handleRTCP(data []byte) {
+	data, err := c.unprotectRTCP(data)
+	if err != nil {
+		logger.Warn("[RTP:ReadPacket:TCP] Dropping SRTCP packet: %v", err)
+		return
+	}
+	c.recordRTCPData(data)
+
+	compound, err := rtp.ParseCompoundRTCPPacket(data)
+	if err != nil {
+		logger.Warn("[RTP:ReadPacket:TCP] Failed to parse RTCP packet: %v, continuing to look for RTP packet", err)
+		return
+	}
+	c.recordRTCP(compound)
+
+	c.rtcpHandlerMu.RLock()
+	handler := c.rtcpHandler
+	c.rtcpHandlerMu.RUnlock()
+
+	if handler == nil {
+		logger.Debug("[RTP:ReadPacket:TCP] RTCP packet received but no handler registered (type=%d, SSRC=0x%x)", compound.GetPacketType(), compound.GetSSRC())
+		return
+	}
+	for _, rtcpPacket := range compound.Packets {
+		if err := handler(rtcpPacket); err != nil {
+			logger.Warn("[RTP:ReadPacket:TCP] RTCP handler error: %v", err)
+		}
+	}
+}
+
+// logRTPHeader logs RTP header details; skipped entirely unless debug logging
+// is enabled, since boxing the arguments would allocate on every packet
+func (c *Client) logRTPHeader(packet *rtp.Packet, channel uint8) {
+	if logger.GetLevel() < logger.LevelDebug {
+		return
+	}
+
+	if c.transportMode == TransportModeTCP {
+		logger.Debug("[RTP:ReadPacket:TCP] RTP Header: Version=%d, Padding=%t, Extension=%t, Marker=%t, PayloadType=%d, SeqNum=%d, Timestamp=%d, SSRC=0x%x, PayloadSize=%d bytes, Channel=%d",
+			packet.Version, packet.Padding, packet.Extension, packet.Marker, packet.PayloadType,
+			packet.SequenceNumber, packet.Timestamp, packet.SSRC, len(packet.Payload), channel)
+		return
+	}
+
+	logger.Debug("[RTP:ReadPacket:UDP] RTP Header: Version=%d, Padding=%t, Extension=%t, Marker=%t, PayloadType=%d, SeqNum=%d, Timestamp=%d, SSRC=0x%x, PayloadSize=%d bytes",
+		packet.Version, packet.Padding, packet.Extension, packet.Marker, packet.PayloadType,
+		packet.SequenceNumber, packet.Timestamp, packet.SSRC, len(packet.Payload))
+}
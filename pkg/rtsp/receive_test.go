@@ -0,0 +1,271 @@
+package rtsp
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopConn replays the same bytes forever. In datagram mode every Read
+// returns the whole buffer, otherwise data is streamed cyclically.
+type loopConn struct {
+	mockConn
+	data     []byte
+	offset   int
+	datagram bool
+}
+
+func (l *loopConn) Read(b []byte) (int, error) {
+	if l.datagram {
+		return copy(b, l.data), nil
+	}
+	n := copy(b, l.data[l.offset:])
+	l.offset = (l.offset + n) % len(l.data)
+	return n, nil
+}
+
+// testRTPPacket returns a marshaled RTP packet with a payload of the given size
+func testRTPPacket(t testing.TB, payloadSize int) []byte {
+	packet := &rtp.Packet{
+		PayloadType:    96,
+		SequenceNumber: 42,
+		Timestamp:      90000,
+		SSRC:           0xdeadbeef,
+		Payload:        make([]byte, payloadSize),
+	}
+	packet.Payload[0] = 0x65
+	data, err := packet.Marshal()
+	require.NoError(t, err)
+	return data
+}
+
+func newUDPTestClient(conn net.Conn) *Client {
+	return &Client{
+		rtpConn:       conn,
+		timeout:       time.Second,
+		transportMode: TransportModeUDP,
+		ctx:           context.Background(),
+	}
+}
+
+func newTCPTestClient(conn net.Conn) *Client {
+	return &Client{
+		conn:          conn,
+		timeout:       time.Second,
+		transportMode: TransportModeTCP,
+		ctx:           context.Background(),
+	}
+}
+
+// TestClient_ReadPacketInto tests the caller-buffer receive path
+func TestClient_ReadPacketInto(t *testing.T) {
+	rtpData := testRTPPacket(t, 1400)
+	rtcpData := []byte{0x81, 0xc9, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78} // RR, no report blocks
+
+	t.Run("TCP skips RTCP and reuses packet", func(t *testing.T) {
+		stream := append(BuildInterleavedFrame(1, rtcpData), BuildInterleavedFrame(0, rtpData)...)
+		client := newTCPTestClient(&loopConn{data: stream})
+
+		var rtcpCount int
+		client.SetRTCPHandler(func(rtp.RTCPPacket) error {
+			rtcpCount++
+			return nil
+		})
+
+		var packet rtp.Packet
+		buf := make([]byte, rtp.MaxPacketBufferSize)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, client.ReadPacketInto(&packet, buf))
+			assert.Equal(t, uint16(42), packet.SequenceNumber)
+			assert.Equal(t, uint32(0xdeadbeef), packet.SSRC)
+			assert.Len(t, packet.Payload, 1400)
+		}
+		assert.Equal(t, 3, rtcpCount)
+	})
+
+	t.Run("UDP jumbo datagram", func(t *testing.T) {
+		jumbo := testRTPPacket(t, 8000)
+		client := newUDPTestClient(&loopConn{data: jumbo, datagram: true})
+
+		var packet rtp.Packet
+		require.NoError(t, client.ReadPacketInto(&packet, make([]byte, 9000)))
+		assert.Len(t, packet.Payload, 8000)
+
+		err := client.ReadPacketInto(&packet, make([]byte, rtp.DefaultPacketBufferSize))
+		assert.ErrorIs(t, err, ErrPacketTooLarge)
+	})
+
+	t.Run("TCP frame larger than buffer", func(t *testing.T) {
+		stream := append(BuildInterleavedFrame(0, rtpData), BuildInterleavedFrame(0, testRTPPacket(t, 10))...)
+		client := newTCPTestClient(newMockConn(stream))
+
+		var packet rtp.Packet
+		err := client.ReadPacketInto(&packet, make([]byte, 100))
+		assert.ErrorIs(t, err, ErrPacketTooLarge)
+
+		// Stream stays aligned on the next frame
+		require.NoError(t, client.ReadPacketInto(&packet, make([]byte, 100)))
+		assert.Len(t, packet.Payload, 10)
+	})
+}
+
+// TestClient_ReadPacketCopiesBuffer tests that ReadPacket results survive later reads
+func TestClient_ReadPacketCopiesBuffer(t *testing.T) {
+	first := testRTPPacket(t, 100)
+	second := testRTPPacket(t, 100)
+	second[12] = 0x41
+
+	client := newTCPTestClient(newMockConn(BuildInterleavedFrame(0, first), BuildInterleavedFrame(0, second)))
+
+	p1, err := client.ReadPacket()
+	require.NoError(t, err)
+	p2, err := client.ReadPacket()
+	require.NoError(t, err)
+
+	assert.Equal(t, byte(0x65), p1.Payload[0])
+	assert.Equal(t, byte(0x41), p2.Payload[0])
+}
+
+// TestClient_ReadPacketIntoAllocations tests that the pooled path does not allocate
+func TestClient_ReadPacketIntoAllocations(t *testing.T) {
+	udp := newUDPTestClient(&loopConn{data: testRTPPacket(t, 1400), datagram: true})
+	tcp := newTCPTestClient(&loopConn{data: BuildInterleavedFrame(0, testRTPPacket(t, 1400))})
+	pool := rtp.NewPacketPool(rtp.MaxPacketBufferSize)
+
+	for name, client := range map[string]*Client{"UDP": udp, "TCP": tcp} {
+		allocs := testing.AllocsPerRun(100, func() {
+			pp := pool.Get()
+			if err := client.ReadPacketInto(&pp.Packet, pp.Buffer()); err != nil {
+				t.Fatal(err)
+			}
+			pp.Release()
+		})
+		assert.Zero(t, allocs, name)
+	}
+}
+
+func BenchmarkClient_ReadPacket_UDP(b *testing.B) {
+	client := newUDPTestClient(&loopConn{data: testRTPPacket(b, 1400), datagram: true})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.ReadPacket(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_ReadPacketInto_UDP(b *testing.B) {
+	client := newUDPTestClient(&loopConn{data: testRTPPacket(b, 1400), datagram: true})
+	pool := rtp.NewPacketPool(rtp.DefaultPacketBufferSize)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pp := pool.Get()
+		if err := client.ReadPacketInto(&pp.Packet, pp.Buffer()); err != nil {
+			b.Fatal(err)
+		}
+		pp.Release()
+	}
+}
+
+func BenchmarkClient_ReadPacket_TCP(b *testing.B) {
+	client := newTCPTestClient(&loopConn{data: BuildInterleavedFrame(0, testRTPPacket(b, 1400))})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.ReadPacket(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClient_ReadPacketInto_TCP(b *testing.B) {
+	client := newTCPTestClient(&loopConn{data: BuildInterleavedFrame(0, testRTPPacket(b, 1400))})
+	pool := rtp.NewPacketPool(rtp.MaxPacketBufferSize)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pp := pool.Get()
+		if err := client.ReadPacketInto(&pp.Packet, pp.Buffer()); err != nil {
+			b.Fatal(err)
+		}
+		pp.Release()
+	}
+}
+
+// TestClient_CompoundRTCPHandler tests that every sub-packet of a compound reaches the RTCP handler
+// on its own
+func TestClient_CompoundRTCPHandler(t *testing.T) {
+	compound := &rtp.CompoundRTCPPacket{Packets: []rtp.RTCPPacket{
+		&rtp.SenderReport{SSRC: 0xdeadbeef, NTPTimestamp: 0xE1F8923456789ABC},
+		&rtp.SDESPacket{Chunks: []rtp.SDESChunk{{SSRC: 0xdeadbeef, Items: []rtp.SDESItem{{Type: rtp.SDES_CNAME, Text: "cam"}}}}},
+		&rtp.ExtendedReport{SSRC: 0xdeadbeef, Blocks: []rtp.XRBlock{&rtp.ReceiverReferenceTime{NTPTimestamp: 1}}},
+		&rtp.RawRTCPPacket{PacketType: 210, Payload: []byte{0, 0, 0, 1}},
+	}}
+	rtcpData, err := compound.Marshal()
+	require.NoError(t, err)
+	rtpData := testRTPPacket(t, 100)
+
+	conn := newMockConn(
+		BuildInterleavedFrame(0, rtpData),
+		BuildInterleavedFrame(1, rtcpData),
+		BuildInterleavedFrame(0, rtpData),
+	)
+	client := newTCPTestClient(conn)
+
+	var received []rtp.RTCPPacket
+	client.SetRTCPHandler(func(packet rtp.RTCPPacket) error {
+		received = append(received, packet)
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ReadPacket()
+		require.NoError(t, err)
+	}
+
+	require.Len(t, received, 4)
+	assert.IsType(t, &rtp.SenderReport{}, received[0])
+	assert.IsType(t, &rtp.SDESPacket{}, received[1])
+	assert.IsType(t, &rtp.ExtendedReport{}, received[2])
+	assert.Equal(t, uint8(210), received[3].GetPacketType())
+
+	// The SR inside the compound still feeds LSR for receiver reports
+	stats := client.GetReceptionStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, uint32(0x92345678), stats[0].LastSR)
+	assert.Equal(t, uint64(0xE1F8923456789ABC), client.GetTimestampMapper(0xdeadbeef).GetState().NTPTimestamp)
+}
+
+// TestClient_ReadRTCPCompound tests that ReadRTCP returns the first packet and ReadCompoundRTCP all of them
+func TestClient_ReadRTCPCompound(t *testing.T) {
+	compound := &rtp.CompoundRTCPPacket{Packets: []rtp.RTCPPacket{
+		&rtp.SenderReport{PacketType: rtp.RTCP_SR, SSRC: 0xdeadbeef, NTPTimestamp: 0xE1F8923456789ABC},
+		&rtp.SDESPacket{PacketType: rtp.RTCP_SDES, Chunks: []rtp.SDESChunk{{SSRC: 0xdeadbeef, Items: []rtp.SDESItem{{Type: rtp.SDES_CNAME, Text: "cam"}}}}},
+	}}
+	rtcpData, err := compound.Marshal()
+	require.NoError(t, err)
+
+	client := newTCPTestClient(newMockConn(
+		BuildInterleavedFrame(1, rtcpData),
+		BuildInterleavedFrame(1, rtcpData),
+	))
+
+	packet, err := client.ReadRTCP()
+	require.NoError(t, err)
+	sr, ok := packet.(*rtp.SenderReport)
+	require.True(t, ok)
+	assert.Equal(t, uint32(0xdeadbeef), sr.SSRC)
+
+	read, err := client.ReadCompoundRTCP()
+	require.NoError(t, err)
+	require.Len(t, read.Packets, 2)
+	assert.IsType(t, &rtp.SenderReport{}, read.Packets[0])
+	assert.IsType(t, &rtp.SDESPacket{}, read.Packets[1])
+}
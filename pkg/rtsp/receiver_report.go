@@ -0,0 +1,335 @@
+package rtsp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// defaultSessionBandwidth is the session bandwidth (bits/s) assumed for RTCP scheduling
+	defaultSessionBandwidth = 2000000
+	// rtcpBandwidthFraction is the share of session bandwidth allotted to RTCP (RFC 3550 section 6.2)
+	rtcpBandwidthFraction = 0.05
+	// udpIPOverhead is added to RTCP packet sizes for interval calculation
+	udpIPOverhead = 28
+)
+
+// receptionSource holds reception statistics for one remote SSRC
+type receptionSource struct {
+	stats       *rtp.ReceptionStats
+	payloadType uint8 // Payload type of the first packet, used to find the SDP track
+	rtcpChannel uint8 // Interleaved channel reports for this source go to (TCP only)
+}
+
+// StartReceiverReports starts sending RTCP Receiver Reports with an SDES CNAME
+// at the randomized RFC 3550 interval. Reports go to the interleaved RTCP
+// channel of each source (TCP) or to the server's RTCP port (UDP).
+func (c *Client) StartReceiverReports() {
+	if c.receiverReportStop != nil {
+		return // Already started
+	}
+
+	stop := make(chan struct{})
+	c.receiverReportStop = stop
+
+	go func() {
+		initial := true
+		avgSize := 0.0
+		for {
+			rtcpBandwidth := defaultSessionBandwidth / 8 * rtcpBandwidthFraction
+			interval := rtp.RTCPInterval(2, 1, rtcpBandwidth, avgSize, false, initial)
+			initial = false
+
+			select {
+			case <-time.After(interval):
+				size, err := c.sendReceiverReports(time.Now())
+				if err != nil {
+					logger.Warn("[Client:ReceiverReport] Failed to send receiver report: %v", err)
+					continue
+				}
+				// Moving average of compound packet size (RFC 3550 section 6.3.3)
+				if avgSize == 0 {
+					avgSize = float64(size + udpIPOverhead)
+				} else {
+					avgSize += (float64(size+udpIPOverhead) - avgSize) / 16
+				}
+
+			case <-stop:
+				return
+
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// StopReceiverReports stops the receiver report goroutine
+func (c *Client) StopReceiverReports() {
+	if c.receiverReportStop != nil {
+		close(c.receiverReportStop)
+		c.receiverReportStop = nil
+	}
+}
+
+// IsReceiverReportsRunning reports whether receiver reports are being sent
+func (c *Client) IsReceiverReportsRunning() bool {
+	return c.receiverReportStop != nil
+}
+
+// GetReceptionStats returns per-SSRC reception statistics, ordered by SSRC
+func (c *Client) GetReceptionStats() []rtp.ReceptionStatistics {
+	c.receptionMu.Lock()
+	defer c.receptionMu.Unlock()
+
+	stats := make([]rtp.ReceptionStatistics, 0, len(c.reception))
+	for _, source := range c.reception {
+		stats = append(stats, source.stats.GetStatistics())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].SSRC < stats[j].SSRC
+	})
+	return stats
+}
+
+// GetTimestampMapper returns the RTP-to-NTP mapper of a source. It is created on
+// demand and keeps updating as Sender Reports from that SSRC arrive.
+func (c *Client) GetTimestampMapper(ssrc uint32) *rtp.TimestampMapper {
+	return c.timestampMappers.Mapper(ssrc)
+}
+
+// GetLocalSSRC returns the SSRC the client uses in its RTCP reports
+func (c *Client) GetLocalSSRC() uint32 {
+	c.receptionMu.Lock()
+	defer c.receptionMu.Unlock()
+	return c.localSSRCLocked()
+}
+
+// recordReception updates reception statistics for a received RTP packet
+func (c *Client) recordReception(packet *rtp.Packet, channel uint8, arrival time.Time) {
+	c.receptionMu.Lock()
+	source, ok := c.reception[packet.SSRC]
+	if !ok {
+		if c.reception == nil {
+			c.reception = make(map[uint32]*receptionSource)
+		}
+		clockRate := c.clockRateFor(packet.PayloadType)
+		source = &receptionSource{
+			stats:       rtp.NewReceptionStats(packet.SSRC, clockRate),
+			payloadType: packet.PayloadType,
+			rtcpChannel: channel + 1,
+		}
+		c.reception[packet.SSRC] = source
+		c.timestampMappers.SetClockRate(packet.SSRC, clockRate)
+	}
+	c.receptionMu.Unlock()
+
+	source.stats.Update(packet, arrival)
+}
+
+// recordSenderReport stores LSR/DLSR reference data and the timestamp mapping from a Sender Report
+func (c *Client) recordSenderReport(sr *rtp.SenderReport) {
+	c.timestampMappers.UpdateFromSR(sr)
+
+	c.receptionMu.Lock()
+	source, ok := c.reception[sr.SSRC]
+	c.receptionMu.Unlock()
+
+	if ok {
+		source.stats.UpdateSR(sr, time.Now())
+	}
+}
+
+// recordRTCP records reception data carried by an incoming compound RTCP packet
+func (c *Client) recordRTCP(compound *rtp.CompoundRTCPPacket) {
+	for _, p := range compound.Packets {
+		if sr, ok := p.(*rtp.SenderReport); ok {
+			c.recordSenderReport(sr)
+		}
+	}
+}
+
+// clockRateFor returns the SDP clock rate of a payload type (90 kHz if unknown)
+func (c *Client) clockRateFor(payloadType uint8) uint32 {
+	if track := c.trackForPayloadType(payloadType); track != nil && track.ClockRate > 0 {
+		return uint32(track.ClockRate)
+	}
+	return 90000
+}
+
+// trackForPayloadType returns the SDP track carrying a payload type, or nil.
+// Secondary formats such as RED or ULPFEC resolve to their media track.
+func (c *Client) trackForPayloadType(payloadType uint8) *SDPTrack {
+	index := c.trackIndexForPayloadType(payloadType)
+	if index < 0 {
+		return nil
+	}
+	return &c.sdpInfo.Tracks[index]
+}
+
+// trackIndexForPayloadType returns the index of the SDP track carrying a payload type, or -1
+func (c *Client) trackIndexForPayloadType(payloadType uint8) int {
+	if c.sdpInfo == nil {
+		return -1
+	}
+	for i := range c.sdpInfo.Tracks {
+		if c.sdpInfo.Tracks[i].PayloadType == int(payloadType) {
+			return i
+		}
+	}
+	for i := range c.sdpInfo.Tracks {
+		for _, format := range c.sdpInfo.Tracks[i].Formats {
+			if format.PayloadType == int(payloadType) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// localSSRCLocked returns the client's RTCP SSRC, generating it on first use
+func (c *Client) localSSRCLocked() uint32 {
+	if c.localSSRC == 0 {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err == nil {
+			c.localSSRC = binary.BigEndian.Uint32(b[:])
+		}
+		if c.localSSRC == 0 {
+			c.localSSRC = uint32(time.Now().UnixNano())
+		}
+	}
+	return c.localSSRC
+}
+
+// buildReceiverReports builds one RR + SDES compound packet per RTCP destination.
+// In UDP mode all sources share one destination (keyed by channel 0).
+func (c *Client) buildReceiverReports(now time.Time) (map[uint8][]byte, error) {
+	c.receptionMu.Lock()
+	ssrc := c.localSSRCLocked()
+	blocks := make(map[uint8][]rtp.ReportBlock)
+	for _, source := range c.reception {
+		channel := uint8(0)
+		if c.transportMode == TransportModeTCP {
+			channel = source.rtcpChannel
+		}
+		blocks[channel] = append(blocks[channel], source.stats.ReportBlock(now))
+	}
+	c.receptionMu.Unlock()
+
+	// Stay audible to the server even before any media arrived
+	if len(blocks) == 0 {
+		channel := uint8(0)
+		if c.transportMode == TransportModeTCP {
+			channel = c.rtcpChannel
+		}
+		blocks[channel] = nil
+	}
+
+	cname := rtcpCNAME(ssrc)
+	reports := make(map[uint8][]byte, len(blocks))
+	for channel, channelBlocks := range blocks {
+		sort.Slice(channelBlocks, func(i, j int) bool {
+			return channelBlocks[i].SSRC < channelBlocks[j].SSRC
+		})
+		if len(channelBlocks) > 31 {
+			channelBlocks = channelBlocks[:31]
+		}
+
+		compound := &rtp.CompoundRTCPPacket{
+			Packets: []rtp.RTCPPacket{
+				&rtp.ReceiverReport{PacketType: rtp.RTCP_RR, SSRC: ssrc, ReportBlocks: channelBlocks},
+				&rtp.SDESPacket{
+					PacketType: rtp.RTCP_SDES,
+					Chunks: []rtp.SDESChunk{{
+						SSRC:  ssrc,
+						Items: []rtp.SDESItem{{Type: rtp.SDES_CNAME, Text: cname}},
+					}},
+				},
+			},
+		}
+		data, err := compound.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		reports[channel] = data
+	}
+
+	return reports, nil
+}
+
+// sendReceiverReports sends receiver reports now and returns the largest compound size
+func (c *Client) sendReceiverReports(now time.Time) (int, error) {
+	reports, err := c.buildReceiverReports(now)
+	if err != nil {
+		return 0, err
+	}
+
+	size := 0
+	for channel, data := range reports {
+		if len(data) > size {
+			size = len(data)
+		}
+
+		if err := c.sendRTCP(channel, data); err != nil {
+			return 0, err
+		}
+		logger.Debug("[Client:ReceiverReport] Sent %d-byte RR (channel %d)", len(data), channel)
+	}
+
+	return size, nil
+}
+
+// sendRTCP sends a compound RTCP packet on an interleaved channel (TCP) or to the server's RTCP port (UDP).
+// On SRTP sessions the packet is sent as SRTCP.
+func (c *Client) sendRTCP(channel uint8, data []byte) error {
+	data, err := c.protectRTCP(channel, data)
+	if err != nil {
+		return err
+	}
+
+	if c.transportMode == TransportModeTCP {
+		if c.conn == nil {
+			return fmt.Errorf("not connected")
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+		_, err := c.conn.Write(BuildInterleavedFrame(channel, data))
+		return err
+	}
+	return c.writeRTCPDatagram(data)
+}
+
+// writeRTCPDatagram sends an RTCP packet from the local RTCP port to the server's RTCP port
+func (c *Client) writeRTCPDatagram(data []byte) error {
+	conn, ok := c.rtcpConn.(net.PacketConn)
+	if !ok || conn == nil {
+		return errors.New("RTCP connection not established")
+	}
+	if len(c.serverPorts) < 2 {
+		return errors.New("server RTCP port unknown")
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(c.host, fmt.Sprint(c.serverPorts[1])))
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(data, addr)
+	return err
+}
+
+// rtcpCNAME returns the canonical name advertised in SDES (RFC 3550 section 6.5.1)
+func rtcpCNAME(ssrc uint32) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("rtsp-client-%08x@%s", ssrc, host)
+}
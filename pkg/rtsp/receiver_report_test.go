@@ -0,0 +1,134 @@
+package rtsp
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitCompoundRTCP parses each sub-packet of a compound RTCP packet
+func splitCompoundRTCP(t *testing.T, data []byte) []rtp.RTCPPacket {
+	compound, err := rtp.ParseCompoundRTCPPacket(data)
+	require.NoError(t, err)
+	return compound.Packets
+}
+
+// TestClient_ReceiverReportTCP tests RR generation on the interleaved RTCP channel
+func TestClient_ReceiverReportTCP(t *testing.T) {
+	rtpData := testRTPPacket(t, 100)
+	next, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: 43, Timestamp: 93000, SSRC: 0xdeadbeef, Payload: []byte{0x65}}).Marshal()
+	require.NoError(t, err)
+	sr, err := (&rtp.SenderReport{SSRC: 0xdeadbeef, NTPTimestamp: 0xE1F8923456789ABC}).Marshal()
+	require.NoError(t, err)
+
+	// RTP on channel 2 so reports must go to channel 3
+	conn := newMockConn(
+		BuildInterleavedFrame(2, rtpData),
+		BuildInterleavedFrame(3, sr),
+		BuildInterleavedFrame(2, next),
+	)
+	client := newTCPTestClient(conn)
+
+	packet := &rtp.Packet{}
+	buf := make([]byte, rtp.MaxPacketBufferSize)
+	require.NoError(t, client.ReadPacketInto(packet, buf))
+	require.NoError(t, client.ReadPacketInto(packet, buf))
+
+	stats := client.GetReceptionStats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, uint32(0xdeadbeef), stats[0].SSRC)
+	// The first packet is consumed by source probation
+	assert.Equal(t, uint32(1), stats[0].PacketsReceived)
+	assert.Equal(t, uint32(0x92345678), stats[0].LastSR)
+
+	size, err := client.sendReceiverReports(time.Now())
+	require.NoError(t, err)
+
+	frame, err := NewInterleavedReader(&conn.writeBuf).ReadFrame()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(3), frame.Channel)
+	assert.Len(t, frame.Payload, size)
+
+	packets := splitCompoundRTCP(t, frame.Payload)
+	require.Len(t, packets, 2)
+
+	rr, ok := packets[0].(*rtp.ReceiverReport)
+	require.True(t, ok)
+	assert.Equal(t, client.GetLocalSSRC(), rr.SSRC)
+	require.Len(t, rr.ReportBlocks, 1)
+	assert.Equal(t, uint32(0xdeadbeef), rr.ReportBlocks[0].SSRC)
+	assert.Equal(t, uint32(43), rr.ReportBlocks[0].HighestSeq)
+	assert.Equal(t, uint32(0x92345678), rr.ReportBlocks[0].LSR)
+
+	sdes, ok := packets[1].(*rtp.SDESPacket)
+	require.True(t, ok)
+	require.Len(t, sdes.Chunks, 1)
+	assert.Equal(t, rr.SSRC, sdes.Chunks[0].SSRC)
+	require.Len(t, sdes.Chunks[0].Items, 1)
+	assert.Equal(t, uint8(rtp.SDES_CNAME), sdes.Chunks[0].Items[0].Type)
+	assert.True(t, strings.HasPrefix(sdes.Chunks[0].Items[0].Text, "rtsp-client-"))
+}
+
+// TestClient_ReceiverReportUDP tests RR delivery to the server RTCP port
+func TestClient_ReceiverReportUDP(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer server.Close()
+
+	local, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer local.Close()
+
+	client := &Client{
+		host:          "127.0.0.1",
+		rtcpConn:      local,
+		serverPorts:   []int{0, server.LocalAddr().(*net.UDPAddr).Port},
+		timeout:       time.Second,
+		transportMode: TransportModeUDP,
+		ctx:           context.Background(),
+	}
+
+	// No media yet: an empty RR is still sent
+	_, err = client.sendReceiverReports(time.Now())
+	require.NoError(t, err)
+
+	buf := make([]byte, 1500)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	n, addr, err := server.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Equal(t, local.LocalAddr().String(), addr.String())
+
+	packets := splitCompoundRTCP(t, buf[:n])
+	require.Len(t, packets, 2)
+	rr, ok := packets[0].(*rtp.ReceiverReport)
+	require.True(t, ok)
+	assert.Empty(t, rr.ReportBlocks)
+
+	client.serverPorts = nil
+	_, err = client.sendReceiverReports(time.Now())
+	assert.Error(t, err)
+}
+
+// TestClient_ReceiverReportLifecycle tests starting and stopping the RR goroutine
+func TestClient_ReceiverReportLifecycle(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newTCPTestClient(newMockConn())
+	client.ctx = ctx
+
+	assert.False(t, client.IsReceiverReportsRunning())
+	client.StartReceiverReports()
+	assert.True(t, client.IsReceiverReportsRunning())
+	client.StartReceiverReports() // No-op when already running
+
+	client.StopReceiverReports()
+	assert.False(t, client.IsReceiverReportsRunning())
+	client.StopReceiverReports()
+}
@@ -0,0 +1,46 @@
+package rtsp
+
+import (
+	"time"
+
+	"github.com/rtsp-client/pkg/logger"
+)
+
+// PacketRecorder receives every RTP and RTCP packet the client reads, such as
+// an rtpdump.Writer. Packets are recorded after SRTP decryption, in arrival order.
+type PacketRecorder interface {
+	WriteRTP(data []byte, at time.Time) error
+	WriteRTCP(data []byte, at time.Time) error
+}
+
+// SetPacketRecorder sets the recorder for received packets; nil stops recording
+func (c *Client) SetPacketRecorder(recorder PacketRecorder) {
+	c.recorderMu.Lock()
+	defer c.recorderMu.Unlock()
+	c.recorder = recorder
+}
+
+// packetRecorder returns the current recorder
+func (c *Client) packetRecorder() PacketRecorder {
+	c.recorderMu.RLock()
+	defer c.recorderMu.RUnlock()
+	return c.recorder
+}
+
+// recordRTPData passes a received RTP packet to the recorder
+func (c *Client) recordRTPData(data []byte) {
+	if recorder := c.packetRecorder(); recorder != nil {
+		if err := recorder.WriteRTP(data, time.Now()); err != nil {
+			logger.Warn("[Client:Record] Failed to record RTP packet: %v", err)
+		}
+	}
+}
+
+// recordRTCPData passes a received RTCP packet to the recorder
+func (c *Client) recordRTCPData(data []byte) {
+	if recorder := c.packetRecorder(); recorder != nil {
+		if err := recorder.WriteRTCP(data, time.Now()); err != nil {
+			logger.Warn("[Client:Record] Failed to record RTCP packet: %v", err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package rtsp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// capturingRecorder keeps copies of recorded packets
+type capturingRecorder struct {
+	mu   sync.Mutex
+	rtp  [][]byte
+	rtcp [][]byte
+}
+
+func (r *capturingRecorder) WriteRTP(data []byte, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rtp = append(r.rtp, append([]byte(nil), data...))
+	return nil
+}
+
+func (r *capturingRecorder) WriteRTCP(data []byte, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rtcp = append(r.rtcp, append([]byte(nil), data...))
+	return nil
+}
+
+func TestClient_SetPacketRecorder(t *testing.T) {
+	first := testRTPPacket(t, 100)
+	second := testRTPPacket(t, 200)
+	sr, err := (&rtp.SenderReport{SSRC: 0x12345678, NTPTimestamp: 1 << 32, RTPTimestamp: 3000}).Marshal()
+	require.NoError(t, err)
+
+	var stream []byte
+	for _, frame := range [][]byte{
+		BuildInterleavedFrame(0, first),
+		BuildInterleavedFrame(1, sr),
+		BuildInterleavedFrame(0, second),
+	} {
+		stream = append(stream, frame...)
+	}
+
+	client := newTCPTestClient(newMockConn(stream))
+	recorder := &capturingRecorder{}
+	client.SetPacketRecorder(recorder)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ReadPacket()
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, [][]byte{first, second}, recorder.rtp)
+	assert.Equal(t, [][]byte{sr}, recorder.rtcp)
+
+	// Removing the recorder stops recording
+	client = newTCPTestClient(newMockConn(BuildInterleavedFrame(0, first)))
+	client.SetPacketRecorder(recorder)
+	client.SetPacketRecorder(nil)
+	_, err = client.ReadPacket()
+	require.NoError(t, err)
+	assert.Len(t, recorder.rtp, 2)
+}
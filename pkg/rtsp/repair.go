@@ -0,0 +1,72 @@
+package rtsp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// repairActive reports whether received packets pass through RTX or RED/FEC processing
+func (c *Client) repairActive() bool {
+	return c.rtxReceiver != nil || c.fecReceiver != nil
+}
+
+// repairPackets restores RTX retransmissions and decodes RED/FEC, returning
+// the media packets packet yields and whether they are retransmissions
+func (c *Client) repairPackets(packet *rtp.Packet) ([]*rtp.Packet, bool) {
+	retransmitted := false
+	if c.rtxReceiver != nil {
+		packet, retransmitted = c.rtxReceiver.Push(packet)
+		if packet == nil {
+			return nil, retransmitted
+		}
+	}
+	return c.decodeFEC(packet), retransmitted
+}
+
+// readRepaired reads packets until RTX/RED/FEC processing yields a media packet
+func (c *Client) readRepaired() (*rtp.Packet, error) {
+	if c.receiveBuffer == nil {
+		c.receiveBuffer = make([]byte, rtp.MaxPacketBufferSize)
+	}
+
+	for len(c.repairQueue) == 0 {
+		data, channel, err := c.readRTP(c.receiveBuffer)
+		if err != nil {
+			return nil, err
+		}
+
+		// Copy out of the shared receive buffer; the FEC receiver keeps packets for recovery
+		packet, err := rtp.ParsePacket(append([]byte(nil), data...))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RTP packet: %w", err)
+		}
+		c.logRTPHeader(packet, channel)
+		c.recordReception(packet, channel, time.Now())
+
+		packets, _ := c.repairPackets(packet)
+		c.repairQueue = append(c.repairQueue, packets...)
+	}
+
+	packet := c.repairQueue[0]
+	c.repairQueue[0] = nil
+	c.repairQueue = c.repairQueue[1:]
+	c.validatePayloadType(packet)
+
+	return packet, nil
+}
+
+// readRepairedInto copies the next repaired packet into buf
+func (c *Client) readRepairedInto(packet *rtp.Packet, buf []byte) error {
+	repaired, err := c.readRepaired()
+	if err != nil {
+		return err
+	}
+
+	n, err := repaired.MarshalTo(buf)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPacketTooLarge, err)
+	}
+	return packet.Unmarshal(buf[:n])
+}
@@ -0,0 +1,48 @@
+package rtsp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+// configureRTX enables RTX restoration for rtx formats with an apt= parameter
+func (c *Client) configureRTX(info *SDPInfo) {
+	c.rtxReceiver = nil
+	c.repairQueue = nil
+
+	var receiver *rtp.RTXReceiver
+	for _, track := range info.Tracks {
+		for _, format := range track.FormatsByCodec("rtx") {
+			apt, err := strconv.Atoi(format.FMTP["apt"])
+			if err != nil || apt < 0 || apt > 0x7F || format.PayloadType < 0 || format.PayloadType > 0x7F {
+				logger.Warn("[Client] Ignoring RTX payload type %d without a valid apt parameter", format.PayloadType)
+				continue
+			}
+			if receiver == nil {
+				receiver = rtp.NewRTXReceiver()
+			}
+			receiver.AddPayloadType(uint8(format.PayloadType), uint8(apt))
+			logger.Info("[Client] RTX enabled: track=%s, payload type=%d, apt=%d", track.Media, format.PayloadType, apt)
+		}
+
+		if receiver == nil {
+			continue
+		}
+		for _, group := range track.SSRCGroups {
+			// FID groups list the media SSRC followed by its RTX SSRC
+			if strings.EqualFold(group.Semantics, "FID") && len(group.SSRCs) == 2 {
+				receiver.AssociateSSRC(group.SSRCs[1], group.SSRCs[0])
+			}
+		}
+	}
+	c.rtxReceiver = receiver
+}
+
+// DisableRTX turns off RTX restoration; RTX packets are then returned as-is
+func (c *Client) DisableRTX() {
+	c.rtxReceiver = nil
+	c.repairQueue = nil
+}
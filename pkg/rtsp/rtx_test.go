@@ -0,0 +1,113 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rtxTestSDP is a track advertising RTX for H.264 with an FID group
+var rtxTestSDP = &SDPInfo{Tracks: []SDPTrack{{
+	Media:       "video",
+	PayloadType: 96,
+	Formats: []SDPFormat{
+		{PayloadType: 96, Codec: "H264", ClockRate: 90000},
+		{PayloadType: 97, Codec: "rtx", ClockRate: 90000, FMTP: map[string]string{"apt": "96"}},
+	},
+	SSRCGroups: []SDPSSRCGroup{{Semantics: "FID", SSRCs: []uint32{1, 2}}},
+}}}
+
+// rtxTestMedia returns a media packet of the stream described by rtxTestSDP
+func rtxTestMedia(seq uint16) *rtp.Packet {
+	return &rtp.Packet{PayloadType: 96, SequenceNumber: seq, Timestamp: uint32(seq) * 3000, SSRC: 1, Payload: []byte{0x41, byte(seq)}}
+}
+
+// TestClient_RTXJitterBuffer tests that RTX fills a jitter buffer gap and duplicates are dropped
+func TestClient_RTXJitterBuffer(t *testing.T) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sender, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer sender.Close()
+
+	send := func(p *rtp.Packet) {
+		data, err := p.Marshal()
+		require.NoError(t, err)
+		_, err = sender.Write(data)
+		require.NoError(t, err)
+	}
+
+	client := newUDPTestClient(conn)
+	client.configureRTX(rtxTestSDP)
+	client.EnableJitterBuffer(64, 10*time.Millisecond, 200*time.Millisecond)
+
+	for _, seq := range []uint16{1, 2, 4} {
+		send(rtxTestMedia(seq))
+	}
+	send(rtp.EncodeRTX(rtxTestMedia(3), 97, 2, 500))
+	send(rtp.EncodeRTX(rtxTestMedia(3), 97, 2, 501))
+	send(rtxTestMedia(3))
+
+	var got []uint16
+	for i := 0; i < 4; i++ {
+		packet, err := client.ReadPacket()
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), packet.SSRC)
+		assert.Equal(t, uint8(96), packet.PayloadType)
+		got = append(got, packet.SequenceNumber)
+	}
+	assert.Equal(t, []uint16{1, 2, 3, 4}, got)
+
+	// Nothing else may be released
+	client.timeout = 50 * time.Millisecond
+	_, err = client.ReadPacket()
+	require.Error(t, err)
+
+	stats := client.GetStats()
+	assert.True(t, stats.RTXEnabled)
+	assert.Equal(t, 1, stats.RTX.Restored)
+	assert.Equal(t, 2, stats.RTX.Duplicates)
+	assert.Equal(t, 1, stats.JitterBuffer.Retransmitted)
+	assert.Zero(t, stats.JitterBuffer.PacketsLost)
+}
+
+// TestClient_RTXDirect tests RTX restoration without the jitter buffer
+func TestClient_RTXDirect(t *testing.T) {
+	var stream []byte
+	for _, p := range []*rtp.Packet{rtxTestMedia(1), rtxTestMedia(2), rtxTestMedia(4), rtp.EncodeRTX(rtxTestMedia(3), 97, 2, 9), rtxTestMedia(3), rtxTestMedia(5)} {
+		data, err := p.Marshal()
+		require.NoError(t, err)
+		stream = append(stream, BuildInterleavedFrame(0, data)...)
+	}
+
+	client := newTCPTestClient(newMockConn(stream))
+	client.configureRTX(rtxTestSDP)
+
+	var got []uint16
+	packet := &rtp.Packet{}
+	buf := make([]byte, rtp.MaxPacketBufferSize)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, client.ReadPacketInto(packet, buf))
+		got = append(got, packet.SequenceNumber)
+	}
+	assert.Equal(t, []uint16{1, 2, 4, 3, 5}, got)
+	assert.Equal(t, 1, client.GetStats().RTX.Duplicates)
+
+	client.DisableRTX()
+	assert.False(t, client.GetStats().RTXEnabled)
+}
+
+// TestClient_ConfigureRTXRequiresApt tests that rtx formats without apt are ignored
+func TestClient_ConfigureRTXRequiresApt(t *testing.T) {
+	client := newTCPTestClient(newMockConn())
+	client.configureRTX(&SDPInfo{Tracks: []SDPTrack{{
+		Formats: []SDPFormat{{PayloadType: 97, Codec: "rtx", FMTP: map[string]string{}}},
+	}}})
+	assert.Nil(t, client.rtxReceiver)
+}
@@ -0,0 +1,257 @@
+package rtsp
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+var (
+	// ErrSRTPNotNegotiated indicates an RTP/SAVP track without a usable a=crypto key
+	ErrSRTPNotNegotiated = errors.New("SRTP track has no supported crypto attribute")
+)
+
+// parseSDPCrypto parses the value of an a=crypto attribute:
+// <tag> <suite> inline:<base64 key||salt>[|lifetime][|MKI:length] [session params]
+func parseSDPCrypto(value string) (SDPCrypto, bool) {
+	parts := strings.Fields(value)
+	if len(parts) < 3 {
+		return SDPCrypto{}, false
+	}
+
+	tag, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return SDPCrypto{}, false
+	}
+
+	// Only the first key parameter is used; further keys would need MKI support
+	keyParams := strings.SplitN(parts[2], ";", 2)[0]
+	if !strings.HasPrefix(keyParams, "inline:") {
+		return SDPCrypto{}, false
+	}
+	encoded := strings.SplitN(strings.TrimPrefix(keyParams, "inline:"), "|", 2)[0]
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Some servers omit base64 padding
+		if key, err = base64.RawStdEncoding.DecodeString(encoded); err != nil {
+			return SDPCrypto{}, false
+		}
+	}
+
+	return SDPCrypto{Tag: tag, Suite: parts[1], Key: key}, true
+}
+
+// isSecureProfile reports whether an m= line protocol requires SRTP
+func isSecureProfile(protocol string) bool {
+	return strings.Contains(strings.ToUpper(protocol), "SAVP")
+}
+
+// newTrackSRTPContext builds an SRTP context from the first supported a=crypto line
+// of an RTP/SAVP track. It returns nil for tracks that are not encrypted.
+func newTrackSRTPContext(track *SDPTrack) (*rtp.SRTPContext, error) {
+	if !isSecureProfile(track.Protocol) {
+		return nil, nil
+	}
+
+	for _, crypto := range track.Crypto {
+		profile, err := rtp.ParseSRTPProfile(crypto.Suite)
+		if err != nil {
+			continue
+		}
+		if len(crypto.Key) != profile.KeyLength()+profile.SaltLength() {
+			logger.Warn("[Client:SRTP] Ignoring crypto tag %d: %d-byte key for %s", crypto.Tag, len(crypto.Key), profile)
+			continue
+		}
+		context, err := rtp.NewSRTPContext(profile, crypto.Key[:profile.KeyLength()], crypto.Key[profile.KeyLength():])
+		if err != nil {
+			continue
+		}
+		logger.Info("[Client:SRTP] Using crypto tag %d (%s) for %s track", crypto.Tag, profile, track.Media)
+		return context, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s track (%s)", ErrSRTPNotNegotiated, track.Media, track.Protocol)
+}
+
+// secureTransport rewrites the Transport header of a SETUP request to the SRTP profile
+func secureTransport(request string) string {
+	return strings.Replace(request, "Transport: RTP/AVP", "Transport: RTP/SAVP", 1)
+}
+
+// setupSRTPContext returns the SRTP context for the track being set up, or nil
+func (c *Client) setupSRTPContext() (*rtp.SRTPContext, error) {
+	if c.sdpInfo == nil || c.trackIndex >= len(c.sdpInfo.Tracks) {
+		return nil, nil
+	}
+	return newTrackSRTPContext(&c.sdpInfo.Tracks[c.trackIndex])
+}
+
+// addSRTPContext registers the SRTP context of a track after a successful SETUP
+func (c *Client) addSRTPContext(trackIndex int, context *rtp.SRTPContext) {
+	c.srtpMu.Lock()
+	defer c.srtpMu.Unlock()
+	if c.srtpContexts == nil {
+		c.srtpContexts = make(map[int]*rtp.SRTPContext)
+	}
+	// A repeated SETUP (e.g. after reconnecting) replaces the track's keys
+	if previous, ok := c.srtpContexts[trackIndex]; ok {
+		for ssrc, learned := range c.srtpSSRCs {
+			if learned == previous {
+				delete(c.srtpSSRCs, ssrc)
+			}
+		}
+	}
+	c.srtpContexts[trackIndex] = context
+}
+
+// srtpActive reports whether any track was set up with SRTP
+func (c *Client) srtpActive() bool {
+	c.srtpMu.Lock()
+	defer c.srtpMu.Unlock()
+	return len(c.srtpContexts) > 0
+}
+
+// srtpContextForSSRC returns the context that previously authenticated ssrc, or
+// the only context when just one track is encrypted
+func (c *Client) srtpContextForSSRC(ssrc uint32) *rtp.SRTPContext {
+	if context, ok := c.srtpSSRCs[ssrc]; ok {
+		return context
+	}
+	if len(c.srtpContexts) == 1 {
+		for _, context := range c.srtpContexts {
+			return context
+		}
+	}
+	return nil
+}
+
+// orderedSRTPContexts returns the SRTP contexts in track order
+func (c *Client) orderedSRTPContexts() []*rtp.SRTPContext {
+	indices := make([]int, 0, len(c.srtpContexts))
+	for index := range c.srtpContexts {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	contexts := make([]*rtp.SRTPContext, 0, len(indices))
+	for _, index := range indices {
+		contexts = append(contexts, c.srtpContexts[index])
+	}
+	return contexts
+}
+
+// learnSRTPSource remembers which context authenticated a remote SSRC
+func (c *Client) learnSRTPSource(ssrc uint32, context *rtp.SRTPContext) {
+	if c.srtpSSRCs == nil {
+		c.srtpSSRCs = make(map[uint32]*rtp.SRTPContext)
+	}
+	c.srtpSSRCs[ssrc] = context
+}
+
+// unprotectRTP decrypts and authenticates an SRTP packet in place. Data is returned
+// unchanged when no track uses SRTP. The context is chosen by SSRC, falling back to
+// the track of the (unencrypted) payload type.
+func (c *Client) unprotectRTP(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return data, nil
+	}
+
+	c.srtpMu.Lock()
+	if len(c.srtpContexts) == 0 {
+		c.srtpMu.Unlock()
+		return data, nil
+	}
+	ssrc := uint32(data[8])<<24 | uint32(data[9])<<16 | uint32(data[10])<<8 | uint32(data[11])
+	context := c.srtpContextForSSRC(ssrc)
+	if context == nil {
+		context = c.srtpContexts[c.trackIndexForPayloadType(data[1]&0x7F)]
+	}
+	c.srtpMu.Unlock()
+
+	if context == nil {
+		return nil, fmt.Errorf("%w: no SRTP context for SSRC 0x%x", rtp.ErrSRTPAuthFailed, ssrc)
+	}
+
+	plain, err := context.DecryptRTP(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c.srtpMu.Lock()
+	c.learnSRTPSource(ssrc, context)
+	c.srtpMu.Unlock()
+	return plain, nil
+}
+
+// unprotectRTCP authenticates and decrypts an SRTCP packet. With several encrypted
+// tracks and an unknown sender SSRC, each context is tried in track order.
+func (c *Client) unprotectRTCP(data []byte) ([]byte, error) {
+	if len(data) < 8 {
+		return data, nil
+	}
+
+	c.srtpMu.Lock()
+	if len(c.srtpContexts) == 0 {
+		c.srtpMu.Unlock()
+		return data, nil
+	}
+	ssrc := uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7])
+	candidates := c.orderedSRTPContexts()
+	if context := c.srtpContextForSSRC(ssrc); context != nil {
+		candidates = []*rtp.SRTPContext{context}
+	}
+	c.srtpMu.Unlock()
+
+	var lastErr error
+	for _, context := range candidates {
+		plain, err := context.DecryptRTCP(append([]byte(nil), data...))
+		if err == nil {
+			c.srtpMu.Lock()
+			c.learnSRTPSource(ssrc, context)
+			c.srtpMu.Unlock()
+			return plain, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// protectRTCP encrypts an outgoing RTCP packet for the track behind an interleaved
+// channel (TCP) or the first encrypted track (UDP). Data is returned unchanged
+// when no track uses SRTP.
+func (c *Client) protectRTCP(channel uint8, data []byte) ([]byte, error) {
+	c.srtpMu.Lock()
+	if len(c.srtpContexts) == 0 {
+		c.srtpMu.Unlock()
+		return data, nil
+	}
+	context, ok := c.srtpContexts[int(channel/2)]
+	if !ok || c.transportMode != TransportModeTCP {
+		context = c.orderedSRTPContexts()[0]
+	}
+	c.srtpMu.Unlock()
+
+	return context.EncryptRTCP(data)
+}
+
+// srtpStatistics sums the statistics of all SRTP contexts
+func (c *Client) srtpStatistics() rtp.SRTPStatistics {
+	c.srtpMu.Lock()
+	defer c.srtpMu.Unlock()
+
+	var total rtp.SRTPStatistics
+	for _, context := range c.srtpContexts {
+		stats := context.GetStatistics()
+		total.RTPDecrypted += stats.RTPDecrypted
+		total.RTCPDecrypted += stats.RTCPDecrypted
+		total.AuthFailures += stats.AuthFailures
+		total.Replayed += stats.Replayed
+	}
+	return total
+}
@@ -0,0 +1,176 @@
+package rtsp
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// srtpTestKey is a 30-byte AES-CM master key||salt
+var srtpTestKey = []byte("0123456789abcdefABCDEFGHIJKLMN")
+
+// srtpTestSDP returns an RTP/SAVP video track keyed with srtpTestKey
+func srtpTestSDP() *SDPInfo {
+	return &SDPInfo{Tracks: []SDPTrack{{
+		ControlURL:  "rtsp://example.com/stream/trackID=0",
+		Media:       "video",
+		Protocol:    "RTP/SAVP",
+		PayloadType: 96,
+		Codec:       "H264",
+		ClockRate:   90000,
+		Crypto: []SDPCrypto{
+			{Tag: 1, Suite: "F8_128_HMAC_SHA1_80", Key: srtpTestKey},
+			{Tag: 2, Suite: "AES_CM_128_HMAC_SHA1_80", Key: srtpTestKey},
+		},
+	}}}
+}
+
+// srtpTestSender returns a context protecting packets with the key of srtpTestSDP
+func srtpTestSender(t *testing.T) *rtp.SRTPContext {
+	sender, err := rtp.NewSRTPContext(rtp.SRTP_AES_CM_128_HMAC_SHA1_80, srtpTestKey[:16], srtpTestKey[16:])
+	require.NoError(t, err)
+	return sender
+}
+
+func TestParseSDPInfoCrypto(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(srtpTestKey)
+	sdp := `v=0
+o=- 0 0 IN IP4 127.0.0.1
+s=Test
+t=0 0
+m=video 0 RTP/SAVP 96
+a=rtpmap:96 H264/90000
+a=crypto:1 AES_CM_128_HMAC_SHA1_80 inline:` + key + `|2^31|1:1
+a=crypto:2 AES_CM_128_HMAC_SHA1_32 inline:` + key + `
+a=crypto:3 AES_CM_128_HMAC_SHA1_32 inline:!!notbase64
+a=control:trackID=0`
+
+	info := parseSDPInfo(sdp, "", "rtsp://example.com/stream")
+	require.NotNil(t, info)
+	require.Len(t, info.Tracks, 1)
+
+	video := info.Tracks[0]
+	assert.Equal(t, "RTP/SAVP", video.Protocol)
+	require.Len(t, video.Crypto, 2)
+	assert.Equal(t, SDPCrypto{Tag: 1, Suite: "AES_CM_128_HMAC_SHA1_80", Key: srtpTestKey}, video.Crypto[0])
+	assert.Equal(t, "AES_CM_128_HMAC_SHA1_32", video.Crypto[1].Suite)
+}
+
+// TestClient_SetupSRTP tests that SETUP requests RTP/SAVP for tracks offering SDES keys
+func TestClient_SetupSRTP(t *testing.T) {
+	response := []byte("RTSP/1.0 200 OK\r\n" +
+		"CSeq: 1\r\n" +
+		"Session: 12345678;timeout=60\r\n" +
+		"Transport: RTP/SAVP/TCP;unicast;interleaved=0-1\r\n\r\n")
+	conn := newMockConn(response)
+
+	client := newTCPTestClient(conn)
+	client.url = "rtsp://example.com/stream"
+	client.sdpInfo = srtpTestSDP()
+
+	require.NoError(t, client.Setup())
+	assert.Contains(t, conn.writeBuf.String(), "Transport: RTP/SAVP/TCP;unicast;interleaved=0-1")
+	assert.True(t, client.GetStats().SRTPEnabled)
+
+	// Without a supported suite the track cannot be set up
+	client = newTCPTestClient(newMockConn(response))
+	client.url = "rtsp://example.com/stream"
+	client.sdpInfo = srtpTestSDP()
+	client.sdpInfo.Tracks[0].Crypto = client.sdpInfo.Tracks[0].Crypto[:1]
+	assert.ErrorIs(t, client.Setup(), ErrSRTPNotNegotiated)
+}
+
+// TestClient_ReadPacketSRTP tests transparent decryption, dropping of forged packets
+// and SRTCP authentication before the RTCP handler runs
+func TestClient_ReadPacketSRTP(t *testing.T) {
+	sender := srtpTestSender(t)
+
+	protect := func(seq uint16) []byte {
+		data, err := (&rtp.Packet{PayloadType: 96, SequenceNumber: seq, Timestamp: 3000, SSRC: 0xABCD, Payload: []byte{0x65, byte(seq)}}).Marshal()
+		require.NoError(t, err)
+		protected, err := sender.EncryptRTP(data)
+		require.NoError(t, err)
+		return protected
+	}
+
+	sr, err := (&rtp.SenderReport{SSRC: 0xABCD, NTPTimestamp: 1 << 32, RTPTimestamp: 3000}).Marshal()
+	require.NoError(t, err)
+	srtcp, err := sender.EncryptRTCP(sr)
+	require.NoError(t, err)
+	forgedSRTCP := append([]byte(nil), srtcp...)
+	forgedSRTCP[len(forgedSRTCP)-1] ^= 0xFF
+
+	first := protect(1)
+	forged := append([]byte(nil), protect(2)...)
+	forged[len(forged)-1] ^= 0xFF
+
+	var stream []byte
+	for _, frame := range [][]byte{
+		BuildInterleavedFrame(0, first),
+		BuildInterleavedFrame(1, forgedSRTCP),
+		BuildInterleavedFrame(1, srtcp),
+		BuildInterleavedFrame(0, forged),
+		BuildInterleavedFrame(0, first), // replay
+		BuildInterleavedFrame(0, protect(3)),
+	} {
+		stream = append(stream, frame...)
+	}
+
+	srtpContext, err := newTrackSRTPContext(&srtpTestSDP().Tracks[0])
+	require.NoError(t, err)
+
+	client := newTCPTestClient(newMockConn(stream))
+	client.sdpInfo = srtpTestSDP()
+	client.addSRTPContext(0, srtpContext)
+
+	var handled []rtp.RTCPPacket
+	client.SetRTCPHandler(func(packet rtp.RTCPPacket) error {
+		handled = append(handled, packet)
+		return nil
+	})
+
+	packet, err := client.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), packet.SequenceNumber)
+	assert.Equal(t, []byte{0x65, 1}, packet.Payload)
+
+	packet, err = client.ReadPacket()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(3), packet.SequenceNumber)
+
+	require.Len(t, handled, 1, "forged SRTCP must not reach the handler")
+	report, ok := handled[0].(*rtp.SenderReport)
+	require.True(t, ok)
+	assert.Equal(t, uint32(3000), report.RTPTimestamp)
+
+	stats := client.GetStats()
+	assert.Equal(t, 2, stats.SRTP.RTPDecrypted)
+	assert.Equal(t, 1, stats.SRTP.RTCPDecrypted)
+	assert.Equal(t, 2, stats.SRTP.AuthFailures)
+	assert.Equal(t, 1, stats.SRTP.Replayed)
+}
+
+// TestClient_SendRTCPSRTP tests that receiver reports are sent as SRTCP
+func TestClient_SendRTCPSRTP(t *testing.T) {
+	conn := newMockConn()
+	client := newTCPTestClient(conn)
+	srtpContext, err := newTrackSRTPContext(&srtpTestSDP().Tracks[0])
+	require.NoError(t, err)
+	client.addSRTPContext(0, srtpContext)
+
+	_, err = client.sendReceiverReports(time.Now())
+	require.NoError(t, err)
+
+	frame, err := NewInterleavedReader(&conn.writeBuf).ReadFrame()
+	require.NoError(t, err)
+
+	plain, err := srtpTestSender(t).DecryptRTCP(frame.Payload)
+	require.NoError(t, err)
+	packet, err := rtp.ParseRTCPPacket(plain)
+	require.NoError(t, err)
+	assert.Equal(t, client.GetLocalSSRC(), packet.GetSSRC())
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/logger"
+)
+
+// AudioSink receives the audio units of a stream, e.g. an ADTSWriter or a muxer
+type AudioSink interface {
+	WriteAudio(unit *decoder.AudioUnit) error
+	Close() error
+}
+
+// ADTSWriter writes AAC access units to a raw .aac file, each with an ADTS header
+type ADTSWriter struct {
+	file   *os.File
+	config decoder.AudioSpecificConfig
+	units  int64
+}
+
+// NewADTSWriter creates an .aac file for AAC audio described by config
+func NewADTSWriter(path string, config *decoder.AudioSpecificConfig) (*ADTSWriter, error) {
+	if config == nil {
+		return nil, fmt.Errorf("%w: no AudioSpecificConfig", decoder.ErrInvalidAudioConfig)
+	}
+	if _, err := config.ADTSHeader(0); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AAC file: %w", err)
+	}
+	return &ADTSWriter{file: file, config: *config}, nil
+}
+
+// WriteAudio writes one access unit
+func (w *ADTSWriter) WriteAudio(unit *decoder.AudioUnit) error {
+	header, err := w.config.ADTSHeader(len(unit.Data))
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(append(header, unit.Data...)); err != nil {
+		return fmt.Errorf("failed to write AAC frame: %w", err)
+	}
+	w.units++
+	return nil
+}
+
+// Count returns the number of access units written
+func (w *ADTSWriter) Count() int64 {
+	return w.units
+}
+
+// Close closes the file
+func (w *ADTSWriter) Close() error {
+	return w.file.Close()
+}
+
+// SetAudioSink routes audio units to sink; a previous sink is closed
+func (s *FrameStorage) SetAudioSink(sink AudioSink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	if s.audioSink != nil {
+		err = s.audioSink.Close()
+	}
+	s.audioSink = sink
+	return err
+}
+
+// EnableAACOutput writes AAC audio to audio.aac next to the video recording.
+// The file stays open across reconnects; later calls keep the existing sink.
+func (s *FrameStorage) EnableAACOutput(config *decoder.AudioSpecificConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.audioSink != nil {
+		return nil
+	}
+	path := filepath.Join(s.outputDir, "audio.aac")
+	writer, err := NewADTSWriter(path, config)
+	if err != nil {
+		return err
+	}
+	logger.Info("[FrameStorage] Writing AAC audio to %s (%d Hz, %d channels)", path, config.SampleRate, config.ChannelConfig)
+	s.audioSink = writer
+	return nil
+}
+
+// SaveAudio hands an audio unit to the audio sink; units are dropped when none is set
+func (s *FrameStorage) SaveAudio(unit *decoder.AudioUnit) error {
+	if unit == nil {
+		return ErrNilFrame
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.audioSink == nil {
+		return nil
+	}
+	if err := s.audioSink.WriteAudio(unit); err != nil {
+		return err
+	}
+	s.stats.AudioUnits++
+	return nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink collects audio units like a muxer would
+type recordingSink struct {
+	units  []*decoder.AudioUnit
+	closed bool
+}
+
+func (r *recordingSink) WriteAudio(unit *decoder.AudioUnit) error {
+	r.units = append(r.units, unit)
+	return nil
+}
+
+func (r *recordingSink) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestFrameStorage_AACOutput(t *testing.T) {
+	storage, err := NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+
+	// Units without a sink are dropped
+	unit := &decoder.AudioUnit{Data: []byte{0x21, 0x10, 0x04}, Timestamp: 1024, Codec: decoder.CodecAAC}
+	require.NoError(t, storage.SaveAudio(unit))
+	assert.ErrorIs(t, storage.SaveAudio(nil), ErrNilFrame)
+
+	assert.ErrorIs(t, storage.EnableAACOutput(&decoder.AudioSpecificConfig{ObjectType: 42, FrequencyIndex: 8}), decoder.ErrADTSUnsupported)
+
+	config := &decoder.AudioSpecificConfig{ObjectType: 2, FrequencyIndex: 8, SampleRate: 16000, ChannelConfig: 1, FrameLength: 1024}
+	require.NoError(t, storage.EnableAACOutput(config))
+	require.NoError(t, storage.EnableAACOutput(config), "reconnects keep the open file")
+	require.NoError(t, storage.SaveAudio(unit))
+	require.NoError(t, storage.SaveAudio(unit))
+	assert.Equal(t, int64(2), storage.GetStats().AudioUnits)
+	require.NoError(t, storage.Close())
+
+	data, err := os.ReadFile(filepath.Join(storage.outputDir, "audio.aac"))
+	require.NoError(t, err)
+	header, err := config.ADTSHeader(len(unit.Data))
+	require.NoError(t, err)
+	frame := append(header, unit.Data...)
+	assert.Equal(t, append(append([]byte{}, frame...), frame...), data)
+}
+
+func TestFrameStorage_SetAudioSink(t *testing.T) {
+	storage, err := NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+
+	first, muxer := &recordingSink{}, &recordingSink{}
+	require.NoError(t, storage.SetAudioSink(first))
+	require.NoError(t, storage.SetAudioSink(muxer))
+	assert.True(t, first.closed)
+
+	unit := &decoder.AudioUnit{Data: []byte{0x01}, Timestamp: 90}
+	require.NoError(t, storage.SaveAudio(unit))
+	assert.Equal(t, []*decoder.AudioUnit{unit}, muxer.units)
+
+	require.NoError(t, storage.Close())
+	assert.True(t, muxer.closed)
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/logger"
+)
+
+const (
+	// Ogg page header_type flags (RFC 3533)
+	oggBOS = 0x02
+	oggEOS = 0x04
+
+	oggMaxSegments = 255
+	// oggPageDuration is the audio buffered per page, 1 second at 48 kHz
+	oggPageDuration = decoder.OpusSampleRate
+	// oggMaxTimestampJump bounds the timestamp gaps kept in granule positions;
+	// larger jumps (reconnects, new SSRC) continue right after the previous packet
+	oggMaxTimestampJump = 2 * decoder.OpusSampleRate
+)
+
+// oggCRCTable is the CRC-32 of Ogg pages: polynomial 0x04C11DB7, not reflected
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// oggCRC computes the checksum of a page whose CRC field is zero
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// OggOpusWriter writes Opus packets to an Ogg/Opus (.opus) file (RFC 7845).
+// Granule positions follow the RTP timestamps, so gaps concealed by the
+// depacketizer keep the file aligned with the stream clock.
+type OggOpusWriter struct {
+	file     *os.File
+	serial   uint32
+	sequence uint32
+
+	// Packets of the page being built
+	segments []byte
+	data     []byte
+
+	started       bool
+	lastTimestamp uint32
+	position      int64 // Samples from the first packet's timestamp to its start
+	granule       int64 // Granule position after the last packet
+	pageGranule   int64 // Granule position of the last page written
+	packets       int64
+}
+
+// NewOggOpusWriter creates an .opus file and writes the OpusHead and OpusTags headers
+func NewOggOpusWriter(path string, channels int) (*OggOpusWriter, error) {
+	if channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("invalid Opus channel count: %d", channels)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Opus file: %w", err)
+	}
+	w := &OggOpusWriter{file: file, serial: rand.Uint32()}
+
+	// Identification header; pre-skip is 0 because RTP does not carry the encoder delay
+	head := []byte("OpusHead")
+	head = append(head, 1, byte(channels))
+	head = binary.LittleEndian.AppendUint16(head, 0)
+	head = binary.LittleEndian.AppendUint32(head, decoder.OpusSampleRate)
+	head = binary.LittleEndian.AppendUint16(head, 0)
+	head = append(head, 0)
+
+	vendor := "rtsp-client"
+	tags := []byte("OpusTags")
+	tags = binary.LittleEndian.AppendUint32(tags, uint32(len(vendor)))
+	tags = append(tags, vendor...)
+	tags = binary.LittleEndian.AppendUint32(tags, 0)
+
+	// Each header goes on its own page with granule position 0
+	w.addPacket(head)
+	if err := w.writePage(oggBOS); err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.addPacket(tags)
+	if err := w.writePage(0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteAudio appends one Opus packet
+func (w *OggOpusWriter) WriteAudio(unit *decoder.AudioUnit) error {
+	if unit.Codec != decoder.CodecOpus {
+		return fmt.Errorf("%w: %s unit in Opus file", decoder.ErrUnsupportedAudioCodec, unit.Codec)
+	}
+	duration, err := decoder.OpusPacketDuration(unit.Data)
+	if err != nil {
+		return err
+	}
+
+	if w.started {
+		if delta := int32(unit.Timestamp - w.lastTimestamp); delta > 0 && delta <= oggMaxTimestampJump {
+			w.position += int64(delta)
+		} else {
+			w.position = w.granule
+		}
+	}
+	w.started = true
+	w.lastTimestamp = unit.Timestamp
+
+	if len(w.segments)+len(unit.Data)/255+1 > oggMaxSegments {
+		if err := w.writePage(0); err != nil {
+			return err
+		}
+	}
+	w.addPacket(unit.Data)
+	if end := w.position + int64(duration); end > w.granule {
+		w.granule = end
+	}
+	w.packets++
+
+	if w.granule-w.pageGranule >= oggPageDuration {
+		return w.writePage(0)
+	}
+	return nil
+}
+
+// addPacket laces a packet into the current page
+func (w *OggOpusWriter) addPacket(packet []byte) {
+	for n := len(packet); ; n -= 255 {
+		if n < 255 {
+			w.segments = append(w.segments, byte(n))
+			break
+		}
+		w.segments = append(w.segments, 255)
+	}
+	w.data = append(w.data, packet...)
+}
+
+// writePage writes the buffered packets as one page ending at the current granule position
+func (w *OggOpusWriter) writePage(flags byte) error {
+	page := []byte("OggS")
+	page = append(page, 0, flags)
+	page = binary.LittleEndian.AppendUint64(page, uint64(w.granule))
+	page = binary.LittleEndian.AppendUint32(page, w.serial)
+	page = binary.LittleEndian.AppendUint32(page, w.sequence)
+	page = binary.LittleEndian.AppendUint32(page, 0)
+	page = append(page, byte(len(w.segments)))
+	page = append(page, w.segments...)
+	page = append(page, w.data...)
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+
+	if _, err := w.file.Write(page); err != nil {
+		return fmt.Errorf("failed to write Ogg page: %w", err)
+	}
+	w.sequence++
+	w.pageGranule = w.granule
+	w.segments, w.data = w.segments[:0], w.data[:0]
+	return nil
+}
+
+// Count returns the number of Opus packets written
+func (w *OggOpusWriter) Count() int64 {
+	return w.packets
+}
+
+// Close writes the last page, marked end of stream, and closes the file
+func (w *OggOpusWriter) Close() error {
+	if err := w.writePage(oggEOS); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+// EnableOpusOutput writes Opus audio to audio.opus next to the video recording.
+// The file stays open across reconnects; later calls keep the existing sink.
+func (s *FrameStorage) EnableOpusOutput(channels int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.audioSink != nil {
+		return nil
+	}
+	path := filepath.Join(s.outputDir, "audio.opus")
+	writer, err := NewOggOpusWriter(path, channels)
+	if err != nil {
+		return err
+	}
+	logger.Info("[FrameStorage] Writing Opus audio to %s (%d channels)", path, channels)
+	s.audioSink = writer
+	return nil
+}
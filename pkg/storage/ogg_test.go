@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oggPage is a parsed Ogg page
+type oggPage struct {
+	flags    byte
+	granule  int64
+	sequence uint32
+	packets  [][]byte
+}
+
+// parseOggPages splits an Ogg file into pages, checking each page's CRC
+func parseOggPages(t *testing.T, data []byte) []oggPage {
+	var pages []oggPage
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), 27)
+		require.Equal(t, "OggS", string(data[:4]))
+		segments := int(data[26])
+		lacing := data[27 : 27+segments]
+		size := 27 + segments
+		for _, l := range lacing {
+			size += int(l)
+		}
+		require.GreaterOrEqual(t, len(data), size)
+
+		page := append([]byte{}, data[:size]...)
+		binary.LittleEndian.PutUint32(page[22:], 0)
+		require.Equal(t, binary.LittleEndian.Uint32(data[22:]), oggCRC(page), "page %d CRC", len(pages))
+
+		p := oggPage{flags: data[5], granule: int64(binary.LittleEndian.Uint64(data[6:])), sequence: binary.LittleEndian.Uint32(data[18:])}
+		body := data[27+segments : size]
+		var packet []byte
+		for _, l := range lacing {
+			packet = append(packet, body[:l]...)
+			body = body[l:]
+			if l < 255 {
+				p.packets = append(p.packets, packet)
+				packet = nil
+			}
+		}
+		pages = append(pages, p)
+		data = data[size:]
+	}
+	return pages
+}
+
+func TestOggCRC(t *testing.T) {
+	// Check value of polynomial 0x04C11DB7 with initial value 0 and no reflection or final XOR
+	assert.Equal(t, uint32(0x89A1897F), oggCRC([]byte("123456789")))
+}
+
+func TestOggOpusWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.opus")
+	writer, err := NewOggOpusWriter(path, 2)
+	require.NoError(t, err)
+
+	frame := []byte{31 << 3, 0x01, 0x02} // CELT 20 ms
+	long := make([]byte, 600)            // Laced as 255, 255, 90
+	long[0] = 31 << 3
+	timestamp := uint32(0xFFFFFC40) // Wraps after the first packet
+	for i := 0; i < 60; i++ {
+		data := frame
+		if i == 1 {
+			data = long
+		}
+		require.NoError(t, writer.WriteAudio(&decoder.AudioUnit{Data: data, Timestamp: timestamp, Codec: decoder.CodecOpus}))
+		timestamp += 960
+		if i == 54 {
+			timestamp += 960 // A packet the depacketizer could not conceal
+		}
+	}
+	assert.Equal(t, int64(60), writer.Count())
+	require.NoError(t, writer.Close())
+
+	assert.ErrorIs(t, writer.WriteAudio(&decoder.AudioUnit{Data: []byte{1}, Codec: decoder.CodecPCMU}), decoder.ErrUnsupportedAudioCodec)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	pages := parseOggPages(t, data)
+	require.Len(t, pages, 4)
+
+	require.Len(t, pages[0].packets, 1)
+	head := pages[0].packets[0]
+	assert.Equal(t, byte(oggBOS), pages[0].flags)
+	assert.Equal(t, "OpusHead", string(head[:8]))
+	assert.Equal(t, byte(1), head[8], "version")
+	assert.Equal(t, byte(2), head[9], "channels")
+	assert.Equal(t, uint32(48000), binary.LittleEndian.Uint32(head[12:]))
+	assert.Equal(t, int64(0), pages[0].granule)
+
+	require.Len(t, pages[1].packets, 1)
+	assert.Equal(t, "OpusTags", string(pages[1].packets[0][:8]))
+	assert.Equal(t, int64(0), pages[1].granule)
+
+	// One second of audio per page; the last page ends the stream
+	require.Len(t, pages[2].packets, 50)
+	assert.Equal(t, long, pages[2].packets[1])
+	assert.Equal(t, int64(50*960), pages[2].granule)
+	require.Len(t, pages[3].packets, 10)
+	assert.Equal(t, byte(oggEOS), pages[3].flags)
+	assert.Equal(t, int64(61*960), pages[3].granule, "includes the 20 ms gap")
+
+	for i, page := range pages {
+		assert.Equal(t, uint32(i), page.sequence)
+	}
+}
+
+func TestFrameStorage_OpusOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	storage, err := NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.EnableOpusOutput(1))
+	require.NoError(t, storage.EnableOpusOutput(2), "existing sink is kept")
+	require.NoError(t, storage.SaveAudio(&decoder.AudioUnit{Data: []byte{31 << 3}, Timestamp: 960, Codec: decoder.CodecOpus}))
+	require.NoError(t, storage.Close())
+	assert.Equal(t, int64(1), storage.GetStats().AudioUnits)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "audio.opus"))
+	require.NoError(t, err)
+	pages := parseOggPages(t, data)
+	require.Len(t, pages, 3)
+	assert.Equal(t, byte(1), pages[0].packets[0][9], "mono")
+	assert.Equal(t, int64(960), pages[2].granule)
+}
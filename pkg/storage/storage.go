@@ -39,6 +39,11 @@ type StorageStats struct {
 	KeyFrames       int64
 	CorruptedFrames int64
 	TotalBytes      int64
+	AudioUnits      int64 // Audio units handed to the audio sink
+
+	// ReferenceBrokenFrames are complete frames stored as corrupted because
+	// they refer to lost or corrupted pictures
+	ReferenceBrokenFrames int64
 }
 
 // FrameWithTimestamp holds a frame and its timestamp for synchronization
@@ -53,6 +58,7 @@ type FrameWithTimestamp struct {
 // This allows decoding P-frames (not just I-frames) by maintaining frame history
 type ContinuousDecoder struct {
 	ffmpegPath     string
+	inputFormat    string // ffmpeg demuxer for the elementary stream: h264 or hevc
 	cmd            *exec.Cmd
 	stdin          io.WriteCloser
 	stdout         io.ReadCloser
@@ -86,15 +92,22 @@ type DecodedFrame struct {
 	IsCorrupted bool
 }
 
-// NewContinuousDecoder creates a new continuous decoder session
+// NewContinuousDecoder creates a new continuous decoder session for H.264
 // timestampConverter: function to convert RTP timestamp to Unix epoch nanoseconds (can be nil)
 func NewContinuousDecoder(ffmpegPath, jpegDir, corruptedJpegDir string, spsNAL, ppsNAL []byte, timestampConverter func(uint32) int64) (*ContinuousDecoder, error) {
-	logger.Info("[ContinuousDecoder] Initializing continuous decoder session")
+	return NewContinuousDecoderWithFormat("h264", ffmpegPath, jpegDir, corruptedJpegDir, spsNAL, ppsNAL, timestampConverter)
+}
+
+// NewContinuousDecoderWithFormat creates a continuous decoder session for the given
+// ffmpeg input format ("h264" or "hevc"). For HEVC, spsNAL should start with the VPS.
+func NewContinuousDecoderWithFormat(inputFormat, ffmpegPath, jpegDir, corruptedJpegDir string, spsNAL, ppsNAL []byte, timestampConverter func(uint32) int64) (*ContinuousDecoder, error) {
+	logger.Info("[ContinuousDecoder] Initializing continuous decoder session (%s)", inputFormat)
 	logger.Debug("[ContinuousDecoder] Configuration: jpegDir=%s, corruptedJpegDir=%s", jpegDir, corruptedJpegDir)
 	logger.Debug("[ContinuousDecoder] SPS size: %d bytes, PPS size: %d bytes", len(spsNAL), len(ppsNAL))
 	
 	cd := &ContinuousDecoder{
 		ffmpegPath:        ffmpegPath,
+		inputFormat:       inputFormat,
 		jpegDir:           jpegDir,
 		corruptedJpegDir:  corruptedJpegDir,
 		spsNAL:            spsNAL,
@@ -135,7 +148,7 @@ func (cd *ContinuousDecoder) start() error {
 	}
 	
 	// FFmpeg command: read H.264 from stdin, output JPEG frames to stdout
-	// -f h264: input format is raw H.264 Annex B
+	// -f h264: input format is raw H.264 Annex B (hevc for H.265)
 	// -i pipe:0: read from stdin
 	// -f image2pipe: output format as image sequence
 	// -vcodec mjpeg: encode as MJPEG (JPEG frames)
@@ -143,7 +156,7 @@ func (cd *ContinuousDecoder) start() error {
 	// pipe:1: write to stdout
 	cmd := exec.Command(cd.ffmpegPath,
 		"-loglevel", "error",      // Only show errors
-		"-f", "h264",               // Input format: H.264 Annex B
+		"-f", cd.inputFormat,       // Input format: H.264/H.265 Annex B
 		"-i", "pipe:0",             // Read from stdin
 		"-f", "image2pipe",         // Output as image sequence
 		"-vcodec", "mjpeg",         // Encode as MJPEG/JPEG
@@ -360,7 +373,8 @@ func (cd *ContinuousDecoder) saveDecodedFrames() {
 		}
 		
 		decodedFrame.Timestamp = frameData.Timestamp
-		decodedFrame.IsCorrupted = frameData.Frame != nil && frameData.Frame.IsCorrupted
+		// Reference-broken frames decode, but with artifacts
+		decodedFrame.IsCorrupted = frameData.Frame != nil && frameData.Frame.IsDamaged()
 		
 		if frameData.Frame != nil {
 			logger.Debug("[ContinuousDecoder:saveDecodedFrames] Frame info: timestamp=%d, isKey=%t, corrupted=%t",
@@ -597,6 +611,8 @@ type FrameStorage struct {
 	mu                 sync.RWMutex
 	saveAsJPG          bool
 	ffmpegPath         string
+	codec              decoder.Codec // Follows the saved frames; H.265 needs -f hevc
+	vpsNAL             []byte        // H.265 only
 	spsNAL             []byte
 	ppsNAL             []byte
 	streamFile         *os.File
@@ -611,6 +627,10 @@ type FrameStorage struct {
 	
 	// Timestamp mapping for converting RTP timestamps to Unix epoch
 	timestampMapper *rtp.TimestampMapper
+	mapperMu        sync.RWMutex // Guards timestampMapper replacement
+
+	audioSink   AudioSink          // Receives audio units next to the video recording
+	audioMapper *rtp.TimestampMapper // Audio source's mapper for naming audio files; guarded by mapperMu
 }
 
 // NewFrameStorage creates a new frame storage handler
@@ -743,6 +763,7 @@ func (s *FrameStorage) SetSPSPPS(spsBase64, ppsBase64 string) error {
 		
 		// Write SPS and PPS to stream file first
 		if s.streamFile != nil {
+			s.streamFile.Write(s.vpsNAL)
 			s.streamFile.Write(s.spsNAL)
 			s.streamFile.Write(s.ppsNAL)
 		}
@@ -751,11 +772,12 @@ func (s *FrameStorage) SetSPSPPS(spsBase64, ppsBase64 string) error {
 		if s.useContinuousMode && s.ffmpegPath != "" && s.continuousDecoder == nil {
 			logger.Debug("[FrameStorage] Initializing continuous decoder with SPS/PPS...")
 			var err error
-			s.continuousDecoder, err = NewContinuousDecoder(
+			s.continuousDecoder, err = NewContinuousDecoderWithFormat(
+				s.ffmpegInputFormat(),
 				s.ffmpegPath,
 				s.jpegDir,
 				s.corruptedJpegDir,
-				s.spsNAL,
+				append(append([]byte{}, s.vpsNAL...), s.spsNAL...),
 				s.ppsNAL,
 				s.getUnixTimestamp, // Pass timestamp converter function
 			)
@@ -772,6 +794,37 @@ func (s *FrameStorage) SetSPSPPS(spsBase64, ppsBase64 string) error {
 	return nil
 }
 
+// SetH265ParameterSets sets the VPS, SPS and PPS from base64-encoded SDP
+// parameters (sprop-vps, sprop-sps, sprop-pps) and switches ffmpeg to HEVC input
+func (s *FrameStorage) SetH265ParameterSets(vpsBase64, spsBase64, ppsBase64 string) error {
+	var vpsNAL []byte
+	if vpsBase64 != "" {
+		vpsData, err := decodeBase64(vpsBase64)
+		if err != nil {
+			return fmt.Errorf("failed to decode VPS: %w", err)
+		}
+		vpsNAL = append([]byte{0x00, 0x00, 0x00, 0x01}, vpsData...)
+	}
+
+	// SaveFrame sets and reads the codec under the same lock
+	s.mu.Lock()
+	s.codec = decoder.CodecH265
+	if vpsNAL != nil {
+		s.vpsNAL = vpsNAL
+	}
+	s.mu.Unlock()
+
+	return s.SetSPSPPS(spsBase64, ppsBase64)
+}
+
+// ffmpegInputFormat returns the ffmpeg demuxer for the stream's codec
+func (s *FrameStorage) ffmpegInputFormat() string {
+	if s.codec == decoder.CodecH265 {
+		return "hevc"
+	}
+	return "h264"
+}
+
 // UpdateTimestampMapping updates the RTP to Unix timestamp mapping from an RTCP Sender Report
 func (s *FrameStorage) UpdateTimestampMapping(sr *rtp.SenderReport) {
 	logger.Debug("[FrameStorage:UpdateTimestampMapping] Received RTCP Sender Report: SSRC=0x%x, RTP=%d, NTP=%d, Packets=%d, Octets=%d",
@@ -783,19 +836,36 @@ func (s *FrameStorage) UpdateTimestampMapping(sr *rtp.SenderReport) {
 		logger.Debug("[FrameStorage:UpdateTimestampMapping] NTP Time (human-readable): %s", ntpTime.Format(time.RFC3339Nano))
 	}
 	
-	s.mu.Lock()
-	s.timestampMapper.UpdateFromSR(sr)
-	s.mu.Unlock()
+	s.mapper().UpdateFromSR(sr)
 	
 	logger.Debug("[FrameStorage:UpdateTimestampMapping] Mapping update completed")
 }
 
+// SetTimestampMapper replaces the RTP-to-NTP mapper, e.g. with the client's mapper
+// for the video SSRC so that Sender Reports of other tracks are ignored
+func (s *FrameStorage) SetTimestampMapper(mapper *rtp.TimestampMapper) {
+	if mapper == nil {
+		return
+	}
+	s.mapperMu.Lock()
+	s.timestampMapper = mapper
+	s.mapperMu.Unlock()
+}
+
+// mapper returns the current timestamp mapper
+func (s *FrameStorage) mapper() *rtp.TimestampMapper {
+	s.mapperMu.RLock()
+	defer s.mapperMu.RUnlock()
+	return s.timestampMapper
+}
+
 // getUnixTimestamp converts an RTP timestamp to Unix epoch timestamp (nanoseconds)
 // Returns the Unix timestamp in nanoseconds, or 0 if mapping is not yet available
 // Thread-safe: TimestampMapper is internally thread-safe, so we can call it without locks
 func (s *FrameStorage) getUnixTimestamp(rtpTimestamp uint32) int64 {
 	// Check mapper state
-	mapperState := s.timestampMapper.GetState()
+	mapper := s.mapper()
+	mapperState := mapper.GetState()
 	if !mapperState.Initialized {
 		logger.Warn("[FrameStorage:getUnixTimestamp] Timestamp mapping not available: mapper not initialized yet. Need RTCP Sender Report. Current state: initialized=%t, rtpTimestamp=%d, ntpTimestamp=%d",
 			mapperState.Initialized, mapperState.RTPTimestamp, mapperState.NTPTimestamp)
@@ -807,7 +877,7 @@ func (s *FrameStorage) getUnixTimestamp(rtpTimestamp uint32) int64 {
 	
 	// Convert RTP timestamp to NTP timestamp
 	// TimestampMapper.RTPToNTP is thread-safe internally
-	ntpTimestamp := s.timestampMapper.RTPToNTP(rtpTimestamp)
+	ntpTimestamp := mapper.RTPToNTP(rtpTimestamp)
 	if ntpTimestamp == 0 {
 		logger.Warn("[FrameStorage:getUnixTimestamp] RTPToNTP returned 0 (mapping check failed)")
 		return 0
@@ -839,6 +909,16 @@ func (s *FrameStorage) SaveFrame(frame *decoder.Frame) error {
 		logger.Debug("[FrameStorage:SaveFrame] Releasing lock for timestamp=%d", frame.Timestamp)
 		s.mu.Unlock()
 	}()
+	s.codec = frame.Codec
+
+	if frame.Codec == decoder.CodecMJPEG {
+		// RTP/JPEG frames are complete images already; no ffmpeg involved
+		if err := s.saveJPEGFrame(frame); err != nil {
+			return err
+		}
+		s.updateStats(frame)
+		return nil
+	}
 
 	// Save each frame as individual H.264 file
 	// Also maintain continuous stream for potential video playback
@@ -851,12 +931,12 @@ func (s *FrameStorage) SaveFrame(frame *decoder.Frame) error {
 	
 	// Always save individual frame as H.264 in H.264 directory
 	var h264TargetDir string
-	if frame.IsCorrupted {
+	if frame.IsDamaged() {
 		h264TargetDir = s.corruptedFramesDir
 	} else {
 		h264TargetDir = s.h264Dir
 	}
-	h264Filename := s.getFilenameH264(frame.Timestamp, frame.IsCorrupted)
+	h264Filename := s.getFilenameH264(frame.Timestamp, frame.IsDamaged())
 	h264Path := filepath.Join(h264TargetDir, h264Filename)
 	logger.Debug("[FrameStorage:SaveFrame] Saving H.264 frame: %s (timestamp: %d, size: %d bytes, keyframe: %t)", h264Path, frame.Timestamp, len(frame.Data), frame.IsKey)
 	if err := os.WriteFile(h264Path, frame.Data, 0644); err != nil {
@@ -875,7 +955,7 @@ func (s *FrameStorage) SaveFrame(frame *decoder.Frame) error {
 			// Frame-by-frame decoding (original approach - keyframes only)
 			// Only decode keyframes - they are self-contained and can be decoded independently
 			// P-frames depend on previous frames and cannot be decoded without reference frames
-			if !frame.IsCorrupted && frame.IsKey {
+			if !frame.IsDamaged() && frame.IsKey {
 				// Save JPEG to JPEG directory
 				jpgFilename := s.getFilenameJPEG(frame.Timestamp, false)
 				jpgPath := filepath.Join(s.jpegDir, jpgFilename)
@@ -887,7 +967,12 @@ func (s *FrameStorage) SaveFrame(frame *decoder.Frame) error {
 		}
 	}
 
-	// Update statistics
+	s.updateStats(frame)
+	return nil
+}
+
+// updateStats accounts a saved frame; the caller must hold s.mu
+func (s *FrameStorage) updateStats(frame *decoder.Frame) {
 	s.stats.TotalFrames++
 	s.stats.TotalBytes += int64(len(frame.Data))
 	if frame.IsKey {
@@ -895,8 +980,22 @@ func (s *FrameStorage) SaveFrame(frame *decoder.Frame) error {
 	}
 	if frame.IsCorrupted {
 		s.stats.CorruptedFrames++
+	} else if frame.IsReferenceBroken {
+		s.stats.ReferenceBrokenFrames++
 	}
+}
 
+// saveJPEGFrame writes a depacketized JPEG image straight to the JPEG directory
+func (s *FrameStorage) saveJPEGFrame(frame *decoder.Frame) error {
+	targetDir := s.jpegDir
+	if frame.IsCorrupted {
+		targetDir = s.corruptedJpegDir
+	}
+	jpgPath := filepath.Join(targetDir, s.getFilenameJPEG(frame.Timestamp, frame.IsCorrupted))
+	logger.Debug("[FrameStorage:SaveFrame] Saving JPEG frame: %s (timestamp: %d, size: %d bytes)", jpgPath, frame.Timestamp, len(frame.Data))
+	if err := os.WriteFile(jpgPath, frame.Data, 0644); err != nil {
+		return fmt.Errorf("failed to write JPEG file: %w", err)
+	}
 	return nil
 }
 
@@ -910,6 +1009,9 @@ func (s *FrameStorage) GetStats() StorageStats {
 		KeyFrames:       s.stats.KeyFrames,
 		CorruptedFrames: s.stats.CorruptedFrames,
 		TotalBytes:      s.stats.TotalBytes,
+		AudioUnits:      s.stats.AudioUnits,
+
+		ReferenceBrokenFrames: s.stats.ReferenceBrokenFrames,
 	}
 }
 
@@ -945,6 +1047,13 @@ func (s *FrameStorage) Close() error {
 		// Optionally remove the stream file after closing
 		// os.Remove(s.streamFilePath)
 	}
+
+	if s.audioSink != nil {
+		if err := s.audioSink.Close(); err != nil {
+			logger.Warn("[FrameStorage] Error closing audio sink: %v", err)
+		}
+		s.audioSink = nil
+	}
 	return nil
 }
 
@@ -964,13 +1073,19 @@ func (s *FrameStorage) getFilename(timestamp uint32, corrupted bool) string {
 // getFilenameH264 generates a filename for H.264 using NTP timestamp in nanoseconds and RTP timestamp
 // Format: NTPNANOSECONDS.RTPTIMESTAMP.h264 (e.g., 1761897425257387428.1234567890.h264)
 // This preserves both NTP (wall-clock) time and RTP timestamp for correlation
+// H.265 frames use the .h265 extension
 func (s *FrameStorage) getFilenameH264(rtpTimestamp uint32, corrupted bool) string {
+	ext := ".h264"
+	if s.codec == decoder.CodecH265 {
+		ext = ".h265"
+	}
+
 	unixNanos := s.getUnixTimestamp(rtpTimestamp)
 	if unixNanos == 0 {
 		// Fallback to RTP timestamp if mapping not available yet
-		filename := fmt.Sprintf("%d_corrupted.h264", rtpTimestamp)
+		filename := fmt.Sprintf("%d_corrupted%s", rtpTimestamp, ext)
 		if !corrupted {
-			filename = fmt.Sprintf("%d.h264", rtpTimestamp)
+			filename = fmt.Sprintf("%d%s", rtpTimestamp, ext)
 		}
 		logger.Debug("[FrameStorage:getFilenameH264] Using RTP timestamp (mapping not available): %s (RTP timestamp: %d)", filename, rtpTimestamp)
 		return filename
@@ -978,9 +1093,9 @@ func (s *FrameStorage) getFilenameH264(rtpTimestamp uint32, corrupted bool) stri
 	
 	// Format: ntpinnanoseconds.rtptimestamp.h264
 	// This preserves full nanosecond precision from RTCP NTP timestamps and RTP timestamp
-	filename := fmt.Sprintf("%d.%d_corrupted.h264", unixNanos, rtpTimestamp)
+	filename := fmt.Sprintf("%d.%d_corrupted%s", unixNanos, rtpTimestamp, ext)
 	if !corrupted {
-		filename = fmt.Sprintf("%d.%d.h264", unixNanos, rtpTimestamp)
+		filename = fmt.Sprintf("%d.%d%s", unixNanos, rtpTimestamp, ext)
 	}
 	logger.Debug("[FrameStorage:getFilenameH264] Using NTP (nanoseconds).RTP timestamp: %s (NTP: %d, RTP: %d)", filename, unixNanos, rtpTimestamp)
 	return filename
@@ -993,7 +1108,7 @@ func (s *FrameStorage) getFilenameJPEG(rtpTimestamp uint32, corrupted bool) stri
 	unixNanos := s.getUnixTimestamp(rtpTimestamp)
 	if unixNanos == 0 {
 		// Fallback to RTP timestamp if mapping not available yet
-		mapperState := s.timestampMapper.GetState()
+		mapperState := s.mapper().GetState()
 		logger.Warn("[FrameStorage:getFilenameJPEG] Cannot use Unix timestamp - mapping not available: RTP timestamp=%d, mapper initialized=%t",
 			rtpTimestamp, mapperState.Initialized)
 		if !mapperState.Initialized {
@@ -1038,6 +1153,7 @@ func (s *FrameStorage) decodeFrameToJPEG(frame *decoder.Frame, outputPath string
 	// Frame data already contains start codes and NAL units (IDR slice)
 	// SPS/PPS are prepended to provide decoder configuration
 	var h264Stream bytes.Buffer
+	h264Stream.Write(s.vpsNAL)
 	h264Stream.Write(s.spsNAL)
 	h264Stream.Write(s.ppsNAL)
 	h264Stream.Write(frame.Data)
@@ -1053,7 +1169,7 @@ func (s *FrameStorage) decodeFrameToJPEG(frame *decoder.Frame, outputPath string
 	// -loglevel warning: show warnings for debugging decode issues
 	cmd := exec.Command(s.ffmpegPath,
 		"-loglevel", "warning",       // Show warnings for debugging
-		"-f", "h264",                  // H.264 Annex B format (with start codes)
+		"-f", s.ffmpegInputFormat(),   // H.264/H.265 Annex B format (with start codes)
 		"-i", "pipe:0",                // Read from stdin
 		"-vframes", "1",               // Decode only 1 frame
 		"-vsync", "0",                 // Don't duplicate/drop frames
@@ -1094,7 +1210,7 @@ func (s *FrameStorage) extractSnapshotFromStream(outputPath string, timestamp ui
 	// -q:v 2: high quality
 	cmd := exec.Command(s.ffmpegPath,
 		"-ss", timePos,
-		"-f", "h264",
+		"-f", s.ffmpegInputFormat(),
 		"-i", s.streamFilePath,
 		"-vframes", "1",
 		"-q:v", "2",
@@ -1,9 +1,11 @@
 package storage
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/rtsp-client/pkg/decoder"
 	"github.com/rtsp-client/pkg/rtp"
@@ -291,6 +293,26 @@ func TestFrameStorage_getFilenameJPEG(t *testing.T) {
 	}
 }
 
+func TestFrameStorage_SetTimestampMapper(t *testing.T) {
+	storage := &FrameStorage{
+		timestampMapper: rtp.NewTimestampMapper(),
+	}
+
+	// Only the video source's mapper names files; 8 kHz audio SRs go elsewhere
+	var mappers rtp.TimestampMapperSet
+	mappers.SetClockRate(0xA, 8000)
+	ntp := rtp.TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	mappers.UpdateFromSR(&rtp.SenderReport{SSRC: 0xA, NTPTimestamp: ntp, RTPTimestamp: 0})
+	mappers.UpdateFromSR(&rtp.SenderReport{SSRC: 0xB, NTPTimestamp: ntp, RTPTimestamp: 0})
+
+	storage.SetTimestampMapper(mappers.Mapper(0xB))
+	storage.SetTimestampMapper(nil)
+
+	expected := time.Date(2026, 1, 1, 0, 0, 1, 0, time.UTC).UnixNano()
+	assert.InDelta(t, expected, storage.getUnixTimestamp(90000), 1000)
+	assert.Equal(t, fmt.Sprintf("%d.90000.jpg", storage.getUnixTimestamp(90000)), storage.getFilenameJPEG(90000, false))
+}
+
 func TestFrameStorage_decodeFrameToJPEG_NoFFmpeg(t *testing.T) {
 	storage := &FrameStorage{
 		ffmpegPath: "", // No ffmpeg
@@ -326,3 +348,125 @@ func TestFrameStorage_decodeFrameToJPEG_NoSPSPPS(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "SPS/PPS not available")
 }
+
+func TestFrameStorage_H265(t *testing.T) {
+	storage, err := NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+	defer storage.Close()
+	assert.Equal(t, "h264", storage.ffmpegInputFormat())
+
+	require.NoError(t, storage.SetH265ParameterSets("QAEMAQ==", "QgEBYA==", "RAHBcg=="))
+	assert.Equal(t, "hevc", storage.ffmpegInputFormat())
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x40, 0x01, 0x0c, 0x01}, storage.vpsNAL)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x42, 0x01, 0x01, 0x60}, storage.spsNAL)
+	assert.Error(t, storage.SetH265ParameterSets("!", "QgEBYA==", "RAHBcg=="))
+
+	frame := &decoder.Frame{
+		Data:      []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf},
+		Timestamp: 3000,
+		IsKey:     true,
+		Codec:     decoder.CodecH265,
+	}
+	require.NoError(t, storage.SaveFrame(frame))
+	_, err = os.Stat(filepath.Join(storage.h264Dir, "3000.h265"))
+	assert.NoError(t, err, "H.265 frames use the .h265 extension")
+}
+
+// TestFrameStorage_H265ConcurrentParameterSets tests applying parameter sets while frames are saved (run with -race)
+func TestFrameStorage_H265ConcurrentParameterSets(t *testing.T) {
+	storage, err := NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			frame := &decoder.Frame{
+				Data:      []byte{0x00, 0x00, 0x00, 0x01, 0x26, 0x01, 0xaf},
+				Timestamp: uint32(i * 3000),
+				IsKey:     true,
+				Codec:     decoder.CodecH265,
+			}
+			assert.NoError(t, storage.SaveFrame(frame))
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		require.NoError(t, storage.SetH265ParameterSets("QAEMAQ==", "QgEBYA==", "RAHBcg=="))
+	}
+	<-done
+
+	assert.Equal(t, "hevc", storage.ffmpegInputFormat())
+}
+
+func TestFrameStorage_MJPEG(t *testing.T) {
+	storage, err := NewFrameStorageWithFormat(t.TempDir(), false)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	image := []byte{0xFF, 0xD8, 0x01, 0x02, 0xFF, 0xD9}
+	require.NoError(t, storage.SaveFrame(&decoder.Frame{Data: image, Timestamp: 3000, IsKey: true, Codec: decoder.CodecMJPEG}))
+	require.NoError(t, storage.SaveFrame(&decoder.Frame{Data: image, Timestamp: 6000, IsKey: true, IsCorrupted: true, Codec: decoder.CodecMJPEG}))
+
+	// Written as-is to jpeg/, even without ffmpeg
+	data, err := os.ReadFile(filepath.Join(storage.jpegDir, "3000.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, image, data)
+	_, err = os.Stat(filepath.Join(storage.corruptedJpegDir, "6000_corrupted.jpg"))
+	assert.NoError(t, err)
+
+	entries, err := os.ReadDir(storage.h264Dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	stats := storage.GetStats()
+	assert.Equal(t, int64(2), stats.TotalFrames)
+	assert.Equal(t, int64(1), stats.CorruptedFrames)
+}
+
+// TestFrameStorage_ReferenceBroken tests that reference-broken frames are stored as corrupted
+func TestFrameStorage_ReferenceBroken(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewFrameStorageWithFormat(tempDir, false)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	frame := &decoder.Frame{Data: []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a}, Timestamp: 3000, Type: decoder.FrameTypeP, IsReferenceBroken: true}
+	require.NoError(t, storage.SaveFrame(frame))
+
+	_, err = os.Stat(filepath.Join(tempDir, "corrupted_frames", "3000_corrupted.h264"))
+	assert.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDir, "h264", "3000.h264"))
+	assert.True(t, os.IsNotExist(err))
+
+	stats := storage.GetStats()
+	assert.Equal(t, int64(1), stats.ReferenceBrokenFrames)
+	assert.Zero(t, stats.CorruptedFrames)
+}
+
+// TestFrameStorage_FrameByFrameSkipsDamagedKeyframes tests that keyframe-only
+// JPEG decoding skips keyframes that are reference-broken
+func TestFrameStorage_FrameByFrameSkipsDamagedKeyframes(t *testing.T) {
+	tempDir := t.TempDir()
+	storage, err := NewFrameStorageWithOptions(tempDir, true, false)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	// Stand-in for ffmpeg that writes its last argument, the output path
+	fakeFFmpeg := filepath.Join(tempDir, "ffmpeg")
+	require.NoError(t, os.WriteFile(fakeFFmpeg, []byte("#!/bin/sh\nfor a; do out=$a; done\ncat > /dev/null\necho jpeg > \"$out\"\n"), 0755))
+	storage.saveAsJPG, storage.ffmpegPath = true, fakeFFmpeg
+	storage.spsNAL = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xc0, 0x1f}
+	storage.ppsNAL = []byte{0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x3c, 0x80}
+
+	data := []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a}
+	require.NoError(t, storage.SaveFrame(&decoder.Frame{Data: data, Timestamp: 3000, IsKey: true, IsReferenceBroken: true}))
+	entries, err := os.ReadDir(storage.jpegDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "reference-broken keyframe decoded")
+
+	require.NoError(t, storage.SaveFrame(&decoder.Frame{Data: data, Timestamp: 6000, IsKey: true}))
+	entries, err = os.ReadDir(storage.jpegDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/logger"
+	"github.com/rtsp-client/pkg/rtp"
+)
+
+const (
+	// WAVE format tags
+	wavFormatPCM   = 1
+	wavFormatALaw  = 6
+	wavFormatMuLaw = 7
+)
+
+// WAVFormat describes the audio a WAVWriter records
+type WAVFormat struct {
+	Codec      decoder.Codec // CodecPCMU, CodecPCMA or CodecL16
+	SampleRate int
+	Channels   int
+	Transcode  bool // Store G.711 as 16-bit PCM instead of μ-law/A-law
+}
+
+// pcm16 reports whether samples are stored as 16-bit little-endian PCM
+func (f WAVFormat) pcm16() bool {
+	return f.Transcode || f.Codec == decoder.CodecL16
+}
+
+// WAVWriter records PCM, μ-law or A-law audio to a WAV file. The RIFF sizes
+// are filled in by Close.
+type WAVWriter struct {
+	file      *os.File
+	path      string
+	format    WAVFormat
+	dataBytes int64
+}
+
+// NewWAVWriter creates a WAV file for format
+func NewWAVWriter(path string, format WAVFormat) (*WAVWriter, error) {
+	switch format.Codec {
+	case decoder.CodecPCMU, decoder.CodecPCMA, decoder.CodecL16:
+	default:
+		return nil, fmt.Errorf("%w: %s", decoder.ErrUnsupportedAudioCodec, format.Codec)
+	}
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, fmt.Errorf("invalid WAV format: %d Hz, %d channels", format.SampleRate, format.Channels)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WAV file: %w", err)
+	}
+	w := &WAVWriter{file: file, path: path, format: format}
+	if _, err := file.Write(w.header()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+	return w, nil
+}
+
+// header builds the RIFF header for the data written so far. Non-PCM formats
+// carry the extended fmt chunk and a fact chunk.
+func (w *WAVWriter) header() []byte {
+	formatTag, bitsPerSample := uint16(wavFormatPCM), uint16(16)
+	if !w.format.pcm16() {
+		formatTag, bitsPerSample = wavFormatMuLaw, 8
+		if w.format.Codec == decoder.CodecPCMA {
+			formatTag = wavFormatALaw
+		}
+	}
+	blockAlign := uint16(w.format.Channels) * bitsPerSample / 8
+
+	fmtChunk := binary.LittleEndian.AppendUint16(nil, formatTag)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(w.format.Channels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(w.format.SampleRate))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(w.format.SampleRate)*uint32(blockAlign))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, blockAlign)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, bitsPerSample)
+
+	var chunks []byte
+	if formatTag == wavFormatPCM {
+		chunks = appendRIFFChunk(chunks, "fmt ", fmtChunk)
+	} else {
+		chunks = appendRIFFChunk(chunks, "fmt ", binary.LittleEndian.AppendUint16(fmtChunk, 0))
+		chunks = appendRIFFChunk(chunks, "fact", binary.LittleEndian.AppendUint32(nil, uint32(w.dataBytes/int64(blockAlign))))
+	}
+
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(int64(4+len(chunks)+8)+w.dataBytes))
+	header = append(header, "WAVE"...)
+	header = append(header, chunks...)
+	header = append(header, "data"...)
+	return binary.LittleEndian.AppendUint32(header, uint32(w.dataBytes))
+}
+
+// appendRIFFChunk appends a chunk with its id and size
+func appendRIFFChunk(dst []byte, id string, body []byte) []byte {
+	dst = append(dst, id...)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(body)))
+	return append(dst, body...)
+}
+
+// WriteAudio appends one audio unit, transcoding it to PCM16 if configured
+func (w *WAVWriter) WriteAudio(unit *decoder.AudioUnit) error {
+	data := unit.Data
+	if w.format.pcm16() {
+		var err error
+		if data, err = decoder.ToPCM16LE(unit.Codec, data); err != nil {
+			return err
+		}
+	} else if unit.Codec != w.format.Codec {
+		return fmt.Errorf("%w: %s unit in %s WAV file", decoder.ErrUnsupportedAudioCodec, unit.Codec, w.format.Codec)
+	}
+
+	if _, err := w.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write WAV data: %w", err)
+	}
+	w.dataBytes += int64(len(data))
+	return nil
+}
+
+// Path returns the current file path
+func (w *WAVWriter) Path() string {
+	return w.path
+}
+
+// Rename moves the file while it is being written
+func (w *WAVWriter) Rename(path string) error {
+	if err := os.Rename(w.path, path); err != nil {
+		return fmt.Errorf("failed to rename WAV file: %w", err)
+	}
+	w.path = path
+	return nil
+}
+
+// Close fills in the RIFF sizes and closes the file
+func (w *WAVWriter) Close() error {
+	if _, err := w.file.WriteAt(w.header(), 0); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to update WAV header: %w", err)
+	}
+	return w.file.Close()
+}
+
+// wavRecording is the FrameStorage audio sink for WAV output. The file is
+// opened at the first unit and named NTPNANOSECONDS.RTPTIMESTAMP.wav after that
+// unit's wall-clock time, like JPEG frames; until the audio source's Sender
+// Report arrives it is named RTPTIMESTAMP.wav and renamed once the mapping is known.
+type wavRecording struct {
+	storage        *FrameStorage
+	format         WAVFormat
+	writer         *WAVWriter
+	startTimestamp uint32
+	named          bool
+}
+
+// WriteAudio opens or renames the file as needed and appends the unit
+func (r *wavRecording) WriteAudio(unit *decoder.AudioUnit) error {
+	if r.writer == nil {
+		r.startTimestamp = unit.Timestamp
+		name, named := r.storage.getFilenameAudio(r.startTimestamp, ".wav")
+		writer, err := NewWAVWriter(filepath.Join(r.storage.outputDir, name), r.format)
+		if err != nil {
+			return err
+		}
+		logger.Info("[FrameStorage] Writing %s audio to %s (%d Hz, %d channels)", r.format.Codec, writer.Path(), r.format.SampleRate, r.format.Channels)
+		r.writer, r.named = writer, named
+	} else if !r.named {
+		if name, named := r.storage.getFilenameAudio(r.startTimestamp, ".wav"); named {
+			if err := r.writer.Rename(filepath.Join(r.storage.outputDir, name)); err != nil {
+				logger.Warn("[FrameStorage] %v", err)
+			}
+			r.named = true
+		}
+	}
+	return r.writer.WriteAudio(unit)
+}
+
+// Close closes the WAV file if one was opened
+func (r *wavRecording) Close() error {
+	if r.writer == nil {
+		return nil
+	}
+	return r.writer.Close()
+}
+
+// EnableWAVOutput records PCM audio to a WAV file next to the video recording.
+// The file stays open across reconnects; later calls keep the existing sink.
+func (s *FrameStorage) EnableWAVOutput(format WAVFormat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.audioSink != nil {
+		return nil
+	}
+	switch format.Codec {
+	case decoder.CodecPCMU, decoder.CodecPCMA, decoder.CodecL16:
+	default:
+		return fmt.Errorf("%w: %s", decoder.ErrUnsupportedAudioCodec, format.Codec)
+	}
+	s.audioSink = &wavRecording{storage: s, format: format}
+	return nil
+}
+
+// SetAudioTimestampMapper sets the mapper of the audio source, used to name
+// audio files on the same NTP time base as video frames
+func (s *FrameStorage) SetAudioTimestampMapper(mapper *rtp.TimestampMapper) {
+	s.mapperMu.Lock()
+	defer s.mapperMu.Unlock()
+	s.audioMapper = mapper
+}
+
+// getFilenameAudio names an audio file after the NTP time of its first sample;
+// named is false while the audio source's Sender Report mapping is unknown
+func (s *FrameStorage) getFilenameAudio(rtpTimestamp uint32, ext string) (filename string, named bool) {
+	s.mapperMu.RLock()
+	mapper := s.audioMapper
+	s.mapperMu.RUnlock()
+
+	if mapper != nil && mapper.GetState().Initialized {
+		if ntpTimestamp := mapper.RTPToNTP(rtpTimestamp); ntpTimestamp != 0 {
+			return fmt.Sprintf("%d.%d%s", rtp.NTPToTime(ntpTimestamp).UnixNano(), rtpTimestamp, ext), true
+		}
+	}
+	return fmt.Sprintf("%d%s", rtpTimestamp, ext), false
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rtsp-client/pkg/decoder"
+	"github.com/rtsp-client/pkg/rtp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAVWriter(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     WAVFormat
+		input      []byte
+		formatTag  uint16
+		bits       uint16
+		dataOffset int
+		data       []byte
+	}{
+		{
+			name:       "μ-law stored as is",
+			format:     WAVFormat{Codec: decoder.CodecPCMU, SampleRate: 8000, Channels: 1},
+			input:      []byte{0xFF, 0x00},
+			formatTag:  wavFormatMuLaw,
+			bits:       8,
+			dataOffset: 58,
+			data:       []byte{0xFF, 0x00},
+		},
+		{
+			name:       "A-law transcoded to PCM16",
+			format:     WAVFormat{Codec: decoder.CodecPCMA, SampleRate: 8000, Channels: 1, Transcode: true},
+			input:      []byte{0xD5, 0xAA},
+			formatTag:  wavFormatPCM,
+			bits:       16,
+			dataOffset: 44,
+			data:       []byte{0x08, 0x00, 0x00, 0x7E},
+		},
+		{
+			name:       "L16 byte-swapped",
+			format:     WAVFormat{Codec: decoder.CodecL16, SampleRate: 44100, Channels: 2},
+			input:      []byte{0x12, 0x34, 0x56, 0x78},
+			formatTag:  wavFormatPCM,
+			bits:       16,
+			dataOffset: 44,
+			data:       []byte{0x34, 0x12, 0x78, 0x56},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audio.wav")
+			writer, err := NewWAVWriter(path, tt.format)
+			require.NoError(t, err)
+			unit := &decoder.AudioUnit{Data: tt.input, Codec: tt.format.Codec}
+			require.NoError(t, writer.WriteAudio(unit))
+			require.NoError(t, writer.WriteAudio(unit))
+			require.NoError(t, writer.Close())
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Len(t, data, tt.dataOffset+2*len(tt.data))
+			assert.Equal(t, "RIFF", string(data[:4]))
+			assert.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:]))
+			assert.Equal(t, "WAVE", string(data[8:12]))
+			assert.Equal(t, tt.formatTag, binary.LittleEndian.Uint16(data[20:]))
+			assert.Equal(t, uint16(tt.format.Channels), binary.LittleEndian.Uint16(data[22:]))
+			assert.Equal(t, uint32(tt.format.SampleRate), binary.LittleEndian.Uint32(data[24:]))
+			assert.Equal(t, tt.bits, binary.LittleEndian.Uint16(data[34:]))
+			assert.Equal(t, "data", string(data[tt.dataOffset-8:tt.dataOffset-4]))
+			assert.Equal(t, uint32(2*len(tt.data)), binary.LittleEndian.Uint32(data[tt.dataOffset-4:]))
+			assert.Equal(t, append(append([]byte{}, tt.data...), tt.data...), data[tt.dataOffset:])
+		})
+	}
+
+	_, err := NewWAVWriter(filepath.Join(t.TempDir(), "x.wav"), WAVFormat{Codec: decoder.CodecAAC, SampleRate: 8000, Channels: 1})
+	assert.ErrorIs(t, err, decoder.ErrUnsupportedAudioCodec)
+}
+
+func TestFrameStorage_WAVOutput(t *testing.T) {
+	outputDir := t.TempDir()
+	storage, err := NewFrameStorageWithFormat(outputDir, false)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.EnableWAVOutput(WAVFormat{Codec: decoder.CodecPCMU, SampleRate: 8000, Channels: 1}))
+	mapper := rtp.NewTimestampMapperWithClockRate(8000)
+	storage.SetAudioTimestampMapper(mapper)
+
+	// No Sender Report yet: named by RTP timestamp
+	unit := &decoder.AudioUnit{Data: make([]byte, 160), Timestamp: 16000, Codec: decoder.CodecPCMU}
+	require.NoError(t, storage.SaveAudio(unit))
+	_, err = os.Stat(filepath.Join(outputDir, "16000.wav"))
+	require.NoError(t, err)
+
+	// The audio SR maps RTP 0 to midnight; the first sample was 2s later
+	mapper.UpdateFromSR(&rtp.SenderReport{SSRC: 0x711, NTPTimestamp: rtp.TimeToNTP(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)), RTPTimestamp: 0})
+	require.NoError(t, storage.SaveAudio(&decoder.AudioUnit{Data: make([]byte, 160), Timestamp: 16160, Codec: decoder.CodecPCMU}))
+	require.NoError(t, storage.Close())
+
+	name, named := storage.getFilenameAudio(16000, ".wav")
+	require.True(t, named)
+	assert.Equal(t, fmt.Sprintf("%d.16000.wav", time.Date(2026, 1, 1, 0, 0, 2, 0, time.UTC).UnixNano()), name)
+	info, err := os.Stat(filepath.Join(outputDir, name))
+	require.NoError(t, err)
+	assert.Equal(t, int64(58+320), info.Size())
+	_, err = os.Stat(filepath.Join(outputDir, "16000.wav"))
+	assert.True(t, os.IsNotExist(err))
+}